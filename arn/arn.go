@@ -0,0 +1,153 @@
+// Package arn parses and builds Amazon Resource Names, the identifiers
+// of AWS resources across services:
+//
+//	arn:partition:service:region:account-id:resource
+//
+// as in "arn:aws:sqs:us-east-1:123456789012:queue1" or
+// "arn:aws:iam::123456789012:user/division/alice".
+package arn
+
+import (
+	"fmt"
+	"github.com/crowdmob/goamz/aws"
+	"regexp"
+	"strings"
+)
+
+// ARN is an Amazon Resource Name split into its fields.
+type ARN struct {
+	// Partition is the partition of the resource: "aws", "aws-cn" or
+	// "aws-us-gov".
+	Partition string
+
+	// Service names the service owning the resource, as in "iam" or
+	// "sns".
+	Service string
+
+	// Region is the region of the resource, empty for resources of
+	// global services such as IAM and S3.
+	Region string
+
+	// AccountID is the ID of the account owning the resource, empty for
+	// S3 buckets.
+	AccountID string
+
+	// Resource identifies the resource within the service, as in
+	// "user/division/alice". See ResourceType and ResourceID.
+	Resource string
+}
+
+var (
+	partitionPattern = regexp.MustCompile(`^aws(-[a-z]+)*$`)
+	servicePattern   = regexp.MustCompile(`^[a-z0-9-]+$`)
+	regionPattern    = regexp.MustCompile(`^[a-z]{2}(-[a-z]+)+-\d+$`)
+
+	// Accounts are 12 digit IDs, or names such as "aws" in the ARNs of
+	// managed IAM policies.
+	accountPattern = regexp.MustCompile(`^(\d{12}|[a-z][a-z0-9-]*)$`)
+)
+
+// New returns the ARN of resource, owned by the given account and
+// service in region, or globally if region is empty. The partition is
+// the one of region, "aws" by default.
+func New(service, region, accountID, resource string) ARN {
+	partition := "aws"
+	if p := aws.PartitionForRegion(region); p != nil {
+		partition = p.ID
+	}
+	return ARN{
+		Partition: partition,
+		Service:   service,
+		Region:    region,
+		AccountID: accountID,
+		Resource:  resource,
+	}
+}
+
+// Parse parses s as an ARN, and checks that its fields are valid.
+func Parse(s string) (ARN, error) {
+	fields := strings.SplitN(s, ":", 6)
+	if len(fields) != 6 || fields[0] != "arn" {
+		return ARN{}, fmt.Errorf("invalid ARN %q: not of the form arn:partition:service:region:account-id:resource", s)
+	}
+	a := ARN{
+		Partition: fields[1],
+		Service:   fields[2],
+		Region:    fields[3],
+		AccountID: fields[4],
+		Resource:  fields[5],
+	}
+	if err := a.Validate(); err != nil {
+		return ARN{}, err
+	}
+	return a, nil
+}
+
+// IsARN reports whether s is a valid ARN.
+func IsARN(s string) bool {
+	_, err := Parse(s)
+	return err == nil
+}
+
+// Validate checks that the fields of a are valid, and that its region
+// belongs to its partition.
+func (a ARN) Validate() error {
+	var reason string
+	switch {
+	case !partitionPattern.MatchString(a.Partition):
+		reason = fmt.Sprintf("invalid partition %q", a.Partition)
+	case !servicePattern.MatchString(a.Service):
+		reason = fmt.Sprintf("invalid service %q", a.Service)
+	case a.Region != "" && !regionPattern.MatchString(a.Region):
+		reason = fmt.Sprintf("invalid region %q", a.Region)
+	case a.AccountID != "" && !accountPattern.MatchString(a.AccountID):
+		reason = fmt.Sprintf("invalid account ID %q", a.AccountID)
+	case a.Resource == "":
+		reason = "missing resource"
+	}
+	if reason == "" && a.Region != "" {
+		if p := aws.PartitionForRegion(a.Region); p != nil && p.ID != a.Partition {
+			reason = fmt.Sprintf("region %q is not in partition %q", a.Region, a.Partition)
+		}
+	}
+	if reason != "" {
+		return fmt.Errorf("invalid ARN %q: %s", a.String(), reason)
+	}
+	return nil
+}
+
+// String returns the ARN in its usual form.
+func (a ARN) String() string {
+	return "arn:" + a.Partition + ":" + a.Service + ":" + a.Region + ":" + a.AccountID + ":" + a.Resource
+}
+
+// untyped holds the services naming their resources without a type, as
+// in "arn:aws:sns:us-east-1:123456789012:topic1".
+var untyped = map[string]bool{
+	"s3":  true,
+	"sns": true,
+	"sqs": true,
+}
+
+// ResourceType returns the type of the resource, found before the first
+// "/" or ":" of Resource, as in "user" for "user/division/alice", or
+// "autoScalingGroup" for "autoScalingGroup:id:autoScalingGroupName/g1".
+// It is empty for the resources of S3, SNS and SQS, which have no type.
+func (a ARN) ResourceType() string {
+	if untyped[a.Service] {
+		return ""
+	}
+	if i := strings.IndexAny(a.Resource, "/:"); i >= 0 {
+		return a.Resource[:i]
+	}
+	return ""
+}
+
+// ResourceID returns Resource without its type, as in "division/alice"
+// for "user/division/alice".
+func (a ARN) ResourceID() string {
+	if t := a.ResourceType(); t != "" {
+		return a.Resource[len(t)+1:]
+	}
+	return a.Resource
+}
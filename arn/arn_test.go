@@ -0,0 +1,89 @@
+package arn_test
+
+import (
+	"github.com/crowdmob/goamz/arn"
+	"gopkg.in/check.v1"
+	"testing"
+)
+
+func Test(t *testing.T) {
+	check.TestingT(t)
+}
+
+type S struct{}
+
+var _ = check.Suite(&S{})
+
+var parseTests = []struct {
+	s                string
+	arn              arn.ARN
+	resourceType, id string
+}{{
+	s:   "arn:aws:sqs:us-east-1:123456789012:queue1",
+	arn: arn.ARN{"aws", "sqs", "us-east-1", "123456789012", "queue1"},
+	id:  "queue1",
+}, {
+	s:            "arn:aws:iam::123456789012:user/division/alice",
+	arn:          arn.ARN{"aws", "iam", "", "123456789012", "user/division/alice"},
+	resourceType: "user",
+	id:           "division/alice",
+}, {
+	s:            "arn:aws:autoscaling:us-west-2:123456789012:autoScalingGroup:8e2f:autoScalingGroupName/g1",
+	arn:          arn.ARN{"aws", "autoscaling", "us-west-2", "123456789012", "autoScalingGroup:8e2f:autoScalingGroupName/g1"},
+	resourceType: "autoScalingGroup",
+	id:           "8e2f:autoScalingGroupName/g1",
+}, {
+	s:   "arn:aws:s3:::bucket/dir/key",
+	arn: arn.ARN{"aws", "s3", "", "", "bucket/dir/key"},
+	id:  "bucket/dir/key",
+}, {
+	s:   "arn:aws:sns:us-east-1:123456789012:topic1:2bcfbf39",
+	arn: arn.ARN{"aws", "sns", "us-east-1", "123456789012", "topic1:2bcfbf39"},
+	id:  "topic1:2bcfbf39",
+}, {
+	s:            "arn:aws:iam::aws:policy/AdministratorAccess",
+	arn:          arn.ARN{"aws", "iam", "", "aws", "policy/AdministratorAccess"},
+	resourceType: "policy",
+	id:           "AdministratorAccess",
+}, {
+	s:   "arn:aws-cn:sqs:cn-north-1:123456789012:queue1",
+	arn: arn.ARN{"aws-cn", "sqs", "cn-north-1", "123456789012", "queue1"},
+	id:  "queue1",
+}}
+
+func (s *S) TestParse(c *check.C) {
+	for _, t := range parseTests {
+		a, err := arn.Parse(t.s)
+		c.Assert(err, check.IsNil, check.Commentf(t.s))
+		c.Check(a, check.Equals, t.arn)
+		c.Check(a.ResourceType(), check.Equals, t.resourceType)
+		c.Check(a.ResourceID(), check.Equals, t.id)
+		c.Check(a.String(), check.Equals, t.s)
+	}
+}
+
+func (s *S) TestParseInvalid(c *check.C) {
+	for _, t := range []struct{ s, err string }{
+		{"queue1", "not of the form .*"},
+		{"arn:aws:sqs:us-east-1:123456789012", "not of the form .*"},
+		{"urn:aws:sqs:us-east-1:123456789012:queue1", "not of the form .*"},
+		{"arn:amazon:sqs:us-east-1:123456789012:queue1", `invalid partition "amazon"`},
+		{"arn:aws::us-east-1:123456789012:queue1", `invalid service ""`},
+		{"arn:aws:sqs:useast1:123456789012:queue1", `invalid region "useast1"`},
+		{"arn:aws:sqs:us-east-1:1234:queue1", `invalid account ID "1234"`},
+		{"arn:aws:sqs:us-east-1:123456789012:", "missing resource"},
+		{"arn:aws:sqs:cn-north-1:123456789012:queue1", `region "cn-north-1" is not in partition "aws"`},
+	} {
+		_, err := arn.Parse(t.s)
+		c.Check(err, check.ErrorMatches, `invalid ARN ".*": `+t.err, check.Commentf(t.s))
+		c.Check(arn.IsARN(t.s), check.Equals, false)
+	}
+}
+
+func (s *S) TestNew(c *check.C) {
+	a := arn.New("iam", "", "123456789012", "role/admin")
+	c.Assert(a.String(), check.Equals, "arn:aws:iam::123456789012:role/admin")
+	a = arn.New("sns", "us-gov-west-1", "123456789012", "topic1")
+	c.Assert(a.String(), check.Equals, "arn:aws-us-gov:sns:us-gov-west-1:123456789012:topic1")
+	c.Assert(a.Validate(), check.IsNil)
+}
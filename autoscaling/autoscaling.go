@@ -1,6 +1,7 @@
 package autoscaling
 
 import (
+	"context"
 	"encoding/xml"
 	"fmt"
 	"github.com/crowdmob/goamz/aws"
@@ -20,6 +21,33 @@ var timeNow = time.Now
 type AutoScaling struct {
 	aws.Auth
 	aws.Region
+
+	// HTTPClient is used to send requests to Auto Scaling. If nil,
+	// http.DefaultClient is used.
+	HTTPClient *http.Client
+
+	// RetryPolicy controls how failed requests are retried. If nil,
+	// aws.DefaultRetryPolicy is used.
+	RetryPolicy *aws.RetryPolicy
+
+	// Handlers holds the hooks run while sending requests. If nil,
+	// aws.DefaultHandlers is used.
+	Handlers *aws.Handlers
+
+	// Clock gives the time requests are signed with, corrected when
+	// Auto Scaling reports clock skew. If nil, aws.DefaultClock is used.
+	Clock *aws.Clock
+
+	// Limiter, if set, limits the rate of the requests sent to Auto Scaling,
+	// and slows them down when Auto Scaling throttles them. It may be shared
+	// between clients.
+	Limiter *aws.RateLimiter
+
+	// SignV2 makes the client sign requests with Signature Version 2,
+	// for legacy endpoints that do not accept Signature Version 4.
+	SignV2 bool
+
+	aws.ContextBinding
 }
 
 type xmlErrors struct {
@@ -46,24 +74,86 @@ func (err *Error) Error() string {
 	return fmt.Sprintf("%s (%s)", err.Message, err.Code)
 }
 
+// ErrorCode returns the AutoScaling error code.
+func (err *Error) ErrorCode() string { return err.Code }
+
+// ErrorMessage returns the message of the error.
+func (err *Error) ErrorMessage() string { return err.Message }
+
+// HTTPStatusCode returns the status code of the HTTP response.
+func (err *Error) HTTPStatusCode() int { return err.StatusCode }
+
+// RequestID returns the ID of the failed request.
+func (err *Error) RequestID() string { return err.RequestId }
+
+// Retryable reports whether sending the request again may succeed.
+func (err *Error) Retryable() bool {
+	retry, _ := retryable(err)
+	return retry
+}
+
 // New creates a new AutoScaling
 func New(auth aws.Auth, region aws.Region) *AutoScaling {
-	return &AutoScaling{auth, region}
+	return &AutoScaling{Auth: auth, Region: region, Clock: &aws.Clock{}}
+}
+
+// NewFromConfig creates a new AutoScaling with the credentials, endpoints, HTTP
+// client, retry policy and rate limiter of cfg.
+func NewFromConfig(cfg *aws.Config) *AutoScaling {
+	as := New(cfg.Auth, cfg.Region)
+	as.HTTPClient = cfg.HTTPClient
+	as.RetryPolicy = cfg.RetryPolicy(&aws.DefaultRetryPolicy)
+	as.Limiter = cfg.Limiter
+	return as
+}
+
+// WithContext returns a shallow copy of as bound to ctx; see
+// aws.ContextBinding.
+func (as *AutoScaling) WithContext(ctx context.Context) *AutoScaling {
+	c := *as
+	c.ContextBinding = aws.BindContext(ctx)
+	return &c
 }
 
 func (as *AutoScaling) query(params map[string]string, resp interface{}) error {
+	policy := aws.RetryPolicyOrDefault(as.RetryPolicy, &aws.DefaultRetryPolicy)
+	info := &aws.RequestInfo{Service: "autoscaling", Operation: params["Action"], Context: as.Context(), Clock: as.Clock, Limiter: as.Limiter}
+	return as.Handlers.Retry(policy, retryable, info, func() error {
+		// Each attempt is signed afresh.
+		return as.send(info, copyParams(params), resp)
+	})
+}
+
+func (as *AutoScaling) send(info *aws.RequestInfo, params map[string]string, resp interface{}) error {
 	params["Version"] = "2011-01-01"
-	params["Timestamp"] = timeNow().In(time.UTC).Format(time.RFC3339)
+	params["Timestamp"] = timeNow().Add(as.Clock.Offset()).In(time.UTC).Format(time.RFC3339)
 	endpoint, err := url.Parse(as.Region.AutoScalingEndpoint)
 	if err != nil {
 		return err
 	}
-	sign(as.Auth, "GET", endpoint.Path, params, endpoint.Host)
+	auth, err := as.Auth.Snapshot()
+	if err != nil {
+		return err
+	}
 	endpoint.RawQuery = multimap(params).Encode()
 	if debug {
 		log.Printf("get { %v } -> {\n", endpoint.String())
 	}
-	r, err := http.Get(endpoint.String())
+	hreq, err := http.NewRequest("GET", endpoint.String(), nil)
+	if err != nil {
+		return err
+	}
+	r, err := as.Handlers.Send(as.HTTPClient, info, hreq, func(hreq *http.Request) error {
+		if !as.SignV2 {
+			signer := aws.NewV4Signer(auth, "autoscaling", as.Region)
+			signer.Clock = as.Clock
+			signer.Sign(hreq)
+			return nil
+		}
+		sign(auth, "GET", endpoint.Path, params, endpoint.Host)
+		hreq.URL.RawQuery = multimap(params).Encode()
+		return nil
+	})
 	if err != nil {
 		return err
 	}
@@ -81,6 +171,22 @@ func (as *AutoScaling) query(params map[string]string, resp interface{}) error {
 	return err
 }
 
+// retryable classifies the errors of failed Auto Scaling requests for retries.
+func retryable(err error) (retry, throttled bool) {
+	if e, ok := err.(*Error); ok {
+		return aws.ClassifyResponse(e.StatusCode, e.Code)
+	}
+	return aws.IsTransientNetError(err), false
+}
+
+func copyParams(p map[string]string) map[string]string {
+	c := make(map[string]string, len(p))
+	for k, v := range p {
+		c[k] = v
+	}
+	return c
+}
+
 func multimap(p map[string]string) url.Values {
 	q := make(url.Values, len(p))
 	for k, v := range p {
@@ -1,6 +1,7 @@
 package aws
 
 import (
+	"context"
 	"time"
 )
 
@@ -15,6 +16,7 @@ type AttemptStrategy struct {
 
 type Attempt struct {
 	strategy AttemptStrategy
+	ctx      context.Context
 	last     time.Time
 	end      time.Time
 	force    bool
@@ -23,9 +25,18 @@ type Attempt struct {
 
 // Start begins a new sequence of attempts for the given strategy.
 func (s AttemptStrategy) Start() *Attempt {
+	return s.StartWithContext(context.Background())
+}
+
+// StartWithContext begins a new sequence of attempts for the given
+// strategy that stops early when ctx is done. Once ctx is done, sleeps
+// between attempts are cut short and no further attempts are offered,
+// except for the first one and any promised by a previous call to HasNext.
+func (s AttemptStrategy) StartWithContext(ctx context.Context) *Attempt {
 	now := time.Now()
 	return &Attempt{
 		strategy: s,
+		ctx:      ctx,
 		last:     now,
 		end:      now.Add(s.Total),
 		force:    true,
@@ -37,12 +48,17 @@ func (s AttemptStrategy) Start() *Attempt {
 func (a *Attempt) Next() bool {
 	now := time.Now()
 	sleep := a.nextSleep(now)
-	if !a.force && !now.Add(sleep).Before(a.end) && a.strategy.Min <= a.count {
+	if !a.force && (a.ctx.Err() != nil || !now.Add(sleep).Before(a.end) && a.strategy.Min <= a.count) {
 		return false
 	}
 	a.force = false
 	if sleep > 0 && a.count > 0 {
-		time.Sleep(sleep)
+		t := time.NewTimer(sleep)
+		select {
+		case <-t.C:
+		case <-a.ctx.Done():
+			t.Stop()
+		}
 		now = time.Now()
 	}
 	a.count++
@@ -62,7 +78,14 @@ func (a *Attempt) nextSleep(now time.Time) time.Duration {
 // one fails. If it returns true, the following call to Next is
 // guaranteed to return true.
 func (a *Attempt) HasNext() bool {
-	if a.force || a.strategy.Min > a.count {
+	if a.force {
+		return true
+	}
+	if a.ctx.Err() != nil {
+		return false
+	}
+	if a.strategy.Min > a.count {
+		a.force = true
 		return true
 	}
 	now := time.Now()
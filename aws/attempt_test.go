@@ -1,6 +1,7 @@
 package aws_test
 
 import (
+	"context"
 	"github.com/crowdmob/goamz/aws"
 	"gopkg.in/check.v1"
 	"time"
@@ -55,3 +56,16 @@ func (S) TestAttemptNextHasNext(c *check.C) {
 	c.Assert(a.HasNext(), check.Equals, false)
 	c.Assert(a.Next(), check.Equals, false)
 }
+
+func (S) TestAttemptWithContext(c *check.C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	a := aws.AttemptStrategy{Total: 5e9, Delay: 1e9}.StartWithContext(ctx)
+	c.Assert(a.Next(), check.Equals, true)
+	c.Assert(a.HasNext(), check.Equals, true)
+	cancel()
+	t0 := time.Now()
+	c.Assert(a.Next(), check.Equals, true)
+	c.Assert(time.Since(t0) < 0.5e9, check.Equals, true)
+	c.Assert(a.HasNext(), check.Equals, false)
+	c.Assert(a.Next(), check.Equals, false)
+}
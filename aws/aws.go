@@ -10,15 +10,16 @@
 package aws
 
 import (
-	"encoding/json"
+	"bytes"
+	"context"
 	"encoding/xml"
 	"errors"
 	"fmt"
 	"io/ioutil"
-	"net"
 	"net/http"
 	"net/url"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -55,19 +56,11 @@ type Region struct {
 	CloudWatchServicepoint ServiceInfo
 	AutoScalingEndpoint    string
 	RDSEndpoint            ServiceInfo
+	STSEndpoint            string
 }
 
-var Regions = map[string]Region{
-	APNortheast.Name:  APNortheast,
-	APSoutheast.Name:  APSoutheast,
-	APSoutheast2.Name: APSoutheast2,
-	EUWest.Name:       EUWest,
-	USEast.Name:       USEast,
-	USWest.Name:       USWest,
-	USWest2.Name:      USWest2,
-	USGovWest.Name:    USGovWest,
-	SAEast.Name:       SAEast,
-}
+// Regions holds the known regions of every partition, by name.
+var Regions = knownRegions()
 
 // Designates a signer interface suitable for signing AWS requests, params
 // should be appropriately encoded for the request before signing.
@@ -84,6 +77,8 @@ type AWSService interface {
 	// Queries the AWS service at a given method/path with the params and
 	// returns an http.Response and error
 	Query(method, path string, params map[string]string) (*http.Response, error)
+	// Like Query, but the request is cancelled when ctx is done.
+	QueryWithContext(ctx context.Context, method, path string, params map[string]string) (*http.Response, error)
 	// Builds an error given an XML payload in the http.Response, can be used
 	// to process an error if the status code is not 200 for example.
 	BuildError(r *http.Response) error
@@ -93,7 +88,34 @@ type AWSService interface {
 // errors when desired
 type Service struct {
 	service ServiceInfo
+	auth    Auth
 	signer  Signer
+	v4      *V4Signer
+
+	// name is the name of the service reported to hooks, read from the
+	// endpoint host.
+	name string
+
+	// HTTPClient is used to send requests to the service. If nil,
+	// http.DefaultClient is used.
+	HTTPClient *http.Client
+
+	// RetryPolicy controls how failed requests are retried. If nil,
+	// DefaultRetryPolicy is used.
+	RetryPolicy *RetryPolicy
+
+	// Handlers holds the hooks run while sending requests. If nil,
+	// DefaultHandlers is used.
+	Handlers *Handlers
+
+	// Clock gives the time requests are signed with, corrected when the
+	// service reports clock skew. If nil, DefaultClock is used.
+	Clock *Clock
+
+	// Limiter, if set, limits the rate of the requests sent to the
+	// service, and slows them down when the service throttles them. It
+	// may be shared between clients.
+	Limiter *RateLimiter
 }
 
 // Create a base set of params for an action
@@ -104,40 +126,162 @@ func MakeParams(action string) map[string]string {
 }
 
 // Create a new AWS server to handle making requests
+//
+// Requests are signed as set by service.Signer. With V4Signature, the
+// name of the service and of the region are read from the endpoint host,
+// as in "monitoring.us-west-2.amazonaws.com"; endpoints without a region
+// are signed for us-east-1. Use NewServiceWithScope when the endpoint
+// host does not name them, as with a local endpoint.
 func NewService(auth Auth, service ServiceInfo) (s *Service, err error) {
-	var signer Signer
+	return NewServiceWithScope(auth, service, "", "")
+}
+
+// NewServiceWithScope is like NewService, but Signature Version 4
+// signatures are scoped to the service with the given signing name, such
+// as "monitoring", and to the signing region of the named region (see
+// SigningRegion). Either is read from the endpoint host when empty.
+func NewServiceWithScope(auth Auth, service ServiceInfo, name, region string) (s *Service, err error) {
+	s = &Service{service: service, auth: auth, Clock: &Clock{}}
+	hostName, hostRegion, err := endpointScope(service.Endpoint)
+	if name == "" {
+		s.name = hostName
+	} else {
+		s.name = name
+	}
+	if region == "" {
+		region = hostRegion
+	} else {
+		region = SigningRegion(s.name, region)
+	}
 	switch service.Signer {
 	case V2Signature:
-		signer, err = NewV2Signer(auth, service)
-	// case V4Signature:
-	// 	signer, err = NewV4Signer(auth, service, Regions["eu-west-1"])
+		s.signer, err = NewV2Signer(auth, service)
+	case V4Signature:
+		if err == nil {
+			s.v4 = NewV4Signer(auth, s.name, Region{Name: region})
+		}
 	default:
 		err = fmt.Errorf("Unsupported signer for service")
 	}
 	if err != nil {
-		return
+		return nil, err
 	}
-	s = &Service{service: service, signer: signer}
-	return
+	return s, nil
+}
+
+// endpointScope returns the service and region names found in the host
+// of endpoint, used to scope Signature Version 4 signatures.
+func endpointScope(endpoint string) (service, region string, err error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", "", err
+	}
+	if u.Host == "" {
+		return "", "", fmt.Errorf("no host in endpoint %q", endpoint)
+	}
+	service = strings.Split(u.Hostname(), ".")[0]
+	if region = regionOfHost(u.Hostname()); region == "" {
+		region = "us-east-1"
+	}
+	return service, region, nil
 }
 
 func (s *Service) Query(method, path string, params map[string]string) (resp *http.Response, err error) {
-	params["Timestamp"] = time.Now().UTC().Format(time.RFC3339)
+	return s.QueryWithContext(context.Background(), method, path, params)
+}
+
+// QueryWithContext is like Query, but the request is cancelled when
+// ctx is done.
+//
+// Requests failing with a transient error are retried according to the
+// RetryPolicy of s. The response of the last attempt is returned whatever
+// its status code, with its body left unread.
+func (s *Service) QueryWithContext(ctx context.Context, method, path string, params map[string]string) (resp *http.Response, err error) {
+	policy := RetryPolicyOrDefault(s.RetryPolicy, &DefaultRetryPolicy)
+	info := &RequestInfo{Service: s.name, Operation: params["Action"], Context: ctx, Clock: s.Clock, Limiter: s.Limiter}
+	err = s.Handlers.Retry(policy, retryableQuery, info, func() error {
+		// The signature is added to the params, so each attempt signs
+		// a copy of them.
+		attempt := make(map[string]string, len(params))
+		for k, v := range params {
+			attempt[k] = v
+		}
+		attempt["Timestamp"] = s.Clock.Now().Format(time.RFC3339)
+		var err error
+		resp, err = s.send(info, method, path, attempt)
+		if err != nil || resp.StatusCode == 200 {
+			return err
+		}
+		// Keep the body of failed responses so that the caller can still
+		// read the error from it.
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			resp = nil
+			return err
+		}
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+		failed := *resp
+		failed.Body = ioutil.NopCloser(bytes.NewReader(body))
+		return s.BuildError(&failed)
+	})
+	if _, ok := err.(*Error); ok && resp != nil {
+		return resp, nil
+	}
+	return resp, err
+}
+
+func retryableQuery(err error) (retry, throttled bool) {
+	if e, ok := err.(*Error); ok {
+		return ClassifyResponse(e.StatusCode, e.Code)
+	}
+	return IsTransientNetError(err), false
+}
+
+func (s *Service) send(info *RequestInfo, method, path string, params map[string]string) (resp *http.Response, err error) {
 	u, err := url.Parse(s.service.Endpoint)
 	if err != nil {
 		return nil, err
 	}
 	u.Path = path
 
-	s.signer.Sign(method, path, params)
+	// Report credentials that could not be refreshed rather than
+	// sending a request bound to fail.
+	if _, err := s.auth.Snapshot(); err != nil {
+		return nil, err
+	}
+	var req *http.Request
 	if method == "GET" {
 		u.RawQuery = multimap(params).Encode()
-		resp, err = http.Get(u.String())
+		req, err = http.NewRequest(method, u.String(), nil)
 	} else if method == "POST" {
-		resp, err = http.PostForm(u.String(), multimap(params))
+		req, err = http.NewRequest(method, u.String(), strings.NewReader(multimap(params).Encode()))
+		if req != nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	} else {
+		err = fmt.Errorf("Unsupported method %q for service query", method)
 	}
-
-	return
+	if err != nil {
+		return nil, err
+	}
+	return s.Handlers.Send(s.HTTPClient, info, req, func(req *http.Request) error {
+		if s.v4 != nil {
+			signer := *s.v4
+			signer.Clock = s.Clock
+			signer.Sign(req)
+			return nil
+		}
+		s.signer.Sign(method, path, params)
+		encoded := multimap(params).Encode()
+		if method == "GET" {
+			req.URL.RawQuery = encoded
+		} else {
+			req.Body = ioutil.NopCloser(strings.NewReader(encoded))
+			req.ContentLength = int64(len(encoded))
+		}
+		return nil
+	})
 }
 
 func (s *Service) BuildError(r *http.Response) error {
@@ -172,24 +316,45 @@ func (err *Error) Error() string {
 	)
 }
 
+// Auth holds the credentials used to sign requests.
+//
+// An Auth returned by GetAuth for temporary credentials, or by
+// Credentials.Auth, is bound to a shared Credentials: all its copies are
+// refreshed together, and Snapshot should be used to read a consistent
+// set of keys before signing.
 type Auth struct {
 	AccessKey, SecretKey string
 	token                string
 	expiration           time.Time
+	creds                *Credentials
 }
 
-func (a *Auth) Token() string {
-	if a.token == "" {
-		return ""
-	}
-	if time.Since(a.expiration) >= -30*time.Second { //in an ideal world this should be zero assuming the instance is synching it's clock
-		*a, _ = GetAuth("", "", "", time.Time{})
+// NewAuth returns an Auth holding the given keys, along with the session
+// token and expiration of temporary credentials, if any. Unlike GetAuth,
+// it never looks for credentials elsewhere when the keys are empty.
+func NewAuth(accessKey, secretKey, token string, expiration time.Time) Auth {
+	return Auth{AccessKey: accessKey, SecretKey: secretKey, token: token, expiration: expiration}
+}
+
+// Snapshot returns a copy of a holding the current keys and token. For an
+// Auth bound to a Credentials, these are read together, so that a refresh
+// cannot happen half way through; an error is returned if the credentials
+// have expired and could not be refreshed.
+func (a *Auth) Snapshot() (Auth, error) {
+	if a.creds == nil {
+		return *a, nil
 	}
-	return a.token
+	return a.creds.Get()
+}
+
+func (a *Auth) Token() string {
+	auth, _ := a.Snapshot()
+	return auth.token
 }
 
 func (a *Auth) Expiration() time.Time {
-	return a.expiration
+	auth, _ := a.Snapshot()
+	return auth.expiration
 }
 
 // ResponseMetadata
@@ -230,96 +395,37 @@ type credentials struct {
 	Expiration      string
 }
 
+// GetMetaData returns the instance metadata item at path, as in
+// "instance-id". See MetadataClient for more control over the requests.
 func GetMetaData(path string) (contents []byte, err error) {
-	c := http.Client{
-		Transport: &http.Transport{
-			Dial: func(netw, addr string) (net.Conn, error) {
-				deadline := time.Now().Add(5 * time.Second)
-				c, err := net.DialTimeout(netw, addr, time.Second*2)
-				if err != nil {
-					return nil, err
-				}
-				c.SetDeadline(deadline)
-				return c, nil
-			},
-		},
-	}
-
-	url := "http://169.254.169.254/latest/meta-data/" + path
-
-	resp, err := c.Get(url)
-	if err != nil {
-		return
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		err = fmt.Errorf("Code %d returned for url %s", resp.StatusCode, url)
-		return
-	}
-
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return
-	}
-	return []byte(body), err
-}
-
-func getInstanceCredentials() (cred credentials, err error) {
-	credentialPath := "iam/security-credentials/"
-
-	// Get the instance role
-	role, err := GetMetaData(credentialPath)
-	if err != nil {
-		return
-	}
-
-	// Get the instance role credentials
-	credentialJSON, err := GetMetaData(credentialPath + string(role))
-	if err != nil {
-		return
-	}
-
-	err = json.Unmarshal([]byte(credentialJSON), &cred)
-	return
+	return NewMetadataClient().GetMetadata(path)
 }
 
 // GetAuth creates an Auth based on either passed in credentials,
-// environment information or instance based role credentials.
+// environment information, the shared credentials file or instance
+// based role credentials, in that order. See DefaultChain.
+//
+// Instance role credentials expire; the returned Auth is then bound to a
+// Credentials that refreshes them as they are about to expire. No
+// goroutine is left behind, so GetAuth may be called for every request.
 func GetAuth(accessKey string, secretKey, token string, expiration time.Time) (auth Auth, err error) {
 	// First try passed in credentials
 	if accessKey != "" && secretKey != "" {
-		return Auth{accessKey, secretKey, token, expiration}, nil
-	}
-
-	// Next try to get auth from the environment
-	auth, err = EnvAuth()
-	if err == nil {
-		// Found auth, return
-		return
+		return NewAuth(accessKey, secretKey, token, expiration), nil
 	}
-
-	// Next try getting auth from the instance role
-	cred, err := getInstanceCredentials()
-	if err == nil {
-		// Found auth, return
-		auth.AccessKey = cred.AccessKeyId
-		auth.SecretKey = cred.SecretAccessKey
-		auth.token = cred.Token
-		exptdate, err := time.Parse("2006-01-02T15:04:05Z", cred.Expiration)
-		if err != nil {
-			err = fmt.Errorf("Error Parseing expiration date: cred.Expiration :%s , error: %s \n", cred.Expiration, err)
-		}
-		auth.expiration = exptdate
+	auth, source, err := DefaultChain().RetrieveWithSource()
+	if err != nil || auth.expiration.IsZero() {
 		return auth, err
 	}
-	err = errors.New("No valid AWS authentication found")
-	return auth, err
+	// Temporary credentials are shared by all copies of auth, and
+	// refreshed from where they came from before they expire.
+	return newCredentials(source, auth).Auth(), nil
 }
 
 // EnvAuth creates an Auth based on environment information.
 // The AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment
-// variables are used.
+// variables are used, along with AWS_SESSION_TOKEN when temporary
+// credentials are in use.
 func EnvAuth() (auth Auth, err error) {
 	auth.AccessKey = os.Getenv("AWS_ACCESS_KEY_ID")
 	if auth.AccessKey == "" {
@@ -330,6 +436,11 @@ func EnvAuth() (auth Auth, err error) {
 	if auth.SecretKey == "" {
 		auth.SecretKey = os.Getenv("AWS_SECRET_KEY")
 	}
+
+	auth.token = os.Getenv("AWS_SESSION_TOKEN")
+	if auth.token == "" {
+		auth.token = os.Getenv("AWS_SECURITY_TOKEN")
+	}
 	if auth.AccessKey == "" {
 		err = errors.New("AWS_ACCESS_KEY_ID or AWS_ACCESS_KEY not found in environment")
 	}
@@ -370,104 +481,52 @@ func Encode(s string) string {
 	return string(e[:ei])
 }
 
-func dialTimeout(network, addr string) (net.Conn, error) {
-	return net.DialTimeout(network, addr, time.Duration(2*time.Second))
-}
-
+// InstanceRegion returns the region of the instance, or "unknown" if the
+// metadata service cannot tell. Use MetadataClient to find out why.
 func InstanceRegion() string {
-	transport := http.Transport{Dial: dialTimeout}
-	client := http.Client{
-		Transport: &transport,
-	}
-	resp, err := client.Get("http://169.254.169.254/latest/meta-data/placement/availability-zone")
+	region, err := NewMetadataClient().Region()
 	if err != nil {
 		return "unknown"
-	} else {
-		defer resp.Body.Close()
-		body, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return "unknown"
-		} else {
-			b := string(body)
-			region := b[:len(b)-1]
-			return region
-		}
 	}
+	return region
 }
 
+// InstanceId returns the id of the instance, or "unknown" if the
+// metadata service cannot tell.
 func InstanceId() string {
-	transport := http.Transport{Dial: dialTimeout}
-	client := http.Client{
-		Transport: &transport,
-	}
-	resp, err := client.Get("http://169.254.169.254/latest/meta-data/instance-id")
+	id, err := NewMetadataClient().InstanceId()
 	if err != nil {
 		return "unknown"
-	} else {
-		defer resp.Body.Close()
-		body, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return "unknown"
-		} else {
-			return string(body)
-		}
 	}
+	return id
 }
 
+// InstanceType returns the type of the instance, or "unknown" if the
+// metadata service cannot tell.
 func InstanceType() string {
-	transport := http.Transport{Dial: dialTimeout}
-	client := http.Client{
-		Transport: &transport,
-	}
-	resp, err := client.Get("http://169.254.169.254/latest/meta-data/instance-type")
+	t, err := NewMetadataClient().InstanceType()
 	if err != nil {
 		return "unknown"
-	} else {
-		defer resp.Body.Close()
-		body, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return "unknown"
-		} else {
-			return string(body)
-		}
 	}
+	return t
 }
 
+// ServerLocalIp returns the private IPv4 address of the instance, or
+// "127.0.0.1" if the metadata service cannot tell.
 func ServerLocalIp() string {
-	transport := http.Transport{Dial: dialTimeout}
-	client := http.Client{
-		Transport: &transport,
-	}
-	resp, err := client.Get("http://169.254.169.254/latest/meta-data/local-ipv4")
+	ip, err := NewMetadataClient().LocalIPv4()
 	if err != nil {
 		return "127.0.0.1"
-	} else {
-		defer resp.Body.Close()
-		body, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return "127.0.0.1"
-		} else {
-			return string(body)
-		}
 	}
+	return ip
 }
 
+// ServerPublicIp returns the public IPv4 address of the instance, or
+// "127.0.0.1" if the metadata service cannot tell.
 func ServerPublicIp() string {
-	transport := http.Transport{Dial: dialTimeout}
-	client := http.Client{
-		Transport: &transport,
-	}
-	resp, err := client.Get("http://169.254.169.254/latest/meta-data/public-ipv4")
+	ip, err := NewMetadataClient().PublicIPv4()
 	if err != nil {
 		return "127.0.0.1"
-	} else {
-		defer resp.Body.Close()
-		body, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return "127.0.0.1"
-		} else {
-			return string(body)
-		}
 	}
+	return ip
 }
-
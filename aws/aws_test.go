@@ -1,8 +1,11 @@
 package aws_test
 
 import (
+	"context"
 	"github.com/crowdmob/goamz/aws"
 	"gopkg.in/check.v1"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
@@ -91,3 +94,65 @@ func (s *S) TestRegionsAreNamed(c *check.C) {
 		c.Assert(n, check.Equals, r.Name)
 	}
 }
+
+func (s *S) TestEndpointScope(c *check.C) {
+	tests := []struct{ endpoint, service, region string }{
+		{"https://monitoring.eu-central-1.amazonaws.com", "monitoring", "eu-central-1"},
+		{"https://rds.us-gov-west-1.amazonaws.com/", "rds", "us-gov-west-1"},
+		{"https://rds.amazonaws.com", "rds", "us-east-1"},
+		{"http://localhost:4444", "localhost", "us-east-1"},
+	}
+	for _, t := range tests {
+		service, region, err := aws.EndpointScope(t.endpoint)
+		c.Assert(err, check.IsNil)
+		c.Check(service, check.Equals, t.service)
+		c.Check(region, check.Equals, t.region)
+	}
+	_, _, err := aws.EndpointScope("monitoring")
+	c.Assert(err, check.NotNil)
+}
+
+func (s *S) TestServiceSignsV4(c *check.C) {
+	var req *http.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req = r
+	}))
+	defer srv.Close()
+
+	auth := aws.Auth{AccessKey: "abc", SecretKey: "123"}
+	service, err := aws.NewService(auth, aws.ServiceInfo{srv.URL, aws.V4Signature})
+	c.Assert(err, check.IsNil)
+	resp, err := service.Query("GET", "/", map[string]string{"Action": "ListMetrics", "Message": "a b"})
+	c.Assert(err, check.IsNil)
+	resp.Body.Close()
+
+	c.Assert(req.FormValue("Action"), check.Equals, "ListMetrics")
+	c.Assert(req.FormValue("Signature"), check.Equals, "")
+	c.Assert(req.Header.Get("Authorization"), check.Matches, "AWS4-HMAC-SHA256 Credential=abc/[0-9]+/us-east-1/127/aws4_request, .*")
+}
+
+func (s *S) TestServiceWithScopeSignsV4(c *check.C) {
+	var req *http.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req = r
+	}))
+	defer srv.Close()
+
+	auth := aws.Auth{AccessKey: "abc", SecretKey: "123"}
+	service, err := aws.NewServiceWithScope(auth, aws.ServiceInfo{srv.URL, aws.V4Signature}, "rds", "eu-west-1")
+	c.Assert(err, check.IsNil)
+	resp, err := service.Query("GET", "/", map[string]string{"Action": "DescribeDBInstances"})
+	c.Assert(err, check.IsNil)
+	resp.Body.Close()
+
+	c.Assert(req.Header.Get("Authorization"), check.Matches, "AWS4-HMAC-SHA256 Credential=abc/[0-9]+/eu-west-1/rds/aws4_request, .*")
+}
+
+func (s *S) TestContextBinding(c *check.C) {
+	var b aws.ContextBinding
+	c.Assert(b.Context(), check.Equals, context.Background())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c.Assert(aws.BindContext(ctx).Context(), check.Equals, ctx)
+	c.Assert(func() { aws.BindContext(nil) }, check.PanicMatches, "nil context")
+}
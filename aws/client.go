@@ -0,0 +1,60 @@
+package aws
+
+import (
+	"context"
+	"net/http"
+)
+
+// ClientOrDefault returns c, or http.DefaultClient if c is nil.
+//
+// Every service client in goamz has an HTTPClient field that is passed
+// through this function before a request is sent. Setting that field is
+// the single way of controlling how requests reach AWS: a shared
+// *http.Client pools connections across clients, and a custom
+// http.RoundTripper can be plugged in as the client's Transport to add
+// proxies, TLS settings, or to record and replay traffic in tests:
+//
+//	client := &http.Client{Transport: myRoundTripper}
+//	e := ec2.New(auth, aws.USEast)
+//	e.HTTPClient = client
+func ClientOrDefault(c *http.Client) *http.Client {
+	if c == nil {
+		return http.DefaultClient
+	}
+	return c
+}
+
+// ContextBinding binds the operations of a client to a context. Every
+// service client in goamz embeds one, and has a WithContext method
+// returning a shallow copy of the client bound to a given context:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+//	defer cancel()
+//	resp, err := e.WithContext(ctx).DescribeInstances(nil, nil)
+//
+// Requests in flight, and the pauses between their retries, are
+// cancelled when the context is done. The context is bound to a copy of
+// the client rather than passed to each operation so that the existing
+// operations keep their signatures, and callers opt in where they need
+// cancellation; as only copies are bound, a client shared between
+// goroutines is never changed. Clients that are not bound use
+// context.Background().
+type ContextBinding struct {
+	ctx context.Context
+}
+
+// BindContext returns a ContextBinding to ctx, which must not be nil.
+func BindContext(ctx context.Context) ContextBinding {
+	if ctx == nil {
+		panic("nil context")
+	}
+	return ContextBinding{ctx}
+}
+
+// Context returns the context the client is bound to.
+func (b ContextBinding) Context() context.Context {
+	if b.ctx != nil {
+		return b.ctx
+	}
+	return context.Background()
+}
@@ -0,0 +1,106 @@
+package aws
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// A Clock gives the time used to stamp and sign requests: the local time
+// corrected by an offset learned from the servers.
+//
+// Signatures are only accepted by AWS within a few minutes of the server
+// time. When a request fails for clock skew, service clients set the
+// offset of their Clock from the Date header of the response, and sign
+// and send the request again. Offset tells the correction applied.
+//
+// Every service client has a Clock field; a nil *Clock stands for
+// DefaultClock. A Clock may be shared between clients.
+type Clock struct {
+	mu     sync.Mutex
+	offset time.Duration
+}
+
+// DefaultClock is the clock of service clients that have no Clock of
+// their own.
+var DefaultClock = &Clock{}
+
+// ClockOrDefault returns c, or DefaultClock if c is nil.
+func ClockOrDefault(c *Clock) *Clock {
+	if c == nil {
+		return DefaultClock
+	}
+	return c
+}
+
+// minSkew is the least change of offset Adjust makes. The Date header
+// has a resolution of a second, and AWS tolerates skews of minutes, so
+// smaller differences are noise.
+const minSkew = time.Minute
+
+// Now returns the local time, corrected by the offset of c, in UTC.
+func (c *Clock) Now() time.Time {
+	return time.Now().Add(c.Offset()).UTC()
+}
+
+// Offset returns the correction applied to the local time.
+func (c *Clock) Offset() time.Duration {
+	c = ClockOrDefault(c)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.offset
+}
+
+// SetOffset sets the correction applied to the local time.
+func (c *Clock) SetOffset(offset time.Duration) {
+	c = ClockOrDefault(c)
+	c.mu.Lock()
+	c.offset = offset
+	c.mu.Unlock()
+}
+
+// Adjust sets the offset of c so that Now matches serverTime, the time
+// just reported by a server, and reports whether the offset changed. It
+// is left alone if it already agrees with serverTime within a minute.
+func (c *Clock) Adjust(serverTime time.Time) bool {
+	c = ClockOrDefault(c)
+	skew := serverTime.Sub(time.Now())
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if d := skew - c.offset; d > -minSkew && d < minSkew {
+		return false
+	}
+	c.offset = skew.Round(time.Second)
+	return true
+}
+
+// clockSkewCodes holds the error codes AWS services use to reject
+// requests signed too far from the server time. Some services can't tell
+// a skewed signature from a wrong one.
+var clockSkewCodes = map[string]bool{
+	"RequestTimeTooSkewed":      true,
+	"RequestExpired":            true,
+	"RequestInTheFuture":        true,
+	"InvalidSignatureException": true,
+	"SignatureDoesNotMatch":     true,
+	"AuthFailure":               true,
+}
+
+// IsClockSkewCode reports whether code is an AWS error code that may be
+// due to the client clock being skewed.
+func IsClockSkewCode(code string) bool {
+	return clockSkewCodes[code]
+}
+
+// adjustClock adjusts c from the Date header of resp, if err tells the
+// request failed for clock skew, and reports whether the offset changed.
+func adjustClock(c *Clock, resp *http.Response, err error) bool {
+	if resp == nil || !IsClockSkewCode(ErrorCode(err)) {
+		return false
+	}
+	date, perr := http.ParseTime(resp.Header.Get("Date"))
+	if perr != nil {
+		return false
+	}
+	return c.Adjust(date)
+}
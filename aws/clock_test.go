@@ -0,0 +1,122 @@
+package aws_test
+
+import (
+	"fmt"
+	"github.com/crowdmob/goamz/aws"
+	"gopkg.in/check.v1"
+	"net/http"
+	"net/http/httptest"
+	"time"
+)
+
+func (s *S) TestClockAdjust(c *check.C) {
+	clock := &aws.Clock{}
+	c.Assert(clock.Offset(), check.Equals, time.Duration(0))
+
+	c.Assert(clock.Adjust(time.Now().Add(10*time.Second)), check.Equals, false)
+	c.Assert(clock.Offset(), check.Equals, time.Duration(0))
+
+	c.Assert(clock.Adjust(time.Now().Add(-time.Hour)), check.Equals, true)
+	c.Assert(clock.Offset(), check.Equals, -time.Hour)
+	c.Assert(clock.Adjust(time.Now().Add(-time.Hour)), check.Equals, false)
+
+	now := clock.Now()
+	c.Assert(now.Location(), check.Equals, time.UTC)
+	c.Assert(time.Since(now) > 59*time.Minute, check.Equals, true)
+}
+
+func (s *S) TestIsClockSkewCode(c *check.C) {
+	c.Assert(aws.IsClockSkewCode("RequestTimeTooSkewed"), check.Equals, true)
+	c.Assert(aws.IsClockSkewCode("SignatureDoesNotMatch"), check.Equals, true)
+	c.Assert(aws.IsClockSkewCode("AccessDenied"), check.Equals, false)
+}
+
+func (s *S) TestServiceCorrectsClockSkew(c *check.C) {
+	serverTime := time.Now().Add(-20 * time.Minute).UTC()
+	var stamps []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stamps = append(stamps, r.FormValue("Timestamp"))
+		w.Header().Set("Date", serverTime.Format(http.TimeFormat))
+		if len(stamps) == 1 {
+			w.WriteHeader(400)
+			fmt.Fprint(w, `<ErrorResponse><Error><Code>RequestExpired</Code><Message>Request has expired.</Message></Error></ErrorResponse>`)
+		}
+	}))
+	defer srv.Close()
+
+	auth := aws.Auth{AccessKey: "abc", SecretKey: "123"}
+	service, err := aws.NewService(auth, aws.ServiceInfo{srv.URL, aws.V2Signature})
+	c.Assert(err, check.IsNil)
+	service.RetryPolicy = &aws.RetryPolicy{MaxAttempts: 2}
+	resp, err := service.Query("GET", "/", map[string]string{"Action": "ListMetrics"})
+	c.Assert(err, check.IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, check.Equals, 200)
+
+	c.Assert(stamps, check.HasLen, 2)
+	stamp, err := time.Parse(time.RFC3339, stamps[1])
+	c.Assert(err, check.IsNil)
+	c.Assert(stamp.Sub(serverTime) > -5*time.Second && stamp.Sub(serverTime) < 5*time.Second, check.Equals, true)
+	offset := service.Clock.Offset()
+	c.Assert(offset < -19*time.Minute && offset > -21*time.Minute, check.Equals, true)
+}
+
+func (s *S) TestServiceCorrectsClockSkewWithoutRetries(c *check.C) {
+	serverTime := time.Now().Add(-20 * time.Minute).UTC()
+	attempts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.Header().Set("Date", serverTime.Format(http.TimeFormat))
+		if attempts == 1 {
+			w.WriteHeader(400)
+			fmt.Fprint(w, `<ErrorResponse><Error><Code>RequestExpired</Code><Message>Request has expired.</Message></Error></ErrorResponse>`)
+		}
+	}))
+	defer srv.Close()
+
+	auth := aws.Auth{AccessKey: "abc", SecretKey: "123"}
+	service, err := aws.NewService(auth, aws.ServiceInfo{srv.URL, aws.V2Signature})
+	c.Assert(err, check.IsNil)
+	service.RetryPolicy = &aws.NoRetries
+	resp, err := service.Query("GET", "/", map[string]string{"Action": "ListMetrics"})
+	c.Assert(err, check.IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, check.Equals, 200)
+	c.Assert(attempts, check.Equals, 2)
+	c.Assert(aws.NoRetries.MaxAttempts, check.Equals, 1)
+
+	// A single attempt is added for the correction.
+	attempts = 0
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		serverTime = serverTime.Add(-time.Hour)
+		w.Header().Set("Date", serverTime.Format(http.TimeFormat))
+		w.WriteHeader(400)
+		fmt.Fprint(w, `<ErrorResponse><Error><Code>RequestExpired</Code><Message>Request has expired.</Message></Error></ErrorResponse>`)
+	})
+	resp, err = service.Query("GET", "/", map[string]string{"Action": "ListMetrics"})
+	c.Assert(err, check.IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, check.Equals, 400)
+	c.Assert(attempts, check.Equals, 2)
+}
+
+func (s *S) TestServiceDoesNotRetryWrongSignature(c *check.C) {
+	attempts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(403)
+		fmt.Fprint(w, `<ErrorResponse><Error><Code>SignatureDoesNotMatch</Code><Message>Bad signature.</Message></Error></ErrorResponse>`)
+	}))
+	defer srv.Close()
+
+	auth := aws.Auth{AccessKey: "abc", SecretKey: "123"}
+	service, err := aws.NewService(auth, aws.ServiceInfo{srv.URL, aws.V4Signature})
+	c.Assert(err, check.IsNil)
+	resp, err := service.Query("GET", "/", map[string]string{"Action": "ListMetrics"})
+	c.Assert(err, check.IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, check.Equals, 403)
+	c.Assert(attempts, check.Equals, 1)
+	c.Assert(service.Clock.Offset(), check.Equals, time.Duration(0))
+}
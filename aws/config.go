@@ -0,0 +1,261 @@
+package aws
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds the settings service clients are created with by the
+// NewFromConfig function of their package, as in:
+//
+//	cfg, err := aws.LoadConfig()
+//	...
+//	e := ec2.NewFromConfig(cfg)
+//	b := s3.NewFromConfig(cfg).Bucket("mybucket")
+//
+// Clients created from the same Config share its HTTP client, retry
+// policy and rate limiter.
+type Config struct {
+	// Auth holds the credentials requests are signed with.
+	Auth Auth
+
+	// Region holds the endpoints of the services, overrides included.
+	Region Region
+
+	// Resolver is the resolver Region was built with, for the services
+	// that have no endpoint in Region.
+	Resolver *Resolver
+
+	// HTTPClient is used by the clients to send requests. If nil,
+	// http.DefaultClient is used.
+	HTTPClient *http.Client
+
+	// MaxAttempts, if not zero, replaces the number of attempts of the
+	// default retry policy of each service; see RetryPolicy.
+	MaxAttempts int
+
+	// Limiter, if set, limits the rate of the requests of the clients.
+	Limiter *RateLimiter
+}
+
+// RetryPolicy returns the retry policy of the clients of a service whose
+// default policy is def: def itself, with MaxAttempts attempts if set.
+// The services keep their own delays, tuned to how they throttle. It
+// returns nil when there is nothing to change, for the clients to follow
+// changes to their default policy.
+func (cfg *Config) RetryPolicy(def *RetryPolicy) *RetryPolicy {
+	if cfg.MaxAttempts == 0 {
+		return nil
+	}
+	policy := *def
+	policy.MaxAttempts = cfg.MaxAttempts
+	return &policy
+}
+
+// A ConfigLoader loads a Config from the environment and the shared
+// credentials and config files used by the AWS command line tools.
+//
+// Each setting is read from an environment variable first, and then
+// from the profile in the config file:
+//
+//	AWS_REGION, AWS_DEFAULT_REGION   region
+//	AWS_ENDPOINT_URL                 endpoint_url
+//	AWS_ENDPOINT_URL_<SERVICE>       services (see below)
+//	AWS_USE_FIPS_ENDPOINT            use_fips_endpoint
+//	AWS_USE_DUALSTACK_ENDPOINT       use_dualstack_endpoint
+//	AWS_MAX_ATTEMPTS                 max_attempts
+//	AWS_CONNECT_TIMEOUT              connect_timeout
+//	AWS_READ_TIMEOUT                 read_timeout
+//
+// AWS_ENDPOINT_URL and endpoint_url replace the endpoint of every
+// service, as when using a local emulator. The endpoint of a single
+// service is replaced by AWS_ENDPOINT_URL_<SERVICE>, as in
+// AWS_ENDPOINT_URL_S3 or AWS_ENDPOINT_URL_ELASTIC_LOAD_BALANCING, or by
+// a services section named by the services setting of the profile:
+//
+//	[profile local]
+//	region = us-east-1
+//	services = local-services
+//
+//	[services local-services]
+//	s3 =
+//	  endpoint_url = http://localhost:4566
+//
+// Timeouts are given in seconds, or as Go durations such as "500ms".
+// The connect timeout bounds the time taken to connect to AWS, and the
+// read timeout the time taken by AWS to start replying.
+type ConfigLoader struct {
+	// Profile is the name of the profile to read. If empty, the
+	// AWS_PROFILE environment variable is used, and then "default".
+	Profile string
+
+	// Filename and ConfigFilename are the paths of the shared
+	// credentials and config files, as in SharedCredentialsProvider.
+	Filename       string
+	ConfigFilename string
+
+	// Credentials supplies the credentials. If nil, they are looked
+	// for in the environment, the shared files for the profile, and
+	// the instance role, in that order.
+	Credentials CredentialsProvider
+}
+
+// LoadConfig loads a Config from the environment and the shared files,
+// for the current profile. See ConfigLoader.
+func LoadConfig() (*Config, error) {
+	return ConfigLoader{}.Load()
+}
+
+// configServices maps the names of services in the Resolver to their
+// names in AWS_ENDPOINT_URL_<SERVICE> variables and services sections,
+// once upper-cased for the former.
+var configServices = map[string]string{
+	"autoscaling":          "auto_scaling",
+	"dynamodb":             "dynamodb",
+	"ec2":                  "ec2",
+	"elasticloadbalancing": "elastic_load_balancing",
+	"iam":                  "iam",
+	"monitoring":           "cloudwatch",
+	"rds":                  "rds",
+	"route53":              "route_53",
+	"s3":                   "s3",
+	"sdb":                  "simpledb",
+	"sns":                  "sns",
+	"sqs":                  "sqs",
+	"sts":                  "sts",
+}
+
+// Load loads the Config.
+func (l ConfigLoader) Load() (*Config, error) {
+	profile := profileName(l.Profile)
+	f, err := readINI(sharedConfigFilename(l.ConfigFilename))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	section := f[configSectionName(profile)]
+	setting := func(env, key string) string {
+		if v := os.Getenv(env); v != "" {
+			return v
+		}
+		return section[key]
+	}
+
+	cfg := &Config{Resolver: &Resolver{Overrides: make(map[string]string)}}
+	if cfg.Resolver.UseFIPS, err = configBool(setting("AWS_USE_FIPS_ENDPOINT", "use_fips_endpoint")); err != nil {
+		return nil, err
+	}
+	if cfg.Resolver.UseDualStack, err = configBool(setting("AWS_USE_DUALSTACK_ENDPOINT", "use_dualstack_endpoint")); err != nil {
+		return nil, err
+	}
+	endpoint := setting("AWS_ENDPOINT_URL", "endpoint_url")
+	services := f["services "+section["services"]]
+	for service, id := range configServices {
+		u := os.Getenv("AWS_ENDPOINT_URL_" + strings.ToUpper(id))
+		if u == "" {
+			u = services[id+".endpoint_url"]
+		}
+		if u == "" {
+			u = endpoint
+		}
+		if u != "" {
+			cfg.Resolver.Overrides[service] = strings.TrimRight(u, "/")
+		}
+	}
+
+	name := os.Getenv("AWS_REGION")
+	if name == "" {
+		name = setting("AWS_DEFAULT_REGION", "region")
+	}
+	if name == "" {
+		return nil, fmt.Errorf("no region set in the environment or for profile %q", profile)
+	}
+	if cfg.Region, err = cfg.Resolver.Region(name); err != nil {
+		return nil, err
+	}
+
+	if v := setting("AWS_MAX_ATTEMPTS", "max_attempts"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid max attempts %q", v)
+		}
+		cfg.MaxAttempts = n
+	}
+	connect, err := configDuration(setting("AWS_CONNECT_TIMEOUT", "connect_timeout"))
+	if err != nil {
+		return nil, err
+	}
+	read, err := configDuration(setting("AWS_READ_TIMEOUT", "read_timeout"))
+	if err != nil {
+		return nil, err
+	}
+	if connect > 0 || read > 0 {
+		cfg.HTTPClient = &http.Client{Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			DialContext: (&net.Dialer{
+				Timeout:   connect,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			TLSHandshakeTimeout:   connect,
+			ResponseHeaderTimeout: read,
+			MaxIdleConnsPerHost:   10,
+			IdleConnTimeout:       90 * time.Second,
+		}}
+	}
+
+	credentials := l.Credentials
+	if credentials == nil {
+		credentials = ChainProvider{
+			EnvProvider{},
+			SharedCredentialsProvider{Filename: l.Filename, ConfigFilename: l.ConfigFilename, Profile: l.Profile},
+			InstanceRoleProvider{},
+		}
+	}
+	source := credentials
+	if chain, ok := credentials.(ChainProvider); ok {
+		cfg.Auth, source, err = chain.RetrieveWithSource()
+	} else {
+		cfg.Auth, err = credentials.Retrieve()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !cfg.Auth.expiration.IsZero() {
+		// As in GetAuth, temporary credentials are refreshed from
+		// where they came from.
+		cfg.Auth = newCredentials(source, cfg.Auth).Auth()
+	}
+	return cfg, nil
+}
+
+// configBool parses the boolean setting v, which is false if empty.
+func configBool(v string) (bool, error) {
+	if v == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("invalid boolean setting %q", v)
+	}
+	return b, nil
+}
+
+// configDuration parses the duration setting v, given in seconds or as a
+// Go duration. It is zero if empty.
+func configDuration(v string) (time.Duration, error) {
+	if v == "" {
+		return 0, nil
+	}
+	if seconds, err := strconv.ParseFloat(v, 64); err == nil && seconds >= 0 {
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration setting %q", v)
+	}
+	return d, nil
+}
@@ -0,0 +1,203 @@
+package aws_test
+
+import (
+	"github.com/crowdmob/goamz/aws"
+	"github.com/crowdmob/goamz/aws/metadatatest"
+	"github.com/crowdmob/goamz/cloudwatch"
+	"github.com/crowdmob/goamz/rds"
+	"gopkg.in/check.v1"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var loaderConfig = `
+[default]
+region = eu-west-1
+max_attempts = 5
+read_timeout = 2.5
+
+[profile local]
+region = us-east-1
+endpoint_url = http://localhost:4566/
+services = local-services
+connect_timeout = 500ms
+
+[services local-services]
+s3 =
+  endpoint_url = http://localhost:9000
+dynamodb =
+  endpoint_url = http://localhost:8000
+
+[profile fips]
+region = us-west-2
+use_fips_endpoint = true
+aws_access_key_id = fipsaccess
+aws_secret_access_key = fipssecret
+
+[profile bad]
+region = us-east-1
+read_timeout = soon
+`
+
+func writeLoaderFiles(c *check.C) aws.ConfigLoader {
+	credentials, _ := writeSharedFiles(c)
+	config := filepath.Join(c.MkDir(), "config")
+	c.Assert(ioutil.WriteFile(config, []byte(loaderConfig), 0600), check.IsNil)
+	return aws.ConfigLoader{Filename: credentials, ConfigFilename: config}
+}
+
+func (s *S) TestLoadConfigDefault(c *check.C) {
+	os.Clearenv()
+	cfg, err := writeLoaderFiles(c).Load()
+	c.Assert(err, check.IsNil)
+	c.Assert(cfg.Auth.AccessKey, check.Equals, "defaultaccess")
+	c.Assert(cfg.Region, check.DeepEquals, aws.EUWest)
+	c.Assert(cfg.MaxAttempts, check.Equals, 5)
+	// Each service keeps its own delays.
+	policy := cfg.RetryPolicy(&aws.RetryPolicy{MaxAttempts: 10, BaseDelay: time.Second})
+	c.Assert(policy.MaxAttempts, check.Equals, 5)
+	c.Assert(policy.BaseDelay, check.Equals, time.Second)
+	transport := cfg.HTTPClient.Transport.(*http.Transport)
+	c.Assert(transport.ResponseHeaderTimeout, check.Equals, 2500*time.Millisecond)
+	c.Assert(transport.TLSHandshakeTimeout, check.Equals, time.Duration(0))
+}
+
+func (s *S) TestLoadConfigProfile(c *check.C) {
+	os.Clearenv()
+	os.Setenv("AWS_ACCESS_KEY_ID", "access")
+	os.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	l := writeLoaderFiles(c)
+	l.Profile = "local"
+	cfg, err := l.Load()
+	c.Assert(err, check.IsNil)
+	c.Assert(cfg.Auth.AccessKey, check.Equals, "access")
+	c.Assert(cfg.Region.Name, check.Equals, "us-east-1")
+	c.Assert(cfg.Region.EC2Endpoint, check.Equals, "http://localhost:4566")
+	c.Assert(cfg.Region.SQSEndpoint, check.Equals, "http://localhost:4566")
+	c.Assert(cfg.Region.S3Endpoint, check.Equals, "http://localhost:9000")
+	c.Assert(cfg.Region.DynamoDBEndpoint, check.Equals, "http://localhost:8000")
+	c.Assert(cfg.Resolver.Overrides["route53"], check.Equals, "http://localhost:4566")
+	c.Assert(cfg.MaxAttempts, check.Equals, 0)
+	c.Assert(cfg.RetryPolicy(&aws.DefaultRetryPolicy), check.IsNil)
+	transport := cfg.HTTPClient.Transport.(*http.Transport)
+	c.Assert(transport.TLSHandshakeTimeout, check.Equals, 500*time.Millisecond)
+	c.Assert(transport.ResponseHeaderTimeout, check.Equals, time.Duration(0))
+}
+
+func (s *S) TestLoadConfigEnvironment(c *check.C) {
+	os.Clearenv()
+	l := writeLoaderFiles(c)
+	os.Setenv("AWS_PROFILE", "local")
+	os.Setenv("AWS_REGION", "eu-central-1")
+	os.Setenv("AWS_ENDPOINT_URL", "http://emulator:4566")
+	os.Setenv("AWS_ENDPOINT_URL_S3", "http://minio:9000")
+	os.Setenv("AWS_ENDPOINT_URL_ELASTIC_LOAD_BALANCING", "http://elb:1234")
+	os.Setenv("AWS_MAX_ATTEMPTS", "1")
+	l.Credentials = aws.StaticProvider{Auth: aws.Auth{AccessKey: "static", SecretKey: "secret"}}
+	cfg, err := l.Load()
+	c.Assert(err, check.IsNil)
+	c.Assert(cfg.Auth.AccessKey, check.Equals, "static")
+	c.Assert(cfg.Region.Name, check.Equals, "eu-central-1")
+	c.Assert(cfg.Region.EC2Endpoint, check.Equals, "http://emulator:4566")
+	c.Assert(cfg.Region.S3Endpoint, check.Equals, "http://minio:9000")
+	c.Assert(cfg.Region.ELBEndpoint, check.Equals, "http://elb:1234")
+	c.Assert(cfg.Region.DynamoDBEndpoint, check.Equals, "http://localhost:8000")
+	c.Assert(cfg.MaxAttempts, check.Equals, 1)
+}
+
+func (s *S) TestLoadConfigFIPS(c *check.C) {
+	os.Clearenv()
+	l := writeLoaderFiles(c)
+	l.Profile = "fips"
+	cfg, err := l.Load()
+	c.Assert(err, check.IsNil)
+	c.Assert(cfg.Auth.AccessKey, check.Equals, "fipsaccess")
+	c.Assert(cfg.Region.EC2Endpoint, check.Equals, "https://ec2-fips.us-west-2.amazonaws.com")
+	c.Assert(cfg.HTTPClient, check.IsNil)
+}
+
+func (s *S) TestLoadConfigErrors(c *check.C) {
+	os.Clearenv()
+	l := writeLoaderFiles(c)
+	l.Profile = "bad"
+	_, err := l.Load()
+	c.Assert(err, check.ErrorMatches, `invalid duration setting "soon"`)
+
+	l.Profile = "dev"
+	_, err = l.Load()
+	c.Assert(err, check.ErrorMatches, `no region set in the environment or for profile "dev"`)
+
+	os.Setenv("AWS_REGION", "moon-base-1")
+	_, err = l.Load()
+	c.Assert(err, check.ErrorMatches, `unknown region "moon-base-1"`)
+
+	os.Setenv("AWS_REGION", "us-east-1")
+	os.Setenv("AWS_USE_DUALSTACK_ENDPOINT", "maybe")
+	_, err = l.Load()
+	c.Assert(err, check.ErrorMatches, `invalid boolean setting "maybe"`)
+}
+
+func (s *S) TestLoadConfigInstanceRole(c *check.C) {
+	os.Clearenv()
+	srv, err := metadatatest.NewServer()
+	c.Assert(err, check.IsNil)
+	defer srv.Quit()
+	err = srv.SetRoleCredentials("web", metadatatest.RoleCredentials{
+		AccessKeyId:     "ASIAROLE",
+		SecretAccessKey: "rolesecret",
+		Token:           "roletoken",
+		Expiration:      time.Now().UTC().Add(time.Hour).Format("2006-01-02T15:04:05Z"),
+	})
+	c.Assert(err, check.IsNil)
+	os.Setenv("AWS_EC2_METADATA_SERVICE_ENDPOINT", srv.URL())
+	os.Setenv("AWS_REGION", "us-east-1")
+
+	// With no shared files, the default profile is not required.
+	dir := c.MkDir()
+	l := aws.ConfigLoader{
+		Filename:       filepath.Join(dir, "credentials"),
+		ConfigFilename: filepath.Join(dir, "config"),
+	}
+	cfg, err := l.Load()
+	c.Assert(err, check.IsNil)
+	c.Assert(cfg.Auth.AccessKey, check.Equals, "ASIAROLE")
+	c.Assert(cfg.Auth.Token(), check.Equals, "roletoken")
+
+	// A profile asked for must exist.
+	l.Profile = "default"
+	_, err = l.Load()
+	c.Assert(err, check.ErrorMatches, `.*profile "default" not found.*`)
+}
+
+func (s *S) TestLoadConfigEndpointSigningScope(c *check.C) {
+	var authorization []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = append(authorization, r.Header.Get("Authorization"))
+		w.WriteHeader(500)
+	}))
+	defer srv.Close()
+
+	os.Clearenv()
+	os.Setenv("AWS_REGION", "eu-west-1")
+	os.Setenv("AWS_ENDPOINT_URL", srv.URL)
+	os.Setenv("AWS_ACCESS_KEY_ID", "access")
+	os.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	os.Setenv("AWS_MAX_ATTEMPTS", "1")
+	cfg, err := aws.ConfigLoader{Filename: "/nonexistent", ConfigFilename: "/nonexistent"}.Load()
+	c.Assert(err, check.IsNil)
+
+	r, err := rds.NewFromConfig(cfg)
+	c.Assert(err, check.IsNil)
+	r.DescribeDBInstances("", 0, "")
+	cw, err := cloudwatch.NewFromConfig(cfg)
+	c.Assert(err, check.IsNil)
+	cw.ListMetrics(&cloudwatch.ListMetricsRequest{})
+
+	c.Assert(authorization, check.HasLen, 2)
+	c.Assert(authorization[0], check.Matches, "AWS4-HMAC-SHA256 Credential=access/[0-9]+/eu-west-1/rds/aws4_request, .*")
+	c.Assert(authorization[1], check.Matches, "AWS4-HMAC-SHA256 Credential=access/[0-9]+/eu-west-1/monitoring/aws4_request, .*")
+}
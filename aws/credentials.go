@@ -0,0 +1,293 @@
+package aws
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// A CredentialsProvider supplies the keys used to sign requests.
+//
+// Providers can be combined with ChainProvider to look for credentials
+// in several places, in order. The providers in this package also
+// implement fmt.Stringer, describing where the credentials came from.
+type CredentialsProvider interface {
+	// Retrieve returns the credentials held by the provider, or an
+	// error if it has none. The error is a *NotConfiguredError if the
+	// provider is simply not set up, as when a variable or file is
+	// missing.
+	Retrieve() (Auth, error)
+}
+
+// NotConfiguredError is returned by a provider that has no credentials
+// to supply at all, as opposed to credentials it cannot read or that are
+// incomplete. ChainProvider moves on to its next provider only after
+// such errors.
+type NotConfiguredError struct {
+	Message string
+}
+
+func (e *NotConfiguredError) Error() string {
+	return e.Message
+}
+
+// notConfigured reports whether err is a *NotConfiguredError.
+func notConfigured(err error) bool {
+	_, ok := err.(*NotConfiguredError)
+	return ok
+}
+
+// ProviderFunc adapts a function to the CredentialsProvider interface,
+// allowing callers to plug in their own source of credentials.
+type ProviderFunc func() (Auth, error)
+
+func (f ProviderFunc) Retrieve() (Auth, error) {
+	return f()
+}
+
+func (f ProviderFunc) String() string {
+	return "custom provider"
+}
+
+// StaticProvider supplies a fixed set of credentials.
+type StaticProvider struct {
+	Auth Auth
+}
+
+func (p StaticProvider) Retrieve() (Auth, error) {
+	if p.Auth.AccessKey == "" && p.Auth.SecretKey == "" {
+		return Auth{}, &NotConfiguredError{"no static credentials"}
+	}
+	if p.Auth.AccessKey == "" || p.Auth.SecretKey == "" {
+		return Auth{}, errors.New("static credentials are incomplete")
+	}
+	return p.Auth, nil
+}
+
+func (p StaticProvider) String() string {
+	return "static credentials"
+}
+
+// EnvProvider supplies credentials from the environment, as EnvAuth does.
+type EnvProvider struct{}
+
+func (EnvProvider) Retrieve() (Auth, error) {
+	auth, err := EnvAuth()
+	if err != nil && auth.AccessKey == "" && auth.SecretKey == "" {
+		return auth, &NotConfiguredError{"no credentials in environment"}
+	}
+	return auth, err
+}
+
+func (EnvProvider) String() string {
+	return "environment"
+}
+
+// SharedCredentialsProvider supplies credentials from the shared
+// credentials file (~/.aws/credentials) and, failing that, from the
+// shared config file (~/.aws/config) used by the AWS command line tools.
+type SharedCredentialsProvider struct {
+	// Filename is the path of the credentials file. If empty, the
+	// AWS_SHARED_CREDENTIALS_FILE environment variable is used, and
+	// then ~/.aws/credentials.
+	Filename string
+
+	// ConfigFilename is the path of the config file. If empty, the
+	// AWS_CONFIG_FILE environment variable is used, and then
+	// ~/.aws/config.
+	ConfigFilename string
+
+	// Profile is the name of the profile to read. If empty, the
+	// AWS_PROFILE environment variable is used, and then "default".
+	Profile string
+}
+
+func (p SharedCredentialsProvider) Retrieve() (auth Auth, err error) {
+	profile := profileName(p.Profile)
+
+	// The credentials file names sections after the profile, while the
+	// config file prefixes every section but the default one.
+	f, err := readINI(sharedCredentialsFilename(p.Filename))
+	if err == nil && f[profile] != nil {
+		return authFromSection(f[profile])
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return auth, err
+	}
+	f, err = readINI(sharedConfigFilename(p.ConfigFilename))
+	if err != nil && !os.IsNotExist(err) {
+		return auth, err
+	}
+	if section := f[configSectionName(profile)]; section != nil {
+		return authFromSection(section)
+	}
+	err = fmt.Errorf("profile %q not found in shared credentials or config file", profile)
+	if !explicitProfile(p.Profile) {
+		// Only a profile asked for must exist.
+		err = &NotConfiguredError{err.Error()}
+	}
+	return auth, err
+}
+
+func (p SharedCredentialsProvider) String() string {
+	return fmt.Sprintf("shared credentials (profile %s)", profileName(p.Profile))
+}
+
+func authFromSection(section map[string]string) (auth Auth, err error) {
+	auth.AccessKey = section["aws_access_key_id"]
+	auth.SecretKey = section["aws_secret_access_key"]
+	auth.token = section["aws_session_token"]
+	if auth.token == "" {
+		auth.token = section["aws_security_token"]
+	}
+	switch {
+	case auth.AccessKey == "" && auth.SecretKey == "":
+		// As for profiles only setting a region.
+		err = &NotConfiguredError{"profile has no aws_access_key_id or aws_secret_access_key"}
+	case auth.AccessKey == "" || auth.SecretKey == "":
+		err = errors.New("profile has no aws_access_key_id or aws_secret_access_key")
+	}
+	return
+}
+
+// InstanceRoleProvider supplies the temporary credentials of the IAM
+// role assigned to the EC2 instance the program is running on.
+type InstanceRoleProvider struct {
+	// Client is used to read the credentials from the metadata
+	// service. If nil, NewMetadataClient is used.
+	Client *MetadataClient
+}
+
+func (p InstanceRoleProvider) Retrieve() (auth Auth, err error) {
+	client := p.Client
+	if client == nil {
+		client = NewMetadataClient()
+	}
+	cred, err := client.instanceCredentials()
+	if e, ok := err.(*MetadataError); ok && (e.StatusCode == 0 || e.NotFound()) {
+		// Not running on EC2, or without a role.
+		return auth, &NotConfiguredError{err.Error()}
+	}
+	if err != nil {
+		return auth, err
+	}
+	auth.AccessKey = cred.AccessKeyId
+	auth.SecretKey = cred.SecretAccessKey
+	auth.token = cred.Token
+	exptdate, err := time.Parse("2006-01-02T15:04:05Z", cred.Expiration)
+	if err != nil {
+		err = fmt.Errorf("Error Parseing expiration date: cred.Expiration :%s , error: %s \n", cred.Expiration, err)
+	}
+	auth.expiration = exptdate
+	return auth, err
+}
+
+func (InstanceRoleProvider) String() string {
+	return "instance role"
+}
+
+// ChainProvider looks for credentials in each of its providers in turn,
+// and returns those of the first one that has them. Providers that are
+// not configured are skipped; any other error stops the search, so that
+// broken credentials are reported rather than silently replaced by those
+// of the next provider.
+type ChainProvider []CredentialsProvider
+
+// DefaultChain returns the chain used by GetAuth when no keys are given:
+// the environment, the shared credentials file for the current profile,
+// and the instance role.
+func DefaultChain() ChainProvider {
+	return ChainProvider{
+		EnvProvider{},
+		SharedCredentialsProvider{},
+		InstanceRoleProvider{},
+	}
+}
+
+func (c ChainProvider) Retrieve() (Auth, error) {
+	auth, _, err := c.RetrieveWithSource()
+	return auth, err
+}
+
+// RetrieveWithSource is like Retrieve, but also reports the provider
+// that supplied the credentials.
+func (c ChainProvider) RetrieveWithSource() (Auth, CredentialsProvider, error) {
+	var errs []string
+	for _, p := range c {
+		auth, err := p.Retrieve()
+		if err == nil {
+			return auth, p, nil
+		}
+		if !notConfigured(err) {
+			return Auth{}, nil, fmt.Errorf("%s: %v", providerName(p), err)
+		}
+		errs = append(errs, providerName(p)+": "+err.Error())
+	}
+	msg := "No valid AWS authentication found"
+	if len(errs) > 0 {
+		msg += " (" + strings.Join(errs, "; ") + ")"
+	}
+	return Auth{}, nil, errors.New(msg)
+}
+
+// providerName describes p in errors.
+func providerName(p CredentialsProvider) string {
+	if s, ok := p.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprintf("%T", p)
+}
+
+// profileName returns the profile to use when none is given explicitly.
+func profileName(profile string) string {
+	if profile != "" {
+		return profile
+	}
+	if profile = os.Getenv("AWS_PROFILE"); profile != "" {
+		return profile
+	}
+	if profile = os.Getenv("AWS_DEFAULT_PROFILE"); profile != "" {
+		return profile
+	}
+	return "default"
+}
+
+// explicitProfile reports whether a profile is asked for, as opposed to
+// the default one being used.
+func explicitProfile(profile string) bool {
+	return profile != "" || os.Getenv("AWS_PROFILE") != "" || os.Getenv("AWS_DEFAULT_PROFILE") != ""
+}
+
+// configSectionName returns the name of the section holding profile in
+// the shared config file.
+func configSectionName(profile string) string {
+	if profile == "default" {
+		return profile
+	}
+	return "profile " + profile
+}
+
+func sharedCredentialsFilename(filename string) string {
+	return sharedFilename(filename, "AWS_SHARED_CREDENTIALS_FILE", "credentials")
+}
+
+func sharedConfigFilename(filename string) string {
+	return sharedFilename(filename, "AWS_CONFIG_FILE", "config")
+}
+
+func sharedFilename(filename, env, name string) string {
+	if filename != "" {
+		return filename
+	}
+	if filename = os.Getenv(env); filename != "" {
+		return filename
+	}
+	home := os.Getenv("HOME")
+	if home == "" {
+		home = os.Getenv("USERPROFILE")
+	}
+	return filepath.Join(home, ".aws", name)
+}
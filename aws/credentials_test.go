@@ -0,0 +1,318 @@
+package aws_test
+
+import (
+	"errors"
+	"github.com/crowdmob/goamz/aws"
+	"gopkg.in/check.v1"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var sharedCredentials = `
+# comment
+[default]
+aws_access_key_id = defaultaccess
+aws_secret_access_key = defaultsecret
+
+[dev]
+aws_access_key_id=devaccess
+aws_secret_access_key=devsecret
+aws_session_token=devtoken
+`
+
+var sharedConfig = `
+[default]
+region = us-east-1
+
+[profile ops]
+region = eu-west-1
+aws_access_key_id = opsaccess
+aws_secret_access_key = opssecret
+`
+
+func writeSharedFiles(c *check.C) (credentials, config string) {
+	dir := c.MkDir()
+	credentials = filepath.Join(dir, "credentials")
+	config = filepath.Join(dir, "config")
+	c.Assert(ioutil.WriteFile(credentials, []byte(sharedCredentials), 0600), check.IsNil)
+	c.Assert(ioutil.WriteFile(config, []byte(sharedConfig), 0600), check.IsNil)
+	return
+}
+
+func (s *S) TestEnvAuthSessionToken(c *check.C) {
+	os.Clearenv()
+	os.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	os.Setenv("AWS_ACCESS_KEY_ID", "access")
+	os.Setenv("AWS_SESSION_TOKEN", "token")
+	auth, err := aws.EnvAuth()
+	c.Assert(err, check.IsNil)
+	c.Assert(auth.Token(), check.Equals, "token")
+}
+
+func (s *S) TestSharedCredentialsDefault(c *check.C) {
+	os.Clearenv()
+	credentials, config := writeSharedFiles(c)
+	p := aws.SharedCredentialsProvider{Filename: credentials, ConfigFilename: config}
+	auth, err := p.Retrieve()
+	c.Assert(err, check.IsNil)
+	c.Assert(auth.AccessKey, check.Equals, "defaultaccess")
+	c.Assert(auth.SecretKey, check.Equals, "defaultsecret")
+	c.Assert(auth.Token(), check.Equals, "")
+}
+
+func (s *S) TestSharedCredentialsProfileFromEnv(c *check.C) {
+	os.Clearenv()
+	credentials, config := writeSharedFiles(c)
+	os.Setenv("AWS_SHARED_CREDENTIALS_FILE", credentials)
+	os.Setenv("AWS_CONFIG_FILE", config)
+	os.Setenv("AWS_PROFILE", "dev")
+	auth, err := aws.SharedCredentialsProvider{}.Retrieve()
+	c.Assert(err, check.IsNil)
+	c.Assert(auth.AccessKey, check.Equals, "devaccess")
+	c.Assert(auth.SecretKey, check.Equals, "devsecret")
+	c.Assert(auth.Token(), check.Equals, "devtoken")
+}
+
+func (s *S) TestSharedCredentialsFromConfig(c *check.C) {
+	os.Clearenv()
+	credentials, config := writeSharedFiles(c)
+	p := aws.SharedCredentialsProvider{Filename: credentials, ConfigFilename: config, Profile: "ops"}
+	auth, err := p.Retrieve()
+	c.Assert(err, check.IsNil)
+	c.Assert(auth.AccessKey, check.Equals, "opsaccess")
+	c.Assert(auth.SecretKey, check.Equals, "opssecret")
+}
+
+func (s *S) TestSharedCredentialsMissingProfile(c *check.C) {
+	os.Clearenv()
+	credentials, config := writeSharedFiles(c)
+	p := aws.SharedCredentialsProvider{Filename: credentials, ConfigFilename: config, Profile: "nope"}
+	_, err := p.Retrieve()
+	c.Assert(err, check.ErrorMatches, `profile "nope" not found .*`)
+}
+
+func (s *S) TestChainProviderSource(c *check.C) {
+	os.Clearenv()
+	credentials, config := writeSharedFiles(c)
+	shared := aws.SharedCredentialsProvider{Filename: credentials, ConfigFilename: config}
+	chain := aws.ChainProvider{aws.EnvProvider{}, shared}
+	auth, source, err := chain.RetrieveWithSource()
+	c.Assert(err, check.IsNil)
+	c.Assert(auth.AccessKey, check.Equals, "defaultaccess")
+	c.Assert(source, check.Equals, aws.CredentialsProvider(shared))
+
+	os.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	os.Setenv("AWS_ACCESS_KEY_ID", "access")
+	auth, source, err = chain.RetrieveWithSource()
+	c.Assert(err, check.IsNil)
+	c.Assert(auth.AccessKey, check.Equals, "access")
+	c.Assert(source, check.Equals, aws.CredentialsProvider(aws.EnvProvider{}))
+}
+
+func (s *S) TestChainProviderCustom(c *check.C) {
+	custom := aws.ProviderFunc(func() (aws.Auth, error) {
+		return aws.GetAuth("custom", "secret", "", time.Time{})
+	})
+	chain := aws.ChainProvider{aws.StaticProvider{}, custom}
+	auth, err := chain.Retrieve()
+	c.Assert(err, check.IsNil)
+	c.Assert(auth.AccessKey, check.Equals, "custom")
+}
+
+func (s *S) TestChainProviderNone(c *check.C) {
+	os.Clearenv()
+	chain := aws.ChainProvider{aws.StaticProvider{}, aws.EnvProvider{}}
+	_, err := chain.Retrieve()
+	c.Assert(err, check.ErrorMatches, `No valid AWS authentication found \(static credentials: no static credentials; environment: no credentials in environment\)`)
+}
+
+func (s *S) TestChainProviderBroken(c *check.C) {
+	os.Clearenv()
+	credentials, config := writeSharedFiles(c)
+	shared := aws.SharedCredentialsProvider{Filename: credentials, ConfigFilename: config}
+	chain := aws.ChainProvider{aws.EnvProvider{}, shared}
+
+	// Incomplete credentials are not replaced by those of the next
+	// provider.
+	os.Setenv("AWS_ACCESS_KEY_ID", "access")
+	_, err := chain.Retrieve()
+	c.Assert(err, check.ErrorMatches, "environment: AWS_SECRET_ACCESS_KEY or AWS_SECRET_KEY not found in environment")
+
+	// Nor is a profile asked for that does not exist.
+	os.Clearenv()
+	os.Setenv("AWS_PROFILE", "nope")
+	_, err = aws.ChainProvider{shared, aws.StaticProvider{Auth: aws.Auth{AccessKey: "a", SecretKey: "s"}}}.Retrieve()
+	c.Assert(err, check.ErrorMatches, `shared credentials \(profile nope\): profile "nope" not found .*`)
+}
+
+// countingProvider hands out new keys, valid for lifetime, on each call.
+type countingProvider struct {
+	mu       sync.Mutex
+	calls    int
+	lifetime time.Duration
+	fail     bool
+}
+
+func (p *countingProvider) Retrieve() (aws.Auth, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls++
+	if p.fail {
+		return aws.Auth{}, errors.New("provider is down")
+	}
+	n := strconv.Itoa(p.calls)
+	return aws.GetAuth("access"+n, "secret"+n, "token"+n, time.Now().Add(p.lifetime))
+}
+
+func (p *countingProvider) Calls() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls
+}
+
+func (s *S) TestCredentialsSharedByCopies(c *check.C) {
+	p := &countingProvider{lifetime: time.Hour}
+	creds, err := aws.NewCredentials(p)
+	c.Assert(err, check.IsNil)
+	defer creds.Stop()
+
+	a := creds.Auth()
+	b := a
+	c.Assert(a.AccessKey, check.Equals, "access1")
+	c.Assert(creds.Refresh(), check.IsNil)
+	for _, auth := range []aws.Auth{a, b} {
+		snap, err := auth.Snapshot()
+		c.Assert(err, check.IsNil)
+		c.Assert(snap.AccessKey, check.Equals, "access2")
+		c.Assert(snap.SecretKey, check.Equals, "secret2")
+		c.Assert(snap.Token(), check.Equals, "token2")
+	}
+}
+
+func (s *S) TestCredentialsBackgroundRefresh(c *check.C) {
+	p := &countingProvider{lifetime: 200 * time.Millisecond}
+	creds, err := aws.NewCredentials(p)
+	c.Assert(err, check.IsNil)
+	defer creds.Stop()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for p.Calls() < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Assert(p.Calls() >= 3, check.Equals, true)
+	c.Assert(creds.Err(), check.IsNil)
+}
+
+func (s *S) TestCredentialsRefreshError(c *check.C) {
+	p := &countingProvider{lifetime: 50 * time.Millisecond}
+	creds, err := aws.NewCredentials(p)
+	c.Assert(err, check.IsNil)
+	creds.Stop()
+
+	p.mu.Lock()
+	p.fail = true
+	p.mu.Unlock()
+	time.Sleep(100 * time.Millisecond)
+
+	auth := creds.Auth()
+	_, err = auth.Snapshot()
+	c.Assert(err, check.ErrorMatches, "provider is down")
+	c.Assert(creds.Err(), check.ErrorMatches, "provider is down")
+}
+
+func (s *S) TestCredentialsConsistentSnapshot(c *check.C) {
+	p := &countingProvider{lifetime: time.Hour}
+	creds, err := aws.NewCredentials(p)
+	c.Assert(err, check.IsNil)
+	defer creds.Stop()
+	auth := creds.Auth()
+
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < 100; i++ {
+			creds.Refresh()
+		}
+		close(done)
+	}()
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		snap, err := auth.Snapshot()
+		c.Assert(err, check.IsNil)
+		c.Assert(snap.SecretKey[len("secret"):], check.Equals, snap.AccessKey[len("access"):])
+		c.Assert(snap.Token()[len("token"):], check.Equals, snap.AccessKey[len("access"):])
+	}
+}
+
+func (s *S) TestCredentialsLazyRefresh(c *check.C) {
+	p := &countingProvider{lifetime: 50 * time.Millisecond}
+	auth, err := p.Retrieve()
+	c.Assert(err, check.IsNil)
+	creds := aws.NewLazyCredentials(p, auth)
+
+	// No goroutine refreshes the credentials: they are refreshed once
+	// used after they expired.
+	time.Sleep(100 * time.Millisecond)
+	c.Assert(p.Calls(), check.Equals, 1)
+	auth = creds.Auth()
+	snap, err := auth.Snapshot()
+	c.Assert(err, check.IsNil)
+	c.Assert(snap.AccessKey, check.Equals, "access2")
+	c.Assert(p.Calls(), check.Equals, 2)
+}
+
+// gatedProvider is a countingProvider whose calls, after the first, wait
+// for gate to be closed.
+type gatedProvider struct {
+	countingProvider
+	entered chan bool
+	gate    chan bool
+}
+
+func (p *gatedProvider) Retrieve() (aws.Auth, error) {
+	if p.Calls() > 0 {
+		p.entered <- true
+		<-p.gate
+	}
+	return p.countingProvider.Retrieve()
+}
+
+func (s *S) TestCredentialsRefreshSingleFlight(c *check.C) {
+	p := &gatedProvider{
+		countingProvider: countingProvider{lifetime: time.Hour},
+		entered:          make(chan bool, 2),
+		gate:             make(chan bool),
+	}
+	creds, err := aws.NewCredentials(p)
+	c.Assert(err, check.IsNil)
+	defer creds.Stop()
+	auth := creds.Auth()
+
+	errs := make(chan error, 2)
+	go func() { errs <- creds.Refresh() }()
+	<-p.entered
+	go func() { errs <- creds.Refresh() }()
+
+	// Signatures go on with the current keys while the provider is
+	// called.
+	snap, err := auth.Snapshot()
+	c.Assert(err, check.IsNil)
+	c.Assert(snap.AccessKey, check.Equals, "access1")
+
+	// Give the second Refresh time to join the refresh in flight.
+	time.Sleep(50 * time.Millisecond)
+	close(p.gate)
+	c.Assert(<-errs, check.IsNil)
+	c.Assert(<-errs, check.IsNil)
+	c.Assert(p.Calls(), check.Equals, 2)
+	snap, err = auth.Snapshot()
+	c.Assert(err, check.IsNil)
+	c.Assert(snap.AccessKey, check.Equals, "access2")
+}
@@ -0,0 +1,297 @@
+package aws
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// A Partition is a group of regions sharing a DNS suffix. Credentials
+// issued in one partition are not valid in the others.
+type Partition struct {
+	// ID names the partition, as in the ARNs of its resources: "aws",
+	// "aws-cn" or "aws-us-gov".
+	ID string
+
+	// DNSSuffix is the domain the endpoints of the partition live in.
+	DNSSuffix string
+
+	// Regions lists the regions known to be part of the partition.
+	Regions []string
+
+	// NoFIPS is set when the partition has no FIPS endpoints.
+	NoFIPS bool
+
+	// pattern matches the names of the regions of the partition,
+	// including regions not listed yet.
+	pattern *regexp.Regexp
+
+	// global maps the services that have a single endpoint for the whole
+	// partition to their host and the region they are signed for.
+	global map[string]globalEndpoint
+
+	// hosts holds the hosts that do not follow the usual pattern, by
+	// service and then region.
+	hosts map[string]map[string]string
+
+	// only restricts services offered in some regions only.
+	only map[string][]string
+}
+
+type globalEndpoint struct {
+	host, region string
+}
+
+// Partitions lists the partitions known to the resolver.
+var Partitions = []*Partition{
+	{
+		ID:        "aws",
+		DNSSuffix: "amazonaws.com",
+		Regions: []string{
+			"us-east-1", "us-east-2", "us-west-1", "us-west-2",
+			"ca-central-1", "sa-east-1",
+			"eu-west-1", "eu-west-2", "eu-west-3", "eu-central-1", "eu-north-1",
+			"ap-northeast-1", "ap-northeast-2", "ap-northeast-3",
+			"ap-southeast-1", "ap-southeast-2", "ap-south-1",
+		},
+		pattern: regexp.MustCompile(`^(us|eu|ap|sa|ca|me|af)-\w+-\d+$`),
+		global: map[string]globalEndpoint{
+			"iam": {"iam.amazonaws.com", "us-east-1"},
+			"sts": {"sts.amazonaws.com", "us-east-1"},
+		},
+		hosts: map[string]map[string]string{
+			"s3": {
+				"us-east-1":      "s3.amazonaws.com",
+				"us-west-1":      "s3-us-west-1.amazonaws.com",
+				"us-west-2":      "s3-us-west-2.amazonaws.com",
+				"eu-west-1":      "s3-eu-west-1.amazonaws.com",
+				"ap-southeast-1": "s3-ap-southeast-1.amazonaws.com",
+				"ap-southeast-2": "s3-ap-southeast-2.amazonaws.com",
+				"ap-northeast-1": "s3-ap-northeast-1.amazonaws.com",
+				"sa-east-1":      "s3-sa-east-1.amazonaws.com",
+			},
+			"sdb": {
+				"us-east-1": "sdb.amazonaws.com",
+			},
+		},
+		only: map[string][]string{
+			"sdb": {
+				"us-east-1", "us-west-1", "us-west-2", "eu-west-1",
+				"ap-southeast-1", "ap-southeast-2", "ap-northeast-1", "sa-east-1",
+			},
+		},
+	},
+	{
+		ID:        "aws-cn",
+		DNSSuffix: "amazonaws.com.cn",
+		Regions:   []string{"cn-north-1", "cn-northwest-1"},
+		NoFIPS:    true,
+		pattern:   regexp.MustCompile(`^cn-\w+-\d+$`),
+		global: map[string]globalEndpoint{
+			"iam": {"iam.cn-north-1.amazonaws.com.cn", "cn-north-1"},
+		},
+		only: map[string][]string{
+			"sdb": nil,
+		},
+	},
+	{
+		ID:        "aws-us-gov",
+		DNSSuffix: "amazonaws.com",
+		Regions:   []string{"us-gov-west-1", "us-gov-east-1"},
+		pattern:   regexp.MustCompile(`^us-gov-\w+-\d+$`),
+		global: map[string]globalEndpoint{
+			"iam": {"iam.us-gov.amazonaws.com", "us-gov-west-1"},
+		},
+		hosts: map[string]map[string]string{
+			"s3": {
+				"us-gov-west-1": "s3-fips-us-gov-west-1.amazonaws.com",
+			},
+		},
+		only: map[string][]string{
+			"sdb": nil,
+		},
+	},
+}
+
+// PartitionForRegion returns the partition the named region belongs to,
+// or nil if the name does not look like the name of a region.
+func PartitionForRegion(region string) *Partition {
+	for _, p := range Partitions {
+		for _, name := range p.Regions {
+			if name == region {
+				return p
+			}
+		}
+	}
+	// The aws partition matches the region names of the other ones too.
+	for i := len(Partitions) - 1; i >= 0; i-- {
+		if Partitions[i].pattern.MatchString(region) {
+			return Partitions[i]
+		}
+	}
+	return nil
+}
+
+// An Endpoint is where requests to a service in a region are sent.
+type Endpoint struct {
+	// URL of the endpoint, as in "https://ec2.us-west-2.amazonaws.com".
+	URL string
+
+	// SigningRegion is the region Signature Version 4 signatures are
+	// scoped to. It differs from the region of the request for services
+	// with a single endpoint per partition, such as IAM.
+	SigningRegion string
+}
+
+// A Resolver builds the endpoints of services from the names of the
+// service and region.
+//
+// Endpoints follow the pattern "https://{service}.{region}.{suffix}" of
+// the partition of the region, with a few exceptions for global services
+// and older S3 and SimpleDB endpoints. FIPS endpoints add "-fips" to the
+// name of the service, and dual-stack endpoints (reachable over both IPv4
+// and IPv6) add "dualstack" before the region.
+type Resolver struct {
+	// Overrides maps service names, as in "ec2" or "s3", to the endpoint
+	// used for them in every region, such as the URL of a local
+	// stand-in. Overridden endpoints are used as they are.
+	Overrides map[string]string
+
+	// UseFIPS selects FIPS 140-2 validated endpoints.
+	UseFIPS bool
+
+	// UseDualStack selects dual-stack endpoints.
+	UseDualStack bool
+}
+
+// DefaultResolver is the resolver used to build the predefined regions.
+var DefaultResolver = &Resolver{}
+
+// ResolveEndpoint returns the endpoint of service in region. It fails if
+// the region is unknown, or if the service is not offered there.
+func (r *Resolver) ResolveEndpoint(service, region string) (Endpoint, error) {
+	if u, ok := r.Overrides[service]; ok {
+		return Endpoint{URL: u, SigningRegion: region}, nil
+	}
+	p := PartitionForRegion(region)
+	if p == nil {
+		return Endpoint{}, fmt.Errorf("unknown region %q", region)
+	}
+	if regions, ok := p.only[service]; ok && !contains(regions, region) {
+		return Endpoint{}, fmt.Errorf("service %q is not available in region %q", service, region)
+	}
+	if r.UseFIPS && p.NoFIPS {
+		return Endpoint{}, fmt.Errorf("partition %s has no FIPS endpoints", p.ID)
+	}
+	name := service
+	if r.UseFIPS {
+		name += "-fips"
+	}
+	e := Endpoint{SigningRegion: region}
+	var host string
+	if g, ok := p.global[service]; ok {
+		e.SigningRegion = g.region
+		host = g.host
+		if r.UseFIPS || r.UseDualStack {
+			// Variants of global endpoints are served from the region
+			// the service lives in.
+			host = ""
+			region = g.region
+		}
+	} else if !r.UseFIPS && !r.UseDualStack {
+		host = p.hosts[service][region]
+	}
+	switch {
+	case host != "":
+	case r.UseDualStack:
+		host = fmt.Sprintf("%s.dualstack.%s.%s", name, region, p.DNSSuffix)
+	default:
+		host = fmt.Sprintf("%s.%s.%s", name, region, p.DNSSuffix)
+	}
+	e.URL = "https://" + host
+	return e, nil
+}
+
+// Region returns the Region holding the endpoints of the services in the
+// named region. Endpoints of services that are not offered in the region
+// are left empty.
+func (r *Resolver) Region(name string) (Region, error) {
+	if PartitionForRegion(name) == nil {
+		return Region{}, fmt.Errorf("unknown region %q", name)
+	}
+	url := func(service string) string {
+		e, err := r.ResolveEndpoint(service, name)
+		if err != nil {
+			return ""
+		}
+		return e.URL
+	}
+	// Buckets outside of us-east-1 are created with a location
+	// constraint, and must be named as DNS labels.
+	legacyS3 := name == "us-east-1"
+	return Region{
+		Name:                   name,
+		EC2Endpoint:            url("ec2"),
+		S3Endpoint:             url("s3"),
+		S3LocationConstraint:   !legacyS3,
+		S3LowercaseBucket:      !legacyS3,
+		SDBEndpoint:            url("sdb"),
+		SNSEndpoint:            url("sns"),
+		SQSEndpoint:            url("sqs"),
+		IAMEndpoint:            url("iam"),
+		ELBEndpoint:            url("elasticloadbalancing"),
+		DynamoDBEndpoint:       url("dynamodb"),
+		CloudWatchServicepoint: ServiceInfo{url("monitoring"), V4Signature},
+		AutoScalingEndpoint:    url("autoscaling"),
+		RDSEndpoint:            ServiceInfo{url("rds"), V4Signature},
+		STSEndpoint:            url("sts"),
+	}, nil
+}
+
+// SigningRegion returns the region Signature Version 4 signatures for
+// service are scoped to, when requests are sent to the named region. It
+// returns region itself if it is unknown.
+func SigningRegion(service, region string) string {
+	e, err := DefaultResolver.ResolveEndpoint(service, region)
+	if err != nil {
+		return region
+	}
+	return e.SigningRegion
+}
+
+func mustRegion(name string) Region {
+	r, err := DefaultResolver.Region(name)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+func knownRegions() map[string]Region {
+	regions := make(map[string]Region)
+	for _, p := range Partitions {
+		for _, name := range p.Regions {
+			regions[name] = mustRegion(name)
+		}
+	}
+	return regions
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// regionOfHost returns the region found in an endpoint host name such as
+// "monitoring.us-west-2.amazonaws.com", or "" if there is none.
+func regionOfHost(host string) string {
+	parts := strings.Split(host, ".")
+	if len(parts) > 3 && PartitionForRegion(parts[1]) != nil {
+		return parts[1]
+	}
+	return ""
+}
@@ -0,0 +1,90 @@
+package aws_test
+
+import (
+	"github.com/crowdmob/goamz/aws"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestResolveEndpoint(c *check.C) {
+	tests := []struct {
+		resolver       aws.Resolver
+		service, name  string
+		url, signingIn string
+	}{
+		{aws.Resolver{}, "ec2", "eu-central-1", "https://ec2.eu-central-1.amazonaws.com", "eu-central-1"},
+		{aws.Resolver{}, "s3", "us-east-1", "https://s3.amazonaws.com", "us-east-1"},
+		{aws.Resolver{}, "s3", "eu-west-1", "https://s3-eu-west-1.amazonaws.com", "eu-west-1"},
+		{aws.Resolver{}, "s3", "eu-central-1", "https://s3.eu-central-1.amazonaws.com", "eu-central-1"},
+		{aws.Resolver{}, "iam", "us-west-2", "https://iam.amazonaws.com", "us-east-1"},
+		{aws.Resolver{}, "iam", "us-gov-west-1", "https://iam.us-gov.amazonaws.com", "us-gov-west-1"},
+		{aws.Resolver{}, "sts", "us-gov-east-1", "https://sts.us-gov-east-1.amazonaws.com", "us-gov-east-1"},
+		{aws.Resolver{}, "sqs", "cn-north-1", "https://sqs.cn-north-1.amazonaws.com.cn", "cn-north-1"},
+		{aws.Resolver{}, "iam", "cn-northwest-1", "https://iam.cn-north-1.amazonaws.com.cn", "cn-north-1"},
+		{aws.Resolver{}, "ec2", "me-south-1", "https://ec2.me-south-1.amazonaws.com", "me-south-1"},
+		{aws.Resolver{UseFIPS: true}, "ec2", "us-east-1", "https://ec2-fips.us-east-1.amazonaws.com", "us-east-1"},
+		{aws.Resolver{UseFIPS: true}, "iam", "us-west-2", "https://iam-fips.us-east-1.amazonaws.com", "us-east-1"},
+		{aws.Resolver{UseDualStack: true}, "s3", "us-west-2", "https://s3.dualstack.us-west-2.amazonaws.com", "us-west-2"},
+		{aws.Resolver{UseFIPS: true, UseDualStack: true}, "s3", "us-gov-west-1", "https://s3-fips.dualstack.us-gov-west-1.amazonaws.com", "us-gov-west-1"},
+		{aws.Resolver{Overrides: map[string]string{"sqs": "http://localhost:4444"}}, "sqs", "eu-west-1", "http://localhost:4444", "eu-west-1"},
+	}
+	for _, t := range tests {
+		e, err := t.resolver.ResolveEndpoint(t.service, t.name)
+		c.Assert(err, check.IsNil)
+		c.Check(e.URL, check.Equals, t.url)
+		c.Check(e.SigningRegion, check.Equals, t.signingIn)
+	}
+}
+
+func (s *S) TestResolveEndpointErrors(c *check.C) {
+	r := aws.Resolver{}
+	_, err := r.ResolveEndpoint("ec2", "moon-base-1")
+	c.Assert(err, check.ErrorMatches, `unknown region "moon-base-1"`)
+	_, err = r.ResolveEndpoint("sdb", "eu-central-1")
+	c.Assert(err, check.ErrorMatches, `service "sdb" is not available in region "eu-central-1"`)
+	r.UseFIPS = true
+	_, err = r.ResolveEndpoint("ec2", "cn-north-1")
+	c.Assert(err, check.ErrorMatches, "partition aws-cn has no FIPS endpoints")
+}
+
+func (s *S) TestPartitionForRegion(c *check.C) {
+	c.Assert(aws.PartitionForRegion("us-east-1").ID, check.Equals, "aws")
+	c.Assert(aws.PartitionForRegion("us-gov-west-1").ID, check.Equals, "aws-us-gov")
+	c.Assert(aws.PartitionForRegion("cn-north-1").ID, check.Equals, "aws-cn")
+	c.Assert(aws.PartitionForRegion("cn-south-9").ID, check.Equals, "aws-cn")
+	c.Assert(aws.PartitionForRegion("ap-east-1").ID, check.Equals, "aws")
+	c.Assert(aws.PartitionForRegion("local"), check.IsNil)
+}
+
+func (s *S) TestResolverRegion(c *check.C) {
+	r := aws.Resolver{Overrides: map[string]string{"ec2": "http://localhost:4444"}}
+	region, err := r.Region("eu-central-1")
+	c.Assert(err, check.IsNil)
+	c.Assert(region.Name, check.Equals, "eu-central-1")
+	c.Assert(region.EC2Endpoint, check.Equals, "http://localhost:4444")
+	c.Assert(region.SQSEndpoint, check.Equals, "https://sqs.eu-central-1.amazonaws.com")
+	c.Assert(region.SDBEndpoint, check.Equals, "")
+	c.Assert(region.S3LocationConstraint, check.Equals, true)
+	c.Assert(region.RDSEndpoint, check.Equals, aws.ServiceInfo{"https://rds.eu-central-1.amazonaws.com", aws.V4Signature})
+
+	_, err = r.Region("nowhere")
+	c.Assert(err, check.NotNil)
+}
+
+func (s *S) TestPredefinedRegions(c *check.C) {
+	c.Assert(aws.USEast.S3Endpoint, check.Equals, "https://s3.amazonaws.com")
+	c.Assert(aws.USEast.SDBEndpoint, check.Equals, "https://sdb.amazonaws.com")
+	c.Assert(aws.USEast.S3LocationConstraint, check.Equals, false)
+	c.Assert(aws.USGovWest.S3Endpoint, check.Equals, "https://s3-fips-us-gov-west-1.amazonaws.com")
+	c.Assert(aws.USGovWest.SDBEndpoint, check.Equals, "")
+	c.Assert(aws.USGovWest.STSEndpoint, check.Equals, "https://sts.us-gov-west-1.amazonaws.com")
+	c.Assert(aws.SAEast.STSEndpoint, check.Equals, "https://sts.amazonaws.com")
+	c.Assert(aws.CNNorth.EC2Endpoint, check.Equals, "https://ec2.cn-north-1.amazonaws.com.cn")
+	c.Assert(aws.Regions["eu-north-1"].Name, check.Equals, "eu-north-1")
+	c.Assert(aws.Regions["cn-northwest-1"].Name, check.Equals, "cn-northwest-1")
+}
+
+func (s *S) TestSigningRegion(c *check.C) {
+	c.Assert(aws.SigningRegion("iam", "eu-west-1"), check.Equals, "us-east-1")
+	c.Assert(aws.SigningRegion("ec2", "eu-west-1"), check.Equals, "eu-west-1")
+	c.Assert(aws.SigningRegion("ec2", "local"), check.Equals, "local")
+}
@@ -0,0 +1,88 @@
+package aws
+
+import "errors"
+
+// APIError is implemented by the errors every service package returns for
+// requests that AWS failed, so that they can be handled alike whichever
+// service returned them.
+type APIError interface {
+	error
+
+	// ErrorCode returns the AWS error code, as in "NoSuchKey" or
+	// "Throttling".
+	ErrorCode() string
+
+	// ErrorMessage returns the human-oriented message of the error.
+	ErrorMessage() string
+
+	// HTTPStatusCode returns the status code of the HTTP response.
+	HTTPStatusCode() int
+
+	// RequestID returns the ID AWS assigned to the failed request, or ""
+	// if it is unknown.
+	RequestID() string
+
+	// Retryable reports whether sending the same request again may
+	// succeed, as decided by the retry policy of the service.
+	Retryable() bool
+}
+
+// AsAPIError returns the first APIError in the chain of err.
+func AsAPIError(err error) (APIError, bool) {
+	var e APIError
+	if errors.As(err, &e) {
+		return e, true
+	}
+	return nil, false
+}
+
+// IsCode reports whether err is an APIError with one of the given codes.
+func IsCode(err error, codes ...string) bool {
+	e, ok := AsAPIError(err)
+	if !ok {
+		return false
+	}
+	for _, code := range codes {
+		if e.ErrorCode() == code {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrorCode returns the AWS error code of err, or "" if err is not an
+// APIError.
+func ErrorCode(err error) string {
+	if e, ok := AsAPIError(err); ok {
+		return e.ErrorCode()
+	}
+	return ""
+}
+
+// IsRetryable reports whether the request that failed with err may
+// succeed if it is sent again: either AWS says so, or err is a transient
+// network failure.
+func IsRetryable(err error) bool {
+	if e, ok := AsAPIError(err); ok {
+		return e.Retryable()
+	}
+	return IsTransientNetError(err)
+}
+
+// ErrorCode returns the AWS error code.
+func (err *Error) ErrorCode() string { return err.Code }
+
+// ErrorMessage returns the message of the error.
+func (err *Error) ErrorMessage() string { return err.Message }
+
+// HTTPStatusCode returns the status code of the HTTP response.
+func (err *Error) HTTPStatusCode() int { return err.StatusCode }
+
+// RequestID returns the ID of the failed request.
+func (err *Error) RequestID() string { return err.RequestId }
+
+// Retryable reports whether sending the request again may succeed.
+func (err *Error) Retryable() bool {
+	retry, _ := ClassifyResponse(err.StatusCode, err.Code)
+	return retry
+}
@@ -0,0 +1,37 @@
+package aws_test
+
+import (
+	"errors"
+	"fmt"
+	"github.com/crowdmob/goamz/aws"
+	"gopkg.in/check.v1"
+	"io"
+)
+
+func (s *S) TestIsCode(c *check.C) {
+	err := &aws.Error{StatusCode: 404, Code: "NoSuchHostedZone", Message: "No hosted zone", RequestId: "r1"}
+	c.Assert(aws.IsCode(err, "NoSuchHostedZone"), check.Equals, true)
+	c.Assert(aws.IsCode(err, "Throttling", "NoSuchHostedZone"), check.Equals, true)
+	c.Assert(aws.IsCode(err, "Throttling"), check.Equals, false)
+	c.Assert(aws.ErrorCode(err), check.Equals, "NoSuchHostedZone")
+
+	wrapped := fmt.Errorf("deleting zone: %w", err)
+	c.Assert(aws.IsCode(wrapped, "NoSuchHostedZone"), check.Equals, true)
+	e, ok := aws.AsAPIError(wrapped)
+	c.Assert(ok, check.Equals, true)
+	c.Assert(e.RequestID(), check.Equals, "r1")
+	c.Assert(e.HTTPStatusCode(), check.Equals, 404)
+	c.Assert(e.ErrorMessage(), check.Equals, "No hosted zone")
+
+	c.Assert(aws.IsCode(errors.New("NoSuchHostedZone"), "NoSuchHostedZone"), check.Equals, false)
+	c.Assert(aws.IsCode(nil, "NoSuchHostedZone"), check.Equals, false)
+	c.Assert(aws.ErrorCode(io.EOF), check.Equals, "")
+}
+
+func (s *S) TestIsRetryable(c *check.C) {
+	c.Assert(aws.IsRetryable(&aws.Error{StatusCode: 400, Code: "Throttling"}), check.Equals, true)
+	c.Assert(aws.IsRetryable(&aws.Error{StatusCode: 503}), check.Equals, true)
+	c.Assert(aws.IsRetryable(&aws.Error{StatusCode: 400, Code: "InvalidInput"}), check.Equals, false)
+	c.Assert(aws.IsRetryable(io.ErrUnexpectedEOF), check.Equals, true)
+	c.Assert(aws.IsRetryable(errors.New("boom")), check.Equals, false)
+}
@@ -27,3 +27,29 @@ func (s *V4Signer) Signature(t time.Time, sts string) string {
 func (s *V4Signer) Authorization(header http.Header, t time.Time, signature string) string {
 	return s.authorization(header, t, signature)
 }
+
+func (s *V4Signer) PresignAt(req *http.Request, expires time.Duration, t time.Time) {
+	s.presign(req, expires, t)
+}
+
+func EndpointScope(endpoint string) (service, region string, err error) {
+	return endpointScope(endpoint)
+}
+
+// RateLimiter:
+// Exporting methods for testing
+
+func (l *RateLimiter) SetNow(now func() time.Time) {
+	l.now = now
+}
+
+func (l *RateLimiter) Reserve(service, operation string) time.Duration {
+	return l.reserve(service, operation)
+}
+
+// Credentials:
+// Exporting methods for testing
+
+func NewLazyCredentials(p CredentialsProvider, auth Auth) *Credentials {
+	return newCredentials(p, auth)
+}
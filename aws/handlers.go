@@ -0,0 +1,278 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// A Hook is called by service clients at some step of sending a request.
+// Hooks run in the goroutine issuing the request, and must not keep r.
+type Hook func(r *RequestInfo)
+
+// Handlers holds the hooks service clients run while sending requests,
+// in order to plug in logging, metrics or tracing.
+//
+// Every service client has a Handlers field; when nil, DefaultHandlers is
+// used. The hooks of each step are run in order:
+//
+//	BeforeSign     the request is built, but not signed yet; hooks may
+//	               add headers, such as trace headers, to be signed
+//	AfterSign      the request is signed
+//	BeforeSend     the request is about to be sent
+//	AfterResponse  the response, or the error sending the request, is
+//	               known; Duration holds the time the attempt took
+//	OnRetry        the attempt failed and the request is about to be
+//	               sent again after RetryDelay
+//
+// Handlers are only read by clients, and may be shared between them once
+// set up.
+type Handlers struct {
+	BeforeSign    []Hook
+	AfterSign     []Hook
+	BeforeSend    []Hook
+	AfterResponse []Hook
+	OnRetry       []Hook
+}
+
+// DefaultHandlers are run by service clients that have no Handlers of
+// their own. Hooks must be added before any client is used.
+var DefaultHandlers = &Handlers{}
+
+// HandlersOrDefault returns h, or DefaultHandlers if h is nil.
+func HandlersOrDefault(h *Handlers) *Handlers {
+	if h == nil {
+		return DefaultHandlers
+	}
+	return h
+}
+
+// RequestInfo describes a request going through the hooks of Handlers.
+//
+// Request and Response are the live values sent and received by the
+// client: hooks may add headers to Request before it is signed, but must
+// not read the bodies. Use DumpRequest and DumpResponse to log them, as
+// they redact credentials and signatures and leave bodies out.
+type RequestInfo struct {
+	// Service is the name of the service, as in "ec2" or "s3".
+	Service string
+
+	// Operation is the name of the action requested, as in
+	// "DescribeInstances" or "PutObject".
+	Operation string
+
+	// Context is the context the request is bound to.
+	Context context.Context
+
+	// Clock is the clock of the client, adjusted when the request fails
+	// for clock skew. If nil, DefaultClock is used.
+	Clock *Clock
+
+	// Limiter is the rate limiter of the client, waited for before each
+	// attempt, and told when the request is throttled. If nil, requests
+	// are not limited.
+	Limiter *RateLimiter
+
+	// Attempt counts the attempts made to send the request, starting at 1.
+	Attempt int
+
+	Request  *http.Request
+	Response *http.Response
+
+	// Err is the error of the failed attempt, in AfterResponse and
+	// OnRetry hooks.
+	Err error
+
+	// Start is when the current attempt was sent, and Duration how long
+	// it took until the response headers were received.
+	Start    time.Time
+	Duration time.Duration
+
+	// RetryDelay is how long the client waits before the next attempt,
+	// in OnRetry hooks.
+	RetryDelay time.Duration
+}
+
+func (h *Handlers) run(hooks []Hook, r *RequestInfo) {
+	for _, hook := range hooks {
+		hook(r)
+	}
+}
+
+// Retry calls op according to policy, as policy.Do does, counting the
+// attempts in r and running the OnRetry hooks of h before each retry.
+//
+// When an attempt fails for clock skew, the clock of r is adjusted from
+// the Date header of the response, and the request retried, as op is
+// expected to sign it again with the corrected time. The first such
+// retry is made on top of the attempts allowed by policy, even with
+// NoRetries.
+//
+// The limiter of r, if any, is told of the attempts that succeed and of
+// those throttled.
+func (h *Handlers) Retry(policy *RetryPolicy, classify RetryClassifier, r *RequestInfo, op func() error) error {
+	h = HandlersOrDefault(h)
+	ctx := r.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	onRetry := func(failed int, err error, delay time.Duration) {
+		r.Err, r.RetryDelay = err, delay
+		h.run(h.OnRetry, r)
+	}
+	classify = policy.classifier(classify)
+	// Copied, as the attempts allowed may grow.
+	p := *policy
+	skewed, corrected := false, false
+	retryable := func(err error) (retry, throttled bool) {
+		if skewed {
+			return true, false
+		}
+		return classify(err)
+	}
+	return p.do(ctx, retryable, func() error {
+		r.Attempt++
+		r.Request, r.Response, r.Err = nil, nil, nil
+		err := op()
+		skewed = err != nil && adjustClock(r.Clock, r.Response, err)
+		if skewed && !corrected {
+			corrected = true
+			if p.MaxAttempts < 1 {
+				p.MaxAttempts = 1
+			}
+			p.MaxAttempts++
+		}
+		if r.Limiter != nil {
+			if err == nil {
+				r.Limiter.Succeeded(r.Service, r.Operation)
+			} else if _, throttled := classify(err); throttled {
+				r.Limiter.Throttled(r.Service, r.Operation)
+			}
+		}
+		return err
+	}, onRetry)
+}
+
+// Send runs the hooks of h around signing req with sign and sending it
+// with client, and returns the response. The request is bound to the
+// context of r, and waits for the limiter of r, if any, before it is
+// signed.
+func (h *Handlers) Send(client *http.Client, r *RequestInfo, req *http.Request, sign func(req *http.Request) error) (*http.Response, error) {
+	h = HandlersOrDefault(h)
+	if r.Attempt == 0 {
+		r.Attempt = 1
+	}
+	if err := r.Limiter.Wait(r.Context, r.Service, r.Operation); err != nil {
+		return nil, err
+	}
+	r.Request = req
+	h.run(h.BeforeSign, r)
+	if err := sign(req); err != nil {
+		return nil, err
+	}
+	h.run(h.AfterSign, r)
+	h.run(h.BeforeSend, r)
+	if r.Context != nil {
+		req = req.WithContext(r.Context)
+	}
+	r.Start = time.Now()
+	resp, err := ClientOrDefault(client).Do(req)
+	r.Duration = time.Since(r.Start)
+	r.Response, r.Err = resp, err
+	h.run(h.AfterResponse, r)
+	return resp, err
+}
+
+// redactedHeaders and redactedParams name the headers and query
+// parameters holding credentials or signatures.
+var (
+	redactedHeaders = []string{"Authorization", "X-Amz-Security-Token", "X-Amzn-Authorization"}
+	redactedParams  = []string{"AWSAccessKeyId", "Signature", "SecurityToken", "X-Amz-Credential", "X-Amz-Signature", "X-Amz-Security-Token"}
+)
+
+const redacted = "REDACTED"
+
+// DumpRequest returns a description of the request for logging, with
+// credentials and signatures redacted and the body left out.
+func (r *RequestInfo) DumpRequest() string {
+	if r.Request == nil {
+		return ""
+	}
+	req := r.Request
+	u := *req.URL
+	if u.RawQuery != "" {
+		q := u.Query()
+		for _, name := range redactedParams {
+			if _, ok := q[name]; ok {
+				q.Set(name, redacted)
+			}
+		}
+		u.RawQuery = q.Encode()
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s %s", req.Method, u.String())
+	dumpHeader(&b, req.Header)
+	if req.Body != nil && req.ContentLength != 0 {
+		fmt.Fprintf(&b, "\n[body of %d bytes redacted]", req.ContentLength)
+	}
+	return b.String()
+}
+
+// DumpResponse returns a description of the response for logging,
+// without its body.
+func (r *RequestInfo) DumpResponse() string {
+	if r.Response == nil {
+		if r.Err != nil {
+			return "error: " + r.Err.Error()
+		}
+		return ""
+	}
+	var b bytes.Buffer
+	b.WriteString(r.Response.Status)
+	dumpHeader(&b, r.Response.Header)
+	return b.String()
+}
+
+func dumpHeader(b *bytes.Buffer, h http.Header) {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := h[k]
+		for _, name := range redactedHeaders {
+			if http.CanonicalHeaderKey(k) == name {
+				v = []string{redacted}
+			}
+		}
+		for _, s := range v {
+			fmt.Fprintf(b, "\n%s: %s", k, s)
+		}
+	}
+}
+
+// NewLogHandlers returns Handlers logging requests, responses and retries
+// to logger, or to the standard logger if nil. Credentials, signatures and
+// bodies are not logged.
+func NewLogHandlers(logger *log.Logger) *Handlers {
+	logf := log.Printf
+	if logger != nil {
+		logf = logger.Printf
+	}
+	return &Handlers{
+		BeforeSend: []Hook{func(r *RequestInfo) {
+			logf("%s.%s attempt %d: %s", r.Service, r.Operation, r.Attempt, r.DumpRequest())
+		}},
+		AfterResponse: []Hook{func(r *RequestInfo) {
+			logf("%s.%s attempt %d took %v: %s", r.Service, r.Operation, r.Attempt, r.Duration, r.DumpResponse())
+		}},
+		OnRetry: []Hook{func(r *RequestInfo) {
+			logf("%s.%s retrying in %v after: %v", r.Service, r.Operation, r.RetryDelay, r.Err)
+		}},
+	}
+}
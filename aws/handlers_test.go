@@ -0,0 +1,113 @@
+package aws_test
+
+import (
+	"bytes"
+	"github.com/crowdmob/goamz/aws"
+	"gopkg.in/check.v1"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+)
+
+func (s *S) TestHandlersRunInOrder(c *check.C) {
+	var traces []string
+	attempts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traces = append(traces, r.Header.Get("X-Trace-Id"))
+		if attempts++; attempts == 1 {
+			w.WriteHeader(503)
+		}
+	}))
+	defer srv.Close()
+
+	var steps []string
+	step := func(name string) aws.Hook {
+		return func(r *aws.RequestInfo) {
+			steps = append(steps, name)
+			c.Check(r.Operation, check.Equals, "ListMetrics")
+		}
+	}
+	h := &aws.Handlers{
+		BeforeSign: []aws.Hook{step("BeforeSign"), func(r *aws.RequestInfo) {
+			r.Request.Header.Set("X-Trace-Id", "trace-1")
+		}},
+		AfterSign:     []aws.Hook{step("AfterSign")},
+		BeforeSend:    []aws.Hook{step("BeforeSend")},
+		AfterResponse: []aws.Hook{step("AfterResponse")},
+		OnRetry: []aws.Hook{step("OnRetry"), func(r *aws.RequestInfo) {
+			c.Check(r.Attempt, check.Equals, 1)
+			c.Check(r.Err, check.FitsTypeOf, &aws.Error{})
+			c.Check(r.RetryDelay <= time.Millisecond, check.Equals, true)
+		}},
+	}
+
+	auth := aws.Auth{AccessKey: "abc", SecretKey: "123"}
+	service, err := aws.NewService(auth, aws.ServiceInfo{srv.URL, aws.V4Signature})
+	c.Assert(err, check.IsNil)
+	service.RetryPolicy = &aws.RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond}
+	service.Handlers = h
+	resp, err := service.Query("GET", "/", map[string]string{"Action": "ListMetrics"})
+	c.Assert(err, check.IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, check.Equals, 200)
+
+	attempt := []string{"BeforeSign", "AfterSign", "BeforeSend", "AfterResponse"}
+	c.Assert(steps, check.DeepEquals, append(append(attempt, "OnRetry"), attempt...))
+	c.Assert(traces, check.DeepEquals, []string{"trace-1", "trace-1"})
+}
+
+func (s *S) TestHandlersSignHeadersAddedBeforeSigning(c *check.C) {
+	var req *http.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req = r
+	}))
+	defer srv.Close()
+
+	auth := aws.Auth{AccessKey: "abc", SecretKey: "123"}
+	service, err := aws.NewService(auth, aws.ServiceInfo{srv.URL, aws.V4Signature})
+	c.Assert(err, check.IsNil)
+	service.Handlers = &aws.Handlers{
+		BeforeSign: []aws.Hook{func(r *aws.RequestInfo) {
+			r.Request.Header.Set("X-Amz-Trace-Id", "trace-1")
+		}},
+	}
+	resp, err := service.Query("GET", "/", map[string]string{"Action": "ListMetrics"})
+	c.Assert(err, check.IsNil)
+	resp.Body.Close()
+	c.Assert(req.Header.Get("Authorization"), check.Matches, ".*SignedHeaders=[^,]*x-amz-trace-id.*")
+}
+
+func (s *S) TestDumpRequestRedacts(c *check.C) {
+	req, err := http.NewRequest("POST", "https://ec2.us-east-1.amazonaws.com/?Action=Run&AWSAccessKeyId=abc&Signature=sig&X-Amz-Credential=cred", strings.NewReader("secret=body"))
+	c.Assert(err, check.IsNil)
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential=abc/...")
+	req.Header.Set("X-Amz-Security-Token", "token")
+	req.Header.Set("Content-Type", "text/plain")
+
+	dump := (&aws.RequestInfo{Request: req}).DumpRequest()
+	c.Assert(dump, check.Equals, "POST https://ec2.us-east-1.amazonaws.com/?AWSAccessKeyId=REDACTED&Action=Run&Signature=REDACTED&X-Amz-Credential=REDACTED"+
+		"\nAuthorization: REDACTED\nContent-Type: text/plain\nX-Amz-Security-Token: REDACTED\n[body of 11 bytes redacted]")
+
+	resp := &http.Response{Status: "200 OK", Header: http.Header{"X-Amzn-Requestid": {"r1"}}}
+	c.Assert((&aws.RequestInfo{Response: resp}).DumpResponse(), check.Equals, "200 OK\nX-Amzn-Requestid: r1")
+}
+
+func (s *S) TestLogHandlers(c *check.C) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	auth := aws.Auth{AccessKey: "abc", SecretKey: "123"}
+	service, err := aws.NewService(auth, aws.ServiceInfo{srv.URL, aws.V2Signature})
+	c.Assert(err, check.IsNil)
+	service.Handlers = aws.NewLogHandlers(log.New(&buf, "", 0))
+	resp, err := service.Query("GET", "/", map[string]string{"Action": "ListMetrics"})
+	c.Assert(err, check.IsNil)
+	resp.Body.Close()
+
+	out := buf.String()
+	c.Assert(out, check.Matches, "(?s)127.ListMetrics attempt 1: GET http://127.*Signature=REDACTED.*\n127.ListMetrics attempt 1 took .*: 200 OK.*")
+	c.Assert(strings.Contains(out, "abc"), check.Equals, false)
+}
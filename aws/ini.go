@@ -0,0 +1,86 @@
+package aws
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// iniFile maps section names to the key/value pairs defined in them.
+type iniFile map[string]map[string]string
+
+// parseINI parses the INI dialect used by the AWS shared credentials
+// and config files. Keys are lower-cased; comments start with '#' or ';'.
+//
+// A key with an empty value may be followed by indented sub-keys, as in
+// the services sections of the config file:
+//
+//	[services local]
+//	s3 =
+//	  endpoint_url = http://localhost:4566
+//
+// These are stored under the parent key, as "s3.endpoint_url".
+func parseINI(r io.Reader) (iniFile, error) {
+	f := make(iniFile)
+	var section map[string]string
+	var parent string
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		raw := scanner.Text()
+		line := strings.TrimSpace(raw)
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		indented := raw[0] == ' ' || raw[0] == '\t'
+		if line[0] == '[' {
+			parent = ""
+			if line[len(line)-1] != ']' {
+				return nil, fmt.Errorf("line %d: bad section header %q", n, line)
+			}
+			name := strings.TrimSpace(line[1 : len(line)-1])
+			section = f[name]
+			if section == nil {
+				section = make(map[string]string)
+				f[name] = section
+			}
+			continue
+		}
+		i := strings.IndexAny(line, "=:")
+		if i < 0 {
+			return nil, fmt.Errorf("line %d: expected key = value, got %q", n, line)
+		}
+		if section == nil {
+			return nil, fmt.Errorf("line %d: key outside of a section", n)
+		}
+		key := strings.ToLower(strings.TrimSpace(line[:i]))
+		value := strings.TrimSpace(line[i+1:])
+		if indented && parent != "" {
+			section[parent+"."+key] = value
+			continue
+		}
+		if parent = ""; value == "" {
+			parent = key
+		}
+		section[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// readINI parses the INI file at path.
+func readINI(path string) (iniFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	f, err := parseINI(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return f, nil
+}
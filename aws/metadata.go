@@ -0,0 +1,340 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMetadataURL is the address of the EC2 instance metadata service.
+const DefaultMetadataURL = "http://169.254.169.254"
+
+// DefaultMetadataTokenTTL is how long the session tokens requested by a
+// MetadataClient remain valid, unless it says otherwise.
+const DefaultMetadataTokenTTL = 6 * time.Hour
+
+// metadataTokenTimeout bounds the time taken to request a session token,
+// as services behind too many network hops never answer.
+const metadataTokenTimeout = time.Second
+
+// MetadataError is returned by MetadataClient when the metadata service
+// cannot be reached, or does not answer a request with a success.
+type MetadataError struct {
+	// Path of the failed request, relative to the base URL.
+	Path string
+
+	// HTTP status code returned by the service, or zero if it could
+	// not be reached.
+	StatusCode int
+
+	// Err is the error that prevented the request from completing,
+	// when StatusCode is zero.
+	Err error
+}
+
+func (e *MetadataError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("metadata %s: %v", e.Path, e.Err)
+	}
+	return fmt.Sprintf("metadata %s: %d %s", e.Path, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// Unwrap returns the error that prevented the request from completing.
+func (e *MetadataError) Unwrap() error {
+	return e.Err
+}
+
+// NotFound reports whether the requested item does not exist, such as
+// the user data of an instance started without any.
+func (e *MetadataError) NotFound() bool {
+	return e.StatusCode == http.StatusNotFound
+}
+
+// MetadataClient reads from the EC2 instance metadata service.
+//
+// Unless DisableTokens is set, the client uses the session-oriented
+// protocol (IMDSv2): it first requests a session token with a PUT, and
+// sends that token with every request. It falls back to plain requests
+// (IMDSv1) when the service does not hand out tokens, or does not answer
+// the PUT in time, and keeps doing so until a plain request is refused.
+// A MetadataClient is safe for concurrent use.
+type MetadataClient struct {
+	// BaseURL is the address of the metadata service. If empty,
+	// DefaultMetadataURL is used.
+	BaseURL string
+
+	// HTTPClient is used to send requests to the service. If nil, a
+	// client giving up after a couple of seconds is used, so that
+	// programs running outside of EC2 do not hang.
+	HTTPClient *http.Client
+
+	// TokenTTL is the lifetime of the session tokens requested. If
+	// zero, DefaultMetadataTokenTTL is used.
+	TokenTTL time.Duration
+
+	// DisableTokens makes the client send plain requests (IMDSv1),
+	// without requesting a session token first.
+	DisableTokens bool
+
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time
+	noTokens    bool // Whether the service was found not to hand out tokens.
+}
+
+// NewMetadataClient returns a MetadataClient for the service at the
+// address given by the AWS_EC2_METADATA_SERVICE_ENDPOINT environment
+// variable, or DefaultMetadataURL.
+func NewMetadataClient() *MetadataClient {
+	return &MetadataClient{BaseURL: os.Getenv("AWS_EC2_METADATA_SERVICE_ENDPOINT")}
+}
+
+var defaultMetadataHTTPClient = &http.Client{
+	Transport: &http.Transport{
+		Dial: func(netw, addr string) (net.Conn, error) {
+			deadline := time.Now().Add(5 * time.Second)
+			c, err := net.DialTimeout(netw, addr, time.Second*2)
+			if err != nil {
+				return nil, err
+			}
+			c.SetDeadline(deadline)
+			return c, nil
+		},
+	},
+}
+
+func (m *MetadataClient) baseURL() string {
+	if m.BaseURL == "" {
+		return DefaultMetadataURL
+	}
+	return strings.TrimRight(m.BaseURL, "/")
+}
+
+func (m *MetadataClient) httpClient() *http.Client {
+	if m.HTTPClient == nil {
+		return defaultMetadataHTTPClient
+	}
+	return m.HTTPClient
+}
+
+func (m *MetadataClient) tokenTTL() time.Duration {
+	if m.TokenTTL == 0 {
+		return DefaultMetadataTokenTTL
+	}
+	return m.TokenTTL
+}
+
+// sessionToken returns the current session token, requesting a new one
+// if there is none or it is about to expire. It returns an empty token
+// when the service does not support them.
+func (m *MetadataClient) sessionToken(ctx context.Context) (string, error) {
+	if m.DisableTokens {
+		return "", nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.noTokens {
+		return "", nil
+	}
+	if m.token != "" && time.Now().Before(m.tokenExpiry) {
+		return m.token, nil
+	}
+	const path = "/latest/api/token"
+	ttl := m.tokenTTL()
+	req, err := http.NewRequest("PUT", m.baseURL()+path, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", strconv.Itoa(int(ttl/time.Second)))
+	tctx, cancel := context.WithTimeout(ctx, metadataTokenTimeout)
+	defer cancel()
+	resp, err := m.httpClient().Do(req.WithContext(tctx))
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", &MetadataError{Path: path, Err: err}
+		}
+		// The service is unreachable, or drops the PUT, as it does
+		// from containers more than one hop away; plain requests tell
+		// which.
+		m.noTokens = true
+		return "", nil
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusMethodNotAllowed:
+		// Older services only answer plain requests.
+		m.noTokens = true
+		return "", nil
+	default:
+		return "", &MetadataError{Path: path, StatusCode: resp.StatusCode}
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", &MetadataError{Path: path, Err: err}
+	}
+	// Renew the token a little before the service expires it.
+	m.token = string(body)
+	m.tokenExpiry = time.Now().Add(ttl - ttl/10)
+	return m.token, nil
+}
+
+// Get returns the item at path, relative to the base URL, as in
+// "/latest/meta-data/instance-id".
+func (m *MetadataClient) Get(path string) ([]byte, error) {
+	return m.GetWithContext(context.Background(), path)
+}
+
+// GetWithContext is like Get, but the request is cancelled when ctx is
+// done.
+func (m *MetadataClient) GetWithContext(ctx context.Context, path string) ([]byte, error) {
+	for retried := false; ; retried = true {
+		token, err := m.sessionToken(ctx)
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequest("GET", m.baseURL()+path, nil)
+		if err != nil {
+			return nil, err
+		}
+		if token != "" {
+			req.Header.Set("X-aws-ec2-metadata-token", token)
+		}
+		resp, err := m.httpClient().Do(req.WithContext(ctx))
+		if err != nil {
+			return nil, &MetadataError{Path: path, Err: err}
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusUnauthorized && !m.DisableTokens && !retried {
+			// The token was rejected, or one is required after all;
+			// request a new one and try again.
+			m.mu.Lock()
+			m.token = ""
+			m.noTokens = false
+			m.mu.Unlock()
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, &MetadataError{Path: path, StatusCode: resp.StatusCode}
+		}
+		if err != nil {
+			return nil, &MetadataError{Path: path, Err: err}
+		}
+		return body, nil
+	}
+}
+
+// GetMetadata returns the instance metadata item at path, as in
+// "instance-id" or "placement/availability-zone".
+func (m *MetadataClient) GetMetadata(path string) ([]byte, error) {
+	return m.Get("/latest/meta-data/" + path)
+}
+
+func (m *MetadataClient) getString(path string) (string, error) {
+	data, err := m.GetMetadata(path)
+	return string(data), err
+}
+
+// UserData returns the user data the instance was started with.
+func (m *MetadataClient) UserData() ([]byte, error) {
+	return m.Get("/latest/user-data")
+}
+
+// InstanceIdentityDocument describes the instance, as reported by the
+// metadata service.
+//
+// See http://goo.gl/3LQJm6 for more details.
+type InstanceIdentityDocument struct {
+	AccountId          string    `json:"accountId"`
+	Architecture       string    `json:"architecture"`
+	AvailabilityZone   string    `json:"availabilityZone"`
+	BillingProducts    []string  `json:"billingProducts"`
+	DevpayProductCodes []string  `json:"devpayProductCodes"`
+	ImageId            string    `json:"imageId"`
+	InstanceId         string    `json:"instanceId"`
+	InstanceType       string    `json:"instanceType"`
+	KernelId           string    `json:"kernelId"`
+	PendingTime        time.Time `json:"pendingTime"`
+	PrivateIp          string    `json:"privateIp"`
+	RamdiskId          string    `json:"ramdiskId"`
+	Region             string    `json:"region"`
+	Version            string    `json:"version"`
+}
+
+// IdentityDocument returns the dynamic identity document of the instance.
+func (m *MetadataClient) IdentityDocument() (*InstanceIdentityDocument, error) {
+	data, err := m.Get("/latest/dynamic/instance-identity/document")
+	if err != nil {
+		return nil, err
+	}
+	doc := new(InstanceIdentityDocument)
+	if err := json.Unmarshal(data, doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// Region returns the name of the region the instance runs in.
+func (m *MetadataClient) Region() (string, error) {
+	zone, err := m.getString("placement/availability-zone")
+	if err != nil {
+		return "", err
+	}
+	zone = strings.TrimSpace(zone)
+	if zone == "" {
+		return "", fmt.Errorf("metadata: empty availability zone")
+	}
+	return zone[:len(zone)-1], nil
+}
+
+// InstanceId returns the id of the instance.
+func (m *MetadataClient) InstanceId() (string, error) {
+	return m.getString("instance-id")
+}
+
+// InstanceType returns the type of the instance.
+func (m *MetadataClient) InstanceType() (string, error) {
+	return m.getString("instance-type")
+}
+
+// LocalIPv4 returns the private IPv4 address of the instance.
+func (m *MetadataClient) LocalIPv4() (string, error) {
+	return m.getString("local-ipv4")
+}
+
+// PublicIPv4 returns the public IPv4 address of the instance.
+func (m *MetadataClient) PublicIPv4() (string, error) {
+	return m.getString("public-ipv4")
+}
+
+// instanceCredentials returns the credentials of the IAM role assigned
+// to the instance.
+func (m *MetadataClient) instanceCredentials() (cred credentials, err error) {
+	credentialPath := "iam/security-credentials/"
+
+	// Get the instance role
+	role, err := m.GetMetadata(credentialPath)
+	if err != nil {
+		return
+	}
+	// Only the first role is used if several are listed.
+	name := strings.TrimSpace(strings.SplitN(string(role), "\n", 2)[0])
+
+	// Get the instance role credentials
+	credentialJSON, err := m.GetMetadata(credentialPath + name)
+	if err != nil {
+		return
+	}
+
+	err = json.Unmarshal(credentialJSON, &cred)
+	return
+}
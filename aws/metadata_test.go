@@ -0,0 +1,191 @@
+package aws_test
+
+import (
+	"github.com/crowdmob/goamz/aws"
+	"github.com/crowdmob/goamz/aws/metadatatest"
+	"gopkg.in/check.v1"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+type MetadataSuite struct {
+	srv    *metadatatest.Server
+	client *aws.MetadataClient
+}
+
+var _ = check.Suite(&MetadataSuite{})
+
+func (s *MetadataSuite) SetUpTest(c *check.C) {
+	srv, err := metadatatest.NewServer()
+	c.Assert(err, check.IsNil)
+	s.srv = srv
+	s.client = &aws.MetadataClient{BaseURL: srv.URL()}
+}
+
+func (s *MetadataSuite) TearDownTest(c *check.C) {
+	s.srv.Quit()
+}
+
+func (s *MetadataSuite) TestInstanceInfo(c *check.C) {
+	id, err := s.client.InstanceId()
+	c.Assert(err, check.IsNil)
+	c.Assert(id, check.Equals, "i-1234567890abcdef0")
+	region, err := s.client.Region()
+	c.Assert(err, check.IsNil)
+	c.Assert(region, check.Equals, "us-east-1")
+	ip, err := s.client.LocalIPv4()
+	c.Assert(err, check.IsNil)
+	c.Assert(ip, check.Equals, "10.0.0.12")
+
+	// A single session token serves every request.
+	c.Assert(s.srv.TokenCount(), check.Equals, 1)
+	for _, req := range s.srv.Requests()[1:] {
+		c.Assert(req.Header.Get("X-aws-ec2-metadata-token"), check.Equals, "token1")
+	}
+}
+
+func (s *MetadataSuite) TestExpiredToken(c *check.C) {
+	s.srv.RequireToken(true)
+	_, err := s.client.InstanceId()
+	c.Assert(err, check.IsNil)
+	s.srv.ExpireTokens()
+	_, err = s.client.InstanceType()
+	c.Assert(err, check.IsNil)
+	c.Assert(s.srv.TokenCount(), check.Equals, 2)
+}
+
+func (s *MetadataSuite) TestTokensRequired(c *check.C) {
+	s.srv.RequireToken(true)
+	s.client.DisableTokens = true
+	_, err := s.client.InstanceId()
+	c.Assert(err, check.ErrorMatches, "metadata /latest/meta-data/instance-id: 401 Unauthorized")
+	c.Assert(err.(*aws.MetadataError).StatusCode, check.Equals, 401)
+}
+
+func (s *MetadataSuite) TestTokensUnsupported(c *check.C) {
+	s.srv.DisableTokens(true)
+	id, err := s.client.InstanceId()
+	c.Assert(err, check.IsNil)
+	c.Assert(id, check.Equals, "i-1234567890abcdef0")
+	c.Assert(s.srv.TokenCount(), check.Equals, 0)
+}
+
+func (s *MetadataSuite) TestTokenTimeout(c *check.C) {
+	var puts, gets int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == "PUT" {
+			// Never answer, as when the PUT is dropped on the way.
+			atomic.AddInt32(&puts, 1)
+			<-req.Context().Done()
+			return
+		}
+		atomic.AddInt32(&gets, 1)
+		c.Check(req.Header.Get("X-aws-ec2-metadata-token"), check.Equals, "")
+		w.Write([]byte("i-1234567890abcdef0"))
+	}))
+	defer srv.Close()
+	client := &aws.MetadataClient{BaseURL: srv.URL}
+
+	start := time.Now()
+	id, err := client.InstanceId()
+	c.Assert(err, check.IsNil)
+	c.Assert(id, check.Equals, "i-1234567890abcdef0")
+	c.Assert(time.Since(start) < 3*time.Second, check.Equals, true)
+
+	// The fallback is remembered.
+	_, err = client.InstanceId()
+	c.Assert(err, check.IsNil)
+	c.Assert(atomic.LoadInt32(&puts), check.Equals, int32(1))
+	c.Assert(atomic.LoadInt32(&gets), check.Equals, int32(2))
+}
+
+func (s *MetadataSuite) TestTokensRequiredAfterFallback(c *check.C) {
+	s.srv.DisableTokens(true)
+	_, err := s.client.InstanceId()
+	c.Assert(err, check.IsNil)
+	s.srv.DisableTokens(false)
+	s.srv.RequireToken(true)
+	_, err = s.client.InstanceType()
+	c.Assert(err, check.IsNil)
+	c.Assert(s.srv.TokenCount(), check.Equals, 1)
+}
+
+func (s *MetadataSuite) TestUserData(c *check.C) {
+	_, err := s.client.UserData()
+	c.Assert(err, check.FitsTypeOf, &aws.MetadataError{})
+	c.Assert(err.(*aws.MetadataError).NotFound(), check.Equals, true)
+
+	s.srv.SetUserData("#!/bin/sh\necho hello\n")
+	data, err := s.client.UserData()
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, "#!/bin/sh\necho hello\n")
+}
+
+func (s *MetadataSuite) TestIdentityDocument(c *check.C) {
+	err := s.srv.SetIdentityDocument(map[string]interface{}{
+		"accountId":        "123456789012",
+		"availabilityZone": "eu-west-1b",
+		"instanceId":       "i-1234567890abcdef0",
+		"pendingTime":      "2016-11-19T16:32:11Z",
+		"region":           "eu-west-1",
+	})
+	c.Assert(err, check.IsNil)
+	doc, err := s.client.IdentityDocument()
+	c.Assert(err, check.IsNil)
+	c.Assert(doc.AccountId, check.Equals, "123456789012")
+	c.Assert(doc.Region, check.Equals, "eu-west-1")
+	c.Assert(doc.AvailabilityZone, check.Equals, "eu-west-1b")
+	c.Assert(doc.PendingTime.Equal(time.Date(2016, 11, 19, 16, 32, 11, 0, time.UTC)), check.Equals, true)
+}
+
+func (s *MetadataSuite) TestUnreachable(c *check.C) {
+	s.srv.Quit()
+	_, err := s.client.InstanceId()
+	c.Assert(err, check.FitsTypeOf, &aws.MetadataError{})
+	e := err.(*aws.MetadataError)
+	c.Assert(e.StatusCode, check.Equals, 0)
+	c.Assert(e.Err, check.NotNil)
+}
+
+func (s *MetadataSuite) TestInstanceRoleCredentials(c *check.C) {
+	environ := os.Environ()
+	defer restoreEnv(environ)
+	os.Clearenv()
+	dir := c.MkDir()
+	os.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+	os.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+	os.Setenv("AWS_EC2_METADATA_SERVICE_ENDPOINT", s.srv.URL())
+
+	expiration := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+	err := s.srv.SetRoleCredentials("web", metadatatest.RoleCredentials{
+		AccessKeyId:     "ASIAROLE",
+		SecretAccessKey: "rolesecret",
+		Token:           "roletoken",
+		Expiration:      expiration.Format("2006-01-02T15:04:05Z"),
+	})
+	c.Assert(err, check.IsNil)
+
+	_, source, err := aws.DefaultChain().RetrieveWithSource()
+	c.Assert(err, check.IsNil)
+	c.Assert(source, check.FitsTypeOf, aws.InstanceRoleProvider{})
+
+	auth, err := aws.GetAuth("", "", "", time.Time{})
+	c.Assert(err, check.IsNil)
+	c.Assert(auth.AccessKey, check.Equals, "ASIAROLE")
+	c.Assert(auth.SecretKey, check.Equals, "rolesecret")
+	c.Assert(auth.Token(), check.Equals, "roletoken")
+	c.Assert(auth.Expiration().Equal(expiration), check.Equals, true)
+}
+
+func restoreEnv(environ []string) {
+	os.Clearenv()
+	for _, kv := range environ {
+		l := strings.SplitN(kv, "=", 2)
+		os.Setenv(l[0], l[1])
+	}
+}
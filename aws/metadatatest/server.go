@@ -0,0 +1,238 @@
+// Package metadatatest implements a fake EC2 instance metadata service, so
+// that code depending on it, such as code using instance role credentials,
+// can be tested outside of EC2.
+package metadatatest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RoleCredentials holds the credentials served for an instance role.
+type RoleCredentials struct {
+	Code            string
+	LastUpdated     string
+	Type            string
+	AccessKeyId     string
+	SecretAccessKey string
+	Token           string
+	Expiration      string
+}
+
+// Server implements a metadata service simulator for use in tests.
+type Server struct {
+	url      string
+	listener net.Listener
+
+	mutex        sync.Mutex
+	items        map[string]string
+	tokens       map[string]time.Time
+	tokenCount   int
+	requireToken bool
+	disableToken bool
+	requests     []*http.Request
+}
+
+// NewServer starts a server answering for an instance in us-east-1a.
+func NewServer() (*Server, error) {
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return nil, fmt.Errorf("cannot listen on localhost: %v", err)
+	}
+	srv := &Server{
+		listener: l,
+		url:      "http://" + l.Addr().String(),
+		items:    make(map[string]string),
+		tokens:   make(map[string]time.Time),
+	}
+	srv.SetMetadata("instance-id", "i-1234567890abcdef0")
+	srv.SetMetadata("instance-type", "m3.medium")
+	srv.SetMetadata("placement/availability-zone", "us-east-1a")
+	srv.SetMetadata("local-ipv4", "10.0.0.12")
+	srv.SetMetadata("public-ipv4", "203.0.113.12")
+	go http.Serve(l, http.HandlerFunc(srv.serveHTTP))
+	return srv, nil
+}
+
+// Quit closes down the server.
+func (srv *Server) Quit() error {
+	return srv.listener.Close()
+}
+
+// URL returns a URL for the server, to be used as the base URL of a
+// MetadataClient or as AWS_EC2_METADATA_SERVICE_ENDPOINT.
+func (srv *Server) URL() string {
+	return srv.url
+}
+
+// SetMetadata sets the instance metadata item at path, relative to
+// /latest/meta-data/. An empty value removes the item.
+func (srv *Server) SetMetadata(path, value string) {
+	srv.set("/latest/meta-data/"+path, value)
+}
+
+// SetUserData sets the user data of the instance.
+func (srv *Server) SetUserData(data string) {
+	srv.set("/latest/user-data", data)
+}
+
+// SetIdentityDocument sets the dynamic identity document of the
+// instance, encoded as JSON.
+func (srv *Server) SetIdentityDocument(doc interface{}) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	srv.set("/latest/dynamic/instance-identity/document", string(data))
+	return nil
+}
+
+// SetRoleCredentials assigns role to the instance, with the given
+// credentials.
+func (srv *Server) SetRoleCredentials(role string, creds RoleCredentials) error {
+	if creds.Code == "" {
+		creds.Code = "Success"
+	}
+	if creds.Type == "" {
+		creds.Type = "AWS-HMAC"
+	}
+	data, err := json.Marshal(creds)
+	if err != nil {
+		return err
+	}
+	srv.SetMetadata("iam/security-credentials/"+role, string(data))
+	return nil
+}
+
+// RequireToken makes the server reject requests without a session
+// token, as instances configured for IMDSv2 only do.
+func (srv *Server) RequireToken(require bool) {
+	srv.mutex.Lock()
+	defer srv.mutex.Unlock()
+	srv.requireToken = require
+}
+
+// DisableTokens makes the server answer token requests with 404 Not
+// Found, as services predating IMDSv2 do.
+func (srv *Server) DisableTokens(disable bool) {
+	srv.mutex.Lock()
+	defer srv.mutex.Unlock()
+	srv.disableToken = disable
+}
+
+// ExpireTokens invalidates all the session tokens handed out so far.
+func (srv *Server) ExpireTokens() {
+	srv.mutex.Lock()
+	defer srv.mutex.Unlock()
+	srv.tokens = make(map[string]time.Time)
+}
+
+// TokenCount returns the number of session tokens handed out so far.
+func (srv *Server) TokenCount() int {
+	srv.mutex.Lock()
+	defer srv.mutex.Unlock()
+	return srv.tokenCount
+}
+
+// Requests returns the requests received by the server so far.
+func (srv *Server) Requests() []*http.Request {
+	srv.mutex.Lock()
+	defer srv.mutex.Unlock()
+	return append([]*http.Request(nil), srv.requests...)
+}
+
+func (srv *Server) set(path, value string) {
+	srv.mutex.Lock()
+	defer srv.mutex.Unlock()
+	if value == "" {
+		delete(srv.items, path)
+	} else {
+		srv.items[path] = value
+	}
+}
+
+func (srv *Server) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	srv.mutex.Lock()
+	defer srv.mutex.Unlock()
+	srv.requests = append(srv.requests, req)
+	if req.URL.Path == "/latest/api/token" {
+		srv.serveToken(w, req)
+		return
+	}
+	if req.Method != "GET" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	token := req.Header.Get("X-aws-ec2-metadata-token")
+	if token != "" {
+		if expiry, ok := srv.tokens[token]; !ok || time.Now().After(expiry) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+	} else if srv.requireToken {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if value, ok := srv.items[req.URL.Path]; ok {
+		w.Write([]byte(value))
+		return
+	}
+	if list := srv.list(req.URL.Path); list != "" {
+		w.Write([]byte(list))
+		return
+	}
+	http.NotFound(w, req)
+}
+
+func (srv *Server) serveToken(w http.ResponseWriter, req *http.Request) {
+	if srv.disableToken {
+		http.NotFound(w, req)
+		return
+	}
+	if req.Method != "PUT" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	ttl, err := strconv.Atoi(req.Header.Get("X-aws-ec2-metadata-token-ttl-seconds"))
+	if err != nil || ttl < 1 || ttl > 21600 {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	srv.tokenCount++
+	token := fmt.Sprintf("token%d", srv.tokenCount)
+	srv.tokens[token] = time.Now().Add(time.Duration(ttl) * time.Second)
+	w.Header().Set("X-aws-ec2-metadata-token-ttl-seconds", strconv.Itoa(ttl))
+	w.Write([]byte(token))
+}
+
+// list returns the entries under the directory at path, one per line,
+// with a trailing slash for those that are directories themselves.
+func (srv *Server) list(path string) string {
+	if !strings.HasSuffix(path, "/") {
+		return ""
+	}
+	seen := make(map[string]bool)
+	var entries []string
+	for p := range srv.items {
+		if !strings.HasPrefix(p, path) {
+			continue
+		}
+		entry := p[len(path):]
+		if i := strings.Index(entry, "/"); i >= 0 {
+			entry = entry[:i+1]
+		}
+		if !seen[entry] {
+			seen[entry] = true
+			entries = append(entries, entry)
+		}
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, "\n")
+}
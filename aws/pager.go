@@ -0,0 +1,72 @@
+package aws
+
+import "fmt"
+
+// A PageFetcher fetches the page of results of a paginated operation
+// starting at token, "" for the first page. It returns the number of
+// items in the page, and the token of the next page, or "" if the page
+// is the last one.
+type PageFetcher func(token string) (n int, next string, err error)
+
+// A Pager walks the items of a paginated operation, fetching pages
+// lazily, as they are needed. The iterators of the service packages,
+// such as s3.ListIter, are built on it: they keep the page fetched last,
+// and return its item at Index.
+//
+// A Pager is used as in:
+//
+//	for p.Next() {
+//		item := page[p.Index()]
+//		...
+//	}
+//	if err := p.Err(); err != nil {
+//		...
+//	}
+type Pager struct {
+	fetch PageFetcher
+	token string
+	n, i  int
+	last  bool
+	err   error
+}
+
+// NewPager returns a Pager fetching pages with fetch.
+func NewPager(fetch PageFetcher) *Pager {
+	return &Pager{fetch: fetch, i: -1}
+}
+
+// Next advances to the next item, fetching the next page if the current
+// one is exhausted. It returns false when there are no more items, or
+// when fetching a page failed.
+func (p *Pager) Next() bool {
+	if p.err != nil {
+		return false
+	}
+	p.i++
+	for p.i >= p.n {
+		if p.last {
+			return false
+		}
+		n, next, err := p.fetch(p.token)
+		if err == nil && next != "" && next == p.token {
+			err = fmt.Errorf("pagination token %q was returned twice", next)
+		}
+		if err != nil {
+			p.err = err
+			return false
+		}
+		p.token, p.last = next, next == ""
+		p.n, p.i = n, 0
+	}
+	return true
+}
+
+// Index returns the index of the current item in the page fetched last.
+func (p *Pager) Index() int {
+	return p.i
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (p *Pager) Err() error {
+	return p.err
+}
@@ -0,0 +1,67 @@
+package aws_test
+
+import (
+	"errors"
+	"github.com/crowdmob/goamz/aws"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestPager(c *check.C) {
+	pages := map[string][]string{
+		"":   {"a", "b"},
+		"p2": {},
+		"p3": {"c"},
+	}
+	next := map[string]string{"": "p2", "p2": "p3"}
+	var fetched []string
+	var page []string
+	p := aws.NewPager(func(token string) (int, string, error) {
+		fetched = append(fetched, token)
+		page = pages[token]
+		return len(page), next[token], nil
+	})
+
+	var items []string
+	for p.Next() {
+		items = append(items, page[p.Index()])
+	}
+	c.Assert(p.Err(), check.IsNil)
+	c.Assert(items, check.DeepEquals, []string{"a", "b", "c"})
+	c.Assert(fetched, check.DeepEquals, []string{"", "p2", "p3"})
+	c.Assert(p.Next(), check.Equals, false)
+	c.Assert(fetched, check.HasLen, 3)
+}
+
+func (s *S) TestPagerFetchesLazily(c *check.C) {
+	calls := 0
+	p := aws.NewPager(func(token string) (int, string, error) {
+		calls++
+		return 2, "more", nil
+	})
+	c.Assert(calls, check.Equals, 0)
+	c.Assert(p.Next(), check.Equals, true)
+	c.Assert(p.Next(), check.Equals, true)
+	c.Assert(calls, check.Equals, 1)
+}
+
+func (s *S) TestPagerError(c *check.C) {
+	boom := errors.New("boom")
+	p := aws.NewPager(func(token string) (int, string, error) {
+		if token == "" {
+			return 1, "p2", nil
+		}
+		return 0, "", boom
+	})
+	c.Assert(p.Next(), check.Equals, true)
+	c.Assert(p.Next(), check.Equals, false)
+	c.Assert(p.Err(), check.Equals, boom)
+	c.Assert(p.Next(), check.Equals, false)
+}
+
+func (s *S) TestPagerRepeatedToken(c *check.C) {
+	p := aws.NewPager(func(token string) (int, string, error) {
+		return 0, "same", nil
+	})
+	c.Assert(p.Next(), check.Equals, false)
+	c.Assert(p.Err(), check.ErrorMatches, `pagination token "same" was returned twice`)
+}
@@ -0,0 +1,257 @@
+package aws
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// A RateLimiter limits the rate of the requests of service clients, so
+// that they stay under the request limits of AWS rather than being
+// throttled. Each operation of each service, as "ec2.DescribeInstances",
+// has a token bucket of its own: its requests are sent at Rate per
+// second on average, in bursts of up to Burst requests.
+//
+// When a request is throttled, the rate of its operation is halved, down
+// to MinRate, and it climbs back to its configured rate as requests
+// succeed.
+//
+// Every service client has a Limiter field; when nil, requests are not
+// limited. AWS limits requests per account and region, so the clients of
+// an account should share a RateLimiter. Its fields must not be modified
+// once it is in use.
+type RateLimiter struct {
+	// Rate is the number of requests per second allowed for each
+	// operation. Zero means no limit.
+	Rate float64
+
+	// Rates overrides Rate for some services or operations, keyed by
+	// service as in "ec2", or by service and operation as in
+	// "ec2.RunInstances".
+	Rates map[string]float64
+
+	// Burst is the number of requests of an operation that may be sent
+	// at once after a pause. If zero, 1 is used.
+	Burst int
+
+	// MinRate is the rate below which throttling doesn't slow down an
+	// operation. If zero, a tenth of the rate of the operation is used.
+	MinRate float64
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	now     func() time.Time // time.Now if nil, for tests.
+}
+
+// increaseSteps is the number of successful requests an operation
+// needs to recover its configured rate once it has been throttled down
+// to nothing.
+const increaseSteps = 50
+
+// RateLimiterState is the state of the bucket of an operation, for
+// metrics.
+type RateLimiterState struct {
+	Service   string
+	Operation string
+
+	// Rate is the current rate of the operation, in requests per
+	// second, and MaxRate the rate it is configured with.
+	Rate    float64
+	MaxRate float64
+
+	// Tokens is the number of requests that may be sent right away. It
+	// is negative when requests are waiting.
+	Tokens float64
+
+	// Requests counts the requests sent, and Throttled those throttled.
+	Requests  int64
+	Throttled int64
+
+	// Waited is the total time requests waited for the limiter.
+	Waited time.Duration
+}
+
+type tokenBucket struct {
+	RateLimiterState
+	last         time.Time // when tokens were last added
+	lastDecrease time.Time // when rate was last decreased
+}
+
+// bucket returns the bucket of the operation, or nil if it is not
+// limited.
+func (l *RateLimiter) bucket(service, operation string, now time.Time) *tokenBucket {
+	key := service + "." + operation
+	if b, ok := l.buckets[key]; ok {
+		return b
+	}
+	rate, ok := l.Rates[key]
+	if !ok {
+		rate, ok = l.Rates[service]
+	}
+	if !ok {
+		rate = l.Rate
+	}
+	if rate <= 0 {
+		return nil
+	}
+	if l.buckets == nil {
+		l.buckets = make(map[string]*tokenBucket)
+	}
+	b := &tokenBucket{last: now}
+	b.Service, b.Operation = service, operation
+	b.Rate, b.MaxRate = rate, rate
+	b.Tokens = float64(l.burst())
+	l.buckets[key] = b
+	return b
+}
+
+func (l *RateLimiter) timeNow() time.Time {
+	if l.now != nil {
+		return l.now()
+	}
+	return time.Now()
+}
+
+func (l *RateLimiter) burst() int {
+	if l.Burst <= 0 {
+		return 1
+	}
+	return l.Burst
+}
+
+// refill adds the tokens earned since the last refill to b.
+func (l *RateLimiter) refill(b *tokenBucket, now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.Tokens += elapsed.Seconds() * b.Rate
+		if max := float64(l.burst()); b.Tokens > max {
+			b.Tokens = max
+		}
+		b.last = now
+	}
+}
+
+// reserve takes a token for a request of the operation, and returns how
+// long the request must wait for it.
+func (l *RateLimiter) reserve(service, operation string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.timeNow()
+	b := l.bucket(service, operation, now)
+	if b == nil {
+		return 0
+	}
+	l.refill(b, now)
+	b.Tokens--
+	b.Requests++
+	if b.Tokens >= 0 {
+		return 0
+	}
+	delay := time.Duration(-b.Tokens / b.Rate * float64(time.Second))
+	b.Waited += delay
+	return delay
+}
+
+// unreserve gives back the token of a request that was not sent.
+func (l *RateLimiter) unreserve(service, operation string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if b := l.buckets[service+"."+operation]; b != nil {
+		b.Tokens++
+		b.Requests--
+	}
+}
+
+// Wait waits until a request of the operation may be sent, or ctx is
+// done. It returns the error of ctx in the latter case.
+func (l *RateLimiter) Wait(ctx context.Context, service, operation string) error {
+	if l == nil {
+		return nil
+	}
+	delay := l.reserve(service, operation)
+	if delay <= 0 {
+		return nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	t := time.NewTimer(delay)
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		t.Stop()
+		l.unreserve(service, operation)
+		return ctx.Err()
+	}
+}
+
+// Throttled records that a request of the operation was throttled, and
+// halves its rate. The rate is decreased at most once per second, as the
+// requests in flight when throttling starts are likely throttled as well.
+func (l *RateLimiter) Throttled(service, operation string) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.timeNow()
+	b := l.bucket(service, operation, now)
+	if b == nil {
+		return
+	}
+	b.Throttled++
+	if now.Sub(b.lastDecrease) < time.Second {
+		return
+	}
+	l.refill(b, now)
+	b.lastDecrease = now
+	min := l.MinRate
+	if min <= 0 {
+		min = b.MaxRate / 10
+	}
+	if b.Rate /= 2; b.Rate < min {
+		b.Rate = min
+	}
+}
+
+// Succeeded records that a request of the operation succeeded, and
+// increases its rate if it was throttled down.
+func (l *RateLimiter) Succeeded(service, operation string) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b := l.buckets[service+"."+operation]
+	if b == nil || b.Rate >= b.MaxRate {
+		return
+	}
+	l.refill(b, l.timeNow())
+	if b.Rate += b.MaxRate / increaseSteps; b.Rate > b.MaxRate {
+		b.Rate = b.MaxRate
+	}
+}
+
+// State returns the state of the operations l has seen requests of,
+// ordered by service and operation.
+func (l *RateLimiter) State() []RateLimiterState {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.timeNow()
+	states := make([]RateLimiterState, 0, len(l.buckets))
+	for _, b := range l.buckets {
+		l.refill(b, now)
+		states = append(states, b.RateLimiterState)
+	}
+	sort.Slice(states, func(i, j int) bool {
+		if states[i].Service != states[j].Service {
+			return states[i].Service < states[j].Service
+		}
+		return states[i].Operation < states[j].Operation
+	})
+	return states
+}
@@ -0,0 +1,123 @@
+package aws_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/crowdmob/goamz/aws"
+	"gopkg.in/check.v1"
+	"net/http"
+	"net/http/httptest"
+	"time"
+)
+
+type fakeTime struct {
+	t time.Time
+}
+
+func (f *fakeTime) now() time.Time          { return f.t }
+func (f *fakeTime) advance(d time.Duration) { f.t = f.t.Add(d) }
+
+func (s *S) TestRateLimiterBuckets(c *check.C) {
+	clock := &fakeTime{time.Date(2014, 1, 1, 0, 0, 0, 0, time.UTC)}
+	l := &aws.RateLimiter{Rate: 10, Burst: 2, Rates: map[string]float64{"ec2": 1, "ec2.DescribeRegions": 0}}
+	l.SetNow(clock.now)
+
+	c.Assert(l.Reserve("sqs", "SendMessage"), check.Equals, time.Duration(0))
+	c.Assert(l.Reserve("sqs", "SendMessage"), check.Equals, time.Duration(0))
+	c.Assert(l.Reserve("sqs", "SendMessage"), check.Equals, 100*time.Millisecond)
+	c.Assert(l.Reserve("sqs", "SendMessage"), check.Equals, 200*time.Millisecond)
+	clock.advance(200 * time.Millisecond)
+	c.Assert(l.Reserve("sqs", "SendMessage"), check.Equals, 100*time.Millisecond)
+	// Operations have budgets of their own.
+	c.Assert(l.Reserve("sqs", "ReceiveMessage"), check.Equals, time.Duration(0))
+
+	c.Assert(l.Reserve("ec2", "DescribeInstances"), check.Equals, time.Duration(0))
+	c.Assert(l.Reserve("ec2", "DescribeInstances"), check.Equals, time.Duration(0))
+	c.Assert(l.Reserve("ec2", "DescribeInstances"), check.Equals, time.Second)
+	for i := 0; i < 5; i++ {
+		c.Assert(l.Reserve("ec2", "DescribeRegions"), check.Equals, time.Duration(0))
+	}
+
+	c.Assert(l.State(), check.DeepEquals, []aws.RateLimiterState{
+		{Service: "ec2", Operation: "DescribeInstances", Rate: 1, MaxRate: 1, Tokens: -1, Requests: 3, Waited: time.Second},
+		{Service: "sqs", Operation: "ReceiveMessage", Rate: 10, MaxRate: 10, Tokens: 1, Requests: 1},
+		{Service: "sqs", Operation: "SendMessage", Rate: 10, MaxRate: 10, Tokens: -1, Requests: 5, Waited: 400 * time.Millisecond},
+	})
+}
+
+func (s *S) TestRateLimiterAdapts(c *check.C) {
+	clock := &fakeTime{time.Date(2014, 1, 1, 0, 0, 0, 0, time.UTC)}
+	l := &aws.RateLimiter{Rate: 100, MinRate: 20}
+	l.SetNow(clock.now)
+	rate := func() float64 {
+		return l.State()[0].Rate
+	}
+
+	l.Throttled("dynamodb", "PutItem")
+	c.Assert(rate(), check.Equals, 50.0)
+	// Throttling errors following closely count once.
+	l.Throttled("dynamodb", "PutItem")
+	c.Assert(rate(), check.Equals, 50.0)
+	clock.advance(time.Second)
+	l.Throttled("dynamodb", "PutItem")
+	c.Assert(rate(), check.Equals, 25.0)
+	clock.advance(time.Second)
+	l.Throttled("dynamodb", "PutItem")
+	c.Assert(rate(), check.Equals, 20.0)
+	c.Assert(l.State()[0].Throttled, check.Equals, int64(4))
+
+	l.Succeeded("dynamodb", "PutItem")
+	c.Assert(rate(), check.Equals, 22.0)
+	for i := 0; i < 100; i++ {
+		l.Succeeded("dynamodb", "PutItem")
+	}
+	c.Assert(rate(), check.Equals, 100.0)
+}
+
+func (s *S) TestRateLimiterWait(c *check.C) {
+	var l *aws.RateLimiter
+	c.Assert(l.Wait(nil, "sqs", "SendMessage"), check.IsNil)
+
+	l = &aws.RateLimiter{Rate: 100}
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		c.Assert(l.Wait(nil, "sqs", "SendMessage"), check.IsNil)
+	}
+	c.Assert(time.Since(start) >= 35*time.Millisecond, check.Equals, true)
+
+	l = &aws.RateLimiter{Rate: 0.1}
+	c.Assert(l.Wait(nil, "sqs", "SendMessage"), check.IsNil)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	c.Assert(l.Wait(ctx, "sqs", "SendMessage"), check.Equals, context.DeadlineExceeded)
+	c.Assert(l.State()[0].Requests, check.Equals, int64(1))
+}
+
+func (s *S) TestServiceRateLimit(c *check.C) {
+	attempts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(400)
+			fmt.Fprint(w, `<ErrorResponse><Error><Code>Throttling</Code><Message>Rate exceeded.</Message></Error></ErrorResponse>`)
+		}
+	}))
+	defer srv.Close()
+
+	auth := aws.Auth{AccessKey: "abc", SecretKey: "123"}
+	service, err := aws.NewService(auth, aws.ServiceInfo{srv.URL, aws.V2Signature})
+	c.Assert(err, check.IsNil)
+	service.RetryPolicy = &aws.RetryPolicy{MaxAttempts: 2}
+	service.Limiter = &aws.RateLimiter{Rate: 1000}
+	resp, err := service.Query("GET", "/", map[string]string{"Action": "ListMetrics"})
+	c.Assert(err, check.IsNil)
+	resp.Body.Close()
+
+	c.Assert(attempts, check.Equals, 2)
+	state := service.Limiter.State()
+	c.Assert(state, check.HasLen, 1)
+	c.Assert(state[0].Operation, check.Equals, "ListMetrics")
+	c.Assert(state[0].Requests, check.Equals, int64(2))
+	c.Assert(state[0].Throttled, check.Equals, int64(1))
+	c.Assert(state[0].Rate > 500 && state[0].Rate < 1000, check.Equals, true)
+}
@@ -0,0 +1,218 @@
+package aws
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// RefreshWindow is how long before their expiration temporary
+	// credentials are refreshed in the background.
+	RefreshWindow = 5 * time.Minute
+
+	// refreshRetryDelay is how long to wait before trying again when a
+	// background refresh fails.
+	refreshRetryDelay = 30 * time.Second
+)
+
+// Credentials holds temporary credentials on behalf of every Auth bound
+// to it, and keeps them fresh.
+//
+// An Auth is copied by value into each client that uses it, so refreshing
+// one copy in place would leave the others with stale keys. An Auth bound
+// to a Credentials with its Auth method shares the keys instead: all
+// copies see a refresh, and every signature is computed from a single,
+// consistent snapshot of AccessKey, SecretKey and token.
+//
+// When the credentials expire, they are refreshed from the provider once
+// they are within RefreshWindow of their expiration: Get starts a refresh
+// and keeps returning the current keys, which are still valid, while it
+// runs. Only one refresh runs at a time. Errors are kept and reported by
+// Err; once the credentials have actually expired, Get waits for a
+// refresh and returns the error if it fails.
+type Credentials struct {
+	provider CredentialsProvider
+
+	mu         sync.Mutex
+	auth       Auth
+	err        error
+	tried      time.Time     // When the last refresh started.
+	refreshing chan struct{} // Closed when the refresh in flight ends.
+
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+// NewCredentials retrieves credentials from p and returns a Credentials
+// holding them. If they expire, a goroutine refreshing them ahead of
+// time is also started, so that they are fresh even after the client has
+// been idle; call Stop to release it once the credentials are no longer
+// needed.
+func NewCredentials(p CredentialsProvider) (*Credentials, error) {
+	auth, err := p.Retrieve()
+	if err != nil {
+		return nil, err
+	}
+	c := newCredentials(p, auth)
+	if !c.auth.expiration.IsZero() {
+		go c.refreshLoop()
+	}
+	return c, nil
+}
+
+// newCredentials returns a Credentials holding auth, retrieved from p,
+// and refreshed by Get alone.
+func newCredentials(p CredentialsProvider, auth Auth) *Credentials {
+	c := &Credentials{provider: p, stop: make(chan struct{}), tried: time.Now()}
+	c.auth, _ = auth.Snapshot()
+	return c
+}
+
+// Auth returns an Auth bound to c. Copies of the returned value all share
+// the credentials held by c.
+func (c *Credentials) Auth() Auth {
+	auth, _ := c.Get()
+	auth.creds = c
+	return auth
+}
+
+// Get returns a snapshot of the current credentials. If they have
+// expired, they are refreshed first, and an error is returned if that
+// fails. If they are about to expire, a refresh is started in the
+// background.
+func (c *Credentials) Get() (Auth, error) {
+	c.mu.Lock()
+	if !c.expired() {
+		if c.refreshing == nil && c.refreshDue() {
+			go c.retrieve(c.beginRefresh())
+		}
+		defer c.mu.Unlock()
+		return c.auth, nil
+	}
+	c.mu.Unlock()
+	err := c.refresh()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.auth, err
+}
+
+// Refresh retrieves new credentials from the provider immediately, or
+// waits for the refresh in flight if there is one.
+func (c *Credentials) Refresh() error {
+	return c.refresh()
+}
+
+// Err returns the error from the most recent refresh, or nil if it
+// succeeded.
+func (c *Credentials) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Stop stops refreshing the credentials in the background. Get still
+// refreshes them as they are about to expire.
+func (c *Credentials) Stop() {
+	c.stopOnce.Do(func() { close(c.stop) })
+}
+
+func (c *Credentials) expired() bool {
+	return !c.auth.expiration.IsZero() && !time.Now().Before(c.auth.expiration)
+}
+
+// refreshDue reports whether the credentials are close enough to their
+// expiration for Get to refresh them. Refreshes are at least
+// refreshRetryDelay apart, so that a failing provider is not called for
+// every request. c.mu must be held.
+func (c *Credentials) refreshDue() bool {
+	if c.auth.expiration.IsZero() {
+		return false
+	}
+	return c.auth.expiration.Sub(time.Now()) <= RefreshWindow && time.Since(c.tried) >= refreshRetryDelay
+}
+
+// refresh retrieves new credentials, unless a refresh is in flight
+// already, and waits for the refresh to end.
+func (c *Credentials) refresh() error {
+	c.mu.Lock()
+	done := c.refreshing
+	if done == nil {
+		done = c.beginRefresh()
+		c.mu.Unlock()
+		c.retrieve(done)
+	} else {
+		c.mu.Unlock()
+		<-done
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// beginRefresh records that a refresh is in flight, and returns the
+// channel closed when it ends. c.mu must be held.
+func (c *Credentials) beginRefresh() chan struct{} {
+	c.refreshing = make(chan struct{})
+	c.tried = time.Now()
+	return c.refreshing
+}
+
+// retrieve retrieves new credentials from the provider and ends the
+// refresh in flight. c.mu is not held while the provider is called, so
+// that signatures proceed with the current credentials meanwhile.
+func (c *Credentials) retrieve(done chan struct{}) {
+	auth, err := c.provider.Retrieve()
+	if err == nil {
+		auth, err = auth.Snapshot()
+	}
+	if err == nil && auth.AccessKey == "" {
+		err = errors.New("credentials provider returned no keys")
+	}
+	c.mu.Lock()
+	c.err = err
+	if err == nil {
+		c.auth = auth
+	}
+	c.refreshing = nil
+	c.mu.Unlock()
+	close(done)
+}
+
+// refreshDelay returns how long to wait before the next background refresh.
+func (c *Credentials) refreshDelay() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.auth.expiration.IsZero() {
+		return -1
+	}
+	left := c.auth.expiration.Sub(time.Now())
+	switch {
+	case left <= 0:
+		return refreshRetryDelay
+	case c.err != nil && refreshRetryDelay < left/2:
+		return refreshRetryDelay
+	case left > 2*RefreshWindow:
+		return left - RefreshWindow
+	}
+	// Short-lived credentials are refreshed half way through what
+	// remains of their lifetime.
+	return left / 2
+}
+
+func (c *Credentials) refreshLoop() {
+	for {
+		d := c.refreshDelay()
+		if d < 0 {
+			return
+		}
+		timer := time.NewTimer(d)
+		select {
+		case <-c.stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		c.Refresh()
+	}
+}
@@ -0,0 +1,218 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy decides how many times, and how long apart, a failed
+// request is attempted again.
+//
+// The delay before each retry is picked at random between zero and a
+// ceiling that doubles with every attempt, up to MaxDelay ("full
+// jitter"), so that clients failing together do not retry together.
+// Requests failing because they were throttled back off from the
+// larger ThrottleDelay.
+//
+// Every service client has a RetryPolicy field; when nil, the client
+// uses the default policy of its service, usually DefaultRetryPolicy.
+// A policy is only read by clients and may be shared between them.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts made, including the
+	// first one. Zero or one disables retries.
+	MaxAttempts int
+
+	// BaseDelay is the ceiling of the delay before the first retry.
+	BaseDelay time.Duration
+
+	// ThrottleDelay replaces BaseDelay after a throttling error. If
+	// zero, BaseDelay is used.
+	ThrottleDelay time.Duration
+
+	// MaxDelay caps the delay between two attempts. If zero, the
+	// delay is not capped.
+	MaxDelay time.Duration
+
+	// Classify, if set, replaces the classification of errors made by
+	// the service client.
+	Classify RetryClassifier
+}
+
+// A RetryClassifier tells whether a failed attempt may be retried, and
+// whether it failed because requests are being throttled.
+type RetryClassifier func(err error) (retry, throttled bool)
+
+// DefaultRetryPolicy is the policy used by service clients that have no
+// policy of their own.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:   3,
+	BaseDelay:     50 * time.Millisecond,
+	ThrottleDelay: 500 * time.Millisecond,
+	MaxDelay:      20 * time.Second,
+}
+
+// NoRetries is a policy making a single attempt.
+var NoRetries = RetryPolicy{MaxAttempts: 1}
+
+// RetryPolicyOrDefault returns p, or def if p is nil.
+func RetryPolicyOrDefault(p, def *RetryPolicy) *RetryPolicy {
+	if p == nil {
+		return def
+	}
+	return p
+}
+
+// Delay returns how long to wait before retrying after the given
+// number of failed attempts.
+func (p *RetryPolicy) Delay(failed int, throttled bool) time.Duration {
+	base := p.BaseDelay
+	if throttled && p.ThrottleDelay > 0 {
+		base = p.ThrottleDelay
+	}
+	ceiling := base
+	for i := 1; i < failed && ceiling <= math.MaxInt64/2; i++ {
+		if p.MaxDelay > 0 && ceiling >= p.MaxDelay {
+			break
+		}
+		ceiling *= 2
+	}
+	if p.MaxDelay > 0 && ceiling > p.MaxDelay {
+		ceiling = p.MaxDelay
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+// Do calls op until it succeeds, it fails with an error that classify
+// (or the policy's own Classify) says cannot be retried, or MaxAttempts
+// attempts have been made, and returns the error of the last attempt.
+// Waiting between attempts stops early when ctx is done.
+func (p *RetryPolicy) Do(ctx context.Context, classify RetryClassifier, op func() error) error {
+	return p.do(ctx, p.classifier(classify), op, nil)
+}
+
+// classifier returns the Classify of p if set, or else classify.
+func (p *RetryPolicy) classifier(classify RetryClassifier) RetryClassifier {
+	if p.Classify != nil {
+		return p.Classify
+	}
+	return classify
+}
+
+// do is like Do, but classifies errors with classify alone, and calls
+// onRetry, if not nil, before waiting to retry.
+func (p *RetryPolicy) do(ctx context.Context, classify RetryClassifier, op func() error, onRetry func(failed int, err error, delay time.Duration)) error {
+	for failed := 1; ; failed++ {
+		err := op()
+		if err == nil || failed >= p.MaxAttempts || ctx.Err() != nil {
+			return err
+		}
+		retry, throttled := classify(err)
+		if !retry {
+			return err
+		}
+		delay := p.Delay(failed, throttled)
+		if onRetry != nil {
+			onRetry(failed, err, delay)
+		}
+		t := time.NewTimer(delay)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		}
+	}
+}
+
+// throttlingCodes holds the error codes AWS services use to ask clients
+// to slow down.
+var throttlingCodes = map[string]bool{
+	"Throttling":                             true,
+	"ThrottlingException":                    true,
+	"ThrottledException":                     true,
+	"RequestThrottled":                       true,
+	"RequestThrottledException":              true,
+	"RequestLimitExceeded":                   true,
+	"TooManyRequestsException":               true,
+	"ProvisionedThroughputExceededException": true,
+	"TransactionInProgressException":         true,
+	"BandwidthLimitExceeded":                 true,
+	"SlowDown":                               true,
+	"PriorRequestNotComplete":                true,
+	"EC2ThrottledException":                  true,
+}
+
+// transientCodes holds the error codes of failures that may go away if
+// the request is sent again.
+var transientCodes = map[string]bool{
+	"InternalError":           true,
+	"InternalFailure":         true,
+	"InternalServerError":     true,
+	"ServiceUnavailable":      true,
+	"Unavailable":             true,
+	"RequestTimeout":          true,
+	"RequestTimeoutException": true,
+	"IDPCommunicationError":   true,
+}
+
+// IsThrottlingCode reports whether code is an AWS error code asking the
+// client to slow down.
+func IsThrottlingCode(code string) bool {
+	return throttlingCodes[code]
+}
+
+// ClassifyResponse classifies the failure of a request from the HTTP
+// status code and AWS error code of the response. It is meant for the
+// RetryClassifier of service clients.
+func ClassifyResponse(statusCode int, code string) (retry, throttled bool) {
+	if throttlingCodes[code] || statusCode == 429 {
+		return true, true
+	}
+	if transientCodes[code] {
+		return true, false
+	}
+	switch statusCode {
+	case 500, 502, 503, 504:
+		return true, false
+	}
+	return false, false
+}
+
+// IsTransientNetError reports whether err is a network failure, such as
+// a connection reset or a truncated response, that may go away if the
+// request is sent again. Cancellation of the request is not transient.
+func IsTransientNetError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		switch opErr.Op {
+		case "dial", "read", "write":
+			return true
+		}
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) && urlErr.Timeout() {
+		return true
+	}
+	return false
+}
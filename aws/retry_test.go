@@ -0,0 +1,137 @@
+package aws_test
+
+import (
+	"context"
+	"errors"
+	"github.com/crowdmob/goamz/aws"
+	"gopkg.in/check.v1"
+	"io"
+	"time"
+)
+
+func (s *S) TestRetryDelayBounds(c *check.C) {
+	p := aws.RetryPolicy{
+		BaseDelay:     10 * time.Millisecond,
+		ThrottleDelay: 100 * time.Millisecond,
+		MaxDelay:      time.Second,
+	}
+	for i := 0; i < 100; i++ {
+		c.Assert(p.Delay(1, false) <= 10*time.Millisecond, check.Equals, true)
+		c.Assert(p.Delay(3, false) <= 40*time.Millisecond, check.Equals, true)
+		c.Assert(p.Delay(3, true) <= 400*time.Millisecond, check.Equals, true)
+		c.Assert(p.Delay(20, true) <= time.Second, check.Equals, true)
+		c.Assert(p.Delay(1, false) >= 0, check.Equals, true)
+	}
+	c.Assert(aws.NoRetries.Delay(1, false), check.Equals, time.Duration(0))
+}
+
+func (s *S) TestRetryDelayUncapped(c *check.C) {
+	p := aws.RetryPolicy{BaseDelay: 10 * time.Millisecond}
+	var longest time.Duration
+	for i := 0; i < 100; i++ {
+		d := p.Delay(4, false)
+		c.Assert(d <= 80*time.Millisecond, check.Equals, true)
+		if d > longest {
+			longest = d
+		}
+		c.Assert(p.Delay(100, false) >= 0, check.Equals, true)
+	}
+	// The ceiling still doubles without MaxDelay.
+	c.Assert(longest > 10*time.Millisecond, check.Equals, true)
+}
+
+var errRetry = errors.New("retry me")
+
+func retryAll(err error) (retry, throttled bool) {
+	return err == errRetry, false
+}
+
+func (s *S) TestRetryDo(c *check.C) {
+	p := aws.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+
+	calls := 0
+	err := p.Do(context.Background(), retryAll, func() error {
+		calls++
+		return errRetry
+	})
+	c.Assert(err, check.Equals, errRetry)
+	c.Assert(calls, check.Equals, 3)
+
+	calls = 0
+	err = p.Do(context.Background(), retryAll, func() error {
+		calls++
+		if calls < 2 {
+			return errRetry
+		}
+		return nil
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(calls, check.Equals, 2)
+
+	calls = 0
+	fatal := errors.New("fatal")
+	err = p.Do(context.Background(), retryAll, func() error {
+		calls++
+		return fatal
+	})
+	c.Assert(err, check.Equals, fatal)
+	c.Assert(calls, check.Equals, 1)
+}
+
+func (s *S) TestRetryDoClassifyOverride(c *check.C) {
+	p := aws.RetryPolicy{
+		MaxAttempts: 5,
+		Classify:    func(error) (bool, bool) { return false, false },
+	}
+	calls := 0
+	err := p.Do(context.Background(), retryAll, func() error {
+		calls++
+		return errRetry
+	})
+	c.Assert(err, check.Equals, errRetry)
+	c.Assert(calls, check.Equals, 1)
+}
+
+func (s *S) TestRetryDoContextDone(c *check.C) {
+	p := aws.RetryPolicy{MaxAttempts: 5, BaseDelay: time.Hour, MaxDelay: time.Hour}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	calls := 0
+	start := time.Now()
+	err := p.Do(ctx, retryAll, func() error {
+		calls++
+		return errRetry
+	})
+	c.Assert(err, check.Equals, errRetry)
+	c.Assert(calls <= 2, check.Equals, true)
+	c.Assert(time.Since(start) < time.Minute, check.Equals, true)
+}
+
+func (s *S) TestClassifyResponse(c *check.C) {
+	tests := []struct {
+		status          int
+		code            string
+		retry, throttle bool
+	}{
+		{400, "Throttling", true, true},
+		{503, "RequestLimitExceeded", true, true},
+		{400, "ProvisionedThroughputExceededException", true, true},
+		{429, "", true, true},
+		{500, "InternalError", true, false},
+		{503, "", true, false},
+		{400, "InvalidParameterValue", false, false},
+		{404, "NoSuchEntity", false, false},
+	}
+	for _, t := range tests {
+		retry, throttled := aws.ClassifyResponse(t.status, t.code)
+		c.Check(retry, check.Equals, t.retry, check.Commentf("%d %s", t.status, t.code))
+		c.Check(throttled, check.Equals, t.throttle, check.Commentf("%d %s", t.status, t.code))
+	}
+}
+
+func (s *S) TestIsTransientNetError(c *check.C) {
+	c.Assert(aws.IsTransientNetError(io.ErrUnexpectedEOF), check.Equals, true)
+	c.Assert(aws.IsTransientNetError(context.Canceled), check.Equals, false)
+	c.Assert(aws.IsTransientNetError(errors.New("other")), check.Equals, false)
+	c.Assert(aws.IsTransientNetError(nil), check.Equals, false)
+}
@@ -11,6 +11,7 @@ import (
 	"net/url"
 	"path"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -32,11 +33,12 @@ func NewV2Signer(auth Auth, service ServiceInfo) (*V2Signer, error) {
 }
 
 func (s *V2Signer) Sign(method, path string, params map[string]string) {
-	params["AWSAccessKeyId"] = s.auth.AccessKey
+	auth, _ := s.auth.Snapshot()
+	params["AWSAccessKeyId"] = auth.AccessKey
 	params["SignatureVersion"] = "2"
 	params["SignatureMethod"] = "HmacSHA256"
-	if s.auth.Token() != "" {
-		params["SecurityToken"] = s.auth.Token()
+	if auth.token != "" {
+		params["SecurityToken"] = auth.token
 	}
 
 	// AWS specifies that the parameters in a signed request must
@@ -53,7 +55,7 @@ func (s *V2Signer) Sign(method, path string, params map[string]string) {
 	}
 	joined := strings.Join(sarray, "&")
 	payload := method + "\n" + s.host + "\n" + path + "\n" + joined
-	hash := hmac.New(sha256.New, []byte(s.auth.SecretKey))
+	hash := hmac.New(sha256.New, []byte(auth.SecretKey))
 	hash.Write([]byte(payload))
 	signature := make([]byte, b64.EncodedLen(hash.Size()))
 	b64.Encode(signature, hash.Sum(nil))
@@ -69,28 +71,25 @@ const (
 
 type Route53Signer struct {
 	auth Auth
+
+	// Clock gives the date requests are signed with. If nil,
+	// DefaultClock is used.
+	Clock *Clock
 }
 
 func NewRoute53Signer(auth Auth) *Route53Signer {
 	return &Route53Signer{auth: auth}
 }
 
-// getCurrentDate fetches the date stamp from the aws servers to
-// ensure the auth headers are within 5 minutes of the server time
+// getCurrentDate returns the date stamp of the request, corrected by the
+// clock offset to be within 5 minutes of the server time
 func (s *Route53Signer) getCurrentDate() string {
-	response, err := http.Get("https://route53.amazonaws.com/date")
-	if err != nil {
-		fmt.Print("Unable to get date from amazon: ", err)
-		return ""
-	}
-
-	response.Body.Close()
-	return response.Header.Get("Date")
+	return s.Clock.Now().Format(http.TimeFormat)
 }
 
 // Creates the authorize signature based on the date stamp and secret key
-func (s *Route53Signer) getHeaderAuthorize(message string) string {
-	hmacSha256 := hmac.New(sha256.New, []byte(s.auth.SecretKey))
+func (s *Route53Signer) getHeaderAuthorize(auth Auth, message string) string {
+	hmacSha256 := hmac.New(sha256.New, []byte(auth.SecretKey))
 	hmacSha256.Write([]byte(message))
 	cryptedString := hmacSha256.Sum(nil)
 
@@ -100,9 +99,10 @@ func (s *Route53Signer) getHeaderAuthorize(message string) string {
 // Adds all the required headers for AWS Route53 API to the request
 // including the authorization
 func (s *Route53Signer) Sign(req *http.Request) {
+	auth, _ := s.auth.Snapshot()
 	date := s.getCurrentDate()
 	authHeader := fmt.Sprintf("AWS3-HTTPS AWSAccessKeyId=%s,Algorithm=%s,Signature=%s",
-		s.auth.AccessKey, "HmacSHA256", s.getHeaderAuthorize(date))
+		auth.AccessKey, "HmacSHA256", s.getHeaderAuthorize(auth, date))
 
 	req.Header.Set("Host", req.Host)
 	req.Header.Set("X-Amzn-Authorization", authHeader)
@@ -118,6 +118,10 @@ type V4Signer struct {
 	auth        Auth
 	serviceName string
 	region      Region
+
+	// Clock gives the time requests without a date are signed with. If
+	// nil, DefaultClock is used.
+	Clock *Clock
 }
 
 /*
@@ -137,9 +141,77 @@ the request.Host.
 
 The signed request will include a new "Authorization" header indicating that the request has been signed.
 
+The session token of temporary credentials is sent in the signed "X-Amz-Security-Token" header.
+
+The payload is hashed unless the request has an "x-amz-content-sha256" header, whose value is
+signed instead, as S3 allows: either the hash of the payload, or "UNSIGNED-PAYLOAD" for streams.
+
 Any changes to the request after signing the request will invalidate the signature.
 */
 func (s *V4Signer) Sign(req *http.Request) {
+	// Sign with a snapshot of the credentials, so that they cannot be
+	// refreshed half way through.
+	signer := *s
+	signer.auth, _ = s.auth.Snapshot()
+	if token := signer.auth.Token(); token != "" {
+		req.Header.Set("X-Amz-Security-Token", token)
+	}
+	signer.sign(req)
+}
+
+// Presign adds to the query of req a signature valid for expires, so that
+// its URL may be handed to anyone to perform the request until then,
+// without credentials. The headers of req are signed too, and must be
+// sent along with the URL.
+//
+// The payload of S3 requests is left unsigned; for other services, the
+// request must have no payload.
+func (s *V4Signer) Presign(req *http.Request, expires time.Duration) {
+	s.presign(req, expires, s.Clock.Now())
+}
+
+func (s *V4Signer) presign(req *http.Request, expires time.Duration, t time.Time) {
+	signer := *s
+	signer.auth, _ = s.auth.Snapshot()
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	header := make(http.Header, len(req.Header)+1)
+	for k, v := range req.Header {
+		header[k] = append([]string(nil), v...)
+	}
+	header.Set("Host", host)
+	signedHeaders := signer.signedHeaders(header)
+
+	query := req.URL.Query()
+	query.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	query.Set("X-Amz-Credential", signer.auth.AccessKey+"/"+signer.credentialScope(t))
+	query.Set("X-Amz-Date", t.Format(ISO8601BasicFormat))
+	query.Set("X-Amz-Expires", strconv.FormatInt(int64(expires/time.Second), 10))
+	query.Set("X-Amz-SignedHeaders", signedHeaders)
+	if token := signer.auth.Token(); token != "" {
+		query.Set("X-Amz-Security-Token", token)
+	}
+	req.URL.RawQuery = query.Encode()
+	// The query is sent as it is signed.
+	req.URL.RawQuery = signer.canonicalQueryString(req.URL)
+
+	payloadHash := unsignedPayload
+	if s.serviceName != "s3" {
+		payloadHash = signer.hash("")
+	}
+	creq := req.Method + "\n" +
+		signer.canonicalURI(req.URL) + "\n" +
+		req.URL.RawQuery + "\n" +
+		signer.canonicalHeaders(header) + "\n\n" +
+		signedHeaders + "\n" +
+		payloadHash
+	sts := signer.stringToSign(t, creq)
+	req.URL.RawQuery += "&X-Amz-Signature=" + signer.signature(t, sts)
+}
+
+func (s *V4Signer) sign(req *http.Request) {
 	req.Header.Set("host", req.Host)                  // host header must be included as a signed header
 	t := s.requestTime(req)                           // Get requst time
 	creq := s.canonicalRequest(req)                   // Build canonical request
@@ -184,7 +256,7 @@ func (s *V4Signer) requestTime(req *http.Request) time.Time {
 	}
 
 	// Create a current time header to be used
-	t = time.Now().UTC()
+	t = s.Clock.Now()
 	req.Header.Set("x-amz-date", t.Format(ISO8601BasicFormat))
 	return t
 }
@@ -212,6 +284,13 @@ func (s *V4Signer) canonicalRequest(req *http.Request) string {
 }
 
 func (s *V4Signer) canonicalURI(u *url.URL) string {
+	if s.serviceName == "s3" {
+		// S3 neither normalizes nor encodes the path again.
+		if p := u.EscapedPath(); p != "" {
+			return p
+		}
+		return "/"
+	}
 	canonicalPath := u.RequestURI()
 	if u.RawQuery != "" {
 		canonicalPath = canonicalPath[:len(canonicalPath)-len(u.RawQuery)-1]
@@ -225,19 +304,27 @@ func (s *V4Signer) canonicalURI(u *url.URL) string {
 }
 
 func (s *V4Signer) canonicalQueryString(u *url.URL) string {
+	// Pairs are sorted by encoded key, and then by encoded value. Every
+	// key is followed by "=", even when its value is empty.
+	query := u.Query()
+	keys := make([]string, 0, len(query))
+	encoded := make(map[string][]string, len(query))
+	for k, vs := range query {
+		ek := Encode(k)
+		keys = append(keys, ek)
+		for _, v := range vs {
+			encoded[ek] = append(encoded[ek], Encode(v))
+		}
+	}
+	sort.Strings(keys)
 	var a []string
-	for k, vs := range u.Query() {
-		k = url.QueryEscape(k)
+	for _, k := range keys {
+		vs := encoded[k]
+		sort.Strings(vs)
 		for _, v := range vs {
-			if v == "" {
-				a = append(a, k)
-			} else {
-				v = url.QueryEscape(v)
-				a = append(a, k+"="+v)
-			}
+			a = append(a, k+"="+v)
 		}
 	}
-	sort.Strings(a)
 	return strings.Join(a, "&")
 }
 
@@ -266,6 +353,9 @@ func (s *V4Signer) signedHeaders(h http.Header) string {
 }
 
 func (s *V4Signer) payloadHash(req *http.Request) string {
+	if h := req.Header.Get("X-Amz-Content-Sha256"); h != "" {
+		return h
+	}
 	var b []byte
 	if req.Body == nil {
 		b = []byte("")
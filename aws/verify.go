@@ -0,0 +1,721 @@
+package aws
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A Verifier checks the signatures of incoming requests as AWS does. It
+// is meant for the fake servers used in tests, such as s3test and
+// ec2test, so that requests signed wrongly fail there rather than
+// against AWS.
+//
+// Requests may be signed with Signature Version 2 in the query or form,
+// Signature Version 4 in the Authorization header or the query, or the
+// S3 REST scheme in the Authorization header or the query. S3 buckets
+// must be addressed in the path, as the fake servers do.
+type Verifier struct {
+	// MaxSkew is how far the time of a request may be from the time of
+	// the verifier. If zero, 15 minutes are allowed, as by AWS.
+	MaxSkew time.Duration
+
+	// Now returns the time requests are checked against. If nil,
+	// time.Now is used.
+	Now func() time.Time
+
+	mu   sync.Mutex
+	keys map[string]Auth
+}
+
+// NewVerifier returns a Verifier accepting requests signed with any of
+// the given credentials.
+func NewVerifier(auths ...Auth) *Verifier {
+	v := &Verifier{keys: make(map[string]Auth)}
+	for _, auth := range auths {
+		v.Add(auth)
+	}
+	return v
+}
+
+// Add makes v accept requests signed with auth, as well as with the
+// credentials it already knows. The current keys of auth are used.
+func (v *Verifier) Add(auth Auth) {
+	auth, err := auth.Snapshot()
+	if err != nil {
+		return
+	}
+	v.mu.Lock()
+	v.keys[auth.AccessKey] = auth
+	v.mu.Unlock()
+}
+
+// Remove makes v reject requests signed with accessKey.
+func (v *Verifier) Remove(accessKey string) {
+	v.mu.Lock()
+	delete(v.keys, accessKey)
+	v.mu.Unlock()
+}
+
+// AccessKeyID returns the access key ID req is signed with, or "" if it
+// is not signed. The signature is not checked; see Verify.
+func AccessKeyID(req *http.Request) string {
+	authz := req.Header.Get("Authorization")
+	query := req.URL.Query()
+	var credential string
+	switch {
+	case strings.HasPrefix(authz, "AWS4-HMAC-SHA256 "):
+		for _, field := range strings.Split(authz[len("AWS4-HMAC-SHA256 "):], ",") {
+			if kv := strings.SplitN(strings.TrimSpace(field), "=", 2); len(kv) == 2 && kv[0] == "Credential" {
+				credential = kv[1]
+			}
+		}
+	case query.Get("X-Amz-Credential") != "":
+		credential = query.Get("X-Amz-Credential")
+	case strings.HasPrefix(authz, "AWS "):
+		if i := strings.LastIndex(authz, ":"); i >= 0 {
+			return authz[len("AWS "):i]
+		}
+		return ""
+	default:
+		params, err := v2Params(req)
+		if err != nil {
+			return ""
+		}
+		return params.Get("AWSAccessKeyId")
+	}
+	// The credential is "<access key>/<date>/<region>/<service>/aws4_request".
+	return strings.SplitN(credential, "/", 2)[0]
+}
+
+// SignatureError is the error returned for requests failing
+// verification. Its code and status are those AWS replies with, so that
+// fake servers can send it back as is.
+type SignatureError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *SignatureError) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// A failure describes an error AWS replies with when verifying a
+// request, in query services and in S3.
+type failure struct {
+	status       int
+	code, s3Code string
+}
+
+var (
+	errUnsigned   = failure{403, "MissingAuthenticationToken", "AccessDenied"}
+	errMalformed  = failure{400, "IncompleteSignature", "AuthorizationHeaderMalformed"}
+	errUnknownKey = failure{403, "InvalidClientTokenId", "InvalidAccessKeyId"}
+	errBadToken   = failure{403, "InvalidClientTokenId", "InvalidToken"}
+	errMismatch   = failure{403, "SignatureDoesNotMatch", "SignatureDoesNotMatch"}
+	errSkewed     = failure{403, "RequestExpired", "RequestTimeTooSkewed"}
+	errExpired    = failure{403, "RequestExpired", "AccessDenied"}
+	errBadPayload = failure{400, "IncompleteSignature", "XAmzContentSHA256Mismatch"}
+)
+
+func (f failure) errorf(s3 bool, format string, a ...interface{}) *SignatureError {
+	code := f.code
+	if s3 {
+		code = f.s3Code
+	}
+	return &SignatureError{StatusCode: f.status, Code: code, Message: fmt.Sprintf(format, a...)}
+}
+
+// Verify checks that req is signed with credentials known to v, and
+// that it is not expired. The error returned, if any, is a
+// *SignatureError.
+//
+// The body of req is read when it is covered by the signature, and
+// replaced so that it can be read again.
+func (v *Verifier) Verify(req *http.Request) error {
+	authz := req.Header.Get("Authorization")
+	query := req.URL.Query()
+	var err *SignatureError
+	switch {
+	case strings.HasPrefix(authz, "AWS4-HMAC-SHA256 "):
+		err = v.verifyV4(req, false)
+	case query.Get("X-Amz-Algorithm") != "":
+		err = v.verifyV4(req, true)
+	case strings.HasPrefix(authz, "AWS "):
+		err = v.verifyS3(req, authz[len("AWS "):], false)
+	case query.Get("Signature") != "" && query.Get("Expires") != "" && query.Get("SignatureVersion") == "":
+		err = v.verifyS3(req, query.Get("AWSAccessKeyId")+":"+query.Get("Signature"), true)
+	default:
+		params, perr := v2Params(req)
+		if perr != nil {
+			err = errMalformed.errorf(false, "cannot read request: %v", perr)
+		} else if params.Get("SignatureVersion") == "2" {
+			err = v.verifyV2(req, params)
+		} else {
+			err = errUnsigned.errorf(false, "Request is missing Authentication Token")
+		}
+	}
+	if err != nil {
+		return err
+	}
+	// Not err, which would make a non-nil error of a nil pointer.
+	return nil
+}
+
+// auth returns the credentials of accessKey, checking that they hold
+// token.
+func (v *Verifier) auth(s3 bool, accessKey, token string) (Auth, *SignatureError) {
+	v.mu.Lock()
+	auth, ok := v.keys[accessKey]
+	v.mu.Unlock()
+	if !ok {
+		return auth, errUnknownKey.errorf(s3, "The AWS access key Id %q does not exist in our records.", accessKey)
+	}
+	if token != auth.Token() {
+		return auth, errBadToken.errorf(s3, "The security token included in the request is invalid.")
+	}
+	return auth, nil
+}
+
+func (v *Verifier) now() time.Time {
+	if v.Now != nil {
+		return v.Now()
+	}
+	return time.Now()
+}
+
+func (v *Verifier) maxSkew() time.Duration {
+	if v.MaxSkew == 0 {
+		return 15 * time.Minute
+	}
+	return v.MaxSkew
+}
+
+// checkTime checks that t, the time a request was signed at, is close
+// enough to the time of v.
+func (v *Verifier) checkTime(s3 bool, t time.Time) *SignatureError {
+	maxSkew := v.maxSkew()
+	now := v.now()
+	if d := now.Sub(t); d > maxSkew || d < -maxSkew {
+		return errSkewed.errorf(s3, "The difference between the request time %s and the current time %s is too large.",
+			t.UTC().Format(time.RFC3339), now.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// checkExpires checks that a request expiring at t has not expired.
+func (v *Verifier) checkExpires(s3 bool, t time.Time) *SignatureError {
+	if now := v.now(); now.After(t) {
+		return errExpired.errorf(s3, "Request has expired at %s, now is %s.",
+			t.UTC().Format(time.RFC3339), now.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// mismatch returns the error for a signature that does not match the
+// one computed from sts.
+func mismatch(s3 bool, sts string) *SignatureError {
+	return errMismatch.errorf(s3, "The request signature we calculated does not match the signature you provided. The string to sign was %q.", sts)
+}
+
+// readBody reads the body of req, and replaces it so that it can be read
+// again.
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return body, err
+}
+
+// ----------------------------------------------------------------------------
+// Signature Version 2
+
+// v2Params returns the parameters of req, found in the query, and in
+// the body of form posts.
+func v2Params(req *http.Request) (url.Values, error) {
+	params := req.URL.Query()
+	if req.Method != "POST" || !strings.HasPrefix(req.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		return params, nil
+	}
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, vs := range form {
+		params[k] = append(params[k], vs...)
+	}
+	return params, nil
+}
+
+func (v *Verifier) verifyV2(req *http.Request, params url.Values) *SignatureError {
+	auth, err := v.auth(false, params.Get("AWSAccessKeyId"), params.Get("SecurityToken"))
+	if err != nil {
+		return err
+	}
+	var newHash func() hash.Hash
+	switch params.Get("SignatureMethod") {
+	case "HmacSHA256":
+		newHash = sha256.New
+	case "HmacSHA1":
+		newHash = sha1.New
+	default:
+		return errMalformed.errorf(false, "Unsupported SignatureMethod %q", params.Get("SignatureMethod"))
+	}
+	if ts := params.Get("Timestamp"); ts != "" {
+		t, terr := parseV2Time(ts)
+		if terr != nil {
+			return errMalformed.errorf(false, "Invalid Timestamp %q", ts)
+		}
+		if err := v.checkTime(false, t); err != nil {
+			return err
+		}
+	} else if exp := params.Get("Expires"); exp != "" {
+		t, terr := parseV2Time(exp)
+		if terr != nil {
+			return errMalformed.errorf(false, "Invalid Expires %q", exp)
+		}
+		if err := v.checkExpires(false, t); err != nil {
+			return err
+		}
+	} else {
+		return errMalformed.errorf(false, "Request must contain either a valid Timestamp or an Expires parameter")
+	}
+
+	signature := params.Get("Signature")
+	var keys, pairs []string
+	for k := range params {
+		if k != "Signature" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		pairs = append(pairs, uriEncode(k, true)+"="+uriEncode(params.Get(k), true))
+	}
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	sts := req.Method + "\n" + req.Host + "\n" + path + "\n" + strings.Join(pairs, "&")
+	h := hmac.New(newHash, []byte(auth.SecretKey))
+	h.Write([]byte(sts))
+	if !hmac.Equal([]byte(b64.EncodeToString(h.Sum(nil))), []byte(signature)) {
+		return mismatch(false, sts)
+	}
+	return nil
+}
+
+// parseV2Time parses the Timestamp or Expires of a request, in ISO 8601
+// with a zone, or without one for UTC.
+func parseV2Time(s string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		t, err = time.Parse("2006-01-02T15:04:05", s)
+	}
+	return t, err
+}
+
+// uriEncode percent-encodes s as AWS signatures require: every byte but
+// the unreserved characters of RFC 3986 is encoded, in upper case.
+// Slashes are kept unless encodeSlash is set.
+func uriEncode(s string, encodeSlash bool) string {
+	var b bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// ----------------------------------------------------------------------------
+// Signature Version 4
+//
+// The canonical request is built here from the specification
+// (http://goo.gl/es1PAu) rather than with V4Signer, so that the
+// verifier catches the mistakes of the signer instead of repeating them.
+
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+func (v *Verifier) verifyV4(req *http.Request, inQuery bool) *SignatureError {
+	var credential, signedHeaders, signature, date, token string
+	var expires time.Duration
+	query := req.URL.Query()
+	if inQuery {
+		if alg := query.Get("X-Amz-Algorithm"); alg != "AWS4-HMAC-SHA256" {
+			return errMalformed.errorf(false, "Unsupported X-Amz-Algorithm %q", alg)
+		}
+		credential = query.Get("X-Amz-Credential")
+		signedHeaders = query.Get("X-Amz-SignedHeaders")
+		signature = query.Get("X-Amz-Signature")
+		date = query.Get("X-Amz-Date")
+		token = query.Get("X-Amz-Security-Token")
+		secs, err := strconv.Atoi(query.Get("X-Amz-Expires"))
+		if err != nil || secs < 0 {
+			return errMalformed.errorf(false, "Invalid X-Amz-Expires %q", query.Get("X-Amz-Expires"))
+		}
+		expires = time.Duration(secs) * time.Second
+	} else {
+		authz := strings.TrimPrefix(req.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ")
+		for _, field := range strings.Split(authz, ",") {
+			kv := strings.SplitN(strings.TrimSpace(field), "=", 2)
+			if len(kv) != 2 {
+				return errMalformed.errorf(false, "Invalid Authorization header field %q", field)
+			}
+			switch kv[0] {
+			case "Credential":
+				credential = kv[1]
+			case "SignedHeaders":
+				signedHeaders = kv[1]
+			case "Signature":
+				signature = kv[1]
+			}
+		}
+		date = req.Header.Get("X-Amz-Date")
+		token = req.Header.Get("X-Amz-Security-Token")
+	}
+
+	// The credential is "<access key>/<date>/<region>/<service>/aws4_request".
+	scope := strings.Split(credential, "/")
+	if len(scope) != 5 || scope[4] != "aws4_request" {
+		return errMalformed.errorf(false, "Invalid credential %q", credential)
+	}
+	s3 := scope[3] == "s3"
+	if signature == "" || signedHeaders == "" {
+		return errMalformed.errorf(s3, "Signature and SignedHeaders are required")
+	}
+	auth, err := v.auth(s3, scope[0], token)
+	if err != nil {
+		return err
+	}
+
+	var t time.Time
+	var terr error
+	if date != "" {
+		t, terr = time.Parse(ISO8601BasicFormat, date)
+	} else if !inQuery {
+		t, terr = http.ParseTime(req.Header.Get("Date"))
+	} else {
+		terr = fmt.Errorf("no X-Amz-Date")
+	}
+	if terr != nil {
+		return errMalformed.errorf(s3, "The request must contain a valid X-Amz-Date or Date")
+	}
+	if scope[1] != t.Format(ISO8601BasicFormatShort) {
+		return errMalformed.errorf(s3, "The credential date %q does not match the request date %q", scope[1], t.Format(ISO8601BasicFormatShort))
+	}
+	if inQuery {
+		if t.After(v.now().Add(v.maxSkew())) {
+			return errSkewed.errorf(s3, "The request was signed in the future, at %s.", t.Format(time.RFC3339))
+		}
+		if err := v.checkExpires(s3, t.Add(expires)); err != nil {
+			return err
+		}
+	} else if err := v.checkTime(s3, t); err != nil {
+		return err
+	}
+
+	names := strings.Split(signedHeaders, ";")
+	header := make(http.Header, len(names))
+	for _, name := range names {
+		switch name {
+		case "host":
+			header.Set("Host", req.Host)
+		case "content-length":
+			header.Set("Content-Length", strconv.FormatInt(req.ContentLength, 10))
+		default:
+			vs := req.Header[http.CanonicalHeaderKey(name)]
+			header[http.CanonicalHeaderKey(name)] = append([]string(nil), vs...)
+		}
+	}
+	if header.Get("Host") == "" {
+		return errMalformed.errorf(s3, "The host header must be signed")
+	}
+
+	payloadHash := req.Header.Get("X-Amz-Content-Sha256")
+	if payloadHash == "" && inQuery && s3 {
+		payloadHash = unsignedPayload
+	}
+	if payloadHash != unsignedPayload {
+		body, berr := readBody(req)
+		if berr != nil {
+			return errMalformed.errorf(s3, "cannot read request body: %v", berr)
+		}
+		sum := sha256Hex(body)
+		if payloadHash != "" && payloadHash != sum {
+			return errBadPayload.errorf(s3, "The provided 'x-amz-content-sha256' header does not match what was computed.")
+		}
+		payloadHash = sum
+	}
+
+	skip := ""
+	if inQuery {
+		skip = "X-Amz-Signature"
+	}
+	creq := req.Method + "\n" +
+		v4CanonicalURI(req.URL, s3) + "\n" +
+		v4CanonicalQuery(req.URL.RawQuery, skip) + "\n" +
+		v4CanonicalHeaders(header, names) + "\n" +
+		signedHeaders + "\n" +
+		payloadHash
+	sts := "AWS4-HMAC-SHA256\n" +
+		t.UTC().Format(ISO8601BasicFormat) + "\n" +
+		strings.Join(scope[1:], "/") + "\n" +
+		sha256Hex([]byte(creq))
+	key := []byte("AWS4" + auth.SecretKey)
+	for _, part := range scope[1:] {
+		key = hmacSHA256(key, part)
+	}
+	if !hmac.Equal([]byte(fmt.Sprintf("%x", hmacSHA256(key, sts))), []byte(signature)) {
+		return mismatch(s3, sts)
+	}
+	return nil
+}
+
+// v4CanonicalURI returns the canonical path of u: each of its segments
+// encoded once. Paths are normalized too, except for S3, which takes
+// them as they are.
+func v4CanonicalURI(u *url.URL, s3 bool) string {
+	escaped := u.EscapedPath()
+	var segments []string
+	for _, segment := range strings.Split(escaped, "/")[1:] {
+		if unescaped, err := url.PathUnescape(segment); err == nil {
+			segment = unescaped
+		}
+		if !s3 {
+			switch segment {
+			case "", ".":
+				continue
+			case "..":
+				if len(segments) > 0 {
+					segments = segments[:len(segments)-1]
+				}
+				continue
+			}
+		}
+		segments = append(segments, uriEncode(segment, true))
+	}
+	path := "/" + strings.Join(segments, "/")
+	if !s3 && len(segments) > 0 && strings.HasSuffix(escaped, "/") {
+		path += "/"
+	}
+	return path
+}
+
+// v4CanonicalQuery returns the canonical form of the query rawQuery,
+// without the parameter skip: its pairs encoded, and sorted by key and
+// then by value.
+func v4CanonicalQuery(rawQuery, skip string) string {
+	var pairs [][2]string
+	for _, param := range strings.Split(rawQuery, "&") {
+		if param == "" {
+			continue
+		}
+		var k, v string
+		if i := strings.Index(param, "="); i >= 0 {
+			k, v = param[:i], param[i+1:]
+		} else {
+			k = param
+		}
+		if unescaped, err := url.QueryUnescape(k); err == nil {
+			k = unescaped
+		}
+		if unescaped, err := url.QueryUnescape(v); err == nil {
+			v = unescaped
+		}
+		if k != skip {
+			pairs = append(pairs, [2]string{uriEncode(k, true), uriEncode(v, true)})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i][0] != pairs[j][0] {
+			return pairs[i][0] < pairs[j][0]
+		}
+		return pairs[i][1] < pairs[j][1]
+	})
+	encoded := make([]string, len(pairs))
+	for i, p := range pairs {
+		encoded[i] = p[0] + "=" + p[1]
+	}
+	return strings.Join(encoded, "&")
+}
+
+// v4CanonicalHeaders returns the canonical headers of the request, one
+// line for each of names: the values of the header trimmed, with their
+// runs of spaces collapsed, and joined with commas.
+func v4CanonicalHeaders(header http.Header, names []string) string {
+	var b bytes.Buffer
+	for _, name := range names {
+		values := header[http.CanonicalHeaderKey(name)]
+		trimmed := make([]string, len(values))
+		for i, v := range values {
+			trimmed[i] = strings.Join(strings.Fields(v), " ")
+		}
+		b.WriteString(name + ":" + strings.Join(trimmed, ",") + "\n")
+	}
+	return b.String()
+}
+
+func sha256Hex(data []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(data))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// ----------------------------------------------------------------------------
+// S3 REST signatures (http://goo.gl/G1LrK)
+
+// s3SubResources holds the query parameters included in the resource
+// S3 signatures cover.
+var s3SubResources = map[string]bool{
+	"acl":                          true,
+	"delete":                       true,
+	"lifecycle":                    true,
+	"location":                     true,
+	"logging":                      true,
+	"notification":                 true,
+	"partNumber":                   true,
+	"policy":                       true,
+	"requestPayment":               true,
+	"response-cache-control":       true,
+	"response-content-disposition": true,
+	"response-content-encoding":    true,
+	"response-content-language":    true,
+	"response-content-type":        true,
+	"response-expires":             true,
+	"torrent":                      true,
+	"uploadId":                     true,
+	"uploads":                      true,
+	"versionId":                    true,
+	"versioning":                   true,
+	"versions":                     true,
+	"website":                      true,
+}
+
+// verifyS3 verifies a request signed with the S3 REST scheme, where
+// credential is "<access key>:<signature>".
+func (v *Verifier) verifyS3(req *http.Request, credential string, inQuery bool) *SignatureError {
+	i := strings.LastIndex(credential, ":")
+	if i < 0 {
+		return errMalformed.errorf(true, "Invalid Authorization header")
+	}
+	auth, err := v.auth(true, credential[:i], req.Header.Get("X-Amz-Security-Token"))
+	if err != nil {
+		return err
+	}
+	signature := credential[i+1:]
+
+	var amz []string
+	var amzDate bool
+	for k, vs := range req.Header {
+		k = strings.ToLower(k)
+		if strings.HasPrefix(k, "x-amz-") {
+			amz = append(amz, k+":"+strings.Join(vs, ","))
+			amzDate = amzDate || k == "x-amz-date"
+		}
+	}
+	sort.Strings(amz)
+
+	var date string
+	if inQuery {
+		date = req.URL.Query().Get("Expires")
+		secs, perr := strconv.ParseInt(date, 10, 64)
+		if perr != nil {
+			return errMalformed.errorf(true, "Invalid Expires %q", date)
+		}
+		if err := v.checkExpires(true, time.Unix(secs, 0)); err != nil {
+			return err
+		}
+	} else {
+		header := "Date"
+		if amzDate {
+			header = "X-Amz-Date"
+		} else {
+			date = req.Header.Get("Date")
+		}
+		t, ok := parseHTTPTime(req.Header.Get(header))
+		if !ok {
+			return errMalformed.errorf(true, "AWS authentication requires a valid Date or x-amz-date header")
+		}
+		if err := v.checkTime(true, t); err != nil {
+			return err
+		}
+	}
+
+	var sub []string
+	for k, vs := range req.URL.Query() {
+		if !s3SubResources[k] {
+			continue
+		}
+		for _, v := range vs {
+			if v == "" {
+				sub = append(sub, k)
+			} else {
+				sub = append(sub, k+"="+v)
+			}
+		}
+	}
+	resource := req.URL.EscapedPath()
+	if len(sub) > 0 {
+		sort.Strings(sub)
+		resource += "?" + strings.Join(sub, "&")
+	}
+
+	var amzHeaders string
+	if len(amz) > 0 {
+		amzHeaders = strings.Join(amz, "\n") + "\n"
+	}
+	sts := req.Method + "\n" +
+		req.Header.Get("Content-MD5") + "\n" +
+		req.Header.Get("Content-Type") + "\n" +
+		date + "\n" +
+		amzHeaders + resource
+	h := hmac.New(sha1.New, []byte(auth.SecretKey))
+	h.Write([]byte(sts))
+	if !hmac.Equal([]byte(b64.EncodeToString(h.Sum(nil))), []byte(signature)) {
+		return mismatch(true, sts)
+	}
+	return nil
+}
+
+// parseHTTPTime parses the time of a Date header, in any of the formats
+// HTTP allows or RFC 1123 with a numeric or UTC zone.
+func parseHTTPTime(s string) (time.Time, bool) {
+	if t, err := http.ParseTime(s); err == nil {
+		return t, true
+	}
+	for _, layout := range []string{time.RFC1123, time.RFC1123Z} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
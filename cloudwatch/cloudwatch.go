@@ -15,6 +15,7 @@
 package cloudwatch
 
 import (
+	"context"
 	"encoding/xml"
 	"errors"
 	"fmt"
@@ -25,8 +26,13 @@ import (
 )
 
 // The CloudWatch type encapsulates all the CloudWatch operations in a region.
+//
+// To send requests through a custom *http.Client, build the service with
+// aws.NewServiceWithScope, set its HTTPClient field and assign it to Service.
 type CloudWatch struct {
 	Service aws.AWSService
+
+	aws.ContextBinding
 }
 
 type Dimension struct {
@@ -175,7 +181,7 @@ var validComparisonOperators = sets.SSet(
 
 // Create a new CloudWatch object for a given namespace
 func NewCloudWatch(auth aws.Auth, region aws.ServiceInfo) (*CloudWatch, error) {
-	service, err := aws.NewService(auth, region)
+	service, err := aws.NewServiceWithScope(auth, region, "monitoring", "")
 	if err != nil {
 		return nil, err
 	}
@@ -184,11 +190,32 @@ func NewCloudWatch(auth aws.Auth, region aws.ServiceInfo) (*CloudWatch, error) {
 	}, nil
 }
 
+// NewFromConfig creates a new CloudWatch with the credentials, endpoint,
+// HTTP client, retry policy and rate limiter of cfg.
+func NewFromConfig(cfg *aws.Config) (*CloudWatch, error) {
+	service, err := aws.NewServiceWithScope(cfg.Auth, cfg.Region.CloudWatchServicepoint, "monitoring", cfg.Region.Name)
+	if err != nil {
+		return nil, err
+	}
+	service.HTTPClient = cfg.HTTPClient
+	service.RetryPolicy = cfg.RetryPolicy(&aws.DefaultRetryPolicy)
+	service.Limiter = cfg.Limiter
+	return &CloudWatch{Service: service}, nil
+}
+
+// WithContext returns a shallow copy of c bound to ctx; see
+// aws.ContextBinding.
+func (c *CloudWatch) WithContext(ctx context.Context) *CloudWatch {
+	cw := *c
+	cw.ContextBinding = aws.BindContext(ctx)
+	return &cw
+}
+
 func (c *CloudWatch) query(method, path string, params map[string]string, resp interface{}) error {
 	// Add basic Cloudwatch param
 	params["Version"] = "2010-08-01"
 
-	r, err := c.Service.Query(method, path, params)
+	r, err := c.Service.QueryWithContext(c.Context(), method, path, params)
 	if err != nil {
 		return err
 	}
@@ -258,8 +285,24 @@ func (c *CloudWatch) GetMetricStatistics(req *GetMetricStatisticsRequest) (resul
 
 // Returns a list of valid metrics stored for the AWS account owner.
 // Returned metrics can be used with GetMetricStatistics to obtain statistical data for a given metric.
+// All the pages of results are fetched, starting at req.NextToken; use
+// MetricsIter to fetch them as they are needed instead.
 
 func (c *CloudWatch) ListMetrics(req *ListMetricsRequest) (result *ListMetricsResponse, err error) {
+	result, err = c.listMetrics(req, req.NextToken)
+	for err == nil && result.ListMetricsResult.NextToken != "" {
+		var page *ListMetricsResponse
+		page, err = c.listMetrics(req, result.ListMetricsResult.NextToken)
+		if err == nil {
+			page.ListMetricsResult.Metrics = append(result.ListMetricsResult.Metrics, page.ListMetricsResult.Metrics...)
+			result = page
+		}
+	}
+	return
+}
+
+// listMetrics fetches the page of metrics matching req starting at token.
+func (c *CloudWatch) listMetrics(req *ListMetricsRequest, token string) (*ListMetricsResponse, error) {
 
 	// Serialize all the params
 	params := aws.MakeParams("ListMetrics")
@@ -276,26 +319,56 @@ func (c *CloudWatch) ListMetrics(req *ListMetricsRequest) (result *ListMetricsRe
 			params[prefix+".Value"] = d.Value
 		}
 	}
+	if token != "" {
+		params["NextToken"] = token
+	}
 
-	result = new(ListMetricsResponse)
-	err = c.query("GET", "/", params, &result)
-	metrics := result.ListMetricsResult.Metrics
-	if result.ListMetricsResult.NextToken != "" {
-		params = aws.MakeParams("ListMetrics")
-		params["NextToken"] = result.ListMetricsResult.NextToken
-		for result.ListMetricsResult.NextToken != "" && err == nil {
-			result = new(ListMetricsResponse)
-			err = c.query("GET", "/", params, &result)
-			if err == nil {
-				newslice := make([]Metric, len(metrics)+len(result.ListMetricsResult.Metrics))
-				copy(newslice, metrics)
-				copy(newslice[len(metrics):], result.ListMetricsResult.Metrics)
-				metrics = newslice
-			}
-		}
-		result.ListMetricsResult.Metrics = metrics
+	result := new(ListMetricsResponse)
+	if err := c.query("GET", "/", params, result); err != nil {
+		return nil, err
 	}
-	return
+	return result, nil
+}
+
+// MetricsIter iterates over the metrics listed by ListMetrics, fetching
+// pages of results as they are needed.
+type MetricsIter struct {
+	pager *aws.Pager
+	page  *ListMetricsResponse
+}
+
+// MetricsIter returns an iterator over the metrics matching req, starting
+// at req.NextToken.
+func (c *CloudWatch) MetricsIter(req *ListMetricsRequest) *MetricsIter {
+	iter := &MetricsIter{}
+	iter.pager = aws.NewPager(func(token string) (int, string, error) {
+		if token == "" {
+			token = req.NextToken
+		}
+		resp, err := c.listMetrics(req, token)
+		if err != nil {
+			return 0, "", err
+		}
+		iter.page = resp
+		return len(resp.ListMetricsResult.Metrics), resp.ListMetricsResult.NextToken, nil
+	})
+	return iter
+}
+
+// Next advances to the next metric. It returns false when there are none
+// left, or when listing failed.
+func (iter *MetricsIter) Next() bool {
+	return iter.pager.Next()
+}
+
+// Metric returns the current metric.
+func (iter *MetricsIter) Metric() Metric {
+	return iter.page.ListMetricsResult.Metrics[iter.pager.Index()]
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (iter *MetricsIter) Err() error {
+	return iter.pager.Err()
 }
 
 func (c *CloudWatch) PutMetricData(metrics []MetricDatum) (result *aws.BaseResponse, err error) {
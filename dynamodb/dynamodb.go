@@ -2,6 +2,7 @@ package dynamodb
 
 import simplejson "github.com/bitly/go-simplejson"
 import (
+	"context"
 	"errors"
 	"github.com/crowdmob/goamz/aws"
 	"io/ioutil"
@@ -14,6 +15,60 @@ import (
 type Server struct {
 	Auth   aws.Auth
 	Region aws.Region
+
+	// HTTPClient is used to send requests to DynamoDB. If nil,
+	// http.DefaultClient is used.
+	HTTPClient *http.Client
+
+	// RetryPolicy controls how failed requests are retried. If nil,
+	// DefaultRetryPolicy is used.
+	RetryPolicy *aws.RetryPolicy
+
+	// Handlers holds the hooks run while sending requests. If nil,
+	// aws.DefaultHandlers is used.
+	Handlers *aws.Handlers
+
+	// Clock gives the time requests are signed with, corrected when
+	// DynamoDB reports clock skew. If nil, aws.DefaultClock is used.
+	Clock *aws.Clock
+
+	// Limiter, if set, limits the rate of the requests sent to DynamoDB,
+	// and slows them down when DynamoDB throttles them. It may be shared
+	// between clients.
+	Limiter *aws.RateLimiter
+
+	aws.ContextBinding
+}
+
+// DefaultRetryPolicy is the retry policy of DynamoDB servers that have
+// none of their own. Tables running out of provisioned throughput
+// throttle requests often, so it tries harder than aws.DefaultRetryPolicy.
+var DefaultRetryPolicy = aws.RetryPolicy{
+	MaxAttempts:   10,
+	BaseDelay:     25 * time.Millisecond,
+	ThrottleDelay: 50 * time.Millisecond,
+	MaxDelay:      20 * time.Second,
+}
+
+// NewFromConfig creates a new Server with the credentials, endpoints,
+// HTTP client, retry policy and rate limiter of cfg.
+func NewFromConfig(cfg *aws.Config) *Server {
+	return &Server{
+		Auth:        cfg.Auth,
+		Region:      cfg.Region,
+		HTTPClient:  cfg.HTTPClient,
+		RetryPolicy: cfg.RetryPolicy(&DefaultRetryPolicy),
+		Clock:       &aws.Clock{},
+		Limiter:     cfg.Limiter,
+	}
+}
+
+// WithContext returns a shallow copy of s bound to ctx; see
+// aws.ContextBinding.
+func (s *Server) WithContext(ctx context.Context) *Server {
+	c := *s
+	c.ContextBinding = aws.BindContext(ctx)
+	return &c
 }
 
 /*
@@ -39,17 +94,37 @@ type Error struct {
 	Status     string
 	Code       string // Dynamodb error code ("MalformedQueryString", ...)
 	Message    string // The human-oriented error message
+	RequestId  string // A unique ID for this request
 }
 
 func (e *Error) Error() string {
 	return e.Code + ": " + e.Message
 }
 
+// ErrorCode returns the DynamoDB error code.
+func (e *Error) ErrorCode() string { return e.Code }
+
+// ErrorMessage returns the message of the error.
+func (e *Error) ErrorMessage() string { return e.Message }
+
+// HTTPStatusCode returns the status code of the HTTP response.
+func (e *Error) HTTPStatusCode() int { return e.StatusCode }
+
+// RequestID returns the ID of the failed request.
+func (e *Error) RequestID() string { return e.RequestId }
+
+// Retryable reports whether sending the request again may succeed.
+func (e *Error) Retryable() bool {
+	retry, _ := retryable(e)
+	return retry
+}
+
 func buildError(r *http.Response, jsonBody []byte) error {
 
 	ddbError := Error{
 		StatusCode: r.StatusCode,
 		Status:     r.Status,
+		RequestId:  r.Header.Get("X-Amzn-RequestId"),
 	}
 	// TODO return error if Unmarshal fails?
 
@@ -72,7 +147,25 @@ func buildError(r *http.Response, jsonBody []byte) error {
 	return &ddbError
 }
 
-func (s *Server) queryServer(target string, query *Query) ([]byte, error) {
+func (s *Server) queryServer(target string, query *Query) (body []byte, err error) {
+	policy := aws.RetryPolicyOrDefault(s.RetryPolicy, &DefaultRetryPolicy)
+	info := &aws.RequestInfo{Service: "dynamodb", Operation: target[strings.LastIndex(target, ".")+1:], Context: s.Context(), Clock: s.Clock, Limiter: s.Limiter}
+	err = s.Handlers.Retry(policy, retryable, info, func() error {
+		body, err = s.send(info, target, query)
+		return err
+	})
+	return body, err
+}
+
+// retryable classifies the errors of failed DynamoDB requests for retries.
+func retryable(err error) (retry, throttled bool) {
+	if e, ok := err.(*Error); ok {
+		return aws.ClassifyResponse(e.StatusCode, e.Code)
+	}
+	return aws.IsTransientNetError(err), false
+}
+
+func (s *Server) send(info *aws.RequestInfo, target string, query *Query) ([]byte, error) {
 	data := strings.NewReader(query.String())
 	hreq, err := http.NewRequest("POST", s.Region.DynamoDBEndpoint+"/", data)
 	if err != nil {
@@ -80,18 +173,23 @@ func (s *Server) queryServer(target string, query *Query) ([]byte, error) {
 	}
 
 	hreq.Header.Set("Content-Type", "application/x-amz-json-1.0")
-	hreq.Header.Set("X-Amz-Date", time.Now().UTC().Format(aws.ISO8601BasicFormat))
+	hreq.Header.Set("X-Amz-Date", s.Clock.Now().Format(aws.ISO8601BasicFormat))
 	hreq.Header.Set("X-Amz-Target", target)
 
-	token := s.Auth.Token()
+	auth, err := s.Auth.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	token := auth.Token()
 	if token != "" {
 		hreq.Header.Set("X-Amz-Security-Token", token)
 	}
 
-	signer := aws.NewV4Signer(s.Auth, "dynamodb", s.Region)
-	signer.Sign(hreq)
-
-	resp, err := http.DefaultClient.Do(hreq)
+	signer := aws.NewV4Signer(auth, "dynamodb", s.Region)
+	resp, err := s.Handlers.Send(s.HTTPClient, info, hreq, func(hreq *http.Request) error {
+		signer.Sign(hreq)
+		return nil
+	})
 
 	if err != nil {
 		log.Printf("Error calling Amazon")
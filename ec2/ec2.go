@@ -11,6 +11,7 @@
 package ec2
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/xml"
@@ -31,12 +32,70 @@ const debug = false
 type EC2 struct {
 	aws.Auth
 	aws.Region
+
+	// HTTPClient is used to send requests to EC2. If nil,
+	// http.DefaultClient is used.
+	HTTPClient *http.Client
+
+	// RetryPolicy controls how failed requests are retried. If nil,
+	// aws.DefaultRetryPolicy is used.
+	RetryPolicy *aws.RetryPolicy
+
+	// Handlers holds the hooks run while sending requests. If nil,
+	// aws.DefaultHandlers is used.
+	Handlers *aws.Handlers
+
+	// Clock gives the time requests are signed with, corrected when
+	// EC2 reports clock skew. If nil, aws.DefaultClock is used.
+	Clock *aws.Clock
+
+	// Limiter, if set, limits the rate of the requests sent to EC2,
+	// and slows them down when EC2 throttles them. It may be shared
+	// between clients.
+	Limiter *aws.RateLimiter
+
+	// SignV2 makes the client sign requests with Signature Version 2,
+	// for legacy endpoints that do not accept Signature Version 4.
+	SignV2 bool
+
+	aws.ContextBinding
+	dryRun  bool
 	private byte // Reserve the right of using private data.
 }
 
 // New creates a new EC2.
 func New(auth aws.Auth, region aws.Region) *EC2 {
-	return &EC2{auth, region, 0}
+	return &EC2{Auth: auth, Region: region, Clock: &aws.Clock{}}
+}
+
+// NewFromConfig creates a new EC2 with the credentials, endpoints, HTTP
+// client, retry policy and rate limiter of cfg.
+func NewFromConfig(cfg *aws.Config) *EC2 {
+	ec2 := New(cfg.Auth, cfg.Region)
+	ec2.HTTPClient = cfg.HTTPClient
+	ec2.RetryPolicy = cfg.RetryPolicy(&aws.DefaultRetryPolicy)
+	ec2.Limiter = cfg.Limiter
+	return ec2
+}
+
+// WithContext returns a shallow copy of ec2 bound to ctx; see
+// aws.ContextBinding.
+func (ec2 *EC2) WithContext(ctx context.Context) *EC2 {
+	c := *ec2
+	c.ContextBinding = aws.BindContext(ctx)
+	return &c
+}
+
+// WithDryRun returns a shallow copy of ec2 whose operations changing
+// resources, as RunInstances or AuthorizeSecurityGroup, are sent with
+// the DryRun flag: EC2 checks that the caller may perform them, without
+// performing them. They then fail with an error satisfying IsDryRun if
+// they would have succeeded, or IsUnauthorized if the caller lacks the
+// permission. Read-only operations are performed as usual.
+func (ec2 *EC2) WithDryRun() *EC2 {
+	c := *ec2
+	c.dryRun = true
+	return &c
 }
 
 // ----------------------------------------------------------------------------
@@ -107,6 +166,37 @@ func (err *Error) Error() string {
 	return fmt.Sprintf("%s (%s)", err.Message, err.Code)
 }
 
+// ErrorCode returns the EC2 error code.
+func (err *Error) ErrorCode() string { return err.Code }
+
+// ErrorMessage returns the message of the error.
+func (err *Error) ErrorMessage() string { return err.Message }
+
+// HTTPStatusCode returns the status code of the HTTP response.
+func (err *Error) HTTPStatusCode() int { return err.StatusCode }
+
+// RequestID returns the ID of the failed request.
+func (err *Error) RequestID() string { return err.RequestId }
+
+// Retryable reports whether sending the request again may succeed.
+func (err *Error) Retryable() bool {
+	retry, _ := retryable(err)
+	return retry
+}
+
+// IsDryRun reports whether err is the DryRunOperation error EC2 returns
+// for a request sent with the DryRun flag that would have succeeded.
+func IsDryRun(err error) bool {
+	return aws.IsCode(err, "DryRunOperation")
+}
+
+// IsUnauthorized reports whether err is the UnauthorizedOperation error
+// EC2 returns when the caller isn't allowed to perform a request, be it
+// a dry run or not.
+func IsUnauthorized(err error) bool {
+	return aws.IsCode(err, "UnauthorizedOperation")
+}
+
 // For now a single error inst is being exposed. In the future it may be useful
 // to provide access to all of them, but rather than doing it as an array/slice,
 // use a *next pointer, so that it's backward compatible and it continues to be
@@ -118,9 +208,20 @@ type xmlErrors struct {
 
 var timeNow = time.Now
 
+// query sends the request described by params, retrying it as the
+// retry policy of ec2 allows, and decodes the response into resp.
 func (ec2 *EC2) query(params map[string]string, resp interface{}) error {
+	policy := aws.RetryPolicyOrDefault(ec2.RetryPolicy, &aws.DefaultRetryPolicy)
+	info := &aws.RequestInfo{Service: "ec2", Operation: params["Action"], Context: ec2.Context(), Clock: ec2.Clock, Limiter: ec2.Limiter}
+	return ec2.Handlers.Retry(policy, retryable, info, func() error {
+		// Each attempt is signed afresh.
+		return ec2.send(info, copyParams(params), resp)
+	})
+}
+
+func (ec2 *EC2) send(info *aws.RequestInfo, params map[string]string, resp interface{}) error {
 	params["Version"] = "2014-02-01"
-	params["Timestamp"] = timeNow().In(time.UTC).Format(time.RFC3339)
+	params["Timestamp"] = timeNow().Add(ec2.Clock.Offset()).In(time.UTC).Format(time.RFC3339)
 	endpoint, err := url.Parse(ec2.Region.EC2Endpoint)
 	if err != nil {
 		return err
@@ -128,16 +229,32 @@ func (ec2 *EC2) query(params map[string]string, resp interface{}) error {
 	if endpoint.Path == "" {
 		endpoint.Path = "/"
 	}
-	if ec2.Auth.Token() != "" {
-		params["SecurityToken"] = ec2.Auth.Token()
+	auth, err := ec2.Auth.Snapshot()
+	if err != nil {
+		return err
 	}
-
-	sign(ec2.Auth, "GET", endpoint.Path, params, endpoint.Host)
 	endpoint.RawQuery = multimap(params).Encode()
 	if debug {
 		log.Printf("get { %v } -> {\n", endpoint.String())
 	}
-	r, err := http.Get(endpoint.String())
+	hreq, err := http.NewRequest("GET", endpoint.String(), nil)
+	if err != nil {
+		return err
+	}
+	r, err := ec2.Handlers.Send(ec2.HTTPClient, info, hreq, func(hreq *http.Request) error {
+		if !ec2.SignV2 {
+			signer := aws.NewV4Signer(auth, "ec2", ec2.Region)
+			signer.Clock = ec2.Clock
+			signer.Sign(hreq)
+			return nil
+		}
+		if auth.Token() != "" {
+			params["SecurityToken"] = auth.Token()
+		}
+		sign(auth, "GET", endpoint.Path, params, endpoint.Host)
+		hreq.URL.RawQuery = multimap(params).Encode()
+		return nil
+	})
 	if err != nil {
 		return err
 	}
@@ -155,6 +272,22 @@ func (ec2 *EC2) query(params map[string]string, resp interface{}) error {
 	return err
 }
 
+// retryable classifies the errors of failed EC2 requests for retries.
+func retryable(err error) (retry, throttled bool) {
+	if e, ok := err.(*Error); ok {
+		return aws.ClassifyResponse(e.StatusCode, e.Code)
+	}
+	return aws.IsTransientNetError(err), false
+}
+
+func copyParams(p map[string]string) map[string]string {
+	c := make(map[string]string, len(p))
+	for k, v := range p {
+		c[k] = v
+	}
+	return c
+}
+
 func multimap(p map[string]string) url.Values {
 	q := make(url.Values, len(p))
 	for k, v := range p {
@@ -184,6 +317,16 @@ func makeParams(action string) map[string]string {
 	return params
 }
 
+// changeParams is makeParams for the actions changing resources, which
+// the clients returned by WithDryRun send with the DryRun flag.
+func (ec2 *EC2) changeParams(action string) map[string]string {
+	params := makeParams(action)
+	if ec2.dryRun {
+		params["DryRun"] = "true"
+	}
+	return params
+}
+
 func addParamsList(params map[string]string, label string, ids []string) {
 	for i, id := range ids {
 		params[label+"."+strconv.Itoa(i+1)] = id
@@ -374,7 +517,7 @@ type IamInstanceProfile struct {
 //
 // See http://goo.gl/Mcm3b for more details.
 func (ec2 *EC2) RunInstances(options *RunInstancesOptions) (resp *RunInstancesResp, err error) {
-	params := makeParams("RunInstances")
+	params := ec2.changeParams("RunInstances")
 	params["ImageId"] = options.ImageId
 	params["InstanceType"] = options.InstanceType
 	var min, max int
@@ -534,7 +677,7 @@ type InstanceStateReason struct {
 //
 // See http://goo.gl/3BKHj for more details.
 func (ec2 *EC2) TerminateInstances(instIds []string) (resp *TerminateInstancesResp, err error) {
-	params := makeParams("TerminateInstances")
+	params := ec2.changeParams("TerminateInstances")
 	addParamsList(params, "InstanceId", instIds)
 	resp = &TerminateInstancesResp{}
 	err = ec2.query(params, resp)
@@ -599,7 +742,7 @@ type AllocateAddressResp struct {
 //
 // See http://goo.gl/aLPmbm for more details
 func (ec2 *EC2) AllocateAddress(domain string) (resp *AllocateAddressResp, err error) {
-	params := makeParams("AllocateAddress")
+	params := ec2.changeParams("AllocateAddress")
 	params["Domain"] = domain
 
 	resp = &AllocateAddressResp{}
@@ -624,7 +767,7 @@ type ReleaseAddressResp struct {
 //
 // See http://goo.gl/Ciw2Z8 for more details
 func (ec2 *EC2) ReleaseAddress(publicIp, allocationId string) (resp *ReleaseAddressResp, err error) {
-	params := makeParams("ReleaseAddress")
+	params := ec2.changeParams("ReleaseAddress")
 
 	if publicIp != "" {
 		params["PublicIp"] = publicIp
@@ -667,7 +810,7 @@ type AssociateAddressResp struct {
 //
 // See http://goo.gl/hhj4z7 for more details
 func (ec2 *EC2) AssociateAddress(options *AssociateAddressOptions) (resp *AssociateAddressResp, err error) {
-	params := makeParams("AssociateAddress")
+	params := ec2.changeParams("AssociateAddress")
 	params["InstanceId"] = options.InstanceId
 	if options.PublicIp != "" {
 		params["PublicIp"] = options.PublicIp
@@ -706,7 +849,7 @@ type DiassociateAddressResp struct {
 // AssociationId - Required for VPC
 // See http://goo.gl/Dapkuz for more details
 func (ec2 *EC2) DiassociateAddress(publicIp, associationId string) (resp *DiassociateAddressResp, err error) {
-	params := makeParams("DiassociateAddress")
+	params := ec2.changeParams("DiassociateAddress")
 	if publicIp != "" {
 		params["PublicIp"] = publicIp
 	}
@@ -857,7 +1000,7 @@ type CreateSnapshotResp struct {
 //
 // See http://goo.gl/ttcda for more details.
 func (ec2 *EC2) CreateSnapshot(volumeId, description string) (resp *CreateSnapshotResp, err error) {
-	params := makeParams("CreateSnapshot")
+	params := ec2.changeParams("CreateSnapshot")
 	params["VolumeId"] = volumeId
 	params["Description"] = description
 
@@ -880,7 +1023,7 @@ func (ec2 *EC2) CreateSnapshot(volumeId, description string) (resp *CreateSnapsh
 //
 // See http://goo.gl/vwU1y for more details.
 func (ec2 *EC2) DeleteSnapshots(ids []string) (resp *SimpleResp, err error) {
-	params := makeParams("DeleteSnapshot")
+	params := ec2.changeParams("DeleteSnapshot")
 	for i, id := range ids {
 		params["SnapshotId."+strconv.Itoa(i+1)] = id
 	}
@@ -958,7 +1101,7 @@ type CreateSecurityGroupResp struct {
 //
 // See http://goo.gl/Eo7Yl for more details.
 func (ec2 *EC2) CreateSecurityGroup(name, description string) (resp *CreateSecurityGroupResp, err error) {
-	params := makeParams("CreateSecurityGroup")
+	params := ec2.changeParams("CreateSecurityGroup")
 	params["GroupName"] = name
 	params["GroupDescription"] = description
 
@@ -1068,7 +1211,7 @@ func (ec2 *EC2) SecurityGroups(groups []SecurityGroup, filter *Filter) (resp *Se
 //
 // See http://goo.gl/QJJDO for more details.
 func (ec2 *EC2) DeleteSecurityGroup(group SecurityGroup) (resp *SimpleResp, err error) {
-	params := makeParams("DeleteSecurityGroup")
+	params := ec2.changeParams("DeleteSecurityGroup")
 	if group.Id != "" {
 		params["GroupId"] = group.Id
 	} else {
@@ -1099,7 +1242,7 @@ func (ec2 *EC2) RevokeSecurityGroup(group SecurityGroup, perms []IPPerm) (resp *
 }
 
 func (ec2 *EC2) authOrRevoke(op string, group SecurityGroup, perms []IPPerm) (resp *SimpleResp, err error) {
-	params := makeParams(op)
+	params := ec2.changeParams(op)
 	if group.Id != "" {
 		params["GroupId"] = group.Id
 	} else {
@@ -1148,7 +1291,7 @@ type Tag struct {
 //
 // See http://goo.gl/Vmkqc for more details
 func (ec2 *EC2) CreateTags(instIds []string, tags []Tag) (resp *SimpleResp, err error) {
-	params := makeParams("CreateTags")
+	params := ec2.changeParams("CreateTags")
 	addParamsList(params, "ResourceId", instIds)
 
 	for j, tag := range tags {
@@ -1184,7 +1327,7 @@ type StopInstanceResp struct {
 //
 // See http://goo.gl/awKeF for more details.
 func (ec2 *EC2) StartInstances(ids ...string) (resp *StartInstanceResp, err error) {
-	params := makeParams("StartInstances")
+	params := ec2.changeParams("StartInstances")
 	addParamsList(params, "InstanceId", ids)
 	resp = &StartInstanceResp{}
 	err = ec2.query(params, resp)
@@ -1198,7 +1341,7 @@ func (ec2 *EC2) StartInstances(ids ...string) (resp *StartInstanceResp, err erro
 //
 // See http://goo.gl/436dJ for more details.
 func (ec2 *EC2) StopInstances(ids ...string) (resp *StopInstanceResp, err error) {
-	params := makeParams("StopInstances")
+	params := ec2.changeParams("StopInstances")
 	addParamsList(params, "InstanceId", ids)
 	resp = &StopInstanceResp{}
 	err = ec2.query(params, resp)
@@ -1216,7 +1359,7 @@ func (ec2 *EC2) StopInstances(ids ...string) (resp *StopInstanceResp, err error)
 //
 // See http://goo.gl/baoUf for more details.
 func (ec2 *EC2) RebootInstances(ids ...string) (resp *SimpleResp, err error) {
-	params := makeParams("RebootInstances")
+	params := ec2.changeParams("RebootInstances")
 	addParamsList(params, "InstanceId", ids)
 	resp = &SimpleResp{}
 	err = ec2.query(params, resp)
@@ -6,6 +6,7 @@ import (
 	"github.com/crowdmob/goamz/testutil"
 	"gopkg.in/check.v1"
 	"testing"
+	"time"
 )
 
 func Test(t *testing.T) {
@@ -24,6 +25,8 @@ func (s *S) SetUpSuite(c *check.C) {
 	testServer.Start()
 	auth := aws.Auth{AccessKey: "abc", SecretKey: "123"}
 	s.ec2 = ec2.New(auth, aws.Region{EC2Endpoint: testServer.URL})
+	// Each test prepares a single response per request.
+	s.ec2.RetryPolicy = &aws.NoRetries
 }
 
 func (s *S) TearDownTest(c *check.C) {
@@ -53,6 +56,13 @@ func (s *S) TestRunInstancesErrorDump(c *check.C) {
 	c.Assert(ec2err.Code, check.Equals, "UnsupportedOperation")
 	c.Assert(ec2err.Message, check.Matches, msg)
 	c.Assert(ec2err.RequestId, check.Equals, "0503f4e9-bbd6-483c-b54f-c4ae9f3b30f4")
+
+	apiErr, ok := aws.AsAPIError(err)
+	c.Assert(ok, check.Equals, true)
+	c.Assert(apiErr.ErrorCode(), check.Equals, "UnsupportedOperation")
+	c.Assert(apiErr.HTTPStatusCode(), check.Equals, 400)
+	c.Assert(apiErr.RequestID(), check.Equals, "0503f4e9-bbd6-483c-b54f-c4ae9f3b30f4")
+	c.Assert(apiErr.Retryable(), check.Equals, false)
 }
 
 func (s *S) TestRunInstancesErrorWithoutXML(c *check.C) {
@@ -185,6 +195,81 @@ func (s *S) TestTerminateInstancesExample(c *check.C) {
 	c.Assert(resp.StateChanges[0].PreviousState.Name, check.Equals, "running")
 }
 
+func (s *S) TestNewFromConfig(c *check.C) {
+	testServer.Response(200, nil, DescribeInstancesExample1)
+
+	cfg := &aws.Config{
+		Auth:        aws.Auth{AccessKey: "abc", SecretKey: "123"},
+		Region:      aws.Region{Name: "us-east-1", EC2Endpoint: testServer.URL},
+		MaxAttempts: 1,
+		Limiter:     &aws.RateLimiter{Rate: 100},
+	}
+	e := ec2.NewFromConfig(cfg)
+	c.Assert(e.RetryPolicy.MaxAttempts, check.Equals, 1)
+	_, err := e.DescribeInstances(nil, nil)
+	testServer.WaitRequest()
+	c.Assert(err, check.IsNil)
+	c.Assert(cfg.Limiter.State(), check.HasLen, 1)
+}
+
+func (s *S) TestDryRun(c *check.C) {
+	testServer.Response(412, nil, DryRunOperationDump)
+	testServer.Response(403, nil, UnauthorizedOperationDump)
+	testServer.Response(200, nil, DescribeInstancesExample1)
+
+	e := s.ec2.WithDryRun()
+	_, err := e.TerminateInstances([]string{"i-1"})
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["DryRun"], check.DeepEquals, []string{"true"})
+	c.Assert(ec2.IsDryRun(err), check.Equals, true)
+	c.Assert(ec2.IsUnauthorized(err), check.Equals, false)
+
+	_, err = e.RunInstances(&ec2.RunInstancesOptions{ImageId: "ami-a6f504cf"})
+	req = testServer.WaitRequest()
+	c.Assert(req.Form["DryRun"], check.DeepEquals, []string{"true"})
+	c.Assert(ec2.IsDryRun(err), check.Equals, false)
+	c.Assert(ec2.IsUnauthorized(err), check.Equals, true)
+
+	// Read-only operations are performed.
+	_, err = e.DescribeInstances(nil, nil)
+	req = testServer.WaitRequest()
+	c.Assert(req.Form["DryRun"], check.IsNil)
+	c.Assert(err, check.IsNil)
+
+	// The original client is left alone.
+	testServer.Response(200, nil, TerminateInstancesExample)
+	_, err = s.ec2.TerminateInstances([]string{"i-1"})
+	req = testServer.WaitRequest()
+	c.Assert(req.Form["DryRun"], check.IsNil)
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestRetryThrottled(c *check.C) {
+	testServer.Response(503, nil, `<Response><Errors><Error><Code>RequestLimitExceeded</Code><Message>Request limit exceeded.</Message></Error></Errors><RequestID>1</RequestID></Response>`)
+	testServer.Response(200, nil, DescribeInstancesExample1)
+
+	e := *s.ec2
+	e.RetryPolicy = &aws.RetryPolicy{MaxAttempts: 3, ThrottleDelay: time.Millisecond}
+	resp, err := e.DescribeInstances([]string{"i-1"}, nil)
+
+	reqs := testServer.WaitRequests(2)
+	c.Assert(reqs[0].Header.Get("Authorization"), check.Not(check.Equals), "")
+	c.Assert(reqs[1].Header.Get("Authorization"), check.Not(check.Equals), "")
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.RequestId, check.Equals, "98e3c9a4-848c-4d6d-8e8a-b1bdEXAMPLE")
+}
+
+func (s *S) TestNoRetryOnClientError(c *check.C) {
+	testServer.Response(400, nil, ErrorDump)
+
+	e := *s.ec2
+	e.RetryPolicy = &aws.RetryPolicy{MaxAttempts: 3}
+	_, err := e.DescribeInstances(nil, nil)
+
+	testServer.WaitRequest()
+	c.Assert(err, check.FitsTypeOf, &ec2.Error{})
+}
+
 func (s *S) TestDescribeInstancesExample1(c *check.C) {
 	testServer.Response(200, nil, DescribeInstancesExample1)
 
@@ -828,6 +913,7 @@ func (s *S) TestSignatureWithEndpointPath(c *check.C) {
 
 	// https://bugs.launchpad.net/goamz/+bug/1022749
 	ec2 := ec2.New(s.ec2.Auth, aws.Region{EC2Endpoint: testServer.URL + "/services/Cloud"})
+	ec2.SignV2 = true
 
 	_, err := ec2.RebootInstances("i-10a64379")
 	c.Assert(err, check.IsNil)
@@ -835,3 +921,20 @@ func (s *S) TestSignatureWithEndpointPath(c *check.C) {
 	req := testServer.WaitRequest()
 	c.Assert(req.Form["Signature"], check.DeepEquals, []string{"VVoC6Y6xfES+KvZo+789thP8+tye4F6fOKBiKmXk4S4="})
 }
+
+func (s *S) TestSignatureV4(c *check.C) {
+	testServer.Response(200, nil, RebootInstancesExample)
+
+	auth := aws.Auth{AccessKey: "abc", SecretKey: "123"}
+	ec2 := ec2.New(auth, aws.Region{Name: "eu-central-1", EC2Endpoint: testServer.URL})
+
+	_, err := ec2.RebootInstances("i-10a64379")
+	c.Assert(err, check.IsNil)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["Signature"], check.IsNil)
+	c.Assert(req.Form["AWSAccessKeyId"], check.IsNil)
+	c.Assert(req.Header.Get("X-Amz-Date"), check.Not(check.Equals), "")
+	c.Assert(req.Header.Get("Authorization"), check.Matches,
+		"AWS4-HMAC-SHA256 Credential=abc/[0-9]+/eu-central-1/ec2/aws4_request, SignedHeaders=host;x-amz-date, Signature=[0-9a-f]{64}")
+}
@@ -5,17 +5,21 @@ import (
 	"github.com/crowdmob/goamz/aws"
 	"github.com/crowdmob/goamz/ec2"
 	"github.com/crowdmob/goamz/ec2/ec2test"
+	"github.com/crowdmob/goamz/policy"
 	"github.com/crowdmob/goamz/testutil"
 	"gopkg.in/check.v1"
+	"net/http"
+	"reflect"
 	"regexp"
 	"sort"
 )
 
 // LocalServer represents a local ec2test fake server.
 type LocalServer struct {
-	auth   aws.Auth
-	region aws.Region
-	srv    *ec2test.Server
+	auth     aws.Auth
+	region   aws.Region
+	srv      *ec2test.Server
+	verifier *aws.Verifier
 }
 
 func (s *LocalServer) SetUp(c *check.C) {
@@ -24,6 +28,7 @@ func (s *LocalServer) SetUp(c *check.C) {
 	c.Assert(srv, check.NotNil)
 
 	s.srv = srv
+	s.srv.SetVerifier(s.verifier)
 	s.region = aws.Region{EC2Endpoint: srv.URL()}
 }
 
@@ -38,7 +43,15 @@ type LocalServerSuite struct {
 	clientTests ClientTests
 }
 
-var _ = check.Suite(&LocalServerSuite{})
+var strictAuth = aws.Auth{AccessKey: "abc", SecretKey: "123"}
+
+var (
+	_ = check.Suite(&LocalServerSuite{})
+	// and once more checking the signatures of requests.
+	_ = check.Suite(&LocalServerSuite{
+		srv: LocalServer{auth: strictAuth, verifier: aws.NewVerifier(strictAuth)},
+	})
+)
 
 func (s *LocalServerSuite) SetUpSuite(c *check.C) {
 	s.srv.SetUp(c)
@@ -79,6 +92,130 @@ func (s *LocalServerSuite) TestUserData(c *check.C) {
 	c.Assert(tinst.UserData, check.DeepEquals, data)
 }
 
+func (s *LocalServerSuite) TestDryRun(c *check.C) {
+	e := s.ec2.WithDryRun()
+	_, err := e.RunInstances(&ec2.RunInstancesOptions{ImageId: imageId, InstanceType: "t1.micro"})
+	c.Assert(ec2.IsDryRun(err), check.Equals, true)
+	ec2err, ok := err.(*ec2.Error)
+	c.Assert(ok, check.Equals, true)
+	c.Assert(ec2err.StatusCode, check.Equals, 412)
+
+	resp, err := s.ec2.DescribeInstances(nil, ec2.NewFilter())
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.Reservations, check.HasLen, 0)
+
+	s.srv.srv.SetPolicy(policy.New(policy.AllowStatement("ec2:Describe*").On("*")))
+	defer s.srv.srv.SetPolicy(nil)
+	_, err = e.RunInstances(&ec2.RunInstancesOptions{ImageId: imageId, InstanceType: "t1.micro"})
+	c.Assert(ec2.IsUnauthorized(err), check.Equals, true)
+	_, err = e.CreateSecurityGroup("goamz-dry-run", "dry run group")
+	c.Assert(ec2.IsUnauthorized(err), check.Equals, true)
+	_, err = e.DescribeInstances(nil, nil)
+	c.Assert(err, check.IsNil)
+}
+
+// recordingTransport records the requests sent through it.
+type recordingTransport struct {
+	reqs []*http.Request
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.reqs = append(t.reqs, req)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// readMethods lists the operations of EC2 changing nothing, and
+// clientMethods the methods of EC2 that are not operations, besides
+// those of its embedded aws.Auth and aws.ContextBinding.
+var (
+	readMethods   = []string{"DescribeAddresses", "DescribeInstances", "Images", "SecurityGroups", "Snapshots"}
+	clientMethods = []string{"WithContext", "WithDryRun"}
+)
+
+// isOperation reports whether the method of EC2 with the given name
+// sends a request.
+func isOperation(name string) bool {
+	_, auth := reflect.TypeOf(&aws.Auth{}).MethodByName(name)
+	_, binding := reflect.TypeOf(aws.ContextBinding{}).MethodByName(name)
+	return !auth && !binding && !contains(clientMethods, name)
+}
+
+func (s *LocalServerSuite) TestDryRunEveryOperation(c *check.C) {
+	t := &recordingTransport{}
+	e := ec2.New(s.srv.auth, s.srv.region)
+	e.HTTPClient = &http.Client{Transport: t}
+	e.RetryPolicy = &aws.NoRetries
+	e = e.WithDryRun()
+
+	// Every operation is called, so that those added later cannot be
+	// left out.
+	v := reflect.ValueOf(e)
+	for i := 0; i < v.NumMethod(); i++ {
+		name := v.Type().Method(i).Name
+		if !isOperation(name) {
+			continue
+		}
+		m := v.Method(i)
+		args := make([]reflect.Value, m.Type().NumIn())
+		for j := range args {
+			if in := m.Type().In(j); in.Kind() == reflect.Ptr {
+				args[j] = reflect.New(in.Elem())
+			} else {
+				args[j] = reflect.Zero(in)
+			}
+		}
+		t.reqs = nil
+		var out []reflect.Value
+		if m.Type().IsVariadic() {
+			out = m.CallSlice(args)
+		} else {
+			out = m.Call(args)
+		}
+		c.Assert(t.reqs, check.HasLen, 1, check.Commentf(name))
+		dryRun := t.reqs[0].URL.Query().Get("DryRun") == "true"
+		c.Check(dryRun, check.Equals, !contains(readMethods, name), check.Commentf(name))
+		err, _ := out[len(out)-1].Interface().(error)
+		if dryRun && !ec2.IsDryRun(err) {
+			// The operations ec2test does not implement fail before
+			// the DryRun flag is checked.
+			c.Check(err, check.ErrorMatches, ".*Unrecognized Action.*", check.Commentf(name))
+		}
+	}
+
+	resp, err := s.ec2.DescribeInstances(nil, nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.Reservations, check.HasLen, 0)
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *LocalServerSuite) TestSignatures(c *check.C) {
+	if s.srv.verifier == nil {
+		c.Skip("the server does not verify signatures")
+	}
+	e := ec2.New(s.srv.auth, s.srv.region)
+	e.SignV2 = true
+	_, err := e.DescribeInstances(nil, nil)
+	c.Assert(err, check.IsNil)
+
+	auth := aws.Auth{AccessKey: strictAuth.AccessKey, SecretKey: "456"}
+	for _, signV2 := range []bool{false, true} {
+		e = ec2.New(auth, s.srv.region)
+		e.SignV2 = signV2
+		_, err = e.DescribeInstances(nil, nil)
+		ec2err, ok := err.(*ec2.Error)
+		c.Assert(ok, check.Equals, true)
+		c.Assert(ec2err.Code, check.Equals, "SignatureDoesNotMatch")
+	}
+}
+
 // AmazonServerSuite runs the ec2test server tests against a live EC2 server.
 // It will only be activated if the -all flag is specified.
 type AmazonServerSuite struct {
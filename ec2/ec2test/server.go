@@ -8,7 +8,9 @@ import (
 	"encoding/base64"
 	"encoding/xml"
 	"fmt"
+	"github.com/crowdmob/goamz/aws"
 	"github.com/crowdmob/goamz/ec2"
+	"github.com/crowdmob/goamz/policy"
 	"io"
 	"net"
 	"net/http"
@@ -55,6 +57,8 @@ type Server struct {
 	reservationId        counter
 	groupId              counter
 	initialInstanceState ec2.InstanceState
+	verifier             *aws.Verifier
+	policy               *policy.Document
 }
 
 // reservation holds a simulated ec2 reservation.
@@ -279,6 +283,25 @@ func (srv *Server) SetInitialInstanceState(state ec2.InstanceState) {
 	srv.mu.Unlock()
 }
 
+// SetVerifier makes the server reject requests that are not signed with
+// credentials known to v, as EC2 does. If v is nil, any request is
+// accepted, which is the default.
+func (srv *Server) SetVerifier(v *aws.Verifier) {
+	srv.mu.Lock()
+	srv.verifier = v
+	srv.mu.Unlock()
+}
+
+// SetPolicy makes the server authorize requests with the identity policy
+// p, as if it were attached to the caller: actions p doesn't allow, as
+// "ec2:RunInstances", fail with UnauthorizedOperation. If p is nil, every
+// action is allowed, which is the default.
+func (srv *Server) SetPolicy(p *policy.Document) {
+	srv.mu.Lock()
+	srv.policy = p
+	srv.mu.Unlock()
+}
+
 // URL returns the URL of the server.
 func (srv *Server) URL() string {
 	return srv.url
@@ -286,6 +309,13 @@ func (srv *Server) URL() string {
 
 // serveHTTP serves the EC2 protocol.
 func (srv *Server) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	srv.mu.Lock()
+	v, p := srv.verifier, srv.policy
+	srv.mu.Unlock()
+	var verr error
+	if v != nil {
+		verr = v.Verify(req)
+	}
 	req.ParseForm()
 
 	a := srv.newAction()
@@ -309,10 +339,26 @@ func (srv *Server) serveHTTP(w http.ResponseWriter, req *http.Request) {
 		}
 	}()
 
-	f := actions[req.Form.Get("Action")]
+	if verr != nil {
+		e := verr.(*aws.SignatureError)
+		fatalf(e.StatusCode, e.Code, "%s", e.Message)
+	}
+	action := req.Form.Get("Action")
+	f := actions[action]
 	if f == nil {
 		fatalf(400, "InvalidParameterValue", "Unrecognized Action")
 	}
+	if p != nil {
+		r := &policy.Request{Action: "ec2:" + action, Resource: "*", Context: policy.RequestContext(req)}
+		if policy.Evaluate(r, p) != policy.Allowed {
+			fatalf(403, "UnauthorizedOperation", "You are not authorized to perform this operation.")
+		}
+	}
+	// Dry runs stop once the request is authorized, leaving the state
+	// of the server unchanged.
+	if req.Form.Get("DryRun") == "true" {
+		fatalf(412, "DryRunOperation", "Request would have succeeded, but DryRun flag is set.")
+	}
 
 	response := f(srv, w, req, a.RequestId)
 	a.Response = response
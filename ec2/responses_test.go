@@ -7,6 +7,20 @@ var ErrorDump = `
 </Error></Errors><RequestID>0503f4e9-bbd6-483c-b54f-c4ae9f3b30f4</RequestID></Response>
 `
 
+var DryRunOperationDump = `
+<?xml version="1.0" encoding="UTF-8"?>
+<Response><Errors><Error><Code>DryRunOperation</Code>
+<Message>Request would have succeeded, but DryRun flag is set.</Message>
+</Error></Errors><RequestID>1a2b3c4d-0000-4000-8000-000000000001</RequestID></Response>
+`
+
+var UnauthorizedOperationDump = `
+<?xml version="1.0" encoding="UTF-8"?>
+<Response><Errors><Error><Code>UnauthorizedOperation</Code>
+<Message>You are not authorized to perform this operation.</Message>
+</Error></Errors><RequestID>1a2b3c4d-0000-4000-8000-000000000002</RequestID></Response>
+`
+
 // http://goo.gl/Mcm3b
 var RunInstancesExample = `
 <RunInstancesResponse xmlns="http://ec2.amazonaws.com/doc/2011-12-15/">
@@ -2,6 +2,7 @@
 package elb
 
 import (
+	"context"
 	"encoding/xml"
 	"fmt"
 	"github.com/crowdmob/goamz/aws"
@@ -14,10 +15,55 @@ import (
 type ELB struct {
 	aws.Auth
 	aws.Region
+
+	// HTTPClient is used to send requests to ELB. If nil,
+	// http.DefaultClient is used.
+	HTTPClient *http.Client
+
+	// RetryPolicy controls how failed requests are retried. If nil,
+	// aws.DefaultRetryPolicy is used.
+	RetryPolicy *aws.RetryPolicy
+
+	// Handlers holds the hooks run while sending requests. If nil,
+	// aws.DefaultHandlers is used.
+	Handlers *aws.Handlers
+
+	// Clock gives the time requests are signed with, corrected when
+	// ELB reports clock skew. If nil, aws.DefaultClock is used.
+	Clock *aws.Clock
+
+	// Limiter, if set, limits the rate of the requests sent to ELB,
+	// and slows them down when ELB throttles them. It may be shared
+	// between clients.
+	Limiter *aws.RateLimiter
+
+	// SignV2 makes the client sign requests with Signature Version 2,
+	// for legacy endpoints that do not accept Signature Version 4.
+	SignV2 bool
+
+	aws.ContextBinding
 }
 
 func New(auth aws.Auth, region aws.Region) *ELB {
-	return &ELB{auth, region}
+	return &ELB{Auth: auth, Region: region, Clock: &aws.Clock{}}
+}
+
+// NewFromConfig creates a new ELB with the credentials, endpoints, HTTP
+// client, retry policy and rate limiter of cfg.
+func NewFromConfig(cfg *aws.Config) *ELB {
+	elb := New(cfg.Auth, cfg.Region)
+	elb.HTTPClient = cfg.HTTPClient
+	elb.RetryPolicy = cfg.RetryPolicy(&aws.DefaultRetryPolicy)
+	elb.Limiter = cfg.Limiter
+	return elb
+}
+
+// WithContext returns a shallow copy of elb bound to ctx; see
+// aws.ContextBinding.
+func (elb *ELB) WithContext(ctx context.Context) *ELB {
+	c := *elb
+	c.ContextBinding = aws.BindContext(ctx)
+	return &c
 }
 
 // The CreateLoadBalancer type encapsulates options for the respective request in AWS.
@@ -269,8 +315,17 @@ func (elb *ELB) ConfigureHealthCheck(lbName string, healthCheck *HealthCheck) (*
 }
 
 func (elb *ELB) query(params map[string]string, resp interface{}) error {
+	policy := aws.RetryPolicyOrDefault(elb.RetryPolicy, &aws.DefaultRetryPolicy)
+	info := &aws.RequestInfo{Service: "elasticloadbalancing", Operation: params["Action"], Context: elb.Context(), Clock: elb.Clock, Limiter: elb.Limiter}
+	return elb.Handlers.Retry(policy, retryable, info, func() error {
+		// Each attempt is signed afresh.
+		return elb.send(info, copyParams(params), resp)
+	})
+}
+
+func (elb *ELB) send(info *aws.RequestInfo, params map[string]string, resp interface{}) error {
 	params["Version"] = "2012-06-01"
-	params["Timestamp"] = time.Now().In(time.UTC).Format(time.RFC3339)
+	params["Timestamp"] = elb.Clock.Now().Format(time.RFC3339)
 	endpoint, err := url.Parse(elb.Region.ELBEndpoint)
 	if err != nil {
 		return err
@@ -278,9 +333,26 @@ func (elb *ELB) query(params map[string]string, resp interface{}) error {
 	if endpoint.Path == "" {
 		endpoint.Path = "/"
 	}
-	sign(elb.Auth, "GET", endpoint.Path, params, endpoint.Host)
+	auth, err := elb.Auth.Snapshot()
+	if err != nil {
+		return err
+	}
 	endpoint.RawQuery = multimap(params).Encode()
-	r, err := http.Get(endpoint.String())
+	hreq, err := http.NewRequest("GET", endpoint.String(), nil)
+	if err != nil {
+		return err
+	}
+	r, err := elb.Handlers.Send(elb.HTTPClient, info, hreq, func(hreq *http.Request) error {
+		if !elb.SignV2 {
+			signer := aws.NewV4Signer(auth, "elasticloadbalancing", elb.Region)
+			signer.Clock = elb.Clock
+			signer.Sign(hreq)
+			return nil
+		}
+		sign(auth, "GET", endpoint.Path, params, endpoint.Host)
+		hreq.URL.RawQuery = multimap(params).Encode()
+		return nil
+	})
 	if err != nil {
 		return err
 	}
@@ -299,6 +371,8 @@ type Error struct {
 	Code string
 	// The human-oriented error message
 	Message string
+	// ID of the failed request
+	RequestId string `xml:"-"`
 }
 
 func (err *Error) Error() string {
@@ -309,8 +383,27 @@ func (err *Error) Error() string {
 	return fmt.Sprintf("%s (%s)", err.Message, err.Code)
 }
 
+// ErrorCode returns the ELB error code.
+func (err *Error) ErrorCode() string { return err.Code }
+
+// ErrorMessage returns the message of the error.
+func (err *Error) ErrorMessage() string { return err.Message }
+
+// HTTPStatusCode returns the status code of the HTTP response.
+func (err *Error) HTTPStatusCode() int { return err.StatusCode }
+
+// RequestID returns the ID of the failed request.
+func (err *Error) RequestID() string { return err.RequestId }
+
+// Retryable reports whether sending the request again may succeed.
+func (err *Error) Retryable() bool {
+	retry, _ := retryable(err)
+	return retry
+}
+
 type xmlErrors struct {
-	Errors []Error `xml:"Error"`
+	Errors    []Error `xml:"Error"`
+	RequestId string
 }
 
 func buildError(r *http.Response) error {
@@ -322,6 +415,7 @@ func buildError(r *http.Response) error {
 	if len(errors.Errors) > 0 {
 		err = errors.Errors[0]
 	}
+	err.RequestId = errors.RequestId
 	err.StatusCode = r.StatusCode
 	if err.Message == "" {
 		err.Message = r.Status
@@ -329,6 +423,22 @@ func buildError(r *http.Response) error {
 	return &err
 }
 
+// retryable classifies the errors of failed ELB requests for retries.
+func retryable(err error) (retry, throttled bool) {
+	if e, ok := err.(*Error); ok {
+		return aws.ClassifyResponse(e.StatusCode, e.Code)
+	}
+	return aws.IsTransientNetError(err), false
+}
+
+func copyParams(p map[string]string) map[string]string {
+	c := make(map[string]string, len(p))
+	for k, v := range p {
+		c[k] = v
+	}
+	return c
+}
+
 func multimap(p map[string]string) url.Values {
 	q := make(url.Values, len(p))
 	for k, v := range p {
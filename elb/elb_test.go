@@ -18,6 +18,8 @@ func (s *S) SetUpSuite(c *check.C) {
 	s.HTTPSuite.SetUpSuite(c)
 	auth := aws.Auth{AccessKey: "abc", SecretKey: "123"}
 	s.elb = elb.New(auth, aws.Region{ELBEndpoint: testServer.URL})
+	// Each test prepares a single response per request.
+	s.elb.RetryPolicy = &aws.NoRetries
 }
 
 func (s *S) TestCreateLoadBalancer(c *check.C) {
@@ -37,7 +39,8 @@ func (s *S) TestCreateLoadBalancer(c *check.C) {
 	resp, err := s.elb.CreateLoadBalancer(createLB)
 	c.Assert(err, check.IsNil)
 	defer s.elb.DeleteLoadBalancer(createLB.Name)
-	values := testServer.WaitRequest().URL.Query()
+	req := testServer.WaitRequest()
+	values := req.URL.Query()
 	c.Assert(values.Get("Version"), check.Equals, "2012-06-01")
 	c.Assert(values.Get("Action"), check.Equals, "CreateLoadBalancer")
 	c.Assert(values.Get("Timestamp"), check.Not(check.Equals), "")
@@ -48,7 +51,7 @@ func (s *S) TestCreateLoadBalancer(c *check.C) {
 	c.Assert(values.Get("Listeners.member.1.InstanceProtocol"), check.Equals, "http")
 	c.Assert(values.Get("Listeners.member.1.Protocol"), check.Equals, "http")
 	c.Assert(values.Get("Listeners.member.1.LoadBalancerPort"), check.Equals, "80")
-	c.Assert(values.Get("Signature"), check.Not(check.Equals), "")
+	c.Assert(req.Header.Get("Authorization"), check.Matches, "AWS4-HMAC-SHA256 .*")
 	c.Assert(resp.DNSName, check.Equals, "testlb-339187009.us-east-1.elb.amazonaws.com")
 }
 
@@ -76,7 +79,8 @@ func (s *S) TestCreateLoadBalancerWithSubnetsAndMoreListeners(c *check.C) {
 	_, err := s.elb.CreateLoadBalancer(createLB)
 	c.Assert(err, check.IsNil)
 	defer s.elb.DeleteLoadBalancer(createLB.Name)
-	values := testServer.WaitRequest().URL.Query()
+	req := testServer.WaitRequest()
+	values := req.URL.Query()
 	c.Assert(values.Get("Listeners.member.1.InstancePort"), check.Equals, "80")
 	c.Assert(values.Get("Listeners.member.1.LoadBalancerPort"), check.Equals, "80")
 	c.Assert(values.Get("Listeners.member.2.InstancePort"), check.Equals, "8080")
@@ -115,9 +119,10 @@ func (s *S) TestDeleteLoadBalancer(c *check.C) {
 	testServer.PrepareResponse(200, nil, DeleteLoadBalancer)
 	resp, err := s.elb.DeleteLoadBalancer("testlb")
 	c.Assert(err, check.IsNil)
-	values := testServer.WaitRequest().URL.Query()
+	req := testServer.WaitRequest()
+	values := req.URL.Query()
 	c.Assert(values.Get("Version"), check.Equals, "2012-06-01")
-	c.Assert(values.Get("Signature"), check.Not(check.Equals), "")
+	c.Assert(req.Header.Get("Authorization"), check.Matches, "AWS4-HMAC-SHA256 .*")
 	c.Assert(values.Get("Timestamp"), check.Not(check.Equals), "")
 	c.Assert(values.Get("Action"), check.Equals, "DeleteLoadBalancer")
 	c.Assert(values.Get("LoadBalancerName"), check.Equals, "testlb")
@@ -128,9 +133,10 @@ func (s *S) TestRegisterInstancesWithLoadBalancer(c *check.C) {
 	testServer.PrepareResponse(200, nil, RegisterInstancesWithLoadBalancer)
 	resp, err := s.elb.RegisterInstancesWithLoadBalancer([]string{"i-b44db8ca", "i-461ecf38"}, "testlb")
 	c.Assert(err, check.IsNil)
-	values := testServer.WaitRequest().URL.Query()
+	req := testServer.WaitRequest()
+	values := req.URL.Query()
 	c.Assert(values.Get("Version"), check.Equals, "2012-06-01")
-	c.Assert(values.Get("Signature"), check.Not(check.Equals), "")
+	c.Assert(req.Header.Get("Authorization"), check.Matches, "AWS4-HMAC-SHA256 .*")
 	c.Assert(values.Get("Timestamp"), check.Not(check.Equals), "")
 	c.Assert(values.Get("Action"), check.Equals, "RegisterInstancesWithLoadBalancer")
 	c.Assert(values.Get("LoadBalancerName"), check.Equals, "testlb")
@@ -154,9 +160,10 @@ func (s *S) TestDeregisterInstancesFromLoadBalancer(c *check.C) {
 	testServer.PrepareResponse(200, nil, DeregisterInstancesFromLoadBalancer)
 	resp, err := s.elb.DeregisterInstancesFromLoadBalancer([]string{"i-b44db8ca", "i-461ecf38"}, "testlb")
 	c.Assert(err, check.IsNil)
-	values := testServer.WaitRequest().URL.Query()
+	req := testServer.WaitRequest()
+	values := req.URL.Query()
 	c.Assert(values.Get("Version"), check.Equals, "2012-06-01")
-	c.Assert(values.Get("Signature"), check.Not(check.Equals), "")
+	c.Assert(req.Header.Get("Authorization"), check.Matches, "AWS4-HMAC-SHA256 .*")
 	c.Assert(values.Get("Timestamp"), check.Not(check.Equals), "")
 	c.Assert(values.Get("Action"), check.Equals, "DeregisterInstancesFromLoadBalancer")
 	c.Assert(values.Get("LoadBalancerName"), check.Equals, "testlb")
@@ -180,9 +187,10 @@ func (s *S) TestDescribeLoadBalancers(c *check.C) {
 	testServer.PrepareResponse(200, nil, DescribeLoadBalancers)
 	resp, err := s.elb.DescribeLoadBalancers()
 	c.Assert(err, check.IsNil)
-	values := testServer.WaitRequest().URL.Query()
+	req := testServer.WaitRequest()
+	values := req.URL.Query()
 	c.Assert(values.Get("Version"), check.Equals, "2012-06-01")
-	c.Assert(values.Get("Signature"), check.Not(check.Equals), "")
+	c.Assert(req.Header.Get("Authorization"), check.Matches, "AWS4-HMAC-SHA256 .*")
 	c.Assert(values.Get("Timestamp"), check.Not(check.Equals), "")
 	c.Assert(values.Get("Action"), check.Equals, "DescribeLoadBalancers")
 	t, _ := time.Parse(time.RFC3339, "2012-12-27T11:51:52.970Z")
@@ -231,9 +239,10 @@ func (s *S) TestDescribeLoadBalancers(c *check.C) {
 func (s *S) TestDescribeLoadBalancersByName(c *check.C) {
 	testServer.PrepareResponse(200, nil, DescribeLoadBalancers)
 	s.elb.DescribeLoadBalancers("somelb")
-	values := testServer.WaitRequest().URL.Query()
+	req := testServer.WaitRequest()
+	values := req.URL.Query()
 	c.Assert(values.Get("Version"), check.Equals, "2012-06-01")
-	c.Assert(values.Get("Signature"), check.Not(check.Equals), "")
+	c.Assert(req.Header.Get("Authorization"), check.Matches, "AWS4-HMAC-SHA256 .*")
 	c.Assert(values.Get("Timestamp"), check.Not(check.Equals), "")
 	c.Assert(values.Get("Action"), check.Equals, "DescribeLoadBalancers")
 	c.Assert(values.Get("LoadBalancerNames.member.1"), check.Equals, "somelb")
@@ -251,9 +260,10 @@ func (s *S) TestDescribeInstanceHealth(c *check.C) {
 	testServer.PrepareResponse(200, nil, DescribeInstanceHealth)
 	resp, err := s.elb.DescribeInstanceHealth("testlb", "i-b44db8ca")
 	c.Assert(err, check.IsNil)
-	values := testServer.WaitRequest().URL.Query()
+	req := testServer.WaitRequest()
+	values := req.URL.Query()
 	c.Assert(values.Get("Version"), check.Equals, "2012-06-01")
-	c.Assert(values.Get("Signature"), check.Not(check.Equals), "")
+	c.Assert(req.Header.Get("Authorization"), check.Matches, "AWS4-HMAC-SHA256 .*")
 	c.Assert(values.Get("Timestamp"), check.Not(check.Equals), "")
 	c.Assert(values.Get("Action"), check.Equals, "DescribeInstanceHealth")
 	c.Assert(values.Get("LoadBalancerName"), check.Equals, "testlb")
@@ -284,9 +294,10 @@ func (s *S) TestConfigureHealthCheck(c *check.C) {
 	}
 	resp, err := s.elb.ConfigureHealthCheck("testlb", &hc)
 	c.Assert(err, check.IsNil)
-	values := testServer.WaitRequest().URL.Query()
+	req := testServer.WaitRequest()
+	values := req.URL.Query()
 	c.Assert(values.Get("Version"), check.Equals, "2012-06-01")
-	c.Assert(values.Get("Signature"), check.Not(check.Equals), "")
+	c.Assert(req.Header.Get("Authorization"), check.Matches, "AWS4-HMAC-SHA256 .*")
 	c.Assert(values.Get("Timestamp"), check.Not(check.Equals), "")
 	c.Assert(values.Get("Action"), check.Equals, "ConfigureHealthCheck")
 	c.Assert(values.Get("LoadBalancerName"), check.Equals, "testlb")
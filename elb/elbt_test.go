@@ -9,9 +9,10 @@ import (
 
 // LocalServer represents a local elbtest fake server.
 type LocalServer struct {
-	auth   aws.Auth
-	region aws.Region
-	srv    *elbtest.Server
+	auth     aws.Auth
+	region   aws.Region
+	srv      *elbtest.Server
+	verifier *aws.Verifier
 }
 
 func (s *LocalServer) SetUp(c *check.C) {
@@ -19,6 +20,7 @@ func (s *LocalServer) SetUp(c *check.C) {
 	c.Assert(err, check.IsNil)
 	c.Assert(srv, check.NotNil)
 	s.srv = srv
+	s.srv.SetVerifier(s.verifier)
 	s.region = aws.Region{ELBEndpoint: srv.URL()}
 }
 
@@ -58,7 +60,15 @@ func (s *AmazonServerSuite) SetUpSuite(c *check.C) {
 	s.ServerTests.elb = elb.New(s.srv.auth, aws.USEast)
 }
 
-var _ = check.Suite(&LocalServerSuite{})
+var strictAuth = aws.Auth{AccessKey: "abc", SecretKey: "123"}
+
+var (
+	_ = check.Suite(&LocalServerSuite{})
+	// and once more checking the signatures of requests.
+	_ = check.Suite(&LocalServerSuite{
+		srv: LocalServer{auth: strictAuth, verifier: aws.NewVerifier(strictAuth)},
+	})
+)
 
 func (s *LocalServerSuite) SetUpSuite(c *check.C) {
 	s.srv.SetUp(c)
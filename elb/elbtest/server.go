@@ -6,6 +6,7 @@ package elbtest
 import (
 	"encoding/xml"
 	"fmt"
+	"github.com/crowdmob/goamz/aws"
 	"github.com/crowdmob/goamz/elb"
 	"net"
 	"net/http"
@@ -27,6 +28,7 @@ type Server struct {
 	instances      []string
 	instanceStates map[string][]*elb.InstanceState
 	instCount      int
+	verifier       *aws.Verifier
 }
 
 // Starts and returns a new server
@@ -52,6 +54,15 @@ func (srv *Server) Quit() {
 	srv.listener.Close()
 }
 
+// SetVerifier makes the server reject requests that are not signed with
+// credentials known to v, as ELB does. If v is nil, any request is
+// accepted, which is the default.
+func (srv *Server) SetVerifier(v *aws.Verifier) {
+	srv.mutex.Lock()
+	srv.verifier = v
+	srv.mutex.Unlock()
+}
+
 // URL returns the URL of the server.
 func (srv *Server) URL() string {
 	return srv.url
@@ -71,9 +82,16 @@ func (srv *Server) error(w http.ResponseWriter, err *elb.Error) {
 }
 
 func (srv *Server) serveHTTP(w http.ResponseWriter, req *http.Request) {
-	req.ParseForm()
 	srv.mutex.Lock()
 	defer srv.mutex.Unlock()
+	if srv.verifier != nil {
+		if err := srv.verifier.Verify(req); err != nil {
+			e := err.(*aws.SignatureError)
+			srv.error(w, &elb.Error{StatusCode: e.StatusCode, Code: e.Code, Message: e.Message})
+			return
+		}
+	}
+	req.ParseForm()
 	f := actions[req.Form.Get("Action")]
 	if f == nil {
 		srv.error(w, &elb.Error{
@@ -2,6 +2,7 @@
 package dp
 
 import (
+	"context"
 	//"fmt"
 	"github.com/crowdmob/goamz/aws"
 	"net/http"
@@ -10,13 +11,35 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"log"
-	"time"
 	//  "github.com/bitly/go-simplejson"
 )
 
 type DP struct {
 	aws.Auth
 	aws.Region
+
+	// HTTPClient is used to send requests to Data Pipeline. If nil,
+	// http.DefaultClient is used.
+	HTTPClient *http.Client
+
+	// RetryPolicy controls how failed requests are retried. If nil,
+	// aws.DefaultRetryPolicy is used.
+	RetryPolicy *aws.RetryPolicy
+
+	// Handlers holds the hooks run while sending requests. If nil,
+	// aws.DefaultHandlers is used.
+	Handlers *aws.Handlers
+
+	// Clock gives the time requests are signed with, corrected when
+	// Data Pipeline reports clock skew. If nil, aws.DefaultClock is used.
+	Clock *aws.Clock
+
+	// Limiter, if set, limits the rate of the requests sent to Data Pipeline,
+	// and slows them down when Data Pipeline throttles them. It may be shared
+	// between clients.
+	Limiter *aws.RateLimiter
+
+	aws.ContextBinding
 }
 
 const (
@@ -24,7 +47,15 @@ const (
 )
 
 func New(auth aws.Auth, region aws.Region) *DP {
-	return &DP{auth, region}
+	return &DP{Auth: auth, Region: region, Clock: &aws.Clock{}}
+}
+
+// WithContext returns a shallow copy of dp bound to ctx; see
+// aws.ContextBinding.
+func (dp *DP) WithContext(ctx context.Context) *DP {
+	c := *dp
+	c.ContextBinding = aws.BindContext(ctx)
+	return &c
 }
 
 type PipelineReq struct {
@@ -218,21 +249,46 @@ type SetTaskStatusReq struct {
 	TaskStatus      string `json:"taskStatus"`
 }
 
-func (dp *DP) queryServer(action string, postData []byte) (int, []byte, error) {
+func (dp *DP) queryServer(action string, postData []byte) (status int, body []byte, err error) {
+	policy := aws.RetryPolicyOrDefault(dp.RetryPolicy, &aws.DefaultRetryPolicy)
+	info := &aws.RequestInfo{Service: "datapipeline", Operation: action, Context: dp.Context(), Clock: dp.Clock, Limiter: dp.Limiter}
+	err = dp.Handlers.Retry(policy, retryable, info, func() error {
+		var err error
+		status, body, err = dp.send(info, action, postData)
+		return err
+	})
+	return status, body, err
+}
+
+// retryable classifies the errors of failed requests for retries.
+func retryable(err error) (retry, throttled bool) {
+	if e, ok := err.(*Error); ok {
+		return aws.ClassifyResponse(e.StatusCode, e.Code)
+	}
+	return aws.IsTransientNetError(err), false
+}
+
+func (dp *DP) send(info *aws.RequestInfo, action string, postData []byte) (int, []byte, error) {
 	hreq, err := http.NewRequest("POST", DataPipelineEndpoint, bytes.NewReader(postData))
 	if err != nil {
 		return 0, nil, err
 	}
 	hreq.Header.Set("Content-Type", "application/x-amz-json-1.1")
-	hreq.Header.Set("X-Amz-Date", time.Now().UTC().Format(aws.ISO8601BasicFormat))
+	hreq.Header.Set("X-Amz-Date", dp.Clock.Now().Format(aws.ISO8601BasicFormat))
 	hreq.Header.Set("X-Amz-Target", "DataPipeline."+action)
-	signer := aws.NewV4Signer(dp.Auth, "datapipeline", dp.Region)
-	signer.Sign(hreq)
+	auth, err := dp.Auth.Snapshot()
+	if err != nil {
+		return 0, nil, err
+	}
+	signer := aws.NewV4Signer(auth, "datapipeline", dp.Region)
 	// dump, err := httputil.DumpRequestOut(hreq, false)
 	// if err == nil {
 	//   fmt.Println("Dump: ", string(dump))
 	// }
-	resp, err := http.DefaultClient.Do(hreq)
+	resp, err := dp.Handlers.Send(dp.HTTPClient, info, hreq, func(hreq *http.Request) error {
+		signer.Sign(hreq)
+		return nil
+	})
 	if err != nil {
 		return 0, nil, err
 	}
@@ -12,17 +12,37 @@ type Error struct {
 	Status     string
 	Code       string // Dynamodb error code ("MalformedQueryString", ...)
 	Message    string // The human-oriented error message
+	RequestId  string // A unique ID for this request
 }
 
 func (e *Error) Error() string {
 	return e.Code + ": " + e.Message
 }
 
+// ErrorCode returns the Data Pipeline error code.
+func (e *Error) ErrorCode() string { return e.Code }
+
+// ErrorMessage returns the message of the error.
+func (e *Error) ErrorMessage() string { return e.Message }
+
+// HTTPStatusCode returns the status code of the HTTP response.
+func (e *Error) HTTPStatusCode() int { return e.StatusCode }
+
+// RequestID returns the ID of the failed request.
+func (e *Error) RequestID() string { return e.RequestId }
+
+// Retryable reports whether sending the request again may succeed.
+func (e *Error) Retryable() bool {
+	retry, _ := retryable(e)
+	return retry
+}
+
 func buildError(r *http.Response, jsonBody []byte) error {
 
 	ddbError := Error{
 		StatusCode: r.StatusCode,
 		Status:     r.Status,
+		RequestId:  r.Header.Get("X-Amzn-RequestId"),
 	}
 	// TODO return error if Unmarshal fails?
 
@@ -14,6 +14,7 @@
 package mturk
 
 import (
+	"context"
 	"encoding/xml"
 	"errors"
 	"fmt"
@@ -23,16 +24,39 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
-	"time"
 )
 
 type MTurk struct {
 	aws.Auth
 	URL *url.URL
+
+	// HTTPClient is used to send requests to Mechanical Turk. If nil,
+	// http.DefaultClient is used.
+	HTTPClient *http.Client
+
+	// RetryPolicy controls how failed requests are retried. If nil,
+	// aws.DefaultRetryPolicy is used.
+	RetryPolicy *aws.RetryPolicy
+
+	// Handlers holds the hooks run while sending requests. If nil,
+	// aws.DefaultHandlers is used.
+	Handlers *aws.Handlers
+
+	// Clock gives the time requests are signed with, corrected when
+	// Mechanical Turk reports clock skew. If nil, aws.DefaultClock is
+	// used.
+	Clock *aws.Clock
+
+	// Limiter, if set, limits the rate of the requests sent to Mechanical Turk,
+	// and slows them down when Mechanical Turk throttles them. It may be shared
+	// between clients.
+	Limiter *aws.RateLimiter
+
+	aws.ContextBinding
 }
 
 func New(auth aws.Auth, sandbox bool) *MTurk {
-	mt := &MTurk{Auth: auth}
+	mt := &MTurk{Auth: auth, Clock: &aws.Clock{}}
 	var err error
 	if sandbox {
 		mt.URL, err = url.Parse("https://mechanicalturk.sandbox.amazonaws.com/")
@@ -45,6 +69,14 @@ func New(auth aws.Auth, sandbox bool) *MTurk {
 	return mt
 }
 
+// WithContext returns a shallow copy of mt bound to ctx; see
+// aws.ContextBinding.
+func (mt *MTurk) WithContext(ctx context.Context) *MTurk {
+	c := *mt
+	c.ContextBinding = aws.BindContext(ctx)
+	return &c
+}
+
 // ----------------------------------------------------------------------------
 // Request dispatching logic.
 
@@ -60,6 +92,24 @@ func (err *Error) Error() string {
 	return err.Message
 }
 
+// ErrorCode returns the Mechanical Turk error code.
+func (err *Error) ErrorCode() string { return err.Code }
+
+// ErrorMessage returns the message of the error.
+func (err *Error) ErrorMessage() string { return err.Message }
+
+// HTTPStatusCode returns the status code of the HTTP response.
+func (err *Error) HTTPStatusCode() int { return err.StatusCode }
+
+// RequestID returns the ID of the failed request.
+func (err *Error) RequestID() string { return err.RequestId }
+
+// Retryable reports whether sending the request again may succeed.
+func (err *Error) Retryable() bool {
+	retry, _ := retryable(err)
+	return retry
+}
+
 // The request stanza included in several response types, for example
 // in a "CreateHITResponse".  http://goo.gl/qGeKf
 type xmlRequest struct {
@@ -433,10 +483,23 @@ func (mt *MTurk) SearchHITs() (s *SearchHITsResult, err error) {
 // to the server.  It then unmarshals the response in to the "resp"
 // parameter using xml.Unmarshal()
 func (mt *MTurk) query(params map[string]string, operation string, resp interface{}) error {
+	policy := aws.RetryPolicyOrDefault(mt.RetryPolicy, &aws.DefaultRetryPolicy)
+	info := &aws.RequestInfo{Service: "mturk", Operation: operation, Context: mt.Context(), Clock: mt.Clock, Limiter: mt.Limiter}
+	return mt.Handlers.Retry(policy, retryable, info, func() error {
+		// Each attempt is signed afresh.
+		return mt.send(info, copyParams(params), operation, resp)
+	})
+}
+
+func (mt *MTurk) send(info *aws.RequestInfo, params map[string]string, operation string, resp interface{}) error {
 	service := "AWSMechanicalTurkRequester"
-	timestamp := time.Now().UTC().Format("2006-01-02T15:04:05Z")
+	timestamp := mt.Clock.Now().Format("2006-01-02T15:04:05Z")
 
-	params["AWSAccessKeyId"] = mt.Auth.AccessKey
+	auth, err := mt.Auth.Snapshot()
+	if err != nil {
+		return err
+	}
+	params["AWSAccessKeyId"] = auth.AccessKey
 	params["Service"] = service
 	params["Timestamp"] = timestamp
 	params["Operation"] = operation
@@ -444,9 +507,16 @@ func (mt *MTurk) query(params map[string]string, operation string, resp interfac
 	// make a copy
 	url := *mt.URL
 
-	sign(mt.Auth, service, operation, timestamp, params)
 	url.RawQuery = multimap(params).Encode()
-	r, err := http.Get(url.String())
+	hreq, err := http.NewRequest("GET", url.String(), nil)
+	if err != nil {
+		return err
+	}
+	r, err := mt.Handlers.Send(mt.HTTPClient, info, hreq, func(hreq *http.Request) error {
+		sign(auth, service, operation, timestamp, params)
+		hreq.URL.RawQuery = multimap(params).Encode()
+		return nil
+	})
 	if err != nil {
 		return err
 	}
@@ -461,6 +531,22 @@ func (mt *MTurk) query(params map[string]string, operation string, resp interfac
 	return err
 }
 
+// retryable classifies the errors of failed Mechanical Turk requests for retries.
+func retryable(err error) (retry, throttled bool) {
+	if e, ok := err.(*Error); ok {
+		return aws.ClassifyResponse(e.StatusCode, e.Code)
+	}
+	return aws.IsTransientNetError(err), false
+}
+
+func copyParams(p map[string]string) map[string]string {
+	c := make(map[string]string, len(p))
+	for k, v := range p {
+		c[k] = v
+	}
+	return c
+}
+
 func multimap(p map[string]string) url.Values {
 	q := make(url.Values, len(p))
 	for k, v := range p {
@@ -21,6 +21,7 @@ package sdb
 //
 
 import (
+	"context"
 	"encoding/xml"
 	"github.com/crowdmob/goamz/aws"
 	"log"
@@ -37,12 +38,53 @@ const debug = false
 type SDB struct {
 	aws.Auth
 	aws.Region
+
+	// HTTPClient is used to send requests to SimpleDB. If nil,
+	// http.DefaultClient is used.
+	HTTPClient *http.Client
+
+	// RetryPolicy controls how failed requests are retried. If nil,
+	// aws.DefaultRetryPolicy is used.
+	RetryPolicy *aws.RetryPolicy
+
+	// Handlers holds the hooks run while sending requests. If nil,
+	// aws.DefaultHandlers is used.
+	Handlers *aws.Handlers
+
+	// Clock gives the time requests are signed with, corrected when
+	// SimpleDB reports clock skew. If nil, aws.DefaultClock is used.
+	Clock *aws.Clock
+
+	// Limiter, if set, limits the rate of the requests sent to SimpleDB,
+	// and slows them down when SimpleDB throttles them. It may be shared
+	// between clients.
+	Limiter *aws.RateLimiter
+
+	aws.ContextBinding
 	private byte // Reserve the right of using private data.
 }
 
 // New creates a new SDB.
 func New(auth aws.Auth, region aws.Region) *SDB {
-	return &SDB{auth, region, 0}
+	return &SDB{Auth: auth, Region: region, Clock: &aws.Clock{}}
+}
+
+// NewFromConfig creates a new SDB with the credentials, endpoints, HTTP
+// client, retry policy and rate limiter of cfg.
+func NewFromConfig(cfg *aws.Config) *SDB {
+	sdb := New(cfg.Auth, cfg.Region)
+	sdb.HTTPClient = cfg.HTTPClient
+	sdb.RetryPolicy = cfg.RetryPolicy(&aws.DefaultRetryPolicy)
+	sdb.Limiter = cfg.Limiter
+	return sdb
+}
+
+// WithContext returns a shallow copy of sdb bound to ctx; see
+// aws.ContextBinding.
+func (sdb *SDB) WithContext(ctx context.Context) *SDB {
+	c := *sdb
+	c.ContextBinding = aws.BindContext(ctx)
+	return &c
 }
 
 // The Domain type represents a collection of items that are described
@@ -57,6 +99,11 @@ func (sdb *SDB) Domain(name string) *Domain {
 	return &Domain{sdb, name}
 }
 
+// WithContext returns a copy of domain whose operations are bound to ctx.
+func (domain *Domain) WithContext(ctx context.Context) *Domain {
+	return &Domain{domain.SDB.WithContext(ctx), domain.Name}
+}
+
 // The Item type represent individual objects that contain one or more
 // name-value attributes stored within a SDB Domain as rows.
 type Item struct {
@@ -70,6 +117,11 @@ func (domain *Domain) Item(name string) *Item {
 	return &Item{domain.SDB, domain, name}
 }
 
+// WithContext returns a copy of item whose operations are bound to ctx.
+func (item *Item) WithContext(ctx context.Context) *Item {
+	return item.Domain.WithContext(ctx).Item(item.Name)
+}
+
 // The Attr type represent categories of data that can be assigned to items.
 type Attr struct {
 	Name  string
@@ -114,6 +166,44 @@ func (sdb *SDB) ListDomainsN(maxDomains int, nextToken string) (resp *ListDomain
 	return
 }
 
+// DomainsIter iterates over the domains listed by ListDomainsN, fetching
+// pages of results as they are needed.
+type DomainsIter struct {
+	sdb   *SDB
+	pager *aws.Pager
+	page  *ListDomainsResp
+}
+
+// DomainsIter returns an iterator over all the domains in sdb.
+func (sdb *SDB) DomainsIter() *DomainsIter {
+	iter := &DomainsIter{sdb: sdb}
+	iter.pager = aws.NewPager(func(token string) (int, string, error) {
+		resp, err := sdb.ListDomainsN(0, token)
+		if err != nil {
+			return 0, "", err
+		}
+		iter.page = resp
+		return len(resp.Domains), resp.NextToken, nil
+	})
+	return iter
+}
+
+// Next advances to the next domain. It returns false when there are none
+// left, or when listing failed.
+func (iter *DomainsIter) Next() bool {
+	return iter.pager.Next()
+}
+
+// Domain returns the current domain.
+func (iter *DomainsIter) Domain() *Domain {
+	return iter.sdb.Domain(iter.page.Domains[iter.pager.Index()])
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (iter *DomainsIter) Err() error {
+	return iter.pager.Err()
+}
+
 // --- SelectExpression
 
 // Response to a Select request.
@@ -300,6 +390,24 @@ func (err *Error) Error() string {
 	return err.Message
 }
 
+// ErrorCode returns the SimpleDB error code.
+func (err *Error) ErrorCode() string { return err.Code }
+
+// ErrorMessage returns the message of the error.
+func (err *Error) ErrorMessage() string { return err.Message }
+
+// HTTPStatusCode returns the status code of the HTTP response.
+func (err *Error) HTTPStatusCode() int { return err.StatusCode }
+
+// RequestID returns the ID of the failed request.
+func (err *Error) RequestID() string { return err.RequestId }
+
+// Retryable reports whether sending the request again may succeed.
+func (err *Error) Retryable() bool {
+	retry, _ := retryable(err)
+	return retry
+}
+
 // SimpleResp represents a response to an SDB request which on success
 // will return no other information besides ResponseMetadata.
 type SimpleResp struct {
@@ -312,6 +420,14 @@ type ResponseMetadata struct {
 	BoxUsage  float64 // The measure of machine utilization for this request.
 }
 
+// retryable classifies the errors of failed SimpleDB requests for retries.
+func retryable(err error) (retry, throttled bool) {
+	if e, ok := err.(*Error); ok {
+		return aws.ClassifyResponse(e.StatusCode, e.Code)
+	}
+	return aws.IsTransientNetError(err), false
+}
+
 func buildError(r *http.Response) error {
 	err := Error{}
 	err.StatusCode = r.StatusCode
@@ -332,6 +448,23 @@ func (domain *Domain) query(item *Item, params url.Values, headers http.Header,
 }
 
 func (sdb *SDB) query(domain *Domain, item *Item, params url.Values, headers http.Header, resp interface{}) error {
+	policy := aws.RetryPolicyOrDefault(sdb.RetryPolicy, &aws.DefaultRetryPolicy)
+	info := &aws.RequestInfo{Service: "sdb", Operation: params.Get("Action"), Context: sdb.Context(), Clock: sdb.Clock, Limiter: sdb.Limiter}
+	return sdb.Handlers.Retry(policy, retryable, info, func() error {
+		// Each attempt is signed afresh.
+		p := make(url.Values, len(params))
+		for k, v := range params {
+			p[k] = v
+		}
+		var h http.Header
+		if headers != nil {
+			h = headers.Clone()
+		}
+		return sdb.send(info, domain, item, p, h, resp)
+	})
+}
+
+func (sdb *SDB) send(info *aws.RequestInfo, domain *Domain, item *Item, params url.Values, headers http.Header, resp interface{}) error {
 	// all SimpleDB operations have path="/"
 	method := "GET"
 	path := "/"
@@ -346,7 +479,7 @@ func (sdb *SDB) query(domain *Domain, item *Item, params url.Values, headers htt
 
 	// setup some default parameters
 	params["Version"] = []string{"2009-04-15"}
-	params["Timestamp"] = []string{time.Now().UTC().Format(time.RFC3339)}
+	params["Timestamp"] = []string{sdb.Clock.Now().Format(time.RFC3339)}
 
 	// set the DomainName param (every request must have one)
 	if domain != nil {
@@ -364,12 +497,12 @@ func (sdb *SDB) query(domain *Domain, item *Item, params url.Values, headers htt
 		return err
 	}
 	headers["Host"] = []string{u.Host}
-	sign(sdb.Auth, method, path, params, headers)
-
-	u.Path = path
-	if len(params) > 0 {
-		u.RawQuery = params.Encode()
+	auth, err := sdb.Auth.Snapshot()
+	if err != nil {
+		return err
 	}
+	u.Path = path
+	u.RawQuery = params.Encode()
 	req := http.Request{
 		URL:        u,
 		Method:     method,
@@ -384,7 +517,11 @@ func (sdb *SDB) query(domain *Domain, item *Item, params url.Values, headers htt
 		delete(headers, "Content-Length")
 	}
 
-	r, err := http.DefaultClient.Do(&req)
+	r, err := sdb.Handlers.Send(sdb.HTTPClient, info, &req, func(req *http.Request) error {
+		sign(auth, method, path, params, req.Header)
+		req.URL.RawQuery = params.Encode()
+		return nil
+	})
 	if err != nil {
 		return err
 	}
@@ -16,6 +16,26 @@ var TestListTopicsXmlOK = `
 </ListTopicsResponse>
 `
 
+var TestListTopicsXmlPage1 = `
+<?xml version="1.0"?>
+<ListTopicsResponse xmlns="http://sns.amazonaws.com/doc/2010-03-31/">
+  <ListTopicsResult>
+    <Topics>
+      <member>
+        <TopicArn>arn:aws:sns:us-east-1:123456789012:First</TopicArn>
+      </member>
+      <member>
+        <TopicArn>arn:aws:sns:us-east-1:123456789012:Second</TopicArn>
+      </member>
+    </Topics>
+    <NextToken>token-2</NextToken>
+  </ListTopicsResult>
+  <ResponseMetadata>
+    <RequestId>bd10b26c-e30e-11e0-ba29-93c3aca2f103</RequestId>
+  </ResponseMetadata>
+</ListTopicsResponse>
+`
+
 var TestCreateTopicXmlOK = `
 <?xml version="1.0"?>
 <CreateTopicResponse xmlns="http://sns.amazonaws.com/doc/2010-03-31/">
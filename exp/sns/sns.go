@@ -25,6 +25,7 @@ package sns
 // BUG(niemeyer): Message.SNS must be dropped.
 
 import (
+	"context"
 	"encoding/xml"
 	"errors"
 	"fmt"
@@ -39,6 +40,33 @@ import (
 type SNS struct {
 	aws.Auth
 	aws.Region
+
+	// HTTPClient is used to send requests to SNS. If nil,
+	// http.DefaultClient is used.
+	HTTPClient *http.Client
+
+	// RetryPolicy controls how failed requests are retried. If nil,
+	// aws.DefaultRetryPolicy is used.
+	RetryPolicy *aws.RetryPolicy
+
+	// Handlers holds the hooks run while sending requests. If nil,
+	// aws.DefaultHandlers is used.
+	Handlers *aws.Handlers
+
+	// Clock gives the time requests are signed with, corrected when
+	// SNS reports clock skew. If nil, aws.DefaultClock is used.
+	Clock *aws.Clock
+
+	// Limiter, if set, limits the rate of the requests sent to SNS,
+	// and slows them down when SNS throttles them. It may be shared
+	// between clients.
+	Limiter *aws.RateLimiter
+
+	// SignV2 makes the client sign requests with Signature Version 2,
+	// for legacy endpoints that do not accept Signature Version 4.
+	SignV2 bool
+
+	aws.ContextBinding
 	private byte // Reserve the right of using private data.
 }
 
@@ -48,7 +76,30 @@ type Topic struct {
 }
 
 func New(auth aws.Auth, region aws.Region) *SNS {
-	return &SNS{auth, region, 0}
+	return &SNS{Auth: auth, Region: region, Clock: &aws.Clock{}}
+}
+
+// NewFromConfig creates a new SNS with the credentials, endpoints, HTTP
+// client, retry policy and rate limiter of cfg.
+func NewFromConfig(cfg *aws.Config) *SNS {
+	sns := New(cfg.Auth, cfg.Region)
+	sns.HTTPClient = cfg.HTTPClient
+	sns.RetryPolicy = cfg.RetryPolicy(&aws.DefaultRetryPolicy)
+	sns.Limiter = cfg.Limiter
+	return sns
+}
+
+// WithContext returns a shallow copy of sns bound to ctx; see
+// aws.ContextBinding.
+func (sns *SNS) WithContext(ctx context.Context) *SNS {
+	c := *sns
+	c.ContextBinding = aws.BindContext(ctx)
+	return &c
+}
+
+// WithContext returns a copy of topic whose operations are bound to ctx.
+func (topic *Topic) WithContext(ctx context.Context) *Topic {
+	return &Topic{topic.SNS.WithContext(ctx), topic.TopicArn}
 }
 
 type Message struct {
@@ -77,7 +128,7 @@ type ResponseMetadata struct {
 
 type ListTopicsResp struct {
 	Topics    []Topic `xml:"ListTopicsResult>Topics>member"`
-	NextToken string
+	NextToken string  `xml:"ListTopicsResult>NextToken"`
 	ResponseMetadata
 }
 
@@ -92,7 +143,7 @@ type DeleteTopicResp struct {
 
 type ListSubscriptionsResp struct {
 	Subscriptions []Subscription `xml:"ListSubscriptionsResult>Subscriptions>member"`
-	NextToken     string
+	NextToken     string         `xml:"ListSubscriptionsResult>NextToken"`
 	ResponseMetadata
 }
 
@@ -125,6 +176,58 @@ func (sns *SNS) ListTopics(NextToken *string) (resp *ListTopicsResp, err error)
 	return
 }
 
+// TopicsIter iterates over the topics listed by ListTopics, fetching
+// pages of results as they are needed.
+type TopicsIter struct {
+	sns   *SNS
+	pager *aws.Pager
+	page  *ListTopicsResp
+}
+
+// TopicsIter returns an iterator over all the topics of the account:
+//
+//	iter := sns.TopicsIter()
+//	for iter.Next() {
+//		fmt.Println(iter.Topic().TopicArn)
+//	}
+//	if err := iter.Err(); err != nil {
+//		...
+//	}
+func (sns *SNS) TopicsIter() *TopicsIter {
+	iter := &TopicsIter{sns: sns}
+	iter.pager = aws.NewPager(func(token string) (int, string, error) {
+		var next *string
+		if token != "" {
+			next = &token
+		}
+		resp, err := sns.ListTopics(next)
+		if err != nil {
+			return 0, "", err
+		}
+		iter.page = resp
+		return len(resp.Topics), resp.NextToken, nil
+	})
+	return iter
+}
+
+// Next advances to the next topic. It returns false when there are none
+// left, or when listing failed.
+func (iter *TopicsIter) Next() bool {
+	return iter.pager.Next()
+}
+
+// Topic returns the current topic.
+func (iter *TopicsIter) Topic() *Topic {
+	topic := iter.page.Topics[iter.pager.Index()]
+	topic.SNS = iter.sns
+	return &topic
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (iter *TopicsIter) Err() error {
+	return iter.pager.Err()
+}
+
 // CreateTopic
 //
 // See http://goo.gl/m9aAt for more details.
@@ -167,6 +270,48 @@ func (sns *SNS) ListSubscriptions(NextToken *string) (resp *ListSubscriptionsRes
 	return
 }
 
+// SubscriptionsIter iterates over the subscriptions listed by
+// ListSubscriptions, fetching pages of results as they are needed.
+type SubscriptionsIter struct {
+	pager *aws.Pager
+	page  *ListSubscriptionsResp
+}
+
+// SubscriptionsIter returns an iterator over all the subscriptions of the
+// account.
+func (sns *SNS) SubscriptionsIter() *SubscriptionsIter {
+	iter := &SubscriptionsIter{}
+	iter.pager = aws.NewPager(func(token string) (int, string, error) {
+		var next *string
+		if token != "" {
+			next = &token
+		}
+		resp, err := sns.ListSubscriptions(next)
+		if err != nil {
+			return 0, "", err
+		}
+		iter.page = resp
+		return len(resp.Subscriptions), resp.NextToken, nil
+	})
+	return iter
+}
+
+// Next advances to the next subscription. It returns false when there
+// are none left, or when listing failed.
+func (iter *SubscriptionsIter) Next() bool {
+	return iter.pager.Next()
+}
+
+// Subscription returns the current subscription.
+func (iter *SubscriptionsIter) Subscription() Subscription {
+	return iter.page.Subscriptions[iter.pager.Index()]
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (iter *SubscriptionsIter) Err() error {
+	return iter.pager.Err()
+}
+
 // GetTopicAttributes
 //
 // See http://goo.gl/WXRoX for more details.
@@ -221,7 +366,9 @@ type SetTopicAttributesResponse struct {
 	ResponseMetadata
 }
 
-// SetTopicAttributes
+// SetTopicAttributes sets an attribute of a topic. The "Policy"
+// attribute may be built with the policy package, as the String of a
+// policy.Document.
 //
 // See http://goo.gl/oVYW7 for more details.
 func (sns *SNS) SetTopicAttributes(AttributeName, AttributeValue, TopicArn string) (resp *SetTopicAttributesResponse, err error) {
@@ -645,21 +792,65 @@ func (err *Error) Error() string {
 	return err.Message
 }
 
+// ErrorCode returns the SNS error code.
+func (err *Error) ErrorCode() string { return err.Code }
+
+// ErrorMessage returns the message of the error.
+func (err *Error) ErrorMessage() string { return err.Message }
+
+// HTTPStatusCode returns the status code of the HTTP response.
+func (err *Error) HTTPStatusCode() int { return err.StatusCode }
+
+// RequestID returns the ID of the failed request.
+func (err *Error) RequestID() string { return err.RequestId }
+
+// Retryable reports whether sending the request again may succeed.
+func (err *Error) Retryable() bool {
+	retry, _ := retryable(err)
+	return retry
+}
+
 type xmlErrors struct {
 	RequestId string
 	Errors    []Error `xml:"Errors>Error"`
 }
 
 func (sns *SNS) query(topic *Topic, message *Message, params map[string]string, resp interface{}) error {
-	params["Timestamp"] = time.Now().UTC().Format(time.RFC3339)
+	policy := aws.RetryPolicyOrDefault(sns.RetryPolicy, &aws.DefaultRetryPolicy)
+	info := &aws.RequestInfo{Service: "sns", Operation: params["Action"], Context: sns.Context(), Clock: sns.Clock, Limiter: sns.Limiter}
+	return sns.Handlers.Retry(policy, retryable, info, func() error {
+		// Each attempt is signed afresh.
+		return sns.send(info, topic, message, copyParams(params), resp)
+	})
+}
+
+func (sns *SNS) send(info *aws.RequestInfo, topic *Topic, message *Message, params map[string]string, resp interface{}) error {
+	params["Timestamp"] = sns.Clock.Now().Format(time.RFC3339)
 	u, err := url.Parse(sns.Region.SNSEndpoint)
 	if err != nil {
 		return err
 	}
 
-	sign(sns.Auth, "GET", "/", params, u.Host)
+	auth, err := sns.Auth.Snapshot()
+	if err != nil {
+		return err
+	}
 	u.RawQuery = multimap(params).Encode()
-	r, err := http.Get(u.String())
+	hreq, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return err
+	}
+	r, err := sns.Handlers.Send(sns.HTTPClient, info, hreq, func(hreq *http.Request) error {
+		if !sns.SignV2 {
+			signer := aws.NewV4Signer(auth, "sns", sns.Region)
+			signer.Clock = sns.Clock
+			signer.Sign(hreq)
+			return nil
+		}
+		sign(auth, "GET", "/", params, u.Host)
+		hreq.URL.RawQuery = multimap(params).Encode()
+		return nil
+	})
 	if err != nil {
 		return err
 	}
@@ -691,6 +882,22 @@ func buildError(r *http.Response) error {
 	return &err
 }
 
+// retryable classifies the errors of failed SNS requests for retries.
+func retryable(err error) (retry, throttled bool) {
+	if e, ok := err.(*Error); ok {
+		return aws.ClassifyResponse(e.StatusCode, e.Code)
+	}
+	return aws.IsTransientNetError(err), false
+}
+
+func copyParams(p map[string]string) map[string]string {
+	c := make(map[string]string, len(p))
+	for k, v := range p {
+		c[k] = v
+	}
+	return c
+}
+
 func multimap(p map[string]string) url.Values {
 	q := make(url.Values, len(p))
 	for k, v := range p {
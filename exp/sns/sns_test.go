@@ -44,6 +44,31 @@ func (s *S) TestListTopicsOK(c *check.C) {
 	c.Assert(err, check.IsNil)
 }
 
+func (s *S) TestTopicsIter(c *check.C) {
+	testServer.Response(200, nil, TestListTopicsXmlPage1)
+	testServer.Response(200, nil, TestListTopicsXmlOK)
+
+	iter := s.sns.TopicsIter()
+	var arns []string
+	for iter.Next() {
+		topic := iter.Topic()
+		c.Assert(topic.SNS, check.Equals, s.sns)
+		arns = append(arns, topic.TopicArn)
+	}
+	c.Assert(iter.Err(), check.IsNil)
+	c.Assert(arns, check.DeepEquals, []string{
+		"arn:aws:sns:us-east-1:123456789012:First",
+		"arn:aws:sns:us-east-1:123456789012:Second",
+		"arn:aws:sns:us-west-1:331995417492:Transcoding",
+	})
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["NextToken"], check.IsNil)
+	req = testServer.WaitRequest()
+	c.Assert(req.Form["Action"], check.DeepEquals, []string{"ListTopics"})
+	c.Assert(req.Form["NextToken"], check.DeepEquals, []string{"token-2"})
+}
+
 func (s *S) TestCreateTopic(c *check.C) {
 	testServer.Response(200, nil, TestCreateTopicXmlOK)
 
@@ -3,8 +3,11 @@
 package iam
 
 import (
+	"context"
 	"encoding/xml"
 	"github.com/crowdmob/goamz/aws"
+	"github.com/crowdmob/goamz/policy"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -16,23 +19,94 @@ import (
 type IAM struct {
 	aws.Auth
 	aws.Region
+
+	// HTTPClient is used to send requests to IAM. If nil,
+	// http.DefaultClient is used.
+	HTTPClient *http.Client
+
+	// RetryPolicy controls how failed requests are retried. If nil,
+	// aws.DefaultRetryPolicy is used.
+	RetryPolicy *aws.RetryPolicy
+
+	// Handlers holds the hooks run while sending requests. If nil,
+	// aws.DefaultHandlers is used.
+	Handlers *aws.Handlers
+
+	// Clock gives the time requests are signed with, corrected when
+	// IAM reports clock skew. If nil, aws.DefaultClock is used.
+	Clock *aws.Clock
+
+	// Limiter, if set, limits the rate of the requests sent to IAM,
+	// and slows them down when IAM throttles them. It may be shared
+	// between clients.
+	Limiter *aws.RateLimiter
+
+	// SignV2 makes the client sign requests with Signature Version 2,
+	// for legacy endpoints that do not accept Signature Version 4.
+	SignV2 bool
+
+	aws.ContextBinding
 }
 
 // New creates a new IAM instance.
 func New(auth aws.Auth, region aws.Region) *IAM {
-	return &IAM{auth, region}
+	return &IAM{Auth: auth, Region: region, Clock: &aws.Clock{}}
+}
+
+// NewFromConfig creates a new IAM with the credentials, endpoints, HTTP
+// client, retry policy and rate limiter of cfg.
+func NewFromConfig(cfg *aws.Config) *IAM {
+	iam := New(cfg.Auth, cfg.Region)
+	iam.HTTPClient = cfg.HTTPClient
+	iam.RetryPolicy = cfg.RetryPolicy(&aws.DefaultRetryPolicy)
+	iam.Limiter = cfg.Limiter
+	return iam
+}
+
+// WithContext returns a shallow copy of iam bound to ctx; see
+// aws.ContextBinding.
+func (iam *IAM) WithContext(ctx context.Context) *IAM {
+	c := *iam
+	c.ContextBinding = aws.BindContext(ctx)
+	return &c
 }
 
 func (iam *IAM) query(params map[string]string, resp interface{}) error {
+	policy := aws.RetryPolicyOrDefault(iam.RetryPolicy, &aws.DefaultRetryPolicy)
+	info := &aws.RequestInfo{Service: "iam", Operation: params["Action"], Context: iam.Context(), Clock: iam.Clock, Limiter: iam.Limiter}
+	return iam.Handlers.Retry(policy, retryable, info, func() error {
+		// Each attempt is signed afresh.
+		return iam.send(info, copyParams(params), resp)
+	})
+}
+
+func (iam *IAM) send(info *aws.RequestInfo, params map[string]string, resp interface{}) error {
 	params["Version"] = "2010-05-08"
-	params["Timestamp"] = time.Now().In(time.UTC).Format(time.RFC3339)
+	params["Timestamp"] = iam.Clock.Now().Format(time.RFC3339)
 	endpoint, err := url.Parse(iam.IAMEndpoint)
 	if err != nil {
 		return err
 	}
-	sign(iam.Auth, "GET", "/", params, endpoint.Host)
+	auth, err := iam.Auth.Snapshot()
+	if err != nil {
+		return err
+	}
 	endpoint.RawQuery = multimap(params).Encode()
-	r, err := http.Get(endpoint.String())
+	hreq, err := http.NewRequest("GET", endpoint.String(), nil)
+	if err != nil {
+		return err
+	}
+	r, err := iam.Handlers.Send(iam.HTTPClient, info, hreq, func(hreq *http.Request) error {
+		if !iam.SignV2 {
+			signer := aws.NewV4Signer(auth, "iam", iam.signingRegion())
+			signer.Clock = iam.Clock
+			signer.Sign(hreq)
+			return nil
+		}
+		sign(auth, "GET", "/", params, endpoint.Host)
+		hreq.URL.RawQuery = multimap(params).Encode()
+		return nil
+	})
 	if err != nil {
 		return err
 	}
@@ -44,13 +118,24 @@ func (iam *IAM) query(params map[string]string, resp interface{}) error {
 }
 
 func (iam *IAM) postQuery(params map[string]string, resp interface{}) error {
+	policy := aws.RetryPolicyOrDefault(iam.RetryPolicy, &aws.DefaultRetryPolicy)
+	info := &aws.RequestInfo{Service: "iam", Operation: params["Action"], Context: iam.Context(), Clock: iam.Clock, Limiter: iam.Limiter}
+	return iam.Handlers.Retry(policy, retryable, info, func() error {
+		return iam.sendPost(info, copyParams(params), resp)
+	})
+}
+
+func (iam *IAM) sendPost(info *aws.RequestInfo, params map[string]string, resp interface{}) error {
 	endpoint, err := url.Parse(iam.IAMEndpoint)
 	if err != nil {
 		return err
 	}
 	params["Version"] = "2010-05-08"
-	params["Timestamp"] = time.Now().In(time.UTC).Format(time.RFC3339)
-	sign(iam.Auth, "POST", "/", params, endpoint.Host)
+	params["Timestamp"] = iam.Clock.Now().Format(time.RFC3339)
+	auth, err := iam.Auth.Snapshot()
+	if err != nil {
+		return err
+	}
 	encoded := multimap(params).Encode()
 	body := strings.NewReader(encoded)
 	req, err := http.NewRequest("POST", endpoint.String(), body)
@@ -60,7 +145,21 @@ func (iam *IAM) postQuery(params map[string]string, resp interface{}) error {
 	req.Header.Set("Host", endpoint.Host)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	req.Header.Set("Content-Length", strconv.Itoa(len(encoded)))
-	r, err := http.DefaultClient.Do(req)
+	r, err := iam.Handlers.Send(iam.HTTPClient, info, req, func(req *http.Request) error {
+		if !iam.SignV2 {
+			signer := aws.NewV4Signer(auth, "iam", iam.signingRegion())
+			signer.Clock = iam.Clock
+			signer.Sign(req)
+			return nil
+		}
+		// Version 2 signatures go in the body.
+		sign(auth, "POST", "/", params, endpoint.Host)
+		encoded := multimap(params).Encode()
+		req.Body = ioutil.NopCloser(strings.NewReader(encoded))
+		req.ContentLength = int64(len(encoded))
+		req.Header.Set("Content-Length", strconv.Itoa(len(encoded)))
+		return nil
+	})
 	if err != nil {
 		return err
 	}
@@ -71,6 +170,15 @@ func (iam *IAM) postQuery(params map[string]string, resp interface{}) error {
 	return xml.NewDecoder(r.Body).Decode(resp)
 }
 
+// signingRegion returns the region requests are signed for. IAM has a
+// single endpoint per partition, signed for the region hosting it.
+func (iam *IAM) signingRegion() aws.Region {
+	if iam.Region.Name == "" {
+		return aws.USEast
+	}
+	return aws.Region{Name: aws.SigningRegion("iam", iam.Region.Name)}
+}
+
 func buildError(r *http.Response) error {
 	var (
 		err    Error
@@ -80,6 +188,7 @@ func buildError(r *http.Response) error {
 	if len(errors.Errors) > 0 {
 		err = errors.Errors[0]
 	}
+	err.RequestId = errors.RequestId
 	err.StatusCode = r.StatusCode
 	if err.Message == "" {
 		err.Message = r.Status
@@ -87,6 +196,22 @@ func buildError(r *http.Response) error {
 	return &err
 }
 
+// retryable classifies the errors of failed IAM requests for retries.
+func retryable(err error) (retry, throttled bool) {
+	if e, ok := err.(*Error); ok {
+		return aws.ClassifyResponse(e.StatusCode, e.Code)
+	}
+	return aws.IsTransientNetError(err), false
+}
+
+func copyParams(p map[string]string) map[string]string {
+	c := make(map[string]string, len(p))
+	for k, v := range p {
+		c[k] = v
+	}
+	return c
+}
+
 func multimap(p map[string]string) url.Values {
 	q := make(url.Values, len(p))
 	for k, v := range p {
@@ -212,8 +337,10 @@ func (iam *IAM) CreateGroup(name string, path string) (*CreateGroupResp, error)
 //
 // See http://goo.gl/W2TRj for more details.
 type GroupsResp struct {
-	Groups    []Group `xml:"ListGroupsResult>Groups>member"`
-	RequestId string  `xml:"ResponseMetadata>RequestId"`
+	Groups      []Group `xml:"ListGroupsResult>Groups>member"`
+	IsTruncated bool    `xml:"ListGroupsResult>IsTruncated"`
+	Marker      string  `xml:"ListGroupsResult>Marker"`
+	RequestId   string  `xml:"ResponseMetadata>RequestId"`
 }
 
 // Groups list the groups that have the specified path prefix.
@@ -223,12 +350,19 @@ type GroupsResp struct {
 //
 // See http://goo.gl/W2TRj for more details.
 func (iam *IAM) Groups(pathPrefix string) (*GroupsResp, error) {
+	return iam.groups(pathPrefix, "")
+}
+
+func (iam *IAM) groups(pathPrefix, marker string) (*GroupsResp, error) {
 	params := map[string]string{
 		"Action": "ListGroups",
 	}
 	if pathPrefix != "" {
 		params["PathPrefix"] = pathPrefix
 	}
+	if marker != "" {
+		params["Marker"] = marker
+	}
 	resp := new(GroupsResp)
 	if err := iam.query(params, resp); err != nil {
 		return nil, err
@@ -236,6 +370,48 @@ func (iam *IAM) Groups(pathPrefix string) (*GroupsResp, error) {
 	return resp, nil
 }
 
+// GroupsIter iterates over the groups listed by Groups, fetching pages of
+// results as they are needed.
+type GroupsIter struct {
+	pager *aws.Pager
+	page  *GroupsResp
+}
+
+// GroupsIter returns an iterator over all the groups that have the
+// specified path prefix. Groups only returns the first page of them.
+func (iam *IAM) GroupsIter(pathPrefix string) *GroupsIter {
+	iter := &GroupsIter{}
+	iter.pager = aws.NewPager(func(marker string) (int, string, error) {
+		resp, err := iam.groups(pathPrefix, marker)
+		if err != nil {
+			return 0, "", err
+		}
+		iter.page = resp
+		next := ""
+		if resp.IsTruncated {
+			next = resp.Marker
+		}
+		return len(resp.Groups), next, nil
+	})
+	return iter
+}
+
+// Next advances to the next group. It returns false when there are none
+// left, or when listing failed.
+func (iter *GroupsIter) Next() bool {
+	return iter.pager.Next()
+}
+
+// Group returns the current group.
+func (iter *GroupsIter) Group() Group {
+	return iter.page.Groups[iter.pager.Index()]
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (iter *GroupsIter) Err() error {
+	return iter.pager.Err()
+}
+
 // DeleteGroup deletes a group from IAM.
 //
 // See http://goo.gl/d5i2i for more details.
@@ -350,6 +526,11 @@ type UserPolicy struct {
 	Document string `xml:"PolicyDocument"`
 }
 
+// Policy parses the document of p, which IAM returns URL encoded.
+func (p *UserPolicy) Policy() (*policy.Document, error) {
+	return policy.Parse(p.Document)
+}
+
 // GetUserPolicy gets a user policy in IAM.
 //
 // See http://goo.gl/BH04O for more details.
@@ -367,7 +548,8 @@ func (iam *IAM) GetUserPolicy(userName, policyName string) (*GetUserPolicyResp,
 	return nil, nil
 }
 
-// PutUserPolicy creates a user policy in IAM.
+// PutUserPolicy creates a user policy in IAM. The policy document may
+// be built with the policy package, as the String of a policy.Document.
 //
 // See http://goo.gl/ldCO8 for more details.
 func (iam *IAM) PutUserPolicy(userName, policyName, policyDocument string) (*SimpleResp, error) {
@@ -405,7 +587,8 @@ type SimpleResp struct {
 }
 
 type xmlErrors struct {
-	Errors []Error `xml:"Error"`
+	Errors    []Error `xml:"Error"`
+	RequestId string
 }
 
 // Error encapsulates an IAM error.
@@ -418,6 +601,9 @@ type Error struct {
 
 	// Message explaining the error.
 	Message string
+
+	// ID of the failed request.
+	RequestId string `xml:"-"`
 }
 
 func (e *Error) Error() string {
@@ -430,3 +616,21 @@ func (e *Error) Error() string {
 	}
 	return prefix + e.Message
 }
+
+// ErrorCode returns the IAM error code.
+func (e *Error) ErrorCode() string { return e.Code }
+
+// ErrorMessage returns the message of the error.
+func (e *Error) ErrorMessage() string { return e.Message }
+
+// HTTPStatusCode returns the status code of the HTTP response.
+func (e *Error) HTTPStatusCode() int { return e.StatusCode }
+
+// RequestID returns the ID of the failed request.
+func (e *Error) RequestID() string { return e.RequestId }
+
+// Retryable reports whether sending the request again may succeed.
+func (e *Error) Retryable() bool {
+	retry, _ := retryable(e)
+	return retry
+}
@@ -59,6 +59,9 @@ func (s *S) TestCreateUserConflict(c *check.C) {
 	c.Assert(ok, check.Equals, true)
 	c.Assert(e.Message, check.Equals, "User with name Bob already exists.")
 	c.Assert(e.Code, check.Equals, "EntityAlreadyExists")
+	c.Assert(e.RequestId, check.Equals, "1d5f5000-1316-11e2-a60f-91a8e6fb6d21")
+	c.Assert(aws.IsCode(err, "EntityAlreadyExists"), check.Equals, true)
+	c.Assert(aws.IsRetryable(err), check.Equals, false)
 }
 
 func (s *S) TestGetUser(c *check.C) {
@@ -153,6 +156,24 @@ func (s *S) TestListGroups(c *check.C) {
 	c.Assert(resp.Groups, check.DeepEquals, expected)
 }
 
+func (s *S) TestGroupsIter(c *check.C) {
+	testServer.Response(200, nil, ListGroupsTruncatedExample)
+	testServer.Response(200, nil, ListGroupsExample)
+	iter := s.iam.GroupsIter("/division_abc/")
+	var names []string
+	for iter.Next() {
+		names = append(names, iter.Group().Name)
+	}
+	c.Assert(iter.Err(), check.IsNil)
+	c.Assert(names, check.DeepEquals, []string{"Auditors", "Admins", "Test", "Managers"})
+
+	values := testServer.WaitRequest().URL.Query()
+	c.Assert(values.Get("Marker"), check.Equals, "")
+	values = testServer.WaitRequest().URL.Query()
+	c.Assert(values.Get("PathPrefix"), check.Equals, "/division_abc/")
+	c.Assert(values.Get("Marker"), check.Equals, "marker-2")
+}
+
 func (s *S) TestListGroupsWithoutPathPrefix(c *check.C) {
 	testServer.Response(200, nil, ListGroupsExample)
 	_, err := s.iam.Groups("")
@@ -4,14 +4,16 @@ import (
 	"github.com/crowdmob/goamz/aws"
 	"github.com/crowdmob/goamz/iam"
 	"github.com/crowdmob/goamz/iam/iamtest"
+	"github.com/crowdmob/goamz/policy"
 	"gopkg.in/check.v1"
 )
 
 // LocalServer represents a local ec2test fake server.
 type LocalServer struct {
-	auth   aws.Auth
-	region aws.Region
-	srv    *iamtest.Server
+	auth     aws.Auth
+	region   aws.Region
+	srv      *iamtest.Server
+	verifier *aws.Verifier
 }
 
 func (s *LocalServer) SetUp(c *check.C) {
@@ -20,6 +22,7 @@ func (s *LocalServer) SetUp(c *check.C) {
 	c.Assert(srv, check.NotNil)
 
 	s.srv = srv
+	s.srv.SetVerifier(s.verifier)
 	s.region = aws.Region{IAMEndpoint: srv.URL()}
 }
 
@@ -31,9 +34,49 @@ type LocalServerSuite struct {
 	ClientTests
 }
 
-var _ = check.Suite(&LocalServerSuite{})
+var strictAuth = aws.Auth{AccessKey: "abc", SecretKey: "123"}
+
+var (
+	_ = check.Suite(&LocalServerSuite{})
+	// and once more checking the signatures of requests.
+	_ = check.Suite(&LocalServerSuite{
+		srv: LocalServer{auth: strictAuth, verifier: aws.NewVerifier(strictAuth)},
+	})
+)
 
 func (s *LocalServerSuite) SetUpSuite(c *check.C) {
 	s.srv.SetUp(c)
 	s.ClientTests.iam = iam.New(s.srv.auth, s.srv.region)
 }
+
+func (s *LocalServerSuite) TestUserPolicyAuthorization(c *check.C) {
+	if s.srv.verifier == nil {
+		c.Skip("policies are only enforced with a verifier")
+	}
+	_, err := s.iam.CreateUser("alice", "/")
+	c.Assert(err, check.IsNil)
+	defer s.iam.DeleteUser("alice")
+	keyResp, err := s.iam.CreateAccessKey("alice")
+	c.Assert(err, check.IsNil)
+	key := keyResp.AccessKey
+	alice := iam.New(aws.Auth{AccessKey: key.Id, SecretKey: key.Secret}, s.srv.region)
+
+	_, err = alice.GetUser("alice")
+	c.Assert(err, check.ErrorMatches, `AccessDenied: User: arn:aws:iam::123456789012:user/alice is not authorized to perform: iam:GetUser on resource: arn:aws:iam::123456789012:user/alice`)
+
+	doc := policy.New(policy.AllowStatement("iam:Get*").On("arn:aws:iam::123456789012:user/${aws:username}"))
+	_, err = s.iam.PutUserPolicy("alice", "self", doc.String())
+	c.Assert(err, check.IsNil)
+	defer s.iam.DeleteUserPolicy("alice", "self")
+	_, err = alice.GetUser("alice")
+	c.Assert(err, check.IsNil)
+	_, err = alice.GetUser("bob")
+	c.Assert(err, check.ErrorMatches, `AccessDenied: .* on resource: arn:aws:iam::123456789012:user/bob`)
+	_, err = alice.CreateUser("bob", "/")
+	c.Assert(err, check.ErrorMatches, `AccessDenied: .*`)
+
+	_, err = s.iam.DeleteAccessKey(key.Id, "alice")
+	c.Assert(err, check.IsNil)
+	_, err = alice.GetUser("alice")
+	c.Assert(err, check.ErrorMatches, `InvalidClientTokenId: .*`)
+}
@@ -4,10 +4,12 @@
 package iamtest
 
 import (
-	"encoding/json"
 	"encoding/xml"
 	"fmt"
+	"github.com/crowdmob/goamz/arn"
+	"github.com/crowdmob/goamz/aws"
 	"github.com/crowdmob/goamz/iam"
+	"github.com/crowdmob/goamz/policy"
 	"net"
 	"net/http"
 	"strings"
@@ -21,6 +23,9 @@ type action struct {
 	reqId string
 }
 
+// accountID is the ID of the account of the server.
+const accountID = "123456789012"
+
 // Server implements an IAM simulator for use in tests.
 type Server struct {
 	reqId        int
@@ -30,6 +35,7 @@ type Server struct {
 	groups       []iam.Group
 	accessKeys   []iam.AccessKey
 	userPolicies []iam.UserPolicy
+	verifier     *aws.Verifier
 	mutex        sync.Mutex
 }
 
@@ -53,6 +59,20 @@ func (srv *Server) Quit() error {
 	return srv.listener.Close()
 }
 
+// SetVerifier makes the server reject requests that are not signed with
+// credentials known to v, as IAM does. If v is nil, any request is
+// accepted, which is the default.
+//
+// With a verifier, the access keys created by the server are added to
+// it, and the requests signed with them are authorized by the policies
+// of their user. Requests signed with other keys are made by the account
+// root, and always authorized.
+func (srv *Server) SetVerifier(v *aws.Verifier) {
+	srv.mutex.Lock()
+	srv.verifier = v
+	srv.mutex.Unlock()
+}
+
 // URL returns a URL for the server.
 func (srv *Server) URL() string {
 	return srv.url
@@ -72,9 +92,16 @@ func (srv *Server) error(w http.ResponseWriter, err *iam.Error) {
 }
 
 func (srv *Server) serveHTTP(w http.ResponseWriter, req *http.Request) {
-	req.ParseForm()
 	srv.mutex.Lock()
 	defer srv.mutex.Unlock()
+	if srv.verifier != nil {
+		if err := srv.verifier.Verify(req); err != nil {
+			e := err.(*aws.SignatureError)
+			srv.error(w, &iam.Error{StatusCode: e.StatusCode, Code: e.Code, Message: e.Message})
+			return
+		}
+	}
+	req.ParseForm()
 	action := req.FormValue("Action")
 	if action == "" {
 		srv.error(w, &iam.Error{
@@ -84,6 +111,10 @@ func (srv *Server) serveHTTP(w http.ResponseWriter, req *http.Request) {
 		})
 	}
 	if a, ok := actions[action]; ok {
+		if err := srv.authorize(req, action); err != nil {
+			srv.error(w, err)
+			return
+		}
 		reqId := fmt.Sprintf("req%0X", srv.reqId)
 		srv.reqId++
 		if resp, err := a(srv, w, req, reqId); err == nil {
@@ -127,7 +158,7 @@ func (srv *Server) createUser(w http.ResponseWriter, req *http.Request, reqId st
 	}
 	user := iam.User{
 		Id:   "USER" + reqId + "EXAMPLE",
-		Arn:  fmt.Sprintf("arn:aws:iam:::123456789012:user%s%s", path, name),
+		Arn:  arn.New("iam", "", accountID, "user"+path+name).String(),
 		Name: name,
 		Path: path,
 	}
@@ -172,13 +203,17 @@ func (srv *Server) createAccessKey(w http.ResponseWriter, req *http.Request, req
 	if _, err := srv.findUser(userName); err != nil {
 		return nil, err
 	}
+	id := fmt.Sprintf("%s%d", userName, len(srv.accessKeys))
 	key := iam.AccessKey{
-		Id:       fmt.Sprintf("%s%d", userName, len(srv.accessKeys)),
-		Secret:   "",
+		Id:       id,
+		Secret:   id + "SECRET",
 		UserName: userName,
 		Status:   "Active",
 	}
 	srv.accessKeys = append(srv.accessKeys, key)
+	if srv.verifier != nil {
+		srv.verifier.Add(aws.Auth{AccessKey: key.Id, SecretKey: key.Secret})
+	}
 	return iam.CreateAccessKeyResp{RequestId: reqId, AccessKey: key}, nil
 }
 
@@ -203,6 +238,9 @@ func (srv *Server) deleteAccessKey(w http.ResponseWriter, req *http.Request, req
 	}
 	copy(srv.accessKeys[index:], srv.accessKeys[index+1:])
 	srv.accessKeys = srv.accessKeys[:len(srv.accessKeys)-1]
+	if srv.verifier != nil {
+		srv.verifier.Remove(key)
+	}
 	return iam.SimpleResp{RequestId: reqId}, nil
 }
 
@@ -217,6 +255,8 @@ func (srv *Server) listAccessKeys(w http.ResponseWriter, req *http.Request, reqI
 	var keys []iam.AccessKey
 	for _, k := range srv.accessKeys {
 		if k.UserName == userName {
+			// Secrets are only returned when keys are created.
+			k.Secret = ""
 			keys = append(keys, k)
 		}
 	}
@@ -243,7 +283,7 @@ func (srv *Server) createGroup(w http.ResponseWriter, req *http.Request, reqId s
 	}
 	group := iam.Group{
 		Id:   "GROUP " + reqId + "EXAMPLE",
-		Arn:  fmt.Sprintf("arn:aws:iam:::123456789012:group%s%s", path, name),
+		Arn:  arn.New("iam", "", accountID, "group"+path+name).String(),
 		Name: name,
 		Path: path,
 	}
@@ -312,20 +352,19 @@ func (srv *Server) putUserPolicy(w http.ResponseWriter, req *http.Request, reqId
 		}
 	}
 	if !exists {
-		policy := iam.UserPolicy{
-			Name:     policyName,
-			UserName: userName,
-			Document: req.FormValue("PolicyDocument"),
-		}
-		var dumb interface{}
-		if err := json.Unmarshal([]byte(policy.Document), &dumb); err != nil {
+		document := req.FormValue("PolicyDocument")
+		if _, err := policy.Parse(document); err != nil {
 			return nil, &iam.Error{
 				StatusCode: 400,
 				Code:       "MalformedPolicyDocument",
-				Message:    "Malformed policy document",
+				Message:    "Malformed policy document: " + err.Error(),
 			}
 		}
-		srv.userPolicies = append(srv.userPolicies, policy)
+		srv.userPolicies = append(srv.userPolicies, iam.UserPolicy{
+			Name:     policyName,
+			UserName: userName,
+			Document: document,
+		})
 	}
 	return iam.SimpleResp{RequestId: reqId}, nil
 }
@@ -402,6 +441,78 @@ func (srv *Server) findUser(userName string) (int, error) {
 	return index, err
 }
 
+// authorize checks that the policies of the user owning the access key
+// req is signed with allow action. Requests signed with keys of no user
+// are made by the account root, and always allowed.
+func (srv *Server) authorize(req *http.Request, action string) *iam.Error {
+	if srv.verifier == nil {
+		return nil
+	}
+	accessKey := aws.AccessKeyID(req)
+	var user *iam.User
+	for _, k := range srv.accessKeys {
+		if k.Id != accessKey {
+			continue
+		}
+		index, err := srv.findUser(k.UserName)
+		if err != nil {
+			return err.(*iam.Error)
+		}
+		user = &srv.users[index]
+	}
+	if user == nil {
+		return nil
+	}
+	var policies []*policy.Document
+	for _, p := range srv.userPolicies {
+		if p.UserName != user.Name {
+			continue
+		}
+		if doc, err := policy.Parse(p.Document); err == nil {
+			policies = append(policies, doc)
+		}
+	}
+	r := &policy.Request{
+		Principal: user.Arn,
+		Action:    "iam:" + action,
+		Resource:  srv.resourceARN(req),
+		Context:   policy.RequestContext(req),
+	}
+	r.Context["aws:username"] = []string{user.Name}
+	r.Context["aws:userid"] = []string{user.Id}
+	if policy.Evaluate(r, policies...) != policy.Allowed {
+		return &iam.Error{
+			StatusCode: 403,
+			Code:       "AccessDenied",
+			Message:    fmt.Sprintf("User: %s is not authorized to perform: %s on resource: %s", user.Arn, r.Action, r.Resource),
+		}
+	}
+	return nil
+}
+
+// resourceARN returns the ARN of the user or group req acts on, or "*".
+func (srv *Server) resourceARN(req *http.Request) string {
+	path := req.FormValue("Path")
+	if path == "" {
+		path = "/"
+	}
+	if name := req.FormValue("UserName"); name != "" {
+		if index, err := srv.findUser(name); err == nil {
+			return srv.users[index].Arn
+		}
+		return arn.New("iam", "", accountID, "user"+path+name).String()
+	}
+	if name := req.FormValue("GroupName"); name != "" {
+		for _, group := range srv.groups {
+			if group.Name == name {
+				return group.Arn
+			}
+		}
+		return arn.New("iam", "", accountID, "group"+path+name).String()
+	}
+	return "*"
+}
+
 // Validates the presence of required request parameters.
 func (srv *Server) validate(req *http.Request, required []string) error {
 	for _, r := range required {
@@ -91,6 +91,26 @@ var ListGroupsExample = `
 </ListGroupsResponse>
 `
 
+var ListGroupsTruncatedExample = `
+<ListGroupsResponse>
+   <ListGroupsResult>
+      <Groups>
+         <member>
+            <Path>/division_abc/</Path>
+            <GroupName>Auditors</GroupName>
+            <GroupId>AGPA1AUDITORSEXAMPLE</GroupId>
+            <Arn>arn:aws:iam::123456789012:group/division_abc/Auditors</Arn>
+         </member>
+      </Groups>
+      <IsTruncated>true</IsTruncated>
+      <Marker>marker-2</Marker>
+   </ListGroupsResult>
+   <ResponseMetadata>
+      <RequestId>7a62c49f-347e-4fc4-9331-6e8eEXAMPLE</RequestId>
+   </ResponseMetadata>
+</ListGroupsResponse>
+`
+
 var RequestIdExample = `
 <AddUserToGroupResponse>
    <ResponseMetadata>
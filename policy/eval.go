@@ -0,0 +1,461 @@
+package policy
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Decision is the outcome of evaluating policies for a request.
+type Decision int
+
+const (
+	// ImplicitDeny is the decision when no statement allows the
+	// request, which is then denied.
+	ImplicitDeny Decision = iota
+
+	// Allowed is the decision when a statement allows the request, and
+	// none denies it.
+	Allowed
+
+	// ExplicitDeny is the decision when a statement denies the request,
+	// whatever other statements allow.
+	ExplicitDeny
+)
+
+func (d Decision) String() string {
+	switch d {
+	case ImplicitDeny:
+		return "ImplicitDeny"
+	case Allowed:
+		return "Allowed"
+	case ExplicitDeny:
+		return "ExplicitDeny"
+	}
+	return "Decision(" + strconv.Itoa(int(d)) + ")"
+}
+
+// Request is a request to authorize.
+type Request struct {
+	// Principal makes the request. It is the ARN of an IAM user or
+	// role, or of the root of an account as in
+	// "arn:aws:iam::123456789012:root", or the name of a service as in
+	// "sns.amazonaws.com". It is empty for anonymous requests.
+	Principal string
+
+	// Action is the action requested, as in "s3:GetObject".
+	Action string
+
+	// Resource is the ARN of the resource acted on.
+	Resource string
+
+	// Context holds the values of the condition keys of the request, as
+	// in "aws:SourceIp" or "aws:CurrentTime". Keys are case
+	// insensitive, and missing keys only satisfy negated and IfExists
+	// conditions.
+	Context map[string][]string
+}
+
+// RequestContext returns the values of the global condition keys of
+// the HTTP request req, as received by a server: "aws:SourceIp",
+// "aws:SecureTransport", "aws:CurrentTime", "aws:EpochTime", and
+// "aws:UserAgent" and "aws:Referer" if set.
+func RequestContext(req *http.Request) map[string][]string {
+	now := time.Now().UTC()
+	ctx := map[string][]string{
+		"aws:SecureTransport": {strconv.FormatBool(req.TLS != nil)},
+		"aws:CurrentTime":     {now.Format(time.RFC3339)},
+		"aws:EpochTime":       {strconv.FormatInt(now.Unix(), 10)},
+	}
+	if host, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		ctx["aws:SourceIp"] = []string{host}
+	}
+	if ua := req.UserAgent(); ua != "" {
+		ctx["aws:UserAgent"] = []string{ua}
+	}
+	if referer := req.Referer(); referer != "" {
+		ctx["aws:Referer"] = []string{referer}
+	}
+	return ctx
+}
+
+// Evaluate evaluates policies for r, as AWS does within an account: r is
+// denied if a statement denies it, and allowed if a statement allows it
+// and none denies it. Otherwise it is implicitly denied.
+//
+// Statements without a principal, as those of identity policies, apply
+// to any request. The statements of resource policies apply to the
+// requests of their principal.
+func Evaluate(r *Request, policies ...*Document) Decision {
+	decision := ImplicitDeny
+	for _, d := range policies {
+		vars := d.Version == Version
+		for i := range d.Statement {
+			s := &d.Statement[i]
+			if !s.matches(r, vars) {
+				continue
+			}
+			if s.Effect == Deny {
+				return ExplicitDeny
+			}
+			if s.Effect == Allow {
+				decision = Allowed
+			}
+		}
+	}
+	return decision
+}
+
+// Matches reports whether s applies to r, whatever its effect.
+func (s *Statement) Matches(r *Request) bool {
+	return s.matches(r, true)
+}
+
+// matches reports whether s applies to r. Policy variables, as in
+// "${aws:username}", are replaced by the values of r if vars is set.
+func (s *Statement) matches(r *Request, vars bool) bool {
+	switch {
+	case s.Principal != nil && !s.Principal.matches(r.Principal):
+		return false
+	case s.NotPrincipal != nil && s.NotPrincipal.matches(r.Principal):
+		return false
+	case len(s.Action) > 0 && !matchAny(s.Action, r.Action, true, nil):
+		return false
+	case len(s.NotAction) > 0 && matchAny(s.NotAction, r.Action, true, nil):
+		return false
+	}
+	var rv *Request
+	if vars {
+		rv = r
+	}
+	switch {
+	case len(s.Resource) > 0 && !matchAny(s.Resource, r.Resource, false, rv):
+		return false
+	case len(s.NotResource) > 0 && matchAny(s.NotResource, r.Resource, false, rv):
+		return false
+	}
+	for op, tests := range s.Condition {
+		for key, values := range tests {
+			if !testCondition(op, r, key, values, rv) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// matches reports whether p is, or includes, principal.
+func (p *Principal) matches(principal string) bool {
+	if p.All {
+		return true
+	}
+	if principal == "" {
+		return false
+	}
+	for _, id := range p.AWS {
+		if !strings.HasPrefix(principal, "arn:") {
+			break
+		}
+		if id == "*" {
+			return true
+		}
+		// An account stands for all of its principals.
+		if account := rootAccount(id); account != "" && account == principalAccount(principal) {
+			return true
+		}
+		if wildcardMatch(id, principal) {
+			return true
+		}
+	}
+	for _, l := range [][]string{p.Service, p.Federated, p.CanonicalUser} {
+		for _, id := range l {
+			if id == principal {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// rootAccount returns the account id stands for, given as an account ID
+// or as the ARN of the account root, or "" if id is neither.
+func rootAccount(id string) string {
+	if accountPattern.MatchString(id) {
+		return id
+	}
+	if fields := strings.SplitN(id, ":", 6); len(fields) == 6 && fields[0] == "arn" && fields[2] == "iam" && fields[5] == "root" {
+		return fields[4]
+	}
+	return ""
+}
+
+// principalAccount returns the account of the IAM or STS principal
+// with the given ARN, or "" if principal isn't one.
+func principalAccount(principal string) string {
+	fields := strings.SplitN(principal, ":", 6)
+	if len(fields) != 6 || fields[0] != "arn" || (fields[2] != "iam" && fields[2] != "sts") {
+		return ""
+	}
+	return fields[4]
+}
+
+// matchAny reports whether s matches one of patterns. Policy variables
+// are replaced by the values of r, if not nil; patterns holding
+// variables r has no value for match nothing.
+func matchAny(patterns []string, s string, fold bool, r *Request) bool {
+	if fold {
+		s = strings.ToLower(s)
+	}
+	for _, p := range patterns {
+		if r != nil {
+			var ok bool
+			if p, ok = r.substitute(p); !ok {
+				continue
+			}
+		}
+		if fold {
+			p = strings.ToLower(p)
+		}
+		if wildcardMatch(p, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// wildcardMatch reports whether s matches pattern, where "*" matches any
+// sequence of characters and "?" any character.
+func wildcardMatch(pattern, s string) bool {
+	p, i := 0, 0
+	star, next := -1, 0
+	for i < len(s) {
+		switch {
+		case p < len(pattern) && (pattern[p] == '?' || pattern[p] == s[i]):
+			p++
+			i++
+		case p < len(pattern) && pattern[p] == '*':
+			star, next = p, i
+			p++
+		case star >= 0:
+			// Let the last star match one more character.
+			next++
+			p, i = star+1, next
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// lookup returns the values of the condition key of r.
+func (r *Request) lookup(key string) ([]string, bool) {
+	if values, ok := r.Context[key]; ok {
+		return values, len(values) > 0
+	}
+	for k, values := range r.Context {
+		if strings.EqualFold(k, key) {
+			return values, len(values) > 0
+		}
+	}
+	return nil, false
+}
+
+// substitute replaces the policy variables of s, as in
+// "${aws:username}", by the values of r. It returns false if r has no
+// value for a variable.
+func (r *Request) substitute(s string) (string, bool) {
+	var out []string
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			break
+		}
+		j := strings.Index(s[i:], "}")
+		if j < 0 {
+			break
+		}
+		values, ok := r.lookup(s[i+2 : i+j])
+		if !ok {
+			return "", false
+		}
+		out = append(out, s[:i], values[0])
+		s = s[i+j+1:]
+	}
+	return strings.Join(append(out, s), ""), true
+}
+
+// comparator compares a value of a request with a value of a condition.
+type comparator struct {
+	match   func(value, want string) bool
+	negated bool
+}
+
+var comparators = map[string]comparator{
+	"StringEquals":              {stringEquals, false},
+	"StringNotEquals":           {stringEquals, true},
+	"StringEqualsIgnoreCase":    {strings.EqualFold, false},
+	"StringNotEqualsIgnoreCase": {strings.EqualFold, true},
+	"StringLike":                {stringLike, false},
+	"StringNotLike":             {stringLike, true},
+	"NumericEquals":             {numeric(func(c int) bool { return c == 0 }), false},
+	"NumericNotEquals":          {numeric(func(c int) bool { return c == 0 }), true},
+	"NumericLessThan":           {numeric(func(c int) bool { return c < 0 }), false},
+	"NumericLessThanEquals":     {numeric(func(c int) bool { return c <= 0 }), false},
+	"NumericGreaterThan":        {numeric(func(c int) bool { return c > 0 }), false},
+	"NumericGreaterThanEquals":  {numeric(func(c int) bool { return c >= 0 }), false},
+	"DateEquals":                {date(func(c int) bool { return c == 0 }), false},
+	"DateNotEquals":             {date(func(c int) bool { return c == 0 }), true},
+	"DateLessThan":              {date(func(c int) bool { return c < 0 }), false},
+	"DateLessThanEquals":        {date(func(c int) bool { return c <= 0 }), false},
+	"DateGreaterThan":           {date(func(c int) bool { return c > 0 }), false},
+	"DateGreaterThanEquals":     {date(func(c int) bool { return c >= 0 }), false},
+	"Bool":                      {strings.EqualFold, false},
+	"BinaryEquals":              {stringEquals, false},
+	"IpAddress":                 {ipAddress, false},
+	"NotIpAddress":              {ipAddress, true},
+	"ArnEquals":                 {stringLike, false},
+	"ArnNotEquals":              {stringLike, true},
+	"ArnLike":                   {stringLike, false},
+	"ArnNotLike":                {stringLike, true},
+}
+
+// testCondition reports whether the values of key in r satisfy the
+// condition operator op with values want. Policy variables are replaced
+// by the values of vars, if not nil.
+//
+// The values of a negated operator, as StringNotEquals, must all differ
+// from want. ForAllValues requires every value to match, and is true of
+// missing keys; ForAnyValue requires a value to match.
+func testCondition(op string, r *Request, key string, want Values, vars *Request) bool {
+	m := conditionPattern.FindStringSubmatch(op)
+	if m == nil {
+		return false
+	}
+	set, base, ifExists := m[1], m[2], m[3] != ""
+	values, ok := r.lookup(key)
+	if base == "Null" {
+		for _, w := range want {
+			if strings.EqualFold(w, strconv.FormatBool(!ok)) {
+				return true
+			}
+		}
+		return false
+	}
+	cmp, known := comparators[base]
+	if !known {
+		return false
+	}
+	if vars != nil {
+		var substituted []string
+		for _, w := range want {
+			if w, ok := vars.substitute(w); ok {
+				substituted = append(substituted, w)
+			}
+		}
+		want = substituted
+	}
+	if !ok {
+		switch {
+		case ifExists:
+			return true
+		case set == "ForAllValues:":
+			return true
+		case set == "ForAnyValue:":
+			return false
+		}
+		return cmp.negated
+	}
+	test := func(value string) bool {
+		for _, w := range want {
+			if cmp.match(value, w) {
+				return !cmp.negated
+			}
+		}
+		return cmp.negated
+	}
+	all := set == "ForAllValues:" || (set == "" && cmp.negated)
+	for _, value := range values {
+		if test(value) != all {
+			return !all
+		}
+	}
+	return all
+}
+
+func stringEquals(value, want string) bool {
+	return value == want
+}
+
+func stringLike(value, want string) bool {
+	return wildcardMatch(want, value)
+}
+
+func numeric(ok func(c int) bool) func(value, want string) bool {
+	return func(value, want string) bool {
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return false
+		}
+		w, err := strconv.ParseFloat(want, 64)
+		if err != nil {
+			return false
+		}
+		switch {
+		case v < w:
+			return ok(-1)
+		case v > w:
+			return ok(1)
+		}
+		return ok(0)
+	}
+}
+
+func date(ok func(c int) bool) func(value, want string) bool {
+	return func(value, want string) bool {
+		v, vok := parseDate(value)
+		w, wok := parseDate(want)
+		if !vok || !wok {
+			return false
+		}
+		switch {
+		case v.Before(w):
+			return ok(-1)
+		case v.After(w):
+			return ok(1)
+		}
+		return ok(0)
+	}
+}
+
+// parseDate parses s as an ISO 8601 date, or as seconds since the epoch
+// as in the value of "aws:EpochTime".
+func parseDate(s string) (time.Time, bool) {
+	if secs, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(secs, 0), true
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04Z07:00", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// ipAddress reports whether the IP address value is in the network
+// want, given in CIDR notation or as a single address.
+func ipAddress(value, want string) bool {
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return false
+	}
+	if !strings.Contains(want, "/") {
+		return ip.Equal(net.ParseIP(want))
+	}
+	_, network, err := net.ParseCIDR(want)
+	return err == nil && network.Contains(ip)
+}
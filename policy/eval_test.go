@@ -0,0 +1,195 @@
+package policy_test
+
+import (
+	"github.com/crowdmob/goamz/policy"
+	"gopkg.in/check.v1"
+	"net/http"
+)
+
+const (
+	alice = "arn:aws:iam::123456789012:user/alice"
+	bob   = "arn:aws:iam::210987654321:user/bob"
+)
+
+func mustParse(c *check.C, s string) *policy.Document {
+	doc, err := policy.Parse(s)
+	c.Assert(err, check.IsNil)
+	return doc
+}
+
+func (s *S) TestEvaluate(c *check.C) {
+	identity := mustParse(c, `{
+		"Version": "2012-10-17",
+		"Statement": [{
+			"Effect": "Allow",
+			"Action": ["s3:Get*", "s3:ListBucket"],
+			"Resource": ["arn:aws:s3:::bucket", "arn:aws:s3:::bucket/*"]
+		}, {
+			"Effect": "Deny",
+			"Action": "s3:GetObject",
+			"Resource": "arn:aws:s3:::bucket/secret/*"
+		}]
+	}`)
+	tests := []struct {
+		action, resource string
+		decision         policy.Decision
+	}{
+		{"s3:GetObject", "arn:aws:s3:::bucket/a/b", policy.Allowed},
+		{"S3:getobject", "arn:aws:s3:::bucket/a/b", policy.Allowed},
+		{"s3:GetObjectAcl", "arn:aws:s3:::bucket/a", policy.Allowed},
+		{"s3:ListBucket", "arn:aws:s3:::bucket", policy.Allowed},
+		{"s3:ListBucket", "arn:aws:s3:::bucket2", policy.ImplicitDeny},
+		{"s3:PutObject", "arn:aws:s3:::bucket/a", policy.ImplicitDeny},
+		{"s3:GetObject", "arn:aws:s3:::bucket/secret/a", policy.ExplicitDeny},
+		{"s3:GetObject", "arn:aws:s3:::Bucket/a", policy.ImplicitDeny},
+	}
+	for _, t := range tests {
+		r := &policy.Request{Principal: alice, Action: t.action, Resource: t.resource}
+		c.Check(policy.Evaluate(r, identity), check.Equals, t.decision, check.Commentf("%s on %s", t.action, t.resource))
+	}
+	r := &policy.Request{Principal: alice, Action: "s3:GetObject", Resource: "arn:aws:s3:::bucket/a"}
+	c.Check(policy.Evaluate(r), check.Equals, policy.ImplicitDeny)
+}
+
+func (s *S) TestEvaluatePrincipals(c *check.C) {
+	tests := []struct {
+		principal string
+		matches   []string
+	}{
+		{`"*"`, []string{alice, bob, "sns.amazonaws.com", ""}},
+		{`{"AWS": "*"}`, []string{alice, bob}},
+		{`{"AWS": "123456789012"}`, []string{alice, "arn:aws:sts::123456789012:assumed-role/r/s"}},
+		{`{"AWS": "arn:aws:iam::123456789012:root"}`, []string{alice}},
+		{`{"AWS": "arn:aws:iam::123456789012:user/alice"}`, []string{alice}},
+		{`{"AWS": ["arn:aws:iam::*:user/bob", "arn:aws:iam::123456789012:user/al?ce"]}`, []string{alice, bob}},
+		{`{"Service": "sns.amazonaws.com"}`, []string{"sns.amazonaws.com"}},
+	}
+	principals := []string{alice, bob, "sns.amazonaws.com", ""}
+	for _, t := range tests {
+		doc := mustParse(c, `{"Statement": {"Effect": "Allow", "Principal": `+t.principal+`, "Action": "sqs:*"}}`)
+		matched := map[string]bool{}
+		for _, p := range t.matches {
+			matched[p] = true
+		}
+		for _, p := range principals {
+			want := policy.ImplicitDeny
+			if matched[p] {
+				want = policy.Allowed
+			}
+			r := &policy.Request{Principal: p, Action: "sqs:SendMessage", Resource: "arn:aws:sqs:us-east-1:123456789012:q"}
+			c.Check(policy.Evaluate(r, doc), check.Equals, want, check.Commentf("%s for %q", t.principal, p))
+		}
+	}
+}
+
+func (s *S) TestEvaluateNot(c *check.C) {
+	doc := mustParse(c, `{"Statement": [{
+		"Effect": "Allow",
+		"NotAction": "iam:*",
+		"NotResource": "arn:aws:s3:::private/*"
+	}, {
+		"Effect": "Deny",
+		"NotPrincipal": {"AWS": "arn:aws:iam::123456789012:user/alice"},
+		"Action": "s3:DeleteObject",
+		"Resource": "*"
+	}]}`)
+	tests := []struct {
+		principal, action, resource string
+		decision                    policy.Decision
+	}{
+		{alice, "s3:GetObject", "arn:aws:s3:::public/a", policy.Allowed},
+		{alice, "s3:GetObject", "arn:aws:s3:::private/a", policy.ImplicitDeny},
+		{alice, "iam:ListUsers", "*", policy.ImplicitDeny},
+		{alice, "s3:DeleteObject", "arn:aws:s3:::public/a", policy.Allowed},
+		{bob, "s3:DeleteObject", "arn:aws:s3:::public/a", policy.ExplicitDeny},
+	}
+	for _, t := range tests {
+		r := &policy.Request{Principal: t.principal, Action: t.action, Resource: t.resource}
+		c.Check(policy.Evaluate(r, doc), check.Equals, t.decision, check.Commentf("%+v", t))
+	}
+}
+
+var conditionTests = []struct {
+	condition string
+	context   map[string][]string
+	matches   bool
+}{
+	{`{"StringLike": {"s3:prefix": ["home/*", "shared/"]}}`, map[string][]string{"s3:prefix": {"home/alice/"}}, true},
+	{`{"StringLike": {"s3:prefix": ["home/*", "shared/"]}}`, map[string][]string{"s3:prefix": {"shared/x"}}, false},
+	{`{"StringLike": {"s3:prefix": "home/*"}}`, nil, false},
+	{`{"StringNotLike": {"s3:prefix": "home/*"}}`, nil, true},
+	{`{"StringNotLike": {"s3:prefix": "home/*"}}`, map[string][]string{"s3:prefix": {"home/a"}}, false},
+	{`{"StringEquals": {"aws:username": "alice"}}`, map[string][]string{"AWS:UserName": {"alice"}}, true},
+	{`{"StringEqualsIgnoreCase": {"aws:username": "ALICE"}}`, map[string][]string{"aws:username": {"alice"}}, true},
+	{`{"IpAddress": {"aws:SourceIp": ["192.0.2.0/24", "203.0.113.7"]}}`, map[string][]string{"aws:SourceIp": {"192.0.2.44"}}, true},
+	{`{"IpAddress": {"aws:SourceIp": ["192.0.2.0/24", "203.0.113.7"]}}`, map[string][]string{"aws:SourceIp": {"203.0.113.7"}}, true},
+	{`{"IpAddress": {"aws:SourceIp": ["192.0.2.0/24", "203.0.113.7"]}}`, map[string][]string{"aws:SourceIp": {"203.0.113.8"}}, false},
+	{`{"NotIpAddress": {"aws:SourceIp": "192.0.2.0/24"}}`, map[string][]string{"aws:SourceIp": {"203.0.113.8"}}, true},
+	{`{"DateLessThan": {"aws:CurrentTime": "2013-06-30T00:00:00Z"}}`, map[string][]string{"aws:CurrentTime": {"2013-06-29T23:59:59Z"}}, true},
+	{`{"DateLessThan": {"aws:CurrentTime": "2013-06-30T00:00:00Z"}}`, map[string][]string{"aws:CurrentTime": {"2013-06-30T00:00:00Z"}}, false},
+	{`{"DateGreaterThan": {"aws:EpochTime": "2013-06-30"}}`, map[string][]string{"aws:EpochTime": {"1372550400"}}, false},
+	{`{"DateGreaterThanEquals": {"aws:EpochTime": "2013-06-30"}}`, map[string][]string{"aws:EpochTime": {"1372550400"}}, true},
+	{`{"NumericLessThanEquals": {"s3:max-keys": "10"}}`, map[string][]string{"s3:max-keys": {"10"}}, true},
+	{`{"NumericLessThanEquals": {"s3:max-keys": "10"}}`, map[string][]string{"s3:max-keys": {"11"}}, false},
+	{`{"Bool": {"aws:SecureTransport": true}}`, map[string][]string{"aws:SecureTransport": {"true"}}, true},
+	{`{"Bool": {"aws:SecureTransport": "true"}}`, map[string][]string{"aws:SecureTransport": {"false"}}, false},
+	{`{"Bool": {"aws:MultiFactorAuthPresent": "true"}}`, nil, false},
+	{`{"BoolIfExists": {"aws:MultiFactorAuthPresent": "true"}}`, nil, true},
+	{`{"Null": {"aws:TokenIssueTime": "true"}}`, nil, true},
+	{`{"Null": {"aws:TokenIssueTime": "false"}}`, nil, false},
+	{`{"ArnLike": {"aws:SourceArn": "arn:aws:sns:*:123456789012:*"}}`, map[string][]string{"aws:SourceArn": {"arn:aws:sns:us-east-1:123456789012:t"}}, true},
+	{`{"ForAllValues:StringEquals": {"tags": ["a", "b"]}}`, map[string][]string{"tags": {"a", "b"}}, true},
+	{`{"ForAllValues:StringEquals": {"tags": ["a", "b"]}}`, map[string][]string{"tags": {"a", "c"}}, false},
+	{`{"ForAllValues:StringEquals": {"tags": ["a", "b"]}}`, nil, true},
+	{`{"ForAnyValue:StringEquals": {"tags": ["a", "b"]}}`, map[string][]string{"tags": {"c", "b"}}, true},
+	{`{"ForAnyValue:StringEquals": {"tags": ["a", "b"]}}`, nil, false},
+	{`{"StringLike": {"s3:prefix": "home/${aws:username}/*"}}`, map[string][]string{"s3:prefix": {"home/alice/x"}, "aws:username": {"alice"}}, true},
+	{`{"StringLike": {"s3:prefix": "home/${aws:username}/*"}}`, map[string][]string{"s3:prefix": {"home/bob/x"}, "aws:username": {"alice"}}, false},
+	{`{"IpAddress": {"aws:SourceIp": "192.0.2.0/24"}, "Bool": {"aws:SecureTransport": "true"}}`, map[string][]string{"aws:SourceIp": {"192.0.2.1"}, "aws:SecureTransport": {"false"}}, false},
+}
+
+func (s *S) TestEvaluateConditions(c *check.C) {
+	for _, t := range conditionTests {
+		doc := mustParse(c, `{"Version": "2012-10-17", "Statement": {"Effect": "Allow", "Action": "s3:*", "Resource": "*", "Condition": `+t.condition+`}}`)
+		want := policy.ImplicitDeny
+		if t.matches {
+			want = policy.Allowed
+		}
+		r := &policy.Request{Principal: alice, Action: "s3:ListBucket", Resource: "arn:aws:s3:::bucket", Context: t.context}
+		c.Check(policy.Evaluate(r, doc), check.Equals, want, check.Commentf("%s with %v", t.condition, t.context))
+	}
+}
+
+func (s *S) TestEvaluatePolicyVariables(c *check.C) {
+	const statement = `"Statement": {"Effect": "Allow", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::bucket/home/${aws:username}/*"}`
+	r := &policy.Request{
+		Principal: alice,
+		Action:    "s3:GetObject",
+		Resource:  "arn:aws:s3:::bucket/home/alice/file",
+		Context:   map[string][]string{"aws:username": {"alice"}},
+	}
+	doc := mustParse(c, `{"Version": "2012-10-17", `+statement+`}`)
+	c.Check(policy.Evaluate(r, doc), check.Equals, policy.Allowed)
+
+	// Variables are only replaced in documents of the current version.
+	doc = mustParse(c, `{"Version": "2008-10-17", `+statement+`}`)
+	c.Check(policy.Evaluate(r, doc), check.Equals, policy.ImplicitDeny)
+
+	doc = mustParse(c, `{"Version": "2012-10-17", `+statement+`}`)
+	r.Context = nil
+	c.Check(policy.Evaluate(r, doc), check.Equals, policy.ImplicitDeny)
+}
+
+func (s *S) TestRequestContext(c *check.C) {
+	req, err := http.NewRequest("GET", "http://localhost/", nil)
+	c.Assert(err, check.IsNil)
+	req.RemoteAddr = "192.0.2.1:4242"
+	req.Header.Set("User-Agent", "goamz")
+	ctx := policy.RequestContext(req)
+	c.Assert(ctx["aws:SourceIp"], check.DeepEquals, []string{"192.0.2.1"})
+	c.Assert(ctx["aws:SecureTransport"], check.DeepEquals, []string{"false"})
+	c.Assert(ctx["aws:UserAgent"], check.DeepEquals, []string{"goamz"})
+	c.Assert(ctx["aws:CurrentTime"], check.HasLen, 1)
+	c.Assert(ctx["aws:EpochTime"], check.HasLen, 1)
+	c.Assert(ctx["aws:Referer"], check.IsNil)
+}
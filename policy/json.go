@@ -0,0 +1,177 @@
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Policies write lists of one value as the value alone: "Action": "s3:*"
+// stands for "Action": ["s3:*"]. Lists are written back that way.
+
+func marshalList(l []string) ([]byte, error) {
+	if len(l) == 1 {
+		return json.Marshal(l[0])
+	}
+	return json.Marshal([]string(l))
+}
+
+func unmarshalList(data []byte) ([]string, error) {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		return []string{s}, nil
+	}
+	var l []string
+	if err := json.Unmarshal(data, &l); err != nil {
+		return nil, fmt.Errorf("expected a string or a list of strings, got %s", data)
+	}
+	return l, nil
+}
+
+func (a Action) MarshalJSON() ([]byte, error) {
+	return marshalList(a)
+}
+
+func (a *Action) UnmarshalJSON(data []byte) (err error) {
+	*a, err = unmarshalList(data)
+	return err
+}
+
+func (r Resource) MarshalJSON() ([]byte, error) {
+	return marshalList(r)
+}
+
+func (r *Resource) UnmarshalJSON(data []byte) (err error) {
+	*r, err = unmarshalList(data)
+	return err
+}
+
+func (v Values) MarshalJSON() ([]byte, error) {
+	return marshalList(v)
+}
+
+// UnmarshalJSON also accepts booleans and numbers, which are kept as
+// written, as in "aws:SecureTransport": false.
+func (v *Values) UnmarshalJSON(data []byte) error {
+	var raw []json.RawMessage
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return err
+		}
+	} else {
+		raw = []json.RawMessage{data}
+	}
+	values := make(Values, 0, len(raw))
+	for _, r := range raw {
+		var x interface{}
+		if err := json.Unmarshal(r, &x); err != nil {
+			return err
+		}
+		switch x := x.(type) {
+		case string:
+			values = append(values, x)
+		case bool, float64:
+			values = append(values, string(bytes.TrimSpace(r)))
+		default:
+			return fmt.Errorf("invalid condition value %s", r)
+		}
+	}
+	*v = values
+	return nil
+}
+
+// principal is the object form of Principal.
+type principal struct {
+	AWS           json.RawMessage `json:",omitempty"`
+	Service       json.RawMessage `json:",omitempty"`
+	Federated     json.RawMessage `json:",omitempty"`
+	CanonicalUser json.RawMessage `json:",omitempty"`
+}
+
+func (p *Principal) MarshalJSON() ([]byte, error) {
+	if p.All {
+		return []byte(`"*"`), nil
+	}
+	var obj principal
+	fields := []struct {
+		raw *json.RawMessage
+		l   []string
+	}{
+		{&obj.AWS, p.AWS},
+		{&obj.Service, p.Service},
+		{&obj.Federated, p.Federated},
+		{&obj.CanonicalUser, p.CanonicalUser},
+	}
+	for _, f := range fields {
+		if len(f.l) == 0 {
+			continue
+		}
+		data, err := marshalList(f.l)
+		if err != nil {
+			return nil, err
+		}
+		*f.raw = data
+	}
+	return json.Marshal(&obj)
+}
+
+func (p *Principal) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		if s != "*" {
+			return fmt.Errorf("invalid principal %q", s)
+		}
+		*p = Principal{All: true}
+		return nil
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return fmt.Errorf("expected \"*\" or an object for principal, got %s", data)
+	}
+	*p = Principal{}
+	for kind, raw := range obj {
+		l, err := unmarshalList(raw)
+		if err != nil {
+			return err
+		}
+		switch kind {
+		case "AWS":
+			p.AWS = l
+		case "Service":
+			p.Service = l
+		case "Federated":
+			p.Federated = l
+		case "CanonicalUser":
+			p.CanonicalUser = l
+		default:
+			return fmt.Errorf("unknown principal type %q", kind)
+		}
+	}
+	return nil
+}
+
+// UnmarshalJSON also accepts a single statement in place of the list of
+// statements of d.
+func (d *Document) UnmarshalJSON(data []byte) error {
+	var doc struct {
+		Version   string
+		Id        string
+		Statement json.RawMessage
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	*d = Document{Version: doc.Version, Id: doc.Id}
+	if len(doc.Statement) == 0 {
+		return nil
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(doc.Statement), []byte("{")) {
+		var s Statement
+		if err := json.Unmarshal(doc.Statement, &s); err != nil {
+			return err
+		}
+		d.Statement = []Statement{s}
+		return nil
+	}
+	return json.Unmarshal(doc.Statement, &d.Statement)
+}
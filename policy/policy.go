@@ -0,0 +1,340 @@
+// Package policy builds, parses and validates the JSON access policy
+// documents of AWS: the IAM policies of users, groups and roles, and the
+// resource policies of S3 buckets, SQS queues and SNS topics.
+//
+// Policies are built from statements:
+//
+//	doc := policy.New(
+//		policy.AllowStatement("sqs:SendMessage").
+//			On("arn:aws:sqs:us-east-1:123456789012:queue1").
+//			By(policy.Service("sns.amazonaws.com")).
+//			When("ArnEquals", "aws:SourceArn", topicARN),
+//	)
+//
+// and the String of a Document is the policy text taken by
+// iam.PutUserPolicy, the "Policy" attribute of SQS queues and SNS topics,
+// and S3 bucket policies.
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Version is the current version of the policy language, set by New.
+const Version = "2012-10-17"
+
+// Document is a policy document.
+type Document struct {
+	// Version is the version of the policy language: Version, or
+	// "2008-10-17" for older policies. AWS assumes "2008-10-17" when
+	// it is empty.
+	Version string `json:",omitempty"`
+
+	// Id optionally identifies the policy.
+	Id string `json:",omitempty"`
+
+	Statement []Statement
+}
+
+// Effect tells whether a statement allows or denies access.
+type Effect string
+
+const (
+	Allow Effect = "Allow"
+	Deny  Effect = "Deny"
+)
+
+// Statement is a statement of a policy, allowing or denying Action on
+// Resource to Principal when Condition holds.
+//
+// Each of Principal, Action and Resource may be replaced by its Not
+// form, which matches everything but the listed values. Resource
+// policies must name a Principal; identity policies must not.
+type Statement struct {
+	Sid          string `json:",omitempty"`
+	Effect       Effect
+	Principal    *Principal `json:",omitempty"`
+	NotPrincipal *Principal `json:",omitempty"`
+	Action       Action     `json:",omitempty"`
+	NotAction    Action     `json:",omitempty"`
+	Resource     Resource   `json:",omitempty"`
+	NotResource  Resource   `json:",omitempty"`
+	Condition    Condition  `json:",omitempty"`
+}
+
+// Action lists actions as "service:Operation", as in "s3:GetObject".
+// The operation may hold the wildcards "*" and "?", and "*" alone
+// stands for every action of every service.
+type Action []string
+
+// Resource lists the ARNs of resources, which may hold the wildcards
+// "*" and "?". "*" alone stands for every resource.
+type Resource []string
+
+// Principal gives the accounts, users, roles or services a statement
+// applies to.
+type Principal struct {
+	// All is set for the anonymous principal "*", which is everyone.
+	All bool
+
+	// AWS lists AWS accounts, as 12 digit IDs or the ARN of their
+	// root, and the ARNs of IAM users and roles. "*" stands for every
+	// authenticated AWS principal.
+	AWS []string
+
+	// Service lists AWS services, as in "sns.amazonaws.com".
+	Service []string
+
+	// Federated lists identity providers of federated users.
+	Federated []string
+
+	// CanonicalUser lists S3 canonical user IDs.
+	CanonicalUser []string
+}
+
+// Condition maps condition operators, as in "StringLike", to the keys
+// they test and the values those are tested against:
+//
+//	policy.Condition{"IpAddress": {"aws:SourceIp": {"192.0.2.0/24"}}}
+type Condition map[string]map[string]Values
+
+// Values lists the values a condition key is tested against.
+type Values []string
+
+// New returns a document of the current version holding statements.
+func New(statements ...*Statement) *Document {
+	d := &Document{Version: Version}
+	for _, s := range statements {
+		d.Statement = append(d.Statement, *s)
+	}
+	return d
+}
+
+// AllowStatement returns a statement allowing actions.
+func AllowStatement(actions ...string) *Statement {
+	return &Statement{Effect: Allow, Action: actions}
+}
+
+// DenyStatement returns a statement denying actions.
+func DenyStatement(actions ...string) *Statement {
+	return &Statement{Effect: Deny, Action: actions}
+}
+
+// On adds resources to the resources of s, and returns s.
+func (s *Statement) On(resources ...string) *Statement {
+	s.Resource = append(s.Resource, resources...)
+	return s
+}
+
+// By sets the principal of s, and returns s.
+func (s *Statement) By(p *Principal) *Statement {
+	s.Principal = p
+	return s
+}
+
+// When adds to s the condition that key matches one of values according
+// to operator, and returns s.
+func (s *Statement) When(operator, key string, values ...string) *Statement {
+	if s.Condition == nil {
+		s.Condition = make(Condition)
+	}
+	if s.Condition[operator] == nil {
+		s.Condition[operator] = make(map[string]Values)
+	}
+	s.Condition[operator][key] = append(s.Condition[operator][key], values...)
+	return s
+}
+
+// Everyone returns the anonymous principal "*".
+func Everyone() *Principal {
+	return &Principal{All: true}
+}
+
+// AWS returns the principal of the given accounts, users and roles.
+func AWS(ids ...string) *Principal {
+	return &Principal{AWS: ids}
+}
+
+// Service returns the principal of the given AWS services.
+func Service(services ...string) *Principal {
+	return &Principal{Service: services}
+}
+
+// Parse parses and validates the policy document s. The document may be
+// URL encoded, as returned by IAM.
+func Parse(s string) (*Document, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "%") {
+		unescaped, err := url.QueryUnescape(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid policy: %v", err)
+		}
+		s = strings.TrimSpace(unescaped)
+	}
+	d := new(Document)
+	if err := json.Unmarshal([]byte(s), d); err != nil {
+		return nil, fmt.Errorf("invalid policy: %v", err)
+	}
+	if err := d.Validate(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// String returns the JSON text of d.
+func (d *Document) String() string {
+	data, err := json.Marshal(d)
+	if err != nil {
+		// Documents are made of strings only.
+		panic(err)
+	}
+	return string(data)
+}
+
+var (
+	actionPattern  = regexp.MustCompile(`^(\*|[a-z0-9-]+:[A-Za-z0-9*?]+)$`)
+	accountPattern = regexp.MustCompile(`^\d{12}$`)
+
+	conditionPattern = regexp.MustCompile(`^(ForAllValues:|ForAnyValue:)?([A-Za-z]+?)(IfExists)?$`)
+)
+
+// operators holds the condition operators of the policy language,
+// without their set prefixes and IfExists suffix.
+var operators = map[string]bool{
+	"StringEquals":              true,
+	"StringNotEquals":           true,
+	"StringEqualsIgnoreCase":    true,
+	"StringNotEqualsIgnoreCase": true,
+	"StringLike":                true,
+	"StringNotLike":             true,
+	"NumericEquals":             true,
+	"NumericNotEquals":          true,
+	"NumericLessThan":           true,
+	"NumericLessThanEquals":     true,
+	"NumericGreaterThan":        true,
+	"NumericGreaterThanEquals":  true,
+	"DateEquals":                true,
+	"DateNotEquals":             true,
+	"DateLessThan":              true,
+	"DateLessThanEquals":        true,
+	"DateGreaterThan":           true,
+	"DateGreaterThanEquals":     true,
+	"Bool":                      true,
+	"BinaryEquals":              true,
+	"IpAddress":                 true,
+	"NotIpAddress":              true,
+	"ArnEquals":                 true,
+	"ArnNotEquals":              true,
+	"ArnLike":                   true,
+	"ArnNotLike":                true,
+	"Null":                      true,
+}
+
+// Validate checks that d is a well formed policy document. It doesn't
+// check that the actions and resources exist.
+func (d *Document) Validate() error {
+	switch d.Version {
+	case "", Version, "2008-10-17":
+	default:
+		return fmt.Errorf("invalid policy: unknown version %q", d.Version)
+	}
+	if len(d.Statement) == 0 {
+		return fmt.Errorf("invalid policy: no statement")
+	}
+	sids := make(map[string]bool)
+	for i := range d.Statement {
+		s := &d.Statement[i]
+		if s.Sid != "" {
+			if sids[s.Sid] {
+				return fmt.Errorf("invalid policy: duplicate statement ID %q", s.Sid)
+			}
+			sids[s.Sid] = true
+		}
+		if err := s.Validate(); err != nil {
+			name := fmt.Sprintf("#%d", i+1)
+			if s.Sid != "" {
+				name = fmt.Sprintf("%q", s.Sid)
+			}
+			return fmt.Errorf("invalid policy: statement %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// Validate checks that s is a well formed statement.
+func (s *Statement) Validate() error {
+	if s.Effect != Allow && s.Effect != Deny {
+		return fmt.Errorf("invalid effect %q", s.Effect)
+	}
+	if s.Principal != nil && s.NotPrincipal != nil {
+		return fmt.Errorf("both Principal and NotPrincipal are set")
+	}
+	for _, p := range []*Principal{s.Principal, s.NotPrincipal} {
+		if err := p.validate(); err != nil {
+			return err
+		}
+	}
+	switch {
+	case len(s.Action) == 0 && len(s.NotAction) == 0:
+		return fmt.Errorf("missing Action")
+	case len(s.Action) > 0 && len(s.NotAction) > 0:
+		return fmt.Errorf("both Action and NotAction are set")
+	}
+	for _, a := range append(s.Action, s.NotAction...) {
+		if !actionPattern.MatchString(a) {
+			return fmt.Errorf("invalid action %q", a)
+		}
+	}
+	if len(s.Resource) > 0 && len(s.NotResource) > 0 {
+		return fmt.Errorf("both Resource and NotResource are set")
+	}
+	for _, r := range append(s.Resource, s.NotResource...) {
+		if r != "*" && !isARN(r) {
+			return fmt.Errorf("invalid resource %q", r)
+		}
+	}
+	for op, tests := range s.Condition {
+		m := conditionPattern.FindStringSubmatch(op)
+		if m == nil || !operators[m[2]] {
+			return fmt.Errorf("unknown condition operator %q", op)
+		}
+		for key, values := range tests {
+			if key == "" {
+				return fmt.Errorf("empty condition key for %s", op)
+			}
+			if len(values) == 0 {
+				return fmt.Errorf("no values for condition %s on %s", op, key)
+			}
+		}
+	}
+	return nil
+}
+
+func (p *Principal) validate() error {
+	if p == nil {
+		return nil
+	}
+	if p.All {
+		return nil
+	}
+	if len(p.AWS)+len(p.Service)+len(p.Federated)+len(p.CanonicalUser) == 0 {
+		return fmt.Errorf("empty principal")
+	}
+	for _, id := range p.AWS {
+		if id != "*" && !accountPattern.MatchString(id) && !isARN(id) {
+			return fmt.Errorf("invalid AWS principal %q", id)
+		}
+	}
+	return nil
+}
+
+// isARN reports whether s looks like an ARN. Its fields may hold
+// wildcards, so they aren't checked.
+func isARN(s string) bool {
+	fields := strings.SplitN(s, ":", 6)
+	return len(fields) == 6 && fields[0] == "arn" && fields[5] != ""
+}
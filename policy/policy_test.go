@@ -0,0 +1,154 @@
+package policy_test
+
+import (
+	"github.com/crowdmob/goamz/policy"
+	"gopkg.in/check.v1"
+	"net/url"
+	"testing"
+)
+
+func Test(t *testing.T) {
+	check.TestingT(t)
+}
+
+type S struct{}
+
+var _ = check.Suite(&S{})
+
+func (s *S) TestBuild(c *check.C) {
+	doc := policy.New(
+		policy.AllowStatement("sqs:SendMessage").
+			On("arn:aws:sqs:us-east-1:123456789012:queue1").
+			By(policy.Service("sns.amazonaws.com")).
+			When("ArnEquals", "aws:SourceArn", "arn:aws:sns:us-east-1:123456789012:topic1"),
+		policy.DenyStatement("s3:*").On("arn:aws:s3:::bucket", "arn:aws:s3:::bucket/*").By(policy.Everyone()),
+	)
+	c.Assert(doc.Validate(), check.IsNil)
+	c.Assert(doc.String(), check.Equals, `{"Version":"2012-10-17","Statement":[`+
+		`{"Effect":"Allow","Principal":{"Service":"sns.amazonaws.com"},"Action":"sqs:SendMessage",`+
+		`"Resource":"arn:aws:sqs:us-east-1:123456789012:queue1",`+
+		`"Condition":{"ArnEquals":{"aws:SourceArn":"arn:aws:sns:us-east-1:123456789012:topic1"}}},`+
+		`{"Effect":"Deny","Principal":"*","Action":"s3:*","Resource":["arn:aws:s3:::bucket","arn:aws:s3:::bucket/*"]}]}`)
+}
+
+const bucketPolicy = `{
+	"Version": "2012-10-17",
+	"Id": "Policy1",
+	"Statement": [{
+		"Sid": "Read",
+		"Effect": "Allow",
+		"Principal": {"AWS": ["123456789012", "arn:aws:iam::210987654321:user/alice"]},
+		"Action": ["s3:GetObject", "s3:List*"],
+		"Resource": "arn:aws:s3:::bucket/*",
+		"Condition": {
+			"IpAddress": {"aws:SourceIp": ["192.0.2.0/24", "203.0.113.0/24"]},
+			"Bool": {"aws:SecureTransport": true}
+		}
+	}, {
+		"Effect": "Deny",
+		"NotPrincipal": {"AWS": "*"},
+		"NotAction": "s3:GetObject",
+		"NotResource": ["arn:aws:s3:::bucket/public/*"]
+	}]
+}`
+
+func (s *S) TestParse(c *check.C) {
+	doc, err := policy.Parse(bucketPolicy)
+	c.Assert(err, check.IsNil)
+	c.Assert(doc, check.DeepEquals, &policy.Document{
+		Version: "2012-10-17",
+		Id:      "Policy1",
+		Statement: []policy.Statement{{
+			Sid:       "Read",
+			Effect:    policy.Allow,
+			Principal: &policy.Principal{AWS: []string{"123456789012", "arn:aws:iam::210987654321:user/alice"}},
+			Action:    policy.Action{"s3:GetObject", "s3:List*"},
+			Resource:  policy.Resource{"arn:aws:s3:::bucket/*"},
+			Condition: policy.Condition{
+				"IpAddress": {"aws:SourceIp": {"192.0.2.0/24", "203.0.113.0/24"}},
+				"Bool":      {"aws:SecureTransport": {"true"}},
+			},
+		}, {
+			Effect:       policy.Deny,
+			NotPrincipal: &policy.Principal{AWS: []string{"*"}},
+			NotAction:    policy.Action{"s3:GetObject"},
+			NotResource:  policy.Resource{"arn:aws:s3:::bucket/public/*"},
+		}},
+	})
+
+	again, err := policy.Parse(doc.String())
+	c.Assert(err, check.IsNil)
+	c.Assert(again, check.DeepEquals, doc)
+}
+
+func (s *S) TestParseSingleStatement(c *check.C) {
+	doc, err := policy.Parse(`{"Statement":{"Effect":"Allow","Action":"*","Resource":"*"}}`)
+	c.Assert(err, check.IsNil)
+	c.Assert(doc, check.DeepEquals, &policy.Document{
+		Statement: []policy.Statement{{
+			Effect:   policy.Allow,
+			Action:   policy.Action{"*"},
+			Resource: policy.Resource{"*"},
+		}},
+	})
+}
+
+func (s *S) TestParseURLEncoded(c *check.C) {
+	doc, err := policy.Parse(url.QueryEscape(bucketPolicy))
+	c.Assert(err, check.IsNil)
+	c.Assert(doc.Id, check.Equals, "Policy1")
+}
+
+var invalidPolicies = []struct {
+	doc, err string
+}{{
+	`{"Statement":[]}`,
+	`invalid policy: no statement`,
+}, {
+	`{"Version":"2014-01-01","Statement":[{"Effect":"Allow","Action":"*"}]}`,
+	`invalid policy: unknown version "2014-01-01"`,
+}, {
+	`{"Statement":[{"Effect":"Permit","Action":"*"}]}`,
+	`invalid policy: statement #1: invalid effect "Permit"`,
+}, {
+	`{"Statement":[{"Effect":"Allow","Resource":"*"}]}`,
+	`invalid policy: statement #1: missing Action`,
+}, {
+	`{"Statement":[{"Effect":"Allow","Action":"s3:*","NotAction":"s3:GetObject"}]}`,
+	`invalid policy: statement #1: both Action and NotAction are set`,
+}, {
+	`{"Statement":[{"Sid":"S1","Effect":"Allow","Action":"GetObject"}]}`,
+	`invalid policy: statement "S1": invalid action "GetObject"`,
+}, {
+	`{"Statement":[{"Effect":"Allow","Action":"*","Resource":"bucket/*"}]}`,
+	`invalid policy: statement #1: invalid resource "bucket/\*"`,
+}, {
+	`{"Statement":[{"Effect":"Allow","Action":"*","Principal":{"AWS":"alice"}}]}`,
+	`invalid policy: statement #1: invalid AWS principal "alice"`,
+}, {
+	`{"Statement":[{"Effect":"Allow","Action":"*","Principal":{"Group":"admins"}}]}`,
+	`invalid policy: unknown principal type "Group"`,
+}, {
+	`{"Statement":[{"Effect":"Allow","Action":"*","Condition":{"StringMatches":{"aws:UserAgent":"x"}}}]}`,
+	`invalid policy: statement #1: unknown condition operator "StringMatches"`,
+}, {
+	`{"Statement":[{"Sid":"A","Effect":"Allow","Action":"*"},{"Sid":"A","Effect":"Deny","Action":"*"}]}`,
+	`invalid policy: duplicate statement ID "A"`,
+}, {
+	`{"Statement":[{"Effect":"Allow","Action":{"s3":"*"}}]}`,
+	`invalid policy: expected a string or a list of strings, got {"s3":"\*"}`,
+}}
+
+func (s *S) TestParseInvalid(c *check.C) {
+	for _, t := range invalidPolicies {
+		_, err := policy.Parse(t.doc)
+		c.Check(err, check.ErrorMatches, t.err, check.Commentf("%s", t.doc))
+	}
+}
+
+func (s *S) TestConditionOperators(c *check.C) {
+	for _, op := range []string{"StringLike", "ForAnyValue:StringLike", "ForAllValues:StringEqualsIfExists", "NumericLessThanIfExists", "Null"} {
+		st := policy.AllowStatement("s3:GetObject").On("*").When(op, "s3:prefix", "home/")
+		c.Check(st.Validate(), check.IsNil, check.Commentf("%s", op))
+	}
+}
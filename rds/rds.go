@@ -1,6 +1,7 @@
 package rds
 
 import (
+	"context"
 	"encoding/xml"
 	"github.com/crowdmob/goamz/aws"
 	"log"
@@ -16,13 +17,18 @@ const (
 )
 
 // The RDS type encapsulates operations within a specific EC2 region.
+//
+// To send requests through a custom *http.Client, build the service with
+// aws.NewServiceWithScope, set its HTTPClient field and assign it to Service.
 type RDS struct {
 	Service aws.AWSService
+
+	aws.ContextBinding
 }
 
 // New creates a new RDS Client.
 func New(auth aws.Auth, region aws.Region) (*RDS, error) {
-	service, err := aws.NewService(auth, region.RDSEndpoint)
+	service, err := aws.NewServiceWithScope(auth, region.RDSEndpoint, ServiceName, region.Name)
 	if err != nil {
 		return nil, err
 	}
@@ -31,15 +37,37 @@ func New(auth aws.Auth, region aws.Region) (*RDS, error) {
 	}, nil
 }
 
+// NewFromConfig creates a new RDS Client with the credentials, endpoint,
+// HTTP client, retry policy and rate limiter of cfg.
+func NewFromConfig(cfg *aws.Config) (*RDS, error) {
+	service, err := aws.NewServiceWithScope(cfg.Auth, cfg.Region.RDSEndpoint, ServiceName, cfg.Region.Name)
+	if err != nil {
+		return nil, err
+	}
+	service.HTTPClient = cfg.HTTPClient
+	service.RetryPolicy = cfg.RetryPolicy(&aws.DefaultRetryPolicy)
+	service.Limiter = cfg.Limiter
+	return &RDS{Service: service}, nil
+}
+
+// WithContext returns a shallow copy of rds bound to ctx; see
+// aws.ContextBinding.
+func (rds *RDS) WithContext(ctx context.Context) *RDS {
+	c := *rds
+	c.ContextBinding = aws.BindContext(ctx)
+	return &c
+}
+
 // ----------------------------------------------------------------------------
 // Request dispatching logic.
 
-// query dispatches a request to the RDS API signed with a version 2 signature
+// query dispatches a request to the RDS API, signed as set by the Signer of
+// the endpoint: with a version 4 signature for the known regions.
 func (rds *RDS) query(method, path string, params map[string]string, resp interface{}) error {
 	// Add basic RDS param
 	params["Version"] = ApiVersion
 
-	r, err := rds.Service.Query(method, path, params)
+	r, err := rds.Service.QueryWithContext(rds.Context(), method, path, params)
 	if err != nil {
 		return err
 	}
@@ -94,3 +122,41 @@ func (rds *RDS) DescribeDBInstances(id string, maxRecords int, marker string) (*
 	err := rds.query("POST", "/", params, resp)
 	return resp, err
 }
+
+// DBInstancesIter iterates over the instances described by
+// DescribeDBInstances, fetching pages of results as they are needed.
+type DBInstancesIter struct {
+	pager *aws.Pager
+	page  *DescribeDBInstancesResponse
+}
+
+// DBInstancesIter returns an iterator over the database instances that
+// DescribeDBInstances with id describes, in all pages of results.
+func (rds *RDS) DBInstancesIter(id string) *DBInstancesIter {
+	iter := &DBInstancesIter{}
+	iter.pager = aws.NewPager(func(marker string) (int, string, error) {
+		resp, err := rds.DescribeDBInstances(id, 0, marker)
+		if err != nil {
+			return 0, "", err
+		}
+		iter.page = resp
+		return len(resp.DBInstances), resp.Marker, nil
+	})
+	return iter
+}
+
+// Next advances to the next instance. It returns false when there are
+// none left, or when describing them failed.
+func (iter *DBInstancesIter) Next() bool {
+	return iter.pager.Next()
+}
+
+// DBInstance returns the current instance.
+func (iter *DBInstancesIter) DBInstance() DBInstance {
+	return iter.page.DBInstances[iter.pager.Index()]
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (iter *DBInstancesIter) Err() error {
+	return iter.pager.Err()
+}
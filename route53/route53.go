@@ -2,10 +2,12 @@ package route53
 
 import (
 	"bytes"
+	"context"
 	"encoding/xml"
 	"fmt"
 	"github.com/crowdmob/goamz/aws"
 	"io"
+	"io/ioutil"
 	"net/http"
 )
 
@@ -14,6 +16,25 @@ type Route53 struct {
 	Endpoint string
 	Signer   *aws.Route53Signer
 	Service  *aws.Service
+
+	// HTTPClient is used to send requests to Route53. If nil,
+	// http.DefaultClient is used.
+	HTTPClient *http.Client
+
+	// RetryPolicy controls how failed requests are retried. If nil,
+	// aws.DefaultRetryPolicy is used.
+	RetryPolicy *aws.RetryPolicy
+
+	// Handlers holds the hooks run while sending requests. If nil,
+	// aws.DefaultHandlers is used.
+	Handlers *aws.Handlers
+
+	// Limiter, if set, limits the rate of the requests sent to Route53,
+	// and slows them down when Route53 throttles them. It may be shared
+	// between clients.
+	Limiter *aws.RateLimiter
+
+	aws.ContextBinding
 }
 
 const route53_host = "https://route53.amazonaws.com"
@@ -21,6 +42,7 @@ const route53_host = "https://route53.amazonaws.com"
 // Factory for the route53 type
 func NewRoute53(auth aws.Auth) (*Route53, error) {
 	signer := aws.NewRoute53Signer(auth)
+	signer.Clock = &aws.Clock{}
 
 	return &Route53{
 		Auth:     auth,
@@ -29,6 +51,32 @@ func NewRoute53(auth aws.Auth) (*Route53, error) {
 	}, nil
 }
 
+// NewFromConfig creates a new Route53 with the credentials, endpoint,
+// HTTP client, retry policy and rate limiter of cfg.
+func NewFromConfig(cfg *aws.Config) (*Route53, error) {
+	r, err := NewRoute53(cfg.Auth)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Resolver != nil {
+		if u, ok := cfg.Resolver.Overrides["route53"]; ok {
+			r.Endpoint = u + "/2013-04-01/hostedzone"
+		}
+	}
+	r.HTTPClient = cfg.HTTPClient
+	r.RetryPolicy = cfg.RetryPolicy(&aws.DefaultRetryPolicy)
+	r.Limiter = cfg.Limiter
+	return r, nil
+}
+
+// WithContext returns a shallow copy of r bound to ctx; see
+// aws.ContextBinding.
+func (r *Route53) WithContext(ctx context.Context) *Route53 {
+	c := *r
+	c.ContextBinding = aws.BindContext(ctx)
+	return &c
+}
+
 // General Structs used in all types of requests
 type HostedZones struct {
 	XMLName    xml.Name `xml:"HostedZones"`
@@ -127,19 +175,57 @@ type DeleteHostedZoneResponse struct {
 }
 
 // query sends the specified HTTP request to the path and signs the request
-// with the required authentication and headers based on the Auth.
+// with the required authentication and headers based on the Auth. The
+// operation names the request to the hooks of r.Handlers.
 //
 // Automatically decodes the response into the the result interface
-func (r *Route53) query(method string, path string, body io.Reader, result interface{}) error {
+func (r *Route53) query(operation, method string, path string, body io.Reader, result interface{}) error {
+	// The body is sent again by each attempt.
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = ioutil.ReadAll(body); err != nil {
+			return err
+		}
+	}
+	policy := aws.RetryPolicyOrDefault(r.RetryPolicy, &aws.DefaultRetryPolicy)
+	info := &aws.RequestInfo{Service: "route53", Operation: operation, Context: r.Context(), Clock: r.Signer.Clock, Limiter: r.Limiter}
+	return r.Handlers.Retry(policy, retryable, info, func() error {
+		var body io.Reader
+		if data != nil {
+			body = bytes.NewReader(data)
+		}
+		return r.send(info, method, path, body, result)
+	})
+}
+
+// retryable classifies the errors of failed Route53 requests for retries.
+func retryable(err error) (retry, throttled bool) {
+	if e, ok := err.(*aws.Error); ok {
+		return aws.ClassifyResponse(e.StatusCode, e.Code)
+	}
+	return aws.IsTransientNetError(err), false
+}
+
+func (r *Route53) send(info *aws.RequestInfo, method string, path string, body io.Reader, result interface{}) error {
 	var err error
 
+	// Report credentials that could not be refreshed before signing.
+	if _, err = r.Auth.Snapshot(); err != nil {
+		return err
+	}
+
 	// Create the POST request and sign the headers
 	req, err := http.NewRequest(method, path, body)
-	r.Signer.Sign(req)
+	if err != nil {
+		return err
+	}
 
 	// Send the request and capture the response
-	client := &http.Client{}
-	res, err := client.Do(req)
+	res, err := r.Handlers.Send(r.HTTPClient, info, req, func(req *http.Request) error {
+		r.Signer.Sign(req)
+		return nil
+	})
 	if err != nil {
 		return err
 	}
@@ -166,7 +252,7 @@ func (r *Route53) CreateHostedZone(hostedZoneReq *CreateHostedZoneRequest) (*Cre
 	}
 
 	result := new(CreateHostedZoneResponse)
-	err = r.query("POST", r.Endpoint, bytes.NewBuffer(xmlBytes), result)
+	err = r.query("CreateHostedZone", "POST", r.Endpoint, bytes.NewBuffer(xmlBytes), result)
 
 	return result, err
 }
@@ -181,7 +267,7 @@ func (r *Route53) ChangeResourceRecordSet(req *ChangeResourceRecordSetsRequest,
 
 	result := new(ChangeResourceRecordSetsResponse)
 	path := fmt.Sprintf("%s/%s/rrset", r.Endpoint, zoneId)
-	err = r.query("POST", path, bytes.NewBuffer(xmlBytes), result)
+	err = r.query("ChangeResourceRecordSets", "POST", path, bytes.NewBuffer(xmlBytes), result)
 
 	return result, err
 }
@@ -197,15 +283,57 @@ func (r *Route53) ListHostedZones(marker string, maxItems int) (result *ListHost
 	}
 
 	result = new(ListHostedZonesResponse)
-	err = r.query("GET", path, nil, result)
+	err = r.query("ListHostedZones", "GET", path, nil, result)
 
 	return
 }
 
+// HostedZonesIter iterates over the hosted zones listed by
+// ListHostedZones, fetching pages of results as they are needed.
+type HostedZonesIter struct {
+	pager *aws.Pager
+	page  *ListHostedZonesResponse
+}
+
+// HostedZonesIter returns an iterator over all the hosted zones of the
+// account, fetched maxItems at a time.
+func (r *Route53) HostedZonesIter(maxItems int) *HostedZonesIter {
+	iter := &HostedZonesIter{}
+	iter.pager = aws.NewPager(func(marker string) (int, string, error) {
+		resp, err := r.ListHostedZones(marker, maxItems)
+		if err != nil {
+			return 0, "", err
+		}
+		iter.page = resp
+		next := ""
+		if resp.IsTruncated {
+			next = resp.NextMarker
+		}
+		return len(resp.HostedZones), next, nil
+	})
+	return iter
+}
+
+// Next advances to the next hosted zone. It returns false when there are
+// none left, or when listing failed.
+func (iter *HostedZonesIter) Next() bool {
+	return iter.pager.Next()
+}
+
+// HostedZone returns the current hosted zone.
+func (iter *HostedZonesIter) HostedZone() HostedZones {
+	return iter.page.HostedZones[iter.pager.Index()]
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (iter *HostedZonesIter) Err() error {
+	return iter.pager.Err()
+}
+
 // GetHostedZone fetches a particular hostedzones DelegationSet by id
 func (r *Route53) GetHostedZone(id string) (result *GetHostedZoneResponse, err error) {
 	result = new(GetHostedZoneResponse)
-	err = r.query("GET", fmt.Sprintf("%s/%v", r.Endpoint, id), nil, result)
+	err = r.query("GetHostedZone", "GET", fmt.Sprintf("%s/%v", r.Endpoint, id), nil, result)
 
 	return
 }
@@ -215,7 +343,7 @@ func (r *Route53) DeleteHostedZone(id string) (result *DeleteHostedZoneResponse,
 	path := fmt.Sprintf("%s/%s", r.Endpoint, id)
 
 	result = new(DeleteHostedZoneResponse)
-	err = r.query("DELETE", path, nil, result)
+	err = r.query("DeleteHostedZone", "DELETE", path, nil, result)
 
 	return
 }
@@ -0,0 +1,142 @@
+package s3
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// A Checkpoint records the progress of a multipart upload sent by
+// Uploader.UploadAt, for the upload to be resumed.
+type Checkpoint struct {
+	Bucket   string
+	Key      string
+	UploadId string
+
+	// Size is the size of the object, and PartSize that of its parts.
+	Size     int64
+	PartSize int64
+
+	// Parts holds the parts sent, in the order they were.
+	Parts []CheckpointPart
+}
+
+// CheckpointPart records a part sent.
+type CheckpointPart struct {
+	N      int
+	ETag   string
+	Offset int64
+	Size   int64
+}
+
+// A CheckpointStore saves the checkpoints of uploads, keyed by bucket
+// name and object key.
+type CheckpointStore interface {
+	// Load returns the checkpoint of the upload to key in bucket, or
+	// nil if there is none.
+	Load(bucket, key string) (*Checkpoint, error)
+
+	// Save replaces the checkpoint of the upload cp records.
+	Save(cp *Checkpoint) error
+
+	// Delete deletes the checkpoint of the upload to key in bucket,
+	// if any.
+	Delete(bucket, key string) error
+}
+
+// FileCheckpointStore is a CheckpointStore keeping each checkpoint as a
+// JSON file in a directory.
+type FileCheckpointStore struct {
+	// Dir is the directory holding the files. If empty, the
+	// goamz/s3-checkpoints directory in the user's cache directory is
+	// used (see os.UserCacheDir).
+	Dir string
+}
+
+// filename returns the name of the file holding the checkpoint of the
+// upload to key in bucket.
+func (s FileCheckpointStore) filename(bucket, key string) (string, error) {
+	dir := s.Dir
+	if dir == "" {
+		cache, err := os.UserCacheDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(cache, "goamz", "s3-checkpoints")
+	}
+	sum := sha1.Sum([]byte(bucket + "/" + key))
+	return filepath.Join(dir, hex.EncodeToString(sum[:])+".json"), nil
+}
+
+// Load implements CheckpointStore.
+func (s FileCheckpointStore) Load(bucket, key string) (*Checkpoint, error) {
+	filename, err := s.filename(bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cp Checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, err
+	}
+	if cp.Bucket != bucket || cp.Key != key {
+		return nil, nil
+	}
+	return &cp, nil
+}
+
+// Save implements CheckpointStore. The file is replaced atomically, so
+// that a crash while saving leaves the previous checkpoint.
+func (s FileCheckpointStore) Save(cp *Checkpoint) error {
+	filename, err := s.filename(cp.Bucket, cp.Key)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(filename)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(dir, ".checkpoint-")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), filename)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// Delete implements CheckpointStore.
+func (s FileCheckpointStore) Delete(bucket, key string) error {
+	filename, err := s.filename(bucket, key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package s3_test
+
+import (
+	"github.com/crowdmob/goamz/s3"
+	"gopkg.in/check.v1"
+	"io/ioutil"
+)
+
+func (s *S) TestFileCheckpointStore(c *check.C) {
+	store := s3.FileCheckpointStore{Dir: c.MkDir()}
+	cp, err := store.Load("bucket", "key")
+	c.Assert(err, check.IsNil)
+	c.Assert(cp, check.IsNil)
+
+	saved := &s3.Checkpoint{
+		Bucket:   "bucket",
+		Key:      "key",
+		UploadId: "upload",
+		Size:     13,
+		PartSize: 5,
+		Parts:    []s3.CheckpointPart{{N: 2, ETag: `"etag2"`, Offset: 5, Size: 5}},
+	}
+	c.Assert(store.Save(saved), check.IsNil)
+	saved.Parts = append(saved.Parts, s3.CheckpointPart{N: 1, ETag: `"etag1"`, Offset: 0, Size: 5})
+	c.Assert(store.Save(saved), check.IsNil)
+	cp, err = store.Load("bucket", "key")
+	c.Assert(err, check.IsNil)
+	c.Assert(cp, check.DeepEquals, saved)
+	cp, err = store.Load("bucket", "other")
+	c.Assert(err, check.IsNil)
+	c.Assert(cp, check.IsNil)
+	files, err := ioutil.ReadDir(store.Dir)
+	c.Assert(err, check.IsNil)
+	c.Assert(files, check.HasLen, 1)
+
+	c.Assert(store.Delete("bucket", "key"), check.IsNil)
+	c.Assert(store.Delete("bucket", "key"), check.IsNil)
+	cp, err = store.Load("bucket", "key")
+	c.Assert(err, check.IsNil)
+	c.Assert(cp, check.IsNil)
+}
@@ -0,0 +1,260 @@
+package s3
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/crowdmob/goamz/aws"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const defaultCopyPartSize = 128 << 20
+
+// A Copier copies objects within S3 through multipart uploads whose parts
+// are copied concurrently from ranges of the source object, as needed for
+// objects larger than the 5GB PutCopy is limited to. The source object
+// may be in a bucket of another region:
+//
+//	src := s3.New(auth, aws.USWest2).Bucket("lake-raw")
+//	c := &s3.Copier{Bucket: s3.New(auth, aws.EUWest).Bucket("lake")}
+//	err := c.Copy("2014/events.parquet", s3.Private, s3.CopyOptions{}, src, "events.parquet")
+type Copier struct {
+	// Bucket is the bucket objects are copied to.
+	Bucket *Bucket
+
+	// PartSize is the size of the parts copied. If zero, 128MB parts
+	// are. It is raised so that objects fit in the 10,000 parts a
+	// multipart upload may have.
+	PartSize int64
+
+	// Concurrency is the number of parts copied at once. If zero, 5
+	// parts are.
+	Concurrency int
+
+	// RetryPolicy controls how the requests copying a part are
+	// retried. If nil, the policy of the bucket's client is used.
+	RetryPolicy *aws.RetryPolicy
+}
+
+// copiedHeaders holds the headers of the source object that Copy keeps
+// with its metadata.
+var copiedHeaders = []string{
+	"Cache-Control",
+	"Content-Disposition",
+	"Content-Encoding",
+	"Content-Language",
+	"Content-Type",
+	"Expires",
+	"x-amz-website-redirect-location",
+}
+
+// Copy copies the object at sourcePath in source to path in c.Bucket,
+// with the ACL perm.
+//
+// As with PutCopy, the content type and metadata of the source object are
+// kept, unless options.MetadataDirective is "REPLACE" and those given by
+// options are used instead. The source object is pinned by its ETag, so
+// that Copy fails with a PreconditionFailed error if it is replaced while
+// being copied. If a part cannot be copied, the other parts are
+// cancelled, the multipart upload is aborted and the error is returned.
+func (c *Copier) Copy(path string, perm ACL, options CopyOptions, source *Bucket, sourcePath string) error {
+	resp, err := source.Head(sourcePath, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	size, err := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64)
+	if err != nil {
+		return fmt.Errorf("s3: invalid Content-Length for %q: %v", sourcePath, err)
+	}
+	partSize := c.PartSize
+	if partSize <= 0 {
+		partSize = defaultCopyPartSize
+	}
+	if partSize < minPartSize {
+		partSize = minPartSize
+	}
+	if partSize, err = fixedPartSize(partSize, size); err != nil {
+		return err
+	}
+
+	headers := map[string][]string{
+		"x-amz-acl": {string(perm)},
+	}
+	if options.MetadataDirective == "REPLACE" {
+		// The checksum of the object is not known before the parts
+		// are copied.
+		options.ContentMD5 = ""
+		options.Options.addHeaders(headers)
+		if options.ContentType != "" {
+			headers["Content-Type"] = []string{options.ContentType}
+		}
+	} else {
+		copyMetadata(headers, resp.Header)
+		if options.SSE {
+			headers["x-amz-server-side-encryption"] = []string{"AES256"}
+		}
+	}
+	m, err := c.Bucket.initMulti(path, headers)
+	if err != nil {
+		return err
+	}
+	copySource := source.Name + "/" + escapePath(sourcePath)
+	parts, err := c.copyParts(m, copySource, resp.Header.Get("ETag"), size, partSize)
+	if err == nil {
+		err = m.Complete(parts)
+	}
+	if err != nil {
+		// Abort even when the copy was cancelled, so that the parts
+		// copied do not linger.
+		m.WithContext(context.Background()).Abort()
+		return err
+	}
+	return nil
+}
+
+// copyMetadata adds to headers the headers of an object, given by h,
+// that Copy keeps.
+func copyMetadata(headers map[string][]string, h http.Header) {
+	for _, name := range copiedHeaders {
+		if v := h.Get(name); v != "" {
+			headers[name] = []string{v}
+		}
+	}
+	for name, v := range h {
+		if strings.HasPrefix(strings.ToLower(name), "x-amz-meta-") {
+			headers[name] = v
+		}
+	}
+}
+
+// copyParts copies the size bytes of the object copySource to the parts
+// of m, and returns them ordered by part number.
+func (c *Copier) copyParts(m *Multi, copySource, etag string, size, partSize int64) ([]Part, error) {
+	m, ctx, cancel := m.sender(c.RetryPolicy)
+	defer cancel()
+	concurrency := c.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+
+	var (
+		mu     sync.Mutex
+		parts  partSlice
+		failed error
+	)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range jobs {
+				if ctx.Err() != nil {
+					continue
+				}
+				offset := int64(n-1) * partSize
+				partLen := partSize
+				if offset+partLen > size {
+					partLen = size - offset
+				}
+				part, err := m.copyPart(n, copySource, etag, offset, partLen)
+				mu.Lock()
+				if err == nil {
+					parts = append(parts, part)
+				} else if failed == nil {
+					failed = err
+					cancel()
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	count := int((size + partSize - 1) / partSize)
+	if count == 0 {
+		// An empty object is copied as a single empty part.
+		count = 1
+	}
+Send:
+	for n := 1; n <= count; n++ {
+		select {
+		case jobs <- n:
+		case <-ctx.Done():
+			break Send
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if failed != nil {
+		return nil, failed
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	sort.Sort(parts)
+	return parts, nil
+}
+
+// copyPartResult holds the response to a part copy, which may be an
+// error.
+type copyPartResult struct {
+	XMLName xml.Name
+	ETag    string
+	Code    string
+	Message string
+}
+
+// copyPart copies the size bytes of the object copySource starting at
+// offset to part n of m. The copy is made only while the ETag of the
+// source object is etag.
+//
+// See https://docs.aws.amazon.com/AmazonS3/latest/API/API_UploadPartCopy.html
+// for details.
+func (m *Multi) copyPart(n int, copySource, etag string, offset, size int64) (Part, error) {
+	headers := map[string][]string{
+		"x-amz-copy-source": {copySource},
+	}
+	if size > 0 {
+		headers["x-amz-copy-source-range"] = []string{fmt.Sprintf("bytes=%d-%d", offset, offset+size-1)}
+	}
+	if etag != "" {
+		headers["x-amz-copy-source-if-match"] = []string{etag}
+	}
+	params := map[string][]string{
+		"uploadId":   {m.UploadId},
+		"partNumber": {strconv.FormatInt(int64(n), 10)},
+	}
+	req := &request{
+		method:  "PUT",
+		bucket:  m.Bucket.Name,
+		path:    m.Key,
+		headers: headers,
+		params:  params,
+	}
+	var resp copyPartResult
+	err := m.Bucket.retry(req, func() error {
+		resp = copyPartResult{}
+		if err := m.Bucket.S3.query(req, &resp); err != nil {
+			return err
+		}
+		if resp.XMLName.Local == "Error" {
+			// As when completing uploads, S3 may report failures
+			// after answering 200.
+			return &Error{StatusCode: 200, Code: resp.Code, Message: resp.Message}
+		}
+		return nil
+	})
+	if err != nil {
+		return Part{}, err
+	}
+	if resp.ETag == "" {
+		return Part{}, errors.New("part copy succeeded with no ETag")
+	}
+	return Part{n, resp.ETag, size}, nil
+}
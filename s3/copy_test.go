@@ -0,0 +1,95 @@
+package s3_test
+
+import (
+	"encoding/xml"
+	"github.com/crowdmob/goamz/s3"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestCopierCopy(c *check.C) {
+	s3.SetMinPartSize(5)
+	defer s3.SetMinPartSize(5 << 20)
+	testServer.Response(200, map[string]string{
+		"Content-Length":    "13",
+		"Content-Type":      "text/csv",
+		"ETag":              `"source"`,
+		"X-Amz-Meta-Colour": "blue",
+	}, "")
+	testServer.Response(200, nil, InitMultiResultDump)
+	// S3 may fail after answering 200; the part is copied again.
+	testServer.Response(200, nil, InternalErrorDump)
+	testServer.Responses(3, 200, nil, CopyPartResultDump)
+	testServer.Response(200, nil, "")
+
+	cp := &s3.Copier{Bucket: s.s3.Bucket("dest"), PartSize: 5, Concurrency: 1}
+	err := cp.Copy("copy", s3.PublicRead, s3.CopyOptions{ContentType: "ignored"}, s.s3.Bucket("src"), "a b/c")
+	c.Assert(err, check.IsNil)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Method, check.Equals, "HEAD")
+	c.Assert(req.URL.Path, check.Equals, "/src/a b/c")
+
+	req = testServer.WaitRequest()
+	c.Assert(req.Method, check.Equals, "POST")
+	c.Assert(req.URL.Path, check.Equals, "/dest/copy")
+	c.Assert(req.Header["Content-Type"], check.DeepEquals, []string{"text/csv"})
+	c.Assert(req.Header["X-Amz-Meta-Colour"], check.DeepEquals, []string{"blue"})
+	c.Assert(req.Header["X-Amz-Acl"], check.DeepEquals, []string{"public-read"})
+
+	var ranges []string
+	for _, req := range testServer.WaitRequests(4) {
+		c.Assert(req.Method, check.Equals, "PUT")
+		c.Assert(req.URL.Path, check.Equals, "/dest/copy")
+		c.Assert(req.Form.Get("uploadId"), check.Matches, "JNbR_[A-Za-z0-9.]+QQ--")
+		c.Assert(req.Header.Get("X-Amz-Copy-Source"), check.Equals, "src/a%20b/c")
+		c.Assert(req.Header.Get("X-Amz-Copy-Source-If-Match"), check.Equals, `"source"`)
+		ranges = append(ranges, req.Form.Get("partNumber")+" "+req.Header.Get("X-Amz-Copy-Source-Range"))
+	}
+	c.Assert(ranges, check.DeepEquals, []string{"1 bytes=0-4", "1 bytes=0-4", "2 bytes=5-9", "3 bytes=10-12"})
+
+	req = testServer.WaitRequest()
+	c.Assert(req.Method, check.Equals, "POST")
+	var payload struct {
+		Part []struct {
+			PartNumber int
+			ETag       string
+		}
+	}
+	c.Assert(xml.NewDecoder(req.Body).Decode(&payload), check.IsNil)
+	c.Assert(payload.Part, check.HasLen, 3)
+	c.Assert(payload.Part[2].ETag, check.Equals, `"etag"`)
+}
+
+func (s *S) TestCopierReplaceAbort(c *check.C) {
+	testServer.Response(200, map[string]string{
+		"Content-Length":    "13",
+		"Content-Type":      "text/csv",
+		"X-Amz-Meta-Colour": "blue",
+	}, "")
+	testServer.Response(200, nil, InitMultiResultDump)
+	testServer.Response(412, nil, PreconditionFailedErrorDump)
+	testServer.Response(204, nil, "")
+
+	options := s3.CopyOptions{
+		Options:           s3.Options{Meta: map[string][]string{"shape": {"round"}}, SSE: true},
+		MetadataDirective: "REPLACE",
+		ContentType:       "text/plain",
+	}
+	cp := &s3.Copier{Bucket: s.s3.Bucket("dest")}
+	err := cp.Copy("copy", s3.Private, options, s.s3.Bucket("src"), "name")
+	c.Assert(err, check.ErrorMatches, "At least one of the pre-conditions you specified did not hold")
+
+	testServer.WaitRequest()
+	req := testServer.WaitRequest()
+	c.Assert(req.Header["Content-Type"], check.DeepEquals, []string{"text/plain"})
+	c.Assert(req.Header["X-Amz-Meta-Shape"], check.DeepEquals, []string{"round"})
+	c.Assert(req.Header["X-Amz-Meta-Colour"], check.IsNil)
+	c.Assert(req.Header["X-Amz-Server-Side-Encryption"], check.DeepEquals, []string{"AES256"})
+	c.Assert(req.Header["X-Amz-Metadata-Directive"], check.IsNil)
+
+	req = testServer.WaitRequest()
+	c.Assert(req.Header.Get("X-Amz-Copy-Source-Range"), check.Equals, "bytes=0-12")
+	req = testServer.WaitRequest()
+	c.Assert(req.Method, check.Equals, "DELETE")
+	c.Assert(req.Form.Get("uploadId"), check.Matches, "JNbR_[A-Za-z0-9.]+QQ--")
+}
@@ -0,0 +1,266 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"github.com/crowdmob/goamz/aws"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+const defaultRangeSize = 8 << 20
+
+// A Downloader downloads objects through ranged requests sent
+// concurrently, as is faster than reading large objects through a single
+// connection with GetReader:
+//
+//	f, err := os.Create("backup.tar")
+//	...
+//	d := &s3.Downloader{Bucket: b, Concurrency: 8}
+//	n, err := d.Download("backups/today.tar", f)
+type Downloader struct {
+	Bucket *Bucket
+
+	// PartSize is the size of the ranges requested. If zero, 8MB
+	// ranges are.
+	PartSize int64
+
+	// Concurrency is the number of ranges requested at once. If zero,
+	// 5 ranges are.
+	Concurrency int
+
+	// RetryPolicy controls how a range is requested again when reading
+	// its content fails. If nil, the policy of the bucket's client is
+	// used.
+	RetryPolicy *aws.RetryPolicy
+}
+
+// Download writes the object at path to w, and returns its size.
+//
+// The ETag of the object is pinned by the ranged requests, so that
+// Download fails with a PreconditionFailed error if the object is
+// replaced while it is being downloaded. If a range cannot be downloaded,
+// the other ranges are cancelled and the error is returned.
+func (d *Downloader) Download(path string, w io.WriterAt) (int64, error) {
+	ctx, cancel := context.WithCancel(d.Bucket.S3.Context())
+	defer cancel()
+	r, err := d.Bucket.WithContext(ctx).GetReaderAt(path)
+	if err != nil {
+		return 0, err
+	}
+	if d.RetryPolicy != nil {
+		r.policy = d.RetryPolicy
+	}
+	rangeSize := d.PartSize
+	if rangeSize <= 0 {
+		rangeSize = defaultRangeSize
+	}
+	concurrency := d.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+
+	var (
+		mu      sync.Mutex
+		written int64
+		failed  error
+	)
+	offsets := make(chan int64)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for off := range offsets {
+				n := rangeSize
+				if off+n > r.size {
+					n = r.size - off
+				}
+				err := r.readRange(w, off, off, n)
+				mu.Lock()
+				if err == nil {
+					written += n
+				} else if failed == nil {
+					failed = err
+					cancel()
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+Send:
+	for off := int64(0); off < r.size; off += rangeSize {
+		select {
+		case offsets <- off:
+		case <-ctx.Done():
+			break Send
+		}
+	}
+	close(offsets)
+	wg.Wait()
+
+	if failed != nil {
+		return 0, failed
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if written != r.size {
+		return 0, fmt.Errorf("s3: downloaded %d bytes of %q, expected %d", written, path, r.size)
+	}
+	return written, nil
+}
+
+// An ObjectReader reads ranges of an S3 object, giving random access to
+// it without downloading all of it, as when reading the index at the end
+// of a zip file:
+//
+//	r, err := b.GetReaderAt("archive.zip")
+//	...
+//	z, err := zip.NewReader(r, r.Size())
+//
+// Its ReadAt method may be called concurrently. As with the Downloader,
+// reads fail with a PreconditionFailed error once the object is replaced.
+type ObjectReader struct {
+	bucket *Bucket
+	path   string
+	size   int64
+	etag   string
+	policy *aws.RetryPolicy
+}
+
+// GetReaderAt returns an ObjectReader for the object at path.
+func (b *Bucket) GetReaderAt(path string) (*ObjectReader, error) {
+	resp, err := b.Head(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	size, err := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("s3: invalid Content-Length for %q: %v", path, err)
+	}
+	return &ObjectReader{
+		bucket: b,
+		path:   path,
+		size:   size,
+		etag:   resp.Header.Get("ETag"),
+		policy: aws.RetryPolicyOrDefault(b.S3.RetryPolicy, &DefaultRetryPolicy),
+	}, nil
+}
+
+// Size returns the size of the object.
+func (r *ObjectReader) Size() int64 {
+	return r.size
+}
+
+// ETag returns the ETag of the object.
+func (r *ObjectReader) ETag() string {
+	return r.etag
+}
+
+// ReadAt reads len(p) bytes of the object starting at offset off.
+func (r *ObjectReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("s3: negative offset %d", off)
+	}
+	if off >= r.size {
+		return 0, io.EOF
+	}
+	n := int64(len(p))
+	if off+n > r.size {
+		n = r.size - off
+	}
+	if n > 0 {
+		if err := r.readRange(bufferAt(p), 0, off, n); err != nil {
+			return 0, err
+		}
+	}
+	if n < int64(len(p)) {
+		return int(n), io.EOF
+	}
+	return int(n), nil
+}
+
+// readRange writes the n bytes of the object starting at offset off to
+// w at offset at. The range is requested again while reading it fails
+// with transient errors; failed requests are retried by the bucket.
+func (r *ObjectReader) readRange(w io.WriterAt, at, off, n int64) error {
+	headers := map[string][]string{
+		"Range": {fmt.Sprintf("bytes=%d-%d", off, off+n-1)},
+	}
+	if r.etag != "" {
+		headers["If-Match"] = []string{r.etag}
+	}
+	contentRange := fmt.Sprintf("bytes %d-%d/%d", off, off+n-1, r.size)
+	return r.policy.Do(r.bucket.S3.Context(), retryableRead, func() error {
+		resp, err := r.bucket.GetResponseWithHeaders(r.path, headers)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusPartialContent || resp.Header.Get("Content-Range") != contentRange {
+			return fmt.Errorf("s3: got range %q of %q, expected %q", resp.Header.Get("Content-Range"), r.path, contentRange)
+		}
+		read, err := io.Copy(&offsetWriter{w, at}, bodyReader{io.LimitReader(resp.Body, n)})
+		if err == nil && read < n {
+			err = &readError{io.ErrUnexpectedEOF}
+		}
+		return err
+	})
+}
+
+// readError is returned when reading the body of a response fails.
+type readError struct {
+	err error
+}
+
+func (e *readError) Error() string {
+	return e.err.Error()
+}
+
+// retryableRead classifies the errors of readRange: the failures of the
+// requests have been retried already, but a response may be cut short.
+func retryableRead(err error) (retry, throttled bool) {
+	if e, ok := err.(*readError); ok {
+		return aws.IsTransientNetError(e.err), false
+	}
+	return false, false
+}
+
+// bodyReader marks the errors of reading a response body as readErrors.
+type bodyReader struct {
+	r io.Reader
+}
+
+func (b bodyReader) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	if err != nil && err != io.EOF {
+		err = &readError{err}
+	}
+	return n, err
+}
+
+// offsetWriter writes to w from offset off onwards.
+type offsetWriter struct {
+	w   io.WriterAt
+	off int64
+}
+
+func (o *offsetWriter) Write(p []byte) (int, error) {
+	n, err := o.w.WriteAt(p, o.off)
+	o.off += int64(n)
+	return n, err
+}
+
+// bufferAt is an io.WriterAt writing into a byte slice.
+type bufferAt []byte
+
+func (b bufferAt) WriteAt(p []byte, off int64) (int, error) {
+	if off+int64(len(p)) > int64(len(b)) {
+		return 0, io.ErrShortWrite
+	}
+	return copy(b[off:], p), nil
+}
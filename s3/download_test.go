@@ -0,0 +1,80 @@
+package s3_test
+
+import (
+	"github.com/crowdmob/goamz/aws"
+	"github.com/crowdmob/goamz/s3"
+	"gopkg.in/check.v1"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+func (s *S) TestDownloaderDownload(c *check.C) {
+	testServer.Response(200, map[string]string{"Content-Length": "10", "ETag": `"etag"`}, "")
+	// The first range is cut short, and requested again.
+	testServer.Response(206, map[string]string{"Content-Range": "bytes 0-3/10"}, "01")
+	testServer.Response(206, map[string]string{"Content-Range": "bytes 0-3/10"}, "0123")
+	testServer.Response(206, map[string]string{"Content-Range": "bytes 4-7/10"}, "4567")
+	testServer.Response(206, map[string]string{"Content-Range": "bytes 8-9/10"}, "89")
+
+	f, err := os.Create(filepath.Join(c.MkDir(), "object"))
+	c.Assert(err, check.IsNil)
+	defer f.Close()
+	d := &s3.Downloader{
+		Bucket:      s.s3.Bucket("bucket"),
+		PartSize:    4,
+		Concurrency: 1,
+		RetryPolicy: &aws.RetryPolicy{MaxAttempts: 2},
+	}
+	n, err := d.Download("name", f)
+	c.Assert(err, check.IsNil)
+	c.Assert(n, check.Equals, int64(10))
+	data, err := ioutil.ReadFile(f.Name())
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, "0123456789")
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Method, check.Equals, "HEAD")
+	var ranges []string
+	for _, req := range testServer.WaitRequests(4) {
+		c.Assert(req.Method, check.Equals, "GET")
+		c.Assert(req.URL.Path, check.Equals, "/bucket/name")
+		c.Assert(req.Header.Get("If-Match"), check.Equals, `"etag"`)
+		ranges = append(ranges, req.Header.Get("Range"))
+	}
+	c.Assert(ranges, check.DeepEquals, []string{"bytes=0-3", "bytes=0-3", "bytes=4-7", "bytes=8-9"})
+}
+
+func (s *S) TestDownloaderChanged(c *check.C) {
+	testServer.Response(200, map[string]string{"Content-Length": "10", "ETag": `"etag"`}, "")
+	testServer.Response(412, nil, PreconditionFailedErrorDump)
+
+	d := &s3.Downloader{Bucket: s.s3.Bucket("bucket"), PartSize: 10}
+	_, err := d.Download("name", nil)
+	c.Assert(err, check.ErrorMatches, "At least one of the pre-conditions you specified did not hold")
+	c.Assert(err.(*s3.Error).StatusCode, check.Equals, 412)
+	testServer.WaitRequests(2)
+}
+
+func (s *S) TestObjectReader(c *check.C) {
+	testServer.Response(200, map[string]string{"Content-Length": "10", "ETag": `"etag"`}, "")
+	testServer.Response(206, map[string]string{"Content-Range": "bytes 6-9/10"}, "6789")
+
+	r, err := s.s3.Bucket("bucket").GetReaderAt("name")
+	c.Assert(err, check.IsNil)
+	c.Assert(r.Size(), check.Equals, int64(10))
+	c.Assert(r.ETag(), check.Equals, `"etag"`)
+
+	p := make([]byte, 8)
+	n, err := r.ReadAt(p, 6)
+	c.Assert(err, check.Equals, io.EOF)
+	c.Assert(string(p[:n]), check.Equals, "6789")
+	n, err = r.ReadAt(p, 10)
+	c.Assert(err, check.Equals, io.EOF)
+	c.Assert(n, check.Equals, 0)
+
+	testServer.WaitRequest()
+	req := testServer.WaitRequest()
+	c.Assert(req.Header.Get("Range"), check.Equals, "bytes=6-9")
+}
@@ -4,13 +4,13 @@ import (
 	"github.com/crowdmob/goamz/aws"
 )
 
-var originalStrategy = attempts
+var originalRetryPolicy = DefaultRetryPolicy
 
-func SetAttemptStrategy(s *aws.AttemptStrategy) {
-	if s == nil {
-		attempts = originalStrategy
+func SetRetryPolicy(p *aws.RetryPolicy) {
+	if p == nil {
+		DefaultRetryPolicy = originalRetryPolicy
 	} else {
-		attempts = *s
+		DefaultRetryPolicy = *p
 	}
 }
 
@@ -25,3 +25,11 @@ func SetListPartsMax(n int) {
 func SetListMultiMax(n int) {
 	listMultiMax = n
 }
+
+func SetMinPartSize(n int64) {
+	minPartSize = n
+}
+
+func UploaderPartSize(u *Uploader, n int) int64 {
+	return u.partSize(n)
+}
@@ -2,14 +2,17 @@ package s3
 
 import (
 	"bytes"
+	"context"
 	"crypto/md5"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/xml"
 	"errors"
 	"io"
+	"net/http"
 	"sort"
 	"strconv"
+	"time"
 )
 
 // Multi represents an unfinished multipart upload.
@@ -23,6 +26,17 @@ type Multi struct {
 	Bucket   *Bucket
 	Key      string
 	UploadId string
+
+	// Initiated holds when the upload was initiated, for the uploads
+	// returned by ListMulti.
+	Initiated time.Time
+}
+
+// WithContext returns a copy of m whose operations are bound to ctx.
+func (m *Multi) WithContext(ctx context.Context) *Multi {
+	c := *m
+	c.Bucket = m.Bucket.WithContext(ctx)
+	return &c
 }
 
 // That's the default. Here just for testing.
@@ -55,17 +69,16 @@ func (b *Bucket) ListMulti(prefix, delim string) (multis []*Multi, prefixes []st
 		"prefix":      {prefix},
 		"delimiter":   {delim},
 	}
-	for attempt := attempts.Start(); attempt.Next(); {
+	for {
 		req := &request{
 			method: "GET",
 			bucket: b.Name,
 			params: params,
 		}
 		var resp listMultiResp
-		err := b.S3.query(req, &resp)
-		if shouldRetry(err) && attempt.HasNext() {
-			continue
-		}
+		err := b.retry(req, func() error {
+			return b.S3.query(req, &resp)
+		})
 		if err != nil {
 			return nil, nil, err
 		}
@@ -80,9 +93,44 @@ func (b *Bucket) ListMulti(prefix, delim string) (multis []*Multi, prefixes []st
 		}
 		params["key-marker"] = []string{resp.NextKeyMarker}
 		params["upload-id-marker"] = []string{resp.NextUploadIdMarker}
-		attempt = attempts.Start() // Last request worked.
 	}
-	panic("unreachable")
+}
+
+// ListOrphanedMulti returns the unfinished multipart uploads in b for keys
+// beginning with prefix that were initiated before cutoff, such as the
+// uploads left behind by crashed processes.
+func (b *Bucket) ListOrphanedMulti(prefix string, cutoff time.Time) ([]*Multi, error) {
+	multis, _, err := b.ListMulti(prefix, "")
+	if err != nil {
+		return nil, err
+	}
+	var orphans []*Multi
+	for _, m := range multis {
+		if m.Initiated.Before(cutoff) {
+			orphans = append(orphans, m)
+		}
+	}
+	return orphans, nil
+}
+
+// AbortOrphanedMulti aborts the uploads returned by ListOrphanedMulti, and
+// returns those it aborted. It tries to abort all of them, and returns the
+// first error met.
+func (b *Bucket) AbortOrphanedMulti(prefix string, cutoff time.Time) (aborted []*Multi, err error) {
+	orphans, err := b.ListOrphanedMulti(prefix, cutoff)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range orphans {
+		if aerr := m.Abort(); aerr != nil {
+			if err == nil {
+				err = aerr
+			}
+			continue
+		}
+		aborted = append(aborted, m)
+	}
+	return aborted, err
 }
 
 // Multi returns a multipart upload handler for the provided key
@@ -107,10 +155,16 @@ func (b *Bucket) Multi(key, contType string, perm ACL) (*Multi, error) {
 // See http://goo.gl/XP8kL for details.
 func (b *Bucket) InitMulti(key string, contType string, perm ACL) (*Multi, error) {
 	headers := map[string][]string{
-		"Content-Type":   {contType},
-		"Content-Length": {"0"},
-		"x-amz-acl":      {string(perm)},
+		"Content-Type": {contType},
+		"x-amz-acl":    {string(perm)},
 	}
+	return b.initMulti(key, headers)
+}
+
+// initMulti initializes a new multipart upload at key with the object
+// headers given, such as its content type and metadata.
+func (b *Bucket) initMulti(key string, headers map[string][]string) (*Multi, error) {
+	headers["Content-Length"] = []string{"0"}
 	params := map[string][]string{
 		"uploads": {""},
 	}
@@ -125,12 +179,9 @@ func (b *Bucket) InitMulti(key string, contType string, perm ACL) (*Multi, error
 	var resp struct {
 		UploadId string `xml:"UploadId"`
 	}
-	for attempt := attempts.Start(); attempt.Next(); {
-		err = b.S3.query(req, &resp)
-		if !shouldRetry(err) {
-			break
-		}
-	}
+	err = b.retry(req, func() error {
+		return b.S3.query(req, &resp)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -158,37 +209,35 @@ func (m *Multi) putPart(n int, r io.ReadSeeker, partSize int64, md5b64 string) (
 		"uploadId":   {m.UploadId},
 		"partNumber": {strconv.FormatInt(int64(n), 10)},
 	}
-	for attempt := attempts.Start(); attempt.Next(); {
+	req := &request{
+		method:  "PUT",
+		bucket:  m.Bucket.Name,
+		path:    m.Key,
+		headers: headers,
+		params:  params,
+		payload: r,
+	}
+	err := m.Bucket.S3.prepare(req)
+	if err != nil {
+		return Part{}, err
+	}
+	var resp *http.Response
+	err = m.Bucket.retry(req, func() error {
 		_, err := r.Seek(0, 0)
 		if err != nil {
-			return Part{}, err
-		}
-		req := &request{
-			method:  "PUT",
-			bucket:  m.Bucket.Name,
-			path:    m.Key,
-			headers: headers,
-			params:  params,
-			payload: r,
-		}
-		err = m.Bucket.S3.prepare(req)
-		if err != nil {
-			return Part{}, err
-		}
-		resp, err := m.Bucket.S3.run(req, nil)
-		if shouldRetry(err) && attempt.HasNext() {
-			continue
-		}
-		if err != nil {
-			return Part{}, err
-		}
-		etag := resp.Header.Get("ETag")
-		if etag == "" {
-			return Part{}, errors.New("part upload succeeded with no ETag")
+			return err
 		}
-		return Part{n, etag, partSize}, nil
+		resp, err = m.Bucket.S3.run(req, nil)
+		return err
+	})
+	if err != nil {
+		return Part{}, err
+	}
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		return Part{}, errors.New("part upload succeeded with no ETag")
 	}
-	panic("unreachable")
+	return Part{n, etag, partSize}, nil
 }
 
 func seekerInfo(r io.ReadSeeker) (size int64, md5hex string, md5b64 string, err error) {
@@ -238,7 +287,7 @@ func (m *Multi) ListParts() ([]Part, error) {
 		"max-parts": {strconv.FormatInt(int64(listPartsMax), 10)},
 	}
 	var parts partSlice
-	for attempt := attempts.Start(); attempt.Next(); {
+	for {
 		req := &request{
 			method: "GET",
 			bucket: m.Bucket.Name,
@@ -246,10 +295,9 @@ func (m *Multi) ListParts() ([]Part, error) {
 			params: params,
 		}
 		var resp listPartsResp
-		err := m.Bucket.S3.query(req, &resp)
-		if shouldRetry(err) && attempt.HasNext() {
-			continue
-		}
+		err := m.Bucket.retry(req, func() error {
+			return m.Bucket.S3.query(req, &resp)
+		})
 		if err != nil {
 			return nil, err
 		}
@@ -259,9 +307,7 @@ func (m *Multi) ListParts() ([]Part, error) {
 			return parts, nil
 		}
 		params["part-number-marker"] = []string{resp.NextPartNumberMarker}
-		attempt = attempts.Start() // Last request worked.
 	}
-	panic("unreachable")
 }
 
 type ReaderAtSeeker interface {
@@ -356,21 +402,16 @@ func (m *Multi) Complete(parts []Part) error {
 	if err != nil {
 		return err
 	}
-	for attempt := attempts.Start(); attempt.Next(); {
-		req := &request{
-			method:  "POST",
-			bucket:  m.Bucket.Name,
-			path:    m.Key,
-			params:  params,
-			payload: bytes.NewReader(data),
-		}
-		err := m.Bucket.S3.query(req, nil)
-		if shouldRetry(err) && attempt.HasNext() {
-			continue
-		}
-		return err
+	req := &request{
+		method: "POST",
+		bucket: m.Bucket.Name,
+		path:   m.Key,
+		params: params,
 	}
-	panic("unreachable")
+	return m.Bucket.retry(req, func() error {
+		req.payload = bytes.NewReader(data)
+		return m.Bucket.S3.query(req, nil)
+	})
 }
 
 // Abort deletes an unifinished multipart upload and any previously
@@ -392,18 +433,13 @@ func (m *Multi) Abort() error {
 	params := map[string][]string{
 		"uploadId": {m.UploadId},
 	}
-	for attempt := attempts.Start(); attempt.Next(); {
-		req := &request{
-			method: "DELETE",
-			bucket: m.Bucket.Name,
-			path:   m.Key,
-			params: params,
-		}
-		err := m.Bucket.S3.query(req, nil)
-		if shouldRetry(err) && attempt.HasNext() {
-			continue
-		}
-		return err
+	req := &request{
+		method: "DELETE",
+		bucket: m.Bucket.Name,
+		path:   m.Key,
+		params: params,
 	}
-	panic("unreachable")
+	return m.Bucket.retry(req, func() error {
+		return m.Bucket.S3.query(req, nil)
+	})
 }
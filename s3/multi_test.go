@@ -7,6 +7,7 @@ import (
 	"io"
 	"io/ioutil"
 	"strings"
+	"time"
 )
 
 func (s *S) TestInitMulti(c *check.C) {
@@ -137,6 +138,31 @@ func (s *S) TestPutPart(c *check.C) {
 	c.Assert(req.Header["Content-Md5"], check.DeepEquals, []string{"JvkO/RDWFPEAJS/1bYja2A=="})
 }
 
+func (s *S) TestPutPartRetry(c *check.C) {
+	headers := map[string]string{
+		"ETag": `"26f90efd10d614f100252ff56d88dad8"`,
+	}
+	testServer.Response(200, nil, InitMultiResultDump)
+	testServer.Response(500, nil, InternalErrorDump)
+	testServer.Response(200, headers, "")
+
+	b := s.s3.Bucket("sample")
+	multi, err := b.InitMulti("multi", "text/plain", s3.Private)
+	c.Assert(err, check.IsNil)
+	part, err := multi.PutPart(1, strings.NewReader("<part 1>"))
+	c.Assert(err, check.IsNil)
+	c.Assert(part.ETag, check.Equals, headers["ETag"])
+
+	testServer.WaitRequest()
+	for i := 0; i < 2; i++ {
+		req := testServer.WaitRequest()
+		c.Assert(req.Method, check.Equals, "PUT")
+		c.Assert(req.ContentLength, check.Equals, int64(8))
+		c.Assert(req.TransferEncoding, check.IsNil)
+		c.Assert(readAll(req.Body), check.Equals, "<part 1>")
+	}
+}
+
 func readAll(r io.Reader) string {
 	data, err := ioutil.ReadAll(r)
 	if err != nil {
@@ -359,6 +385,7 @@ func (s *S) TestListMulti(c *check.C) {
 	c.Assert(multis[0].UploadId, check.Equals, "iUVug89pPvSswrikD")
 	c.Assert(multis[1].Key, check.Equals, "multi2")
 	c.Assert(multis[1].UploadId, check.Equals, "DkirwsSvPp98guVUi")
+	c.Assert(multis[1].Initiated, check.Equals, time.Date(2013, 2, 14, 9, 30, 0, 0, time.UTC))
 
 	req := testServer.WaitRequest()
 	c.Assert(req.Method, check.Equals, "GET")
@@ -368,3 +395,23 @@ func (s *S) TestListMulti(c *check.C) {
 	c.Assert(req.Form["delimiter"], check.DeepEquals, []string{"/"})
 	c.Assert(req.Form["max-uploads"], check.DeepEquals, []string{"1000"})
 }
+
+func (s *S) TestAbortOrphanedMulti(c *check.C) {
+	testServer.Response(200, nil, ListMultiResultDump)
+	testServer.Response(204, nil, "")
+
+	b := s.s3.Bucket("sample")
+
+	aborted, err := b.AbortOrphanedMulti("multi", time.Date(2013, 2, 1, 0, 0, 0, 0, time.UTC))
+	c.Assert(err, check.IsNil)
+	c.Assert(aborted, check.HasLen, 1)
+	c.Assert(aborted[0].Key, check.Equals, "multi1")
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Method, check.Equals, "GET")
+	c.Assert(req.Form["prefix"], check.DeepEquals, []string{"multi"})
+	req = testServer.WaitRequest()
+	c.Assert(req.Method, check.Equals, "DELETE")
+	c.Assert(req.URL.Path, check.Equals, "/sample/multi1")
+	c.Assert(req.Form.Get("uploadId"), check.Equals, "iUVug89pPvSswrikD")
+}
@@ -7,6 +7,12 @@ var GetObjectErrorDump = `
 <HostId>L4ee/zrm1irFXY5F45fKXIRdOf9ktsKY/8TDVawuMK2jWRb1RF84i1uBzkdNqS5D</HostId></Error>
 `
 
+var RequestTimeTooSkewedErrorDump = `
+<?xml version="1.0" encoding="UTF-8"?>
+<Error><Code>RequestTimeTooSkewed</Code><Message>The difference between the request time and the current time is too large.</Message>
+<RequestId>8E7F96A6A4A3B2C1</RequestId></Error>
+`
+
 var GetListResultDump1 = `
 <?xml version="1.0" encoding="UTF-8"?>
 <ListBucketResult xmlns="http://s3.amazonaws.com/doc/2006-03-01">
@@ -56,6 +62,28 @@ var GetListResultDump2 = `
 </ListBucketResult>
 `
 
+var GetListResultDumpTruncated = `
+<?xml version="1.0" encoding="UTF-8"?>
+<ListBucketResult xmlns="http://s3.amazonaws.com/doc/2006-03-01">
+  <Name>example-bucket</Name>
+  <Prefix>photos/2006/</Prefix>
+  <Delimiter>/</Delimiter>
+  <IsTruncated>true</IsTruncated>
+  <NextMarker>photos/2006/d.jpg</NextMarker>
+  <Contents>
+    <Key>photos/2006/c.jpg</Key>
+    <Size>3</Size>
+  </Contents>
+  <Contents>
+    <Key>photos/2006/d.jpg</Key>
+    <Size>4</Size>
+  </Contents>
+  <CommonPrefixes>
+    <Prefix>photos/2006/apr/</Prefix>
+  </CommonPrefixes>
+</ListBucketResult>
+`
+
 var InitMultiResultDump = `
 <?xml version="1.0" encoding="UTF-8"?>
 <InitiateMultipartUploadResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
@@ -164,7 +192,7 @@ var ListMultiResultDump = `
       <DisplayName>joe</DisplayName>
     </Owner>
     <StorageClass>STANDARD</StorageClass>
-    <Initiated>2013-01-30T18:15:47.000Z</Initiated>
+    <Initiated>2013-02-14T09:30:00.000Z</Initiated>
   </Upload>
   <CommonPrefixes>
     <Prefix>a/</Prefix>
@@ -196,3 +224,52 @@ var InternalErrorDump = `
   <HostId>kjhwqk</HostId>
 </Error>
 `
+
+var AccessDeniedErrorDump = `
+<?xml version="1.0" encoding="UTF-8"?>
+<Error>
+  <Code>AccessDenied</Code>
+  <Message>Access Denied</Message>
+  <RequestId>3F1B667FAD71C3D8</RequestId>
+  <HostId>kjhwqk</HostId>
+</Error>
+`
+
+var PreconditionFailedErrorDump = `
+<?xml version="1.0" encoding="UTF-8"?>
+<Error>
+  <Code>PreconditionFailed</Code>
+  <Message>At least one of the pre-conditions you specified did not hold</Message>
+  <Condition>If-Match</Condition>
+  <RequestId>3F1B667FAD71C3D8</RequestId>
+  <HostId>kjhwqk</HostId>
+</Error>
+`
+
+var ListPartsResumeDump = `
+<?xml version="1.0" encoding="UTF-8"?>
+<ListPartsResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+  <Bucket>sample</Bucket>
+  <Key>multi</Key>
+  <UploadId>JNbR_cMdwnGiD12jKAd6WK2PUkfj2VxA7i4nCwjE6t71nI9Tl3eVDPFlU0nOixhftH7I17ZPGkV3QA.l7ZD.QQ--</UploadId>
+  <StorageClass>STANDARD</StorageClass>
+  <PartNumberMarker>0</PartNumberMarker>
+  <NextPartNumberMarker>1</NextPartNumberMarker>
+  <MaxParts>1000</MaxParts>
+  <IsTruncated>false</IsTruncated>
+  <Part>
+    <PartNumber>1</PartNumber>
+    <LastModified>2013-01-30T13:45:51.000Z</LastModified>
+    <ETag>&quot;etag1&quot;</ETag>
+    <Size>5</Size>
+  </Part>
+</ListPartsResult>
+`
+
+var CopyPartResultDump = `
+<?xml version="1.0" encoding="UTF-8"?>
+<CopyPartResult>
+  <LastModified>2013-01-30T13:45:51.000Z</LastModified>
+  <ETag>&quot;etag&quot;</ETag>
+</CopyPartResult>
+`
@@ -12,6 +12,7 @@ package s3
 
 import (
 	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/md5"
 	"crypto/sha1"
@@ -39,7 +40,39 @@ type S3 struct {
 	aws.Region
 	ConnectTimeout time.Duration
 	ReadTimeout    time.Duration
-	private        byte // Reserve the right of using private data.
+
+	// HTTPClient is used to send requests to S3. If nil, a new
+	// connection honouring ConnectTimeout and ReadTimeout is
+	// dialed for every request. When set, the timeouts are
+	// ignored and connections are pooled by the client's
+	// Transport.
+	HTTPClient *http.Client
+
+	// RetryPolicy controls how failed requests are retried. If nil,
+	// DefaultRetryPolicy is used.
+	RetryPolicy *aws.RetryPolicy
+
+	// Handlers holds the hooks run while sending requests. If nil,
+	// aws.DefaultHandlers is used.
+	Handlers *aws.Handlers
+
+	// Clock gives the time requests are signed with, corrected when
+	// S3 reports clock skew. If nil, aws.DefaultClock is used.
+	Clock *aws.Clock
+
+	// SignV4 makes the client sign requests and URLs with Signature
+	// Version 4, as required by the regions opened since 2014 and by
+	// most S3-compatible stores, rather than with the legacy S3
+	// signatures.
+	SignV4 bool
+
+	// Limiter, if set, limits the rate of the requests sent to S3,
+	// and slows them down when S3 throttles them. It may be shared
+	// between clients.
+	Limiter *aws.RateLimiter
+
+	aws.ContextBinding
+	private byte // Reserve the right of using private data.
 }
 
 // The Bucket type encapsulates operations with an S3 bucket.
@@ -81,15 +114,48 @@ type CopyObjectResult struct {
 	LastModified string
 }
 
-var attempts = aws.AttemptStrategy{
-	Min:   5,
-	Total: 5 * time.Second,
-	Delay: 200 * time.Millisecond,
+// DefaultRetryPolicy is the policy used by S3 clients that have none.
+var DefaultRetryPolicy = aws.RetryPolicy{
+	MaxAttempts:   5,
+	BaseDelay:     200 * time.Millisecond,
+	ThrottleDelay: time.Second,
+	MaxDelay:      5 * time.Second,
 }
 
 // New creates a new S3.
 func New(auth aws.Auth, region aws.Region) *S3 {
-	return &S3{auth, region, 0, 0, 0}
+	return &S3{Auth: auth, Region: region, Clock: &aws.Clock{}}
+}
+
+// NewFromConfig creates a new S3 with the credentials, endpoints, HTTP
+// client, retry policy and rate limiter of cfg.
+func NewFromConfig(cfg *aws.Config) *S3 {
+	s3 := New(cfg.Auth, cfg.Region)
+	s3.HTTPClient = cfg.HTTPClient
+	s3.RetryPolicy = cfg.RetryPolicy(&DefaultRetryPolicy)
+	s3.Limiter = cfg.Limiter
+	return s3
+}
+
+// WithContext returns a shallow copy of s3 bound to ctx; see
+// aws.ContextBinding.
+func (s3 *S3) WithContext(ctx context.Context) *S3 {
+	c := *s3
+	c.ContextBinding = aws.BindContext(ctx)
+	return &c
+}
+
+// retry calls op according to the retry policy of s3. The attempts op
+// makes to send req are counted for the hooks of s3.Handlers.
+func (s3 *S3) retry(req *request, op func() error) error {
+	policy := aws.RetryPolicyOrDefault(s3.RetryPolicy, &DefaultRetryPolicy)
+	req.info = s3.requestInfo(req)
+	return s3.Handlers.Retry(policy, retryable, req.info, op)
+}
+
+// requestInfo describes req to the hooks of s3.Handlers.
+func (s3 *S3) requestInfo(req *request) *aws.RequestInfo {
+	return &aws.RequestInfo{Service: "s3", Operation: req.operation(), Context: s3.Context(), Clock: s3.Clock, Limiter: s3.Limiter}
 }
 
 // Bucket returns a Bucket with the given name.
@@ -100,6 +166,11 @@ func (s3 *S3) Bucket(name string) *Bucket {
 	return &Bucket{s3, name}
 }
 
+// WithContext returns a copy of b whose operations are bound to ctx.
+func (b *Bucket) WithContext(ctx context.Context) *Bucket {
+	return &Bucket{b.S3.WithContext(ctx), b.Name}
+}
+
 var createBucketConfiguration = `<CreateBucketConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
   <LocationConstraint>%s</LocationConstraint>
 </CreateBucketConfiguration>`
@@ -154,12 +225,9 @@ func (b *Bucket) DelBucket() (err error) {
 		bucket: b.Name,
 		path:   "/",
 	}
-	for attempt := attempts.Start(); attempt.Next(); {
-		err = b.S3.query(req, nil)
-		if !shouldRetry(err) {
-			break
-		}
-	}
+	err = b.retry(req, func() error {
+		return b.S3.query(req, nil)
+	})
 	return err
 }
 
@@ -211,17 +279,14 @@ func (b *Bucket) GetResponseWithHeaders(path string, headers map[string][]string
 	if err != nil {
 		return nil, err
 	}
-	for attempt := attempts.Start(); attempt.Next(); {
-		resp, err := b.S3.run(req, nil)
-		if shouldRetry(err) && attempt.HasNext() {
-			continue
-		}
-		if err != nil {
-			return nil, err
-		}
-		return resp, nil
+	err = b.retry(req, func() error {
+		resp, err = b.S3.run(req, nil)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
-	panic("unreachable")
+	return resp, nil
 }
 
 // Exists checks whether or not an object exists on an S3 bucket using a HEAD request.
@@ -235,27 +300,22 @@ func (b *Bucket) Exists(path string) (exists bool, err error) {
 	if err != nil {
 		return
 	}
-	for attempt := attempts.Start(); attempt.Next(); {
-		resp, err := b.S3.run(req, nil)
-
-		if shouldRetry(err) && attempt.HasNext() {
-			continue
-		}
-
-		if err != nil {
-			// We can treat a 403 or 404 as non existance
-			if e, ok := err.(*Error); ok && (e.StatusCode == 403 || e.StatusCode == 404) {
-				return false, nil
-			}
-			return false, err
-		}
-
-		if resp.StatusCode/100 == 2 {
-			exists = true
+	var resp *http.Response
+	err = b.retry(req, func() error {
+		resp, err = b.S3.run(req, nil)
+		return err
+	})
+	if err != nil {
+		// We can treat a 403 or 404 as non existance
+		if e, ok := err.(*Error); ok && (e.StatusCode == 403 || e.StatusCode == 404) {
+			return false, nil
 		}
-		return exists, err
+		return false, err
+	}
+	if resp.StatusCode/100 == 2 {
+		exists = true
 	}
-	return false, fmt.Errorf("S3 Currently Unreachable")
+	return exists, nil
 }
 
 // Head HEADs an object in the S3 bucket, returns the response with
@@ -271,25 +331,23 @@ func (b *Bucket) Head(path string, headers map[string][]string) (*http.Response,
 	if err != nil {
 		return nil, err
 	}
-
-	for attempt := attempts.Start(); attempt.Next(); {
-		resp, err := b.S3.run(req, nil)
-		if shouldRetry(err) && attempt.HasNext() {
-			continue
-		}
-		if err != nil {
-			return nil, err
-		}
-		return resp, err
+	var resp *http.Response
+	err = b.retry(req, func() error {
+		resp, err = b.S3.run(req, nil)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
-	return nil, fmt.Errorf("S3 Currently Unreachable")
+	return resp, nil
 }
 
 // Put inserts an object into the S3 bucket.
 //
 // See http://goo.gl/FEBPD for details.
 func (b *Bucket) Put(path string, data []byte, contType string, perm ACL, options Options) error {
-	body := bytes.NewBuffer(data)
+	// A bytes.Reader can be hashed for V4 signatures.
+	body := bytes.NewReader(data)
 	return b.PutReader(path, body, int64(len(data)), contType, perm, options)
 }
 
@@ -414,6 +472,50 @@ func (b *Bucket) PutBucketSubresource(subresource string, r io.Reader, length in
 	return b.S3.query(req, nil)
 }
 
+// PutPolicy sets the policy of the bucket, which may be built with the
+// policy package as the String of a policy.Document.
+func (b *Bucket) PutPolicy(policy string) error {
+	return b.PutBucketSubresource("policy", strings.NewReader(policy), int64(len(policy)))
+}
+
+// GetPolicy returns the policy of the bucket. It fails with the
+// NoSuchBucketPolicy error code if the bucket has none.
+func (b *Bucket) GetPolicy() (string, error) {
+	req := &request{
+		bucket: b.Name,
+		path:   "/",
+		params: url.Values{"policy": {""}},
+	}
+	err := b.S3.prepare(req)
+	if err != nil {
+		return "", err
+	}
+	var data []byte
+	err = b.retry(req, func() error {
+		resp, err := b.S3.run(req, nil)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		data, err = ioutil.ReadAll(resp.Body)
+		return err
+	})
+	return string(data), err
+}
+
+// DelPolicy removes the policy of the bucket.
+func (b *Bucket) DelPolicy() error {
+	req := &request{
+		method: "DELETE",
+		bucket: b.Name,
+		path:   "/",
+		params: url.Values{"policy": {""}},
+	}
+	return b.retry(req, func() error {
+		return b.S3.query(req, nil)
+	})
+}
+
 // Del removes an object from the S3 bucket.
 //
 // See http://goo.gl/APeTt for details.
@@ -483,6 +585,8 @@ type ListResp struct {
 	IsTruncated    bool
 	Contents       []Key
 	CommonPrefixes []string `xml:">Prefix"`
+	// NextMarker is only set when a delimiter is given.
+	NextMarker string
 }
 
 // The Key type represents an item stored in an S3 bucket.
@@ -567,12 +671,9 @@ func (b *Bucket) List(prefix, delim, marker string, max int) (result *ListResp,
 		params: params,
 	}
 	result = &ListResp{}
-	for attempt := attempts.Start(); attempt.Next(); {
-		err = b.S3.query(req, result)
-		if !shouldRetry(err) {
-			break
-		}
-	}
+	err = b.retry(req, func() error {
+		return b.S3.query(req, result)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -581,15 +682,17 @@ func (b *Bucket) List(prefix, delim, marker string, max int) (result *ListResp,
 
 // The VersionsResp type holds the results of a list bucket Versions operation.
 type VersionsResp struct {
-	Name            string
-	Prefix          string
-	KeyMarker       string
-	VersionIdMarker string
-	MaxKeys         int
-	Delimiter       string
-	IsTruncated     bool
-	Versions        []Version
-	CommonPrefixes  []string `xml:">Prefix"`
+	Name                string
+	Prefix              string
+	KeyMarker           string
+	VersionIdMarker     string
+	NextKeyMarker       string
+	NextVersionIdMarker string
+	MaxKeys             int
+	Delimiter           string
+	IsTruncated         bool
+	Versions            []Version
+	CommonPrefixes      []string `xml:">Prefix"`
 }
 
 // The Version type represents an object version stored in an S3 bucket.
@@ -628,18 +731,136 @@ func (b *Bucket) Versions(prefix, delim, keyMarker string, versionIdMarker strin
 		params: params,
 	}
 	result = &VersionsResp{}
-	for attempt := attempts.Start(); attempt.Next(); {
-		err = b.S3.query(req, result)
-		if !shouldRetry(err) {
-			break
-		}
-	}
+	err = b.retry(req, func() error {
+		return b.S3.query(req, result)
+	})
 	if err != nil {
 		return nil, err
 	}
 	return result, nil
 }
 
+// ListIter iterates over the keys and common prefixes of the objects in
+// the bucket, as listed by List with the prefix and delim parameters.
+// Pages of results are fetched as they are needed.
+type ListIter struct {
+	pager *aws.Pager
+	page  *ListResp
+}
+
+// ListIter returns an iterator over the keys and common prefixes that
+// List with prefix and delim would return, in all pages of results:
+//
+//	iter := b.ListIter("photos/", "/")
+//	for iter.Next() {
+//		fmt.Println(iter.Key().Key, iter.Prefix())
+//	}
+//	if err := iter.Err(); err != nil {
+//		...
+//	}
+func (b *Bucket) ListIter(prefix, delim string) *ListIter {
+	iter := &ListIter{}
+	iter.pager = aws.NewPager(func(marker string) (int, string, error) {
+		resp, err := b.List(prefix, delim, marker, 0)
+		if err != nil {
+			return 0, "", err
+		}
+		iter.page = resp
+		next := ""
+		if resp.IsTruncated {
+			next = resp.NextMarker
+		}
+		if resp.IsTruncated && next == "" {
+			// Without a delimiter, the listing resumes after the last key.
+			if n := len(resp.Contents); n > 0 {
+				next = resp.Contents[n-1].Key
+			}
+			if n := len(resp.CommonPrefixes); n > 0 && resp.CommonPrefixes[n-1] > next {
+				next = resp.CommonPrefixes[n-1]
+			}
+		}
+		return len(resp.Contents) + len(resp.CommonPrefixes), next, nil
+	})
+	return iter
+}
+
+// Next advances to the next key or common prefix. It returns false when
+// there are none left, or when listing failed.
+func (iter *ListIter) Next() bool {
+	return iter.pager.Next()
+}
+
+// Key returns the current key, or the zero Key if the current entry is a
+// common prefix. Within a page, keys come before common prefixes.
+func (iter *ListIter) Key() Key {
+	if i := iter.pager.Index(); i < len(iter.page.Contents) {
+		return iter.page.Contents[i]
+	}
+	return Key{}
+}
+
+// Prefix returns the current common prefix, or "" if the current entry
+// is a key.
+func (iter *ListIter) Prefix() string {
+	if i := iter.pager.Index() - len(iter.page.Contents); i >= 0 {
+		return iter.page.CommonPrefixes[i]
+	}
+	return ""
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (iter *ListIter) Err() error {
+	return iter.pager.Err()
+}
+
+// VersionsIter iterates over the versions of the objects in the bucket,
+// as listed by Versions. Pages of results are fetched as they are needed.
+type VersionsIter struct {
+	pager *aws.Pager
+	page  *VersionsResp
+}
+
+// VersionsIter returns an iterator over the versions that Versions with
+// prefix and delim would return, in all pages of results. Common
+// prefixes are left out.
+func (b *Bucket) VersionsIter(prefix, delim string) *VersionsIter {
+	iter := &VersionsIter{}
+	iter.pager = aws.NewPager(func(token string) (int, string, error) {
+		// The listing resumes at a key and a version of it.
+		markers, err := url.ParseQuery(token)
+		if err != nil {
+			return 0, "", err
+		}
+		resp, err := b.Versions(prefix, delim, markers.Get("key"), markers.Get("version"), 0)
+		if err != nil {
+			return 0, "", err
+		}
+		iter.page = resp
+		next := ""
+		if resp.IsTruncated {
+			next = url.Values{"key": {resp.NextKeyMarker}, "version": {resp.NextVersionIdMarker}}.Encode()
+		}
+		return len(resp.Versions), next, nil
+	})
+	return iter
+}
+
+// Next advances to the next version. It returns false when there are
+// none left, or when listing failed.
+func (iter *VersionsIter) Next() bool {
+	return iter.pager.Next()
+}
+
+// Version returns the current version.
+func (iter *VersionsIter) Version() Version {
+	return iter.page.Versions[iter.pager.Index()]
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (iter *VersionsIter) Err() error {
+	return iter.pager.Err()
+}
+
 // URL returns a non-signed URL that allows retriving the
 // object at path. It only works if the object is publicly
 // readable (see SignedURL).
@@ -652,7 +873,7 @@ func (b *Bucket) URL(path string) string {
 	if err != nil {
 		panic(err)
 	}
-	u, err := req.url()
+	u, err := b.S3.url(req)
 	if err != nil {
 		panic(err)
 	}
@@ -662,24 +883,74 @@ func (b *Bucket) URL(path string) string {
 
 // SignedURL returns a signed URL that allows anyone holding the URL
 // to retrieve the object at path. The signature is valid until expires.
+// It returns "" if the URL cannot be signed; see SignURL.
 func (b *Bucket) SignedURL(path string, expires time.Time) string {
+	return b.SignedURLWithMethod("GET", path, expires, nil, nil)
+}
+
+// SignURL is like SignedURL, but returns an error if the URL cannot be
+// signed.
+func (b *Bucket) SignURL(path string, expires time.Time) (string, error) {
+	return b.SignURLWithMethod("GET", path, expires, nil, nil)
+}
+
+// SignedURLWithMethod returns a signed URL that allows anyone holding
+// the URL to perform the request with the given method, as "GET", "PUT"
+// or "DELETE", on the object at path, until expires. The URL carries
+// params, and the request must be sent with headers, which are signed
+// too, as in:
+//
+//	u := b.SignedURLWithMethod("PUT", "images/a.png", expires, nil,
+//		http.Header{"Content-Type": {"image/png"}})
+//
+// The signature version is the one of the requests of b. It returns ""
+// if the URL cannot be signed; see SignURLWithMethod.
+func (b *Bucket) SignedURLWithMethod(method, path string, expires time.Time, params url.Values, headers http.Header) string {
+	// A bad bucket name or endpoint panics, as in URL.
+	b.URL(path)
+	u, err := b.SignURLWithMethod(method, path, expires, params, headers)
+	if err != nil {
+		log.Printf("s3: cannot sign URL for %q: %v", path, err)
+		return ""
+	}
+	return u
+}
+
+// SignURLWithMethod is like SignedURLWithMethod, but returns an error if
+// the URL cannot be signed, as when the credentials cannot be refreshed.
+// With Signature Version 4, expires must be at most 7 days ahead, the
+// longest validity S3 accepts.
+func (b *Bucket) SignURLWithMethod(method, path string, expires time.Time, params url.Values, headers http.Header) (string, error) {
 	req := &request{
-		bucket: b.Name,
-		path:   path,
-		params: url.Values{"Expires": {strconv.FormatInt(expires.Unix(), 10)}},
+		method:  method,
+		bucket:  b.Name,
+		path:    path,
+		params:  params,
+		headers: headers,
 	}
 	err := b.S3.prepare(req)
 	if err != nil {
-		panic(err)
+		return "", err
+	}
+	if b.S3.SignV4 {
+		u, err := b.S3.presignV4(req, expires)
+		if err != nil {
+			return "", err
+		}
+		return u.String(), nil
+	}
+	req.params.Set("Expires", strconv.FormatInt(expires.Unix(), 10))
+	if err := b.S3.signRequest(req); err != nil {
+		return "", err
 	}
 	u, err := req.url()
 	if err != nil {
-		panic(err)
+		return "", err
 	}
-	if b.S3.Auth.Token() != "" {
-		return u.String() + "&x-amz-security-token=" + url.QueryEscape(req.headers["X-Amz-Security-Token"][0])
+	if token := req.headers["X-Amz-Security-Token"]; len(token) > 0 {
+		return u.String() + "&x-amz-security-token=" + url.QueryEscape(token[0]), nil
 	} else {
-		return u.String()
+		return u.String(), nil
 	}
 }
 
@@ -687,14 +958,35 @@ func (b *Bucket) SignedURL(path string, expires time.Time) string {
 // to upload the object at path. The signature is valid until expires.
 // contenttype is a string like image/png
 // path is the resource name in s3 terminalogy like images/ali.png [obviously exclusing the bucket name itself]
+// It returns "" if the URL cannot be signed; see SignUploadURL.
 func (b *Bucket) UploadSignedURL(path, method, content_type string, expires time.Time) string {
+	if b.S3.SignV4 {
+		b.URL(path)
+	}
+	u, err := b.SignUploadURL(path, method, content_type, expires)
+	if err != nil {
+		log.Println("ERROR sining url for S3 upload", err)
+		return ""
+	}
+	return u
+}
+
+// SignUploadURL is like UploadSignedURL, but returns an error if the URL
+// cannot be signed.
+func (b *Bucket) SignUploadURL(path, method, content_type string, expires time.Time) (string, error) {
 	expire_date := expires.Unix()
 	if method != "POST" {
 		method = "PUT"
 	}
+	if b.S3.SignV4 {
+		return b.SignURLWithMethod(method, path, expires, nil, http.Header{"Content-Type": {content_type}})
+	}
 	stringToSign := method + "\n\n" + content_type + "\n" + strconv.FormatInt(expire_date, 10) + "\n/" + b.Name + "/" + path
 	fmt.Println("String to sign:\n", stringToSign)
-	a := b.S3.Auth
+	a, err := b.S3.Auth.Snapshot()
+	if err != nil {
+		return "", err
+	}
 	secretKey := a.SecretKey
 	accessId := a.AccessKey
 	mac := hmac.New(sha1.New, []byte(secretKey))
@@ -705,8 +997,7 @@ func (b *Bucket) UploadSignedURL(path, method, content_type string, expires time
 
 	signedurl, err := url.Parse("https://" + b.Name + ".s3.amazonaws.com/")
 	if err != nil {
-		log.Println("ERROR sining url for S3 upload", err)
-		return ""
+		return "", err
 	}
 	signedurl.Path += path
 	params := url.Values{}
@@ -718,15 +1009,16 @@ func (b *Bucket) UploadSignedURL(path, method, content_type string, expires time
 	}
 
 	signedurl.RawQuery = params.Encode()
-	return signedurl.String()
+	return signedurl.String(), nil
 }
 
 // PostFormArgs returns the action and input fields needed to allow anonymous
 // uploads to a bucket within the expiration limit
 func (b *Bucket) PostFormArgs(path string, expires time.Time, redirect string) (action string, fields map[string]string) {
+	auth, _ := b.Auth.Snapshot()
 	conditions := make([]string, 0)
 	fields = map[string]string{
-		"AWSAccessKeyId": b.Auth.AccessKey,
+		"AWSAccessKeyId": auth.AccessKey,
 		"key":            path,
 	}
 
@@ -743,7 +1035,7 @@ func (b *Bucket) PostFormArgs(path string, expires time.Time, redirect string) (
 	policy64 := base64.StdEncoding.EncodeToString([]byte(policy))
 	fields["policy"] = policy64
 
-	signer := hmac.New(sha1.New, []byte(b.Auth.SecretKey))
+	signer := hmac.New(sha1.New, []byte(auth.SecretKey))
 	signer.Write([]byte(policy64))
 	fields["signature"] = base64.StdEncoding.EncodeToString(signer.Sum(nil))
 
@@ -759,7 +1051,10 @@ type request struct {
 	headers  http.Header
 	baseurl  string
 	payload  io.Reader
+	length   int64
 	prepared bool
+	signpath string
+	info     *aws.RequestInfo
 }
 
 func (req *request) url() (*url.URL, error) {
@@ -772,6 +1067,82 @@ func (req *request) url() (*url.URL, error) {
 	return u, nil
 }
 
+// url returns the URL req is sent to, once prepared. With V4 signatures,
+// its path is escaped as it is signed.
+func (s3 *S3) url(req *request) (*url.URL, error) {
+	u, err := req.url()
+	if err == nil && s3.SignV4 {
+		u.RawPath = escapePath(u.Path)
+	}
+	return u, err
+}
+
+// verbs maps HTTP methods to the verbs starting the names of the S3
+// operations they perform.
+var verbs = map[string]string{"GET": "Get", "PUT": "Put", "POST": "Post", "HEAD": "Head", "DELETE": "Delete"}
+
+// operation returns the name of the S3 operation req performs, as in
+// "GetObject", for the hooks of Handlers.
+func (req *request) operation() string {
+	method := req.method
+	if method == "" {
+		method = "GET"
+	}
+	has := func(param string) bool {
+		_, ok := req.params[param]
+		return ok
+	}
+	copied := len(req.headers["x-amz-copy-source"]) > 0
+	switch {
+	case has("uploadId"):
+		switch method {
+		case "PUT":
+			if copied {
+				return "UploadPartCopy"
+			}
+			return "UploadPart"
+		case "POST":
+			return "CompleteMultipartUpload"
+		case "DELETE":
+			return "AbortMultipartUpload"
+		}
+		return "ListParts"
+	case has("uploads"):
+		if method == "POST" {
+			return "CreateMultipartUpload"
+		}
+		return "ListMultipartUploads"
+	case has("delete"):
+		return "DeleteObjects"
+	case has("location"):
+		return "GetBucketLocation"
+	case has("versions"):
+		return "ListObjectVersions"
+	case has("website"):
+		return verbs[method] + "BucketWebsite"
+	case has("policy"):
+		return verbs[method] + "BucketPolicy"
+	}
+	key := req.path
+	if req.prepared && req.bucket != "" {
+		// Once prepared, the path may start with the bucket name.
+		key = strings.TrimPrefix(req.signpath, "/"+req.bucket)
+	}
+	if strings.Trim(key, "/") == "" {
+		switch method {
+		case "GET":
+			return "ListObjects"
+		case "PUT":
+			return "CreateBucket"
+		}
+		return verbs[method] + "Bucket"
+	}
+	if method == "PUT" && copied {
+		return "CopyObject"
+	}
+	return verbs[method] + "Object"
+}
+
 // query prepares and runs the req request.
 // If resp is not nil, the XML data contained in the response
 // body will be unmarshalled on it.
@@ -785,8 +1156,6 @@ func (s3 *S3) query(req *request, resp interface{}) error {
 
 // prepare sets up req to be delivered to S3.
 func (s3 *S3) prepare(req *request) error {
-	var signpath = req.path
-
 	if !req.prepared {
 		req.prepared = true
 		if req.method == "" {
@@ -801,12 +1170,18 @@ func (s3 *S3) prepare(req *request) error {
 		for k, v := range req.headers {
 			headers[k] = v
 		}
+		// net/http writes the length from ContentLength only, and it
+		// is kept apart so that every attempt sends it.
+		if v, ok := headers["Content-Length"]; ok {
+			req.length, _ = strconv.ParseInt(v[0], 10, 64)
+			delete(headers, "Content-Length")
+		}
 		req.params = params
 		req.headers = headers
 		if !strings.HasPrefix(req.path, "/") {
 			req.path = "/" + req.path
 		}
-		signpath = req.path
+		req.signpath = req.path
 		if req.bucket != "" {
 			req.baseurl = s3.Region.S3BucketEndpoint
 			if req.baseurl == "" {
@@ -820,23 +1195,34 @@ func (s3 *S3) prepare(req *request) error {
 				}
 				req.baseurl = strings.Replace(req.baseurl, "${bucket}", req.bucket, -1)
 			}
-			signpath = "/" + req.bucket + signpath
+			req.signpath = "/" + req.bucket + req.signpath
 		}
 	}
+	return nil
+}
 
+// signRequest signs req, once prepared.
+func (s3 *S3) signRequest(req *request) error {
 	// Always sign again as it's not clear how far the
 	// server has handled a previous attempt.
 	u, err := url.Parse(req.baseurl)
 	if err != nil {
 		return fmt.Errorf("bad S3 endpoint URL %q: %v", req.baseurl, err)
 	}
-	reqSignpathSpaceFix := (&url.URL{Path: signpath}).String()
+	reqSignpathSpaceFix := (&url.URL{Path: req.signpath}).String()
 	req.headers["Host"] = []string{u.Host}
-	req.headers["Date"] = []string{time.Now().In(time.UTC).Format(time.RFC1123)}
-	if s3.Auth.Token() != "" {
-		req.headers["X-Amz-Security-Token"] = []string{s3.Auth.Token()}
+	auth, err := s3.Auth.Snapshot()
+	if err != nil {
+		return err
+	}
+	if s3.SignV4 {
+		return s3.signV4(req, auth)
 	}
-	sign(s3.Auth, req.method, reqSignpathSpaceFix, req.params, req.headers)
+	req.headers["Date"] = []string{s3.Clock.Now().Format(time.RFC1123)}
+	if auth.Token() != "" {
+		req.headers["X-Amz-Security-Token"] = []string{auth.Token()}
+	}
+	sign(auth, req.method, reqSignpathSpaceFix, req.params, req.headers)
 	return nil
 }
 
@@ -848,7 +1234,7 @@ func (s3 *S3) run(req *request, resp interface{}) (*http.Response, error) {
 		log.Printf("Running S3 request: %#v", req)
 	}
 
-	u, err := req.url()
+	u, err := s3.url(req)
 	if err != nil {
 		return nil, err
 	}
@@ -858,39 +1244,22 @@ func (s3 *S3) run(req *request, resp interface{}) (*http.Response, error) {
 		Method:     req.method,
 		ProtoMajor: 1,
 		ProtoMinor: 1,
-		Close:      true,
+		Close:      s3.HTTPClient == nil,
 		Header:     req.headers,
 	}
 
-	if v, ok := req.headers["Content-Length"]; ok {
-		hreq.ContentLength, _ = strconv.ParseInt(v[0], 10, 64)
-		delete(req.headers, "Content-Length")
-	}
+	hreq.ContentLength = req.length
 	if req.payload != nil {
 		hreq.Body = ioutil.NopCloser(req.payload)
 	}
 
-	c := http.Client{
-		Transport: &http.Transport{
-			Dial: func(netw, addr string) (c net.Conn, err error) {
-				deadline := time.Now().Add(s3.ReadTimeout)
-				if s3.ConnectTimeout > 0 {
-					c, err = net.DialTimeout(netw, addr, s3.ConnectTimeout)
-				} else {
-					c, err = net.Dial(netw, addr)
-				}
-				if err != nil {
-					return
-				}
-				if s3.ReadTimeout > 0 {
-					err = c.SetDeadline(deadline)
-				}
-				return
-			},
-		},
+	info := req.info
+	if info == nil {
+		info = s3.requestInfo(req)
 	}
-
-	hresp, err := c.Do(&hreq)
+	hresp, err := s3.Handlers.Send(s3.httpClient(), info, &hreq, func(*http.Request) error {
+		return s3.signRequest(req)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -898,7 +1267,8 @@ func (s3 *S3) run(req *request, resp interface{}) (*http.Response, error) {
 		dump, _ := httputil.DumpResponse(hresp, true)
 		log.Printf("} -> %s\n", dump)
 	}
-	if hresp.StatusCode != 200 && hresp.StatusCode != 204 {
+	// 206 answers requests for a Range of an object.
+	if hresp.StatusCode != 200 && hresp.StatusCode != 204 && hresp.StatusCode != 206 {
 		return nil, buildError(hresp)
 	}
 	if resp != nil {
@@ -913,6 +1283,32 @@ func (s3 *S3) run(req *request, resp interface{}) (*http.Response, error) {
 	return hresp, err
 }
 
+// httpClient returns the client used to send requests to S3.
+func (s3 *S3) httpClient() *http.Client {
+	if s3.HTTPClient != nil {
+		return s3.HTTPClient
+	}
+	return &http.Client{
+		Transport: &http.Transport{
+			Dial: func(netw, addr string) (c net.Conn, err error) {
+				deadline := time.Now().Add(s3.ReadTimeout)
+				if s3.ConnectTimeout > 0 {
+					c, err = net.DialTimeout(netw, addr, s3.ConnectTimeout)
+				} else {
+					c, err = net.Dial(netw, addr)
+				}
+				if err != nil {
+					return
+				}
+				if s3.ReadTimeout > 0 {
+					err = c.SetDeadline(deadline)
+				}
+				return
+			},
+		},
+	}
+}
+
 // Error represents an error in an operation with S3.
 type Error struct {
 	StatusCode int    // HTTP status code (200, 403, ...)
@@ -927,6 +1323,24 @@ func (e *Error) Error() string {
 	return e.Message
 }
 
+// ErrorCode returns the S3 error code.
+func (e *Error) ErrorCode() string { return e.Code }
+
+// ErrorMessage returns the message of the error.
+func (e *Error) ErrorMessage() string { return e.Message }
+
+// HTTPStatusCode returns the status code of the HTTP response.
+func (e *Error) HTTPStatusCode() int { return e.StatusCode }
+
+// RequestID returns the ID of the failed request.
+func (e *Error) RequestID() string { return e.RequestId }
+
+// Retryable reports whether sending the request again may succeed.
+func (e *Error) Retryable() bool {
+	retry, _ := retryable(e)
+	return retry
+}
+
 func buildError(r *http.Response) error {
 	if debug {
 		log.Printf("got error (status code %v)", r.StatusCode)
@@ -953,29 +1367,18 @@ func buildError(r *http.Response) error {
 	return &err
 }
 
-func shouldRetry(err error) bool {
-	if err == nil {
-		return false
-	}
-	switch err {
-	case io.ErrUnexpectedEOF, io.EOF:
-		return true
-	}
-	switch e := err.(type) {
-	case *net.DNSError:
-		return true
-	case *net.OpError:
-		switch e.Op {
-		case "read", "write":
-			return true
-		}
-	case *Error:
+// retryable classifies the errors of failed S3 requests for retries.
+// Besides transient failures, S3 may report uploads and buckets that were
+// just created as missing, so those errors are retried as well.
+func retryable(err error) (retry, throttled bool) {
+	if e, ok := err.(*Error); ok {
 		switch e.Code {
-		case "InternalError", "NoSuchUpload", "NoSuchBucket":
-			return true
+		case "NoSuchUpload", "NoSuchBucket":
+			return true, false
 		}
+		return aws.ClassifyResponse(e.StatusCode, e.Code)
 	}
-	return false
+	return aws.IsTransientNetError(err), false
 }
 
 func hasCode(err error, code string) bool {
@@ -2,12 +2,17 @@ package s3_test
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"github.com/crowdmob/goamz/aws"
 	"github.com/crowdmob/goamz/s3"
 	"github.com/crowdmob/goamz/testutil"
 	"gopkg.in/check.v1"
 	"io/ioutil"
 	"net/http"
+	"net/url"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -31,15 +36,15 @@ func (s *S) SetUpSuite(c *check.C) {
 }
 
 func (s *S) TearDownSuite(c *check.C) {
-	s3.SetAttemptStrategy(nil)
+	s3.SetRetryPolicy(nil)
 }
 
 func (s *S) SetUpTest(c *check.C) {
-	attempts := aws.AttemptStrategy{
-		Total: 300 * time.Millisecond,
-		Delay: 100 * time.Millisecond,
-	}
-	s3.SetAttemptStrategy(&attempts)
+	s3.SetRetryPolicy(&aws.RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   100 * time.Millisecond,
+		MaxDelay:    100 * time.Millisecond,
+	})
 }
 
 func (s *S) TearDownTest(c *check.C) {
@@ -47,7 +52,7 @@ func (s *S) TearDownTest(c *check.C) {
 }
 
 func (s *S) DisableRetries() {
-	s3.SetAttemptStrategy(&aws.AttemptStrategy{})
+	s3.SetRetryPolicy(&aws.NoRetries)
 }
 
 // PutBucket docs: http://goo.gl/kBTCu
@@ -98,6 +103,51 @@ func (s *S) TestDelBucket(c *check.C) {
 	c.Assert(req.Header["Date"], check.Not(check.Equals), "")
 }
 
+func (s *S) TestPutPolicy(c *check.C) {
+	testServer.Response(204, nil, "")
+
+	doc := `{"Statement":[{"Effect":"Allow","Principal":"*","Action":"s3:GetObject","Resource":"arn:aws:s3:::bucket/*"}]}`
+	b := s.s3.Bucket("bucket")
+	err := b.PutPolicy(doc)
+	c.Assert(err, check.IsNil)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Method, check.Equals, "PUT")
+	c.Assert(req.URL.Path, check.Equals, "/bucket/")
+	c.Assert(req.URL.RawQuery, check.Equals, "policy=")
+	body, err := ioutil.ReadAll(req.Body)
+	c.Assert(err, check.IsNil)
+	c.Assert(string(body), check.Equals, doc)
+}
+
+func (s *S) TestGetPolicy(c *check.C) {
+	doc := `{"Statement":[{"Effect":"Allow","Principal":"*","Action":"s3:GetObject","Resource":"arn:aws:s3:::bucket/*"}]}`
+	testServer.Response(200, nil, doc)
+
+	b := s.s3.Bucket("bucket")
+	text, err := b.GetPolicy()
+	c.Assert(err, check.IsNil)
+	c.Assert(text, check.Equals, doc)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Method, check.Equals, "GET")
+	c.Assert(req.URL.Path, check.Equals, "/bucket/")
+	c.Assert(req.URL.RawQuery, check.Equals, "policy=")
+}
+
+func (s *S) TestDelPolicy(c *check.C) {
+	testServer.Response(204, nil, "")
+
+	b := s.s3.Bucket("bucket")
+	err := b.DelPolicy()
+	c.Assert(err, check.IsNil)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Method, check.Equals, "DELETE")
+	c.Assert(req.URL.Path, check.Equals, "/bucket/")
+	c.Assert(req.URL.RawQuery, check.Equals, "policy=")
+}
+
 // GetObject docs: http://goo.gl/isCO7
 
 func (s *S) TestGet(c *check.C) {
@@ -115,6 +165,90 @@ func (s *S) TestGet(c *check.C) {
 	c.Assert(string(data), check.Equals, "content")
 }
 
+type countingTransport struct {
+	n int
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.n++
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func (s *S) TestGetWithHTTPClient(c *check.C) {
+	testServer.Response(200, nil, "content")
+
+	transport := &countingTransport{}
+	s3c := s3.New(s.s3.Auth, s.s3.Region)
+	s3c.HTTPClient = &http.Client{Transport: transport}
+	data, err := s3c.Bucket("bucket").Get("name")
+
+	req := testServer.WaitRequest()
+	c.Assert(req.URL.Path, check.Equals, "/bucket/name")
+
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, "content")
+	c.Assert(transport.n, check.Equals, 1)
+}
+
+func (s *S) TestGetWithHandlers(c *check.C) {
+	testServer.Response(503, nil, "")
+	testServer.Response(200, nil, "content")
+
+	var sent []string
+	retries := 0
+	s3c := s3.New(s.s3.Auth, s.s3.Region)
+	s3c.Handlers = &aws.Handlers{
+		BeforeSign: []aws.Hook{func(r *aws.RequestInfo) {
+			r.Request.Header.Set("X-Amz-Meta-Trace", "trace-1")
+		}},
+		AfterResponse: []aws.Hook{func(r *aws.RequestInfo) {
+			sent = append(sent, fmt.Sprintf("%s.%s %d: %d", r.Service, r.Operation, r.Attempt, r.Response.StatusCode))
+		}},
+		OnRetry: []aws.Hook{func(r *aws.RequestInfo) {
+			retries++
+		}},
+	}
+	data, err := s3c.Bucket("bucket").Get("name")
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, "content")
+
+	testServer.WaitRequest()
+	req := testServer.WaitRequest()
+	c.Assert(req.Header.Get("X-Amz-Meta-Trace"), check.Equals, "trace-1")
+	c.Assert(sent, check.DeepEquals, []string{"s3.GetObject 1: 503", "s3.GetObject 2: 200"})
+	c.Assert(retries, check.Equals, 1)
+}
+
+func (s *S) TestHandlersOperation(c *check.C) {
+	var ops []string
+	s3c := s3.New(s.s3.Auth, s.s3.Region)
+	s3c.Handlers = &aws.Handlers{
+		BeforeSend: []aws.Hook{func(r *aws.RequestInfo) {
+			ops = append(ops, r.Operation)
+		}},
+	}
+	b := s3c.Bucket("bucket")
+	for i := 0; i < 5; i++ {
+		testServer.Response(200, nil, "")
+	}
+	c.Assert(b.PutBucket(s3.Private), check.IsNil)
+	c.Assert(b.Put("name", nil, "text/plain", s3.Private, s3.Options{}), check.IsNil)
+	c.Assert(b.Del("name"), check.IsNil)
+	c.Assert(b.DelBucket(), check.IsNil)
+	_, err := b.Head("name", nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(ops, check.DeepEquals, []string{"CreateBucket", "PutObject", "DeleteObject", "DeleteBucket", "HeadObject"})
+}
+
+func (s *S) TestGetWithCancelledContext(c *check.C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	b := s.s3.Bucket("bucket").WithContext(ctx)
+	_, err := b.Get("name")
+	c.Assert(err, check.ErrorMatches, ".*context canceled")
+}
+
 func (s *S) TestURL(c *check.C) {
 	testServer.Response(200, nil, "content")
 
@@ -149,6 +283,25 @@ func (s *S) TestGetReader(c *check.C) {
 	c.Assert(req.Header["Date"], check.Not(check.Equals), "")
 }
 
+func (s *S) TestGetCorrectsClockSkew(c *check.C) {
+	defer s.s3.Clock.SetOffset(0)
+	serverTime := time.Now().Add(time.Hour).UTC()
+	testServer.Response(403, map[string]string{"Date": serverTime.Format(http.TimeFormat)}, RequestTimeTooSkewedErrorDump)
+	testServer.Response(200, nil, "content")
+
+	b := s.s3.Bucket("bucket")
+	data, err := b.Get("name")
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, "content")
+
+	testServer.WaitRequest()
+	req := testServer.WaitRequest()
+	date, err := time.Parse(time.RFC1123, req.Header.Get("Date"))
+	c.Assert(err, check.IsNil)
+	c.Assert(date.Sub(serverTime) > -5*time.Second && date.Sub(serverTime) < 5*time.Second, check.Equals, true)
+	c.Assert(s.s3.Clock.Offset() > 59*time.Minute, check.Equals, true)
+}
+
 func (s *S) TestGetNotFound(c *check.C) {
 	for i := 0; i < 10; i++ {
 		testServer.Response(404, nil, GetObjectErrorDump)
@@ -236,6 +389,108 @@ func (s *S) TestPutReader(c *check.C) {
 	c.Assert(req.Header["X-Amz-Acl"], check.DeepEquals, []string{"private"})
 }
 
+func (s *S) TestPutSignV4(c *check.C) {
+	testServer.Response(200, nil, "")
+	testServer.Response(200, nil, "")
+	s3v4 := *s.s3
+	s3v4.SignV4 = true
+	b := s3v4.Bucket("bucket")
+
+	err := b.Put("a b+c", []byte("content"), "content-type", s3.Private, s3.Options{})
+	c.Assert(err, check.IsNil)
+	req := testServer.WaitRequest()
+	c.Assert(req.URL.EscapedPath(), check.Equals, "/bucket/a%20b%2Bc")
+	c.Assert(req.Header["Date"], check.IsNil)
+	c.Assert(req.Header["X-Amz-Date"], check.HasLen, 1)
+	c.Assert(req.Header["X-Amz-Content-Sha256"], check.DeepEquals, []string{"ed7002b439e9ac845f22357d822bac1444730fbdb6016d3ec9432297b9ec9f73"})
+	c.Assert(req.Header.Get("Authorization"), check.Matches,
+		`AWS4-HMAC-SHA256 Credential=abc/\d{8}/faux-region-1/s3/aws4_request, SignedHeaders=content-type;host;x-amz-acl;x-amz-content-sha256;x-amz-date, Signature=[0-9a-f]{64}`)
+
+	// Streams are not read twice.
+	buf := bytes.NewBufferString("content")
+	err = b.PutReader("name", buf, int64(buf.Len()), "content-type", s3.Private, s3.Options{})
+	c.Assert(err, check.IsNil)
+	req = testServer.WaitRequest()
+	c.Assert(req.Header["X-Amz-Content-Sha256"], check.DeepEquals, []string{"UNSIGNED-PAYLOAD"})
+	c.Assert(req.Header["Content-Length"], check.DeepEquals, []string{"7"})
+}
+
+func (s *S) TestSignedURLV4(c *check.C) {
+	s3v4 := *s.s3
+	s3v4.SignV4 = true
+	b := s3v4.Bucket("bucket")
+	expires := time.Now().Add(time.Hour)
+
+	u, err := url.Parse(b.SignedURL("a/b c", expires))
+	c.Assert(err, check.IsNil)
+	c.Assert(u.EscapedPath(), check.Equals, "/bucket/a/b%20c")
+	q := u.Query()
+	c.Assert(q.Get("X-Amz-Algorithm"), check.Equals, "AWS4-HMAC-SHA256")
+	c.Assert(q.Get("X-Amz-Credential"), check.Matches, `abc/\d{8}/faux-region-1/s3/aws4_request`)
+	c.Assert(q.Get("X-Amz-Expires"), check.Equals, "3600")
+	c.Assert(q.Get("X-Amz-SignedHeaders"), check.Equals, "host")
+	c.Assert(q.Get("X-Amz-Signature"), check.Matches, "[0-9a-f]{64}")
+
+	u, err = url.Parse(b.SignedURLWithMethod("PUT", "name", expires,
+		url.Values{"x-id": {"PutObject"}}, http.Header{"Content-Type": {"text/plain"}}))
+	c.Assert(err, check.IsNil)
+	q = u.Query()
+	c.Assert(q.Get("x-id"), check.Equals, "PutObject")
+	c.Assert(q.Get("X-Amz-SignedHeaders"), check.Equals, "content-type;host")
+	c.Assert(q.Get("Expires"), check.Equals, "")
+}
+
+func (s *S) TestSignedURLTooLate(c *check.C) {
+	s3v4 := *s.s3
+	s3v4.SignV4 = true
+	b := s3v4.Bucket("bucket")
+
+	u, err := b.SignURL("name", time.Now().Add(7*24*time.Hour))
+	c.Assert(err, check.IsNil)
+	c.Assert(u, check.Matches, ".*X-Amz-Expires=604800.*")
+	_, err = b.SignURL("name", time.Now().Add(8*24*time.Hour))
+	c.Assert(err, check.ErrorMatches, "s3: presigned URL expiry .* is more than 7 days ahead")
+	c.Assert(b.SignedURL("name", time.Now().Add(8*24*time.Hour)), check.Equals, "")
+}
+
+// expiredProvider retrieves expired credentials once, and then fails.
+type expiredProvider struct {
+	calls int32
+}
+
+func (p *expiredProvider) Retrieve() (aws.Auth, error) {
+	if atomic.AddInt32(&p.calls, 1) == 1 {
+		return aws.NewAuth("abc", "123", "token", time.Now().Add(-time.Minute)), nil
+	}
+	return aws.Auth{}, errors.New("no credentials")
+}
+
+func (s *S) TestSignedURLRefreshFailure(c *check.C) {
+	creds, err := aws.NewCredentials(&expiredProvider{})
+	c.Assert(err, check.IsNil)
+	defer creds.Stop()
+
+	for _, v4 := range []bool{false, true} {
+		s3c := *s.s3
+		s3c.Auth = creds.Auth()
+		s3c.SignV4 = v4
+		b := s3c.Bucket("bucket")
+		expires := time.Now().Add(time.Hour)
+
+		_, err = b.SignURL("name", expires)
+		c.Assert(err, check.ErrorMatches, ".*no credentials")
+		_, err = b.SignUploadURL("name", "PUT", "text/plain", expires)
+		c.Assert(err, check.ErrorMatches, ".*no credentials")
+		c.Assert(b.SignedURL("name", expires), check.Equals, "")
+		c.Assert(b.UploadSignedURL("name", "PUT", "text/plain", expires), check.Equals, "")
+	}
+
+	// Bad bucket names still panic.
+	s3c := *s.s3
+	s3c.Region.S3BucketEndpoint = "https://${bucket}.s3.example.com"
+	c.Assert(func() { s3c.Bucket("a/b").SignedURL("name", time.Now()) }, check.PanicMatches, "bad S3 bucket.*")
+}
+
 // DelObject docs: http://goo.gl/APeTt
 
 func (s *S) TestDelObject(c *check.C) {
@@ -338,6 +593,50 @@ func (s *S) TestListWithDelimiter(c *check.C) {
 	c.Assert(data.CommonPrefixes, check.DeepEquals, []string{"photos/2006/feb/", "photos/2006/jan/"})
 }
 
+func (s *S) TestListIter(c *check.C) {
+	testServer.Response(200, nil, GetListResultDumpTruncated)
+	testServer.Response(200, nil, GetListResultDump2)
+
+	iter := s.s3.Bucket("quotes").ListIter("photos/2006/", "/")
+	var entries []string
+	for iter.Next() {
+		if p := iter.Prefix(); p != "" {
+			entries = append(entries, "prefix "+p)
+		} else {
+			entries = append(entries, "key "+iter.Key().Key)
+		}
+	}
+	c.Assert(iter.Err(), check.IsNil)
+	c.Assert(entries, check.DeepEquals, []string{
+		"key photos/2006/c.jpg",
+		"key photos/2006/d.jpg",
+		"prefix photos/2006/apr/",
+		"prefix photos/2006/feb/",
+		"prefix photos/2006/jan/",
+	})
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Form["marker"], check.DeepEquals, []string{""})
+	req = testServer.WaitRequest()
+	c.Assert(req.Form["prefix"], check.DeepEquals, []string{"photos/2006/"})
+	c.Assert(req.Form["delimiter"], check.DeepEquals, []string{"/"})
+	c.Assert(req.Form["marker"], check.DeepEquals, []string{"photos/2006/d.jpg"})
+}
+
+func (s *S) TestListIterError(c *check.C) {
+	s.DisableRetries()
+	testServer.Response(200, nil, GetListResultDumpTruncated)
+	testServer.Response(404, nil, GetObjectErrorDump)
+
+	iter := s.s3.Bucket("quotes").ListIter("photos/2006/", "/")
+	n := 0
+	for iter.Next() {
+		n++
+	}
+	c.Assert(n, check.Equals, 3)
+	c.Assert(iter.Err(), check.ErrorMatches, "The specified bucket does not exist")
+}
+
 func (s *S) TestExists(c *check.C) {
 	testServer.Response(200, nil, "")
 
@@ -2,9 +2,16 @@ package s3_test
 
 import (
 	"github.com/crowdmob/goamz/aws"
+	"github.com/crowdmob/goamz/policy"
 	"github.com/crowdmob/goamz/s3"
 	"github.com/crowdmob/goamz/s3/s3test"
 	"gopkg.in/check.v1"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 )
 
 type LocalServer struct {
@@ -12,6 +19,7 @@ type LocalServer struct {
 	region aws.Region
 	srv    *s3test.Server
 	config *s3test.Config
+	signV4 bool
 }
 
 func (s *LocalServer) SetUp(c *check.C) {
@@ -27,6 +35,13 @@ func (s *LocalServer) SetUp(c *check.C) {
 	}
 }
 
+// client returns a client of the server signing requests with auth.
+func (s *LocalServer) client(auth aws.Auth) *s3.S3 {
+	client := s3.New(auth, s.region)
+	client.SignV4 = s.signV4
+	return client
+}
+
 // LocalServerSuite defines tests that will run
 // against the local s3test server. It includes
 // selected tests from ClientTests;
@@ -37,6 +52,11 @@ type LocalServerSuite struct {
 	clientTests ClientTests
 }
 
+var (
+	strictAuth = aws.Auth{AccessKey: "abc", SecretKey: "123"}
+	aliceAuth  = aws.Auth{AccessKey: "alice", SecretKey: "456"}
+)
+
 var (
 	// run tests twice, once in us-east-1 mode, once not.
 	_ = check.Suite(&LocalServerSuite{})
@@ -47,14 +67,35 @@ var (
 			},
 		},
 	})
+	// and once more checking the signatures of requests.
+	_ = check.Suite(&LocalServerSuite{
+		srv: LocalServer{
+			auth: strictAuth,
+			config: &s3test.Config{
+				Verifier:   aws.NewVerifier(strictAuth, aliceAuth),
+				Principals: map[string]string{aliceAuth.AccessKey: "arn:aws:iam::123456789012:user/alice"},
+			},
+		},
+	})
+	// and with Signature Version 4.
+	_ = check.Suite(&LocalServerSuite{
+		srv: LocalServer{
+			auth: strictAuth,
+			config: &s3test.Config{
+				Verifier:   aws.NewVerifier(strictAuth, aliceAuth),
+				Principals: map[string]string{aliceAuth.AccessKey: "arn:aws:iam::123456789012:user/alice"},
+			},
+			signV4: true,
+		},
+	})
 )
 
 func (s *LocalServerSuite) SetUpSuite(c *check.C) {
 	s.srv.SetUp(c)
-	s.clientTests.s3 = s3.New(s.srv.auth, s.srv.region)
+	s.clientTests.s3 = s.srv.client(s.srv.auth)
 
-	// TODO Sadly the fake server ignores auth completely right now. :-(
-	s.clientTests.authIsBroken = true
+	// The fake server ignores auth unless it has a verifier.
+	s.clientTests.authIsBroken = s.srv.config == nil || s.srv.config.Verifier == nil
 	s.clientTests.Cleanup()
 }
 
@@ -77,3 +118,141 @@ func (s *LocalServerSuite) TestBucketList(c *check.C) {
 func (s *LocalServerSuite) TestDoublePutBucket(c *check.C) {
 	s.clientTests.TestDoublePutBucket(c)
 }
+
+func (s *LocalServerSuite) TestBadSignature(c *check.C) {
+	if s.clientTests.authIsBroken {
+		c.Skip("the server does not verify signatures")
+	}
+	auth := aws.Auth{AccessKey: strictAuth.AccessKey, SecretKey: "456"}
+	b := s.srv.client(auth).Bucket("bucket")
+	_, err := b.Get("name")
+	s3err, ok := err.(*s3.Error)
+	c.Assert(ok, check.Equals, true)
+	c.Assert(s3err.StatusCode, check.Equals, 403)
+	c.Assert(s3err.Code, check.Equals, "SignatureDoesNotMatch")
+}
+
+func (s *LocalServerSuite) TestBucketPolicy(c *check.C) {
+	if s.clientTests.authIsBroken {
+		c.Skip("the server only enforces policies when it verifies signatures")
+	}
+	b := testBucket(s.clientTests.s3)
+	c.Assert(b.PutBucket(s3.Private), check.IsNil)
+	for _, name := range []string{"home/alice/a", "public/p", "secret"} {
+		c.Assert(b.Put(name, []byte(name), "text/plain", s3.Private, s3.Options{}), check.IsNil)
+		defer b.Del(name)
+	}
+	alice := s.srv.client(aliceAuth).Bucket(b.Name)
+	anonymousGet := func(name string) int {
+		resp, err := http.Get(b.URL(name))
+		c.Assert(err, check.IsNil)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	_, err := b.GetPolicy()
+	c.Assert(err, check.ErrorMatches, "The bucket policy does not exist")
+	_, err = alice.Get("home/alice/a")
+	c.Assert(err, check.ErrorMatches, "Access Denied")
+	c.Assert(anonymousGet("public/p"), check.Equals, 403)
+
+	arn := "arn:aws:s3:::" + b.Name
+	doc := policy.New(
+		policy.AllowStatement("s3:GetObject").On(arn+"/home/alice/*").By(policy.AWS("arn:aws:iam::123456789012:user/alice")),
+		policy.AllowStatement("s3:GetObject").On(arn+"/public/*").By(policy.Everyone()).When("IpAddress", "aws:SourceIp", "127.0.0.0/8"),
+		policy.DenyStatement("s3:DeleteObject").On(arn+"/home/*").By(policy.Everyone()),
+	)
+	c.Assert(b.PutPolicy(doc.String()), check.IsNil)
+	text, err := b.GetPolicy()
+	c.Assert(err, check.IsNil)
+	c.Assert(text, check.Equals, doc.String())
+
+	data, err := alice.Get("home/alice/a")
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, "home/alice/a")
+	_, err = alice.Get("secret")
+	c.Assert(err, check.ErrorMatches, "Access Denied")
+	c.Assert(alice.Put("home/alice/b", nil, "text/plain", s3.Private, s3.Options{}), check.ErrorMatches, "Access Denied")
+	c.Assert(anonymousGet("public/p"), check.Equals, 200)
+	c.Assert(anonymousGet("secret"), check.Equals, 403)
+
+	// The owner may do anything but what the policy denies.
+	_, err = b.Get("secret")
+	c.Assert(err, check.IsNil)
+	c.Assert(b.Del("home/alice/a"), check.ErrorMatches, "Access Denied")
+
+	c.Assert(b.PutPolicy(`{"Statement": {"Effect": "Allow", "Action": "s3:*", "Resource": "*"}}`), check.ErrorMatches, "Missing required field Principal")
+	c.Assert(b.DelPolicy(), check.IsNil)
+	_, err = b.GetPolicy()
+	c.Assert(err, check.ErrorMatches, "The bucket policy does not exist")
+	c.Assert(b.Del("home/alice/a"), check.IsNil)
+}
+
+func (s *LocalServerSuite) TestSignedURL(c *check.C) {
+	if s.clientTests.authIsBroken {
+		c.Skip("the server does not verify signatures")
+	}
+	b := testBucket(s.clientTests.s3)
+	c.Assert(b.PutBucket(s3.Private), check.IsNil)
+	do := func(method, u, contentType, body string) int {
+		req, err := http.NewRequest(method, u, strings.NewReader(body))
+		c.Assert(err, check.IsNil)
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		c.Assert(err, check.IsNil)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	expires := time.Now().Add(time.Hour)
+
+	header := http.Header{"Content-Type": {"text/plain"}}
+	u := b.SignedURLWithMethod("PUT", "a b/c", expires, nil, header)
+	c.Assert(do("PUT", u, "text/plain", "content"), check.Equals, 200)
+	// The content type is signed.
+	c.Assert(do("PUT", u, "text/html", "content"), check.Equals, 403)
+	data, err := b.Get("a b/c")
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, "content")
+
+	c.Assert(do("GET", b.SignedURL("a b/c", expires), "", ""), check.Equals, 200)
+	c.Assert(do("GET", b.SignedURL("a b/c", time.Now().Add(-time.Minute)), "", ""), check.Equals, 403)
+	// The method is signed too.
+	c.Assert(do("DELETE", b.SignedURL("a b/c", expires), "", ""), check.Equals, 403)
+	c.Assert(do("DELETE", b.SignedURLWithMethod("DELETE", "a b/c", expires, nil, nil), "", ""), check.Equals, 200)
+	_, err = b.Get("a b/c")
+	c.Assert(err, check.ErrorMatches, "The specified key does not exist.")
+}
+
+func (s *LocalServerSuite) TestDownload(c *check.C) {
+	b := testBucket(s.clientTests.s3)
+	c.Assert(b.PutBucket(s3.Private), check.IsNil)
+	content := strings.Repeat("0123456789", 100)
+	c.Assert(b.Put("name", []byte(content), "text/plain", s3.Private, s3.Options{}), check.IsNil)
+	defer b.Del("name")
+
+	f, err := os.Create(filepath.Join(c.MkDir(), "name"))
+	c.Assert(err, check.IsNil)
+	defer f.Close()
+	d := &s3.Downloader{Bucket: b, PartSize: 64, Concurrency: 4}
+	n, err := d.Download("name", f)
+	c.Assert(err, check.IsNil)
+	c.Assert(n, check.Equals, int64(len(content)))
+	data, err := ioutil.ReadFile(f.Name())
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, content)
+
+	r, err := b.GetReaderAt("name")
+	c.Assert(err, check.IsNil)
+	c.Assert(r.Size(), check.Equals, int64(len(content)))
+	tail := make([]byte, 5)
+	_, err = r.ReadAt(tail, 995)
+	c.Assert(err, check.IsNil)
+	c.Assert(string(tail), check.Equals, "56789")
+
+	// Reads fail once the object is replaced.
+	c.Assert(b.Put("name", []byte("other"), "text/plain", s3.Private, s3.Options{}), check.IsNil)
+	_, err = r.ReadAt(tail, 0)
+	c.Assert(err, check.ErrorMatches, "At least one of the pre-conditions you specified did not hold")
+}
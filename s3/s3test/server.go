@@ -7,6 +7,8 @@ import (
 	"encoding/hex"
 	"encoding/xml"
 	"fmt"
+	"github.com/crowdmob/goamz/aws"
+	"github.com/crowdmob/goamz/policy"
 	"github.com/crowdmob/goamz/s3"
 	"io"
 	"io/ioutil"
@@ -51,6 +53,19 @@ type Config struct {
 	// all other regions.
 	// http://docs.amazonwebservices.com/AmazonS3/latest/API/ErrorResponses.html
 	Send409Conflict bool
+
+	// Verifier, if set, makes the Server reject requests that are not
+	// signed with credentials known to it, as S3 does, and enforce
+	// bucket policies. Requests without a signature are only allowed
+	// to read public buckets, and what bucket policies allow everyone.
+	Verifier *aws.Verifier
+
+	// Principals maps access keys to the ARNs of the IAM users or roles
+	// they belong to. With a Verifier, requests signed with these keys
+	// are only allowed what bucket policies allow them. Requests signed
+	// with other keys are made by the owner of the buckets, and allowed
+	// what bucket policies don't deny.
+	Principals map[string]string
 }
 
 func (c *Config) send409Conflict() bool {
@@ -60,6 +75,25 @@ func (c *Config) send409Conflict() bool {
 	return false
 }
 
+func (c *Config) verifier() *aws.Verifier {
+	if c != nil {
+		return c.Verifier
+	}
+	return nil
+}
+
+// ownerARN is the principal of requests made by the owner of the
+// buckets.
+const ownerARN = "arn:aws:iam::123456789012:root"
+
+// principal returns the principal of requests signed with accessKey.
+func (c *Config) principal(accessKey string) string {
+	if p, ok := c.Principals[accessKey]; ok {
+		return p
+	}
+	return ownerARN
+}
+
 // Server is a fake S3 server for testing purposes.
 // All of the data for the server is kept in memory.
 type Server struct {
@@ -72,10 +106,12 @@ type Server struct {
 }
 
 type bucket struct {
-	name    string
-	acl     s3.ACL
-	ctime   time.Time
-	objects map[string]*object
+	name       string
+	acl        s3.ACL
+	ctime      time.Time
+	objects    map[string]*object
+	policy     *policy.Document
+	policyText string // the policy as it was put.
 }
 
 type object struct {
@@ -133,6 +169,11 @@ func fatalf(code int, codeStr string, errf string, a ...interface{}) {
 
 // serveHTTP serves the S3 protocol.
 func (srv *Server) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	// The signature is verified before the body is read by ParseForm.
+	var verr error
+	if v := srv.config.verifier(); v != nil {
+		verr = v.Verify(req)
+	}
 	// ignore error from ParseForm as it's usually spurious.
 	req.ParseForm()
 
@@ -171,7 +212,21 @@ func (srv *Server) serveHTTP(w http.ResponseWriter, req *http.Request) {
 		}
 	}()
 
+	unsigned := false
+	if e, ok := verr.(*aws.SignatureError); ok {
+		if e.Code != "MissingAuthenticationToken" {
+			fatalf(e.StatusCode, e.Code, "%s", e.Message)
+		}
+		unsigned = true
+	}
 	r = srv.resourceForURL(req.URL)
+	if srv.config.verifier() != nil {
+		principal := ""
+		if !unsigned {
+			principal = srv.config.principal(aws.AccessKeyID(req))
+		}
+		authorize(req, r, principal)
+	}
 
 	var resp interface{}
 	switch req.Method {
@@ -191,6 +246,109 @@ func (srv *Server) serveHTTP(w http.ResponseWriter, req *http.Request) {
 	}
 }
 
+// authorize checks that principal may make req on r, as allowed by the
+// policy of the bucket. The owner of the buckets may do anything the
+// policy doesn't deny, and anonymous requests, with an empty principal,
+// may also read public buckets.
+func authorize(req *http.Request, r resource, principal string) {
+	b := resourceBucket(r)
+	decision := policy.ImplicitDeny
+	if b != nil && b.policy != nil {
+		pr := &policy.Request{
+			Principal: principal,
+			Action:    s3Action(req, r),
+			Resource:  resourceARN(r),
+			Context:   policy.RequestContext(req),
+		}
+		q := req.URL.Query()
+		for _, key := range []string{"prefix", "delimiter", "max-keys"} {
+			if v, ok := q[key]; ok {
+				pr.Context["s3:"+key] = v
+			}
+		}
+		if acl := req.Header.Get("x-amz-acl"); acl != "" {
+			pr.Context["s3:x-amz-acl"] = []string{acl}
+		}
+		decision = policy.Evaluate(pr, b.policy)
+	}
+	switch {
+	case decision == policy.ExplicitDeny:
+	case decision == policy.Allowed, principal == ownerARN:
+		return
+	case principal == "" && isPublicRead(req, b):
+		return
+	}
+	fatalf(403, "AccessDenied", "Access Denied")
+}
+
+// resourceBucket returns the existing bucket r is in, or nil.
+func resourceBucket(r resource) *bucket {
+	switch r := r.(type) {
+	case objectResource:
+		return r.bucket
+	case bucketResource:
+		return r.bucket
+	case policyResource:
+		return r.bucket
+	}
+	return nil
+}
+
+// resourceARN returns the ARN of the bucket or object r, or "*".
+func resourceARN(r resource) string {
+	switch r := r.(type) {
+	case objectResource:
+		return "arn:aws:s3:::" + r.bucket.name + "/" + r.name
+	case bucketResource:
+		return "arn:aws:s3:::" + r.name
+	case policyResource:
+		return "arn:aws:s3:::" + r.name
+	}
+	return "*"
+}
+
+// s3Action returns the policy action of req on r, as in "s3:GetObject".
+func s3Action(req *http.Request, r resource) string {
+	switch r.(type) {
+	case objectResource:
+		switch req.Method {
+		case "GET", "HEAD":
+			return "s3:GetObject"
+		case "DELETE":
+			return "s3:DeleteObject"
+		}
+		return "s3:PutObject"
+	case bucketResource:
+		switch req.Method {
+		case "GET", "HEAD":
+			return "s3:ListBucket"
+		case "PUT":
+			return "s3:CreateBucket"
+		case "POST":
+			return "s3:DeleteObject"
+		}
+		return "s3:DeleteBucket"
+	case policyResource:
+		switch req.Method {
+		case "GET", "HEAD":
+			return "s3:GetBucketPolicy"
+		case "DELETE":
+			return "s3:DeleteBucketPolicy"
+		}
+		return "s3:PutBucketPolicy"
+	}
+	return "s3:ListAllMyBuckets"
+}
+
+// isPublicRead reports whether req reads the public bucket b, or an
+// object in it, so that it may be sent unsigned.
+func isPublicRead(req *http.Request, b *bucket) bool {
+	if req.Method != "GET" && req.Method != "HEAD" {
+		return false
+	}
+	return b != nil && (b.acl == s3.PublicRead || b.acl == s3.PublicReadWrite)
+}
+
 // xmlMarshal is the same as xml.Marshal except that
 // it panics on error. The marshalling should not fail,
 // but we want to know if it does.
@@ -205,7 +363,6 @@ func xmlMarshal(w io.Writer, x interface{}) {
 var unimplementedBucketResourceNames = map[string]bool{
 	"acl":            true,
 	"lifecycle":      true,
-	"policy":         true,
 	"location":       true,
 	"logging":        true,
 	"notification":   true,
@@ -242,6 +399,9 @@ func (srv *Server) resourceForURL(u *url.URL) (r resource) {
 	}
 	q := u.Query()
 	if objectName == "" {
+		if _, ok := q["policy"]; ok {
+			return policyResource{b}
+		}
 		for name := range q {
 			if unimplementedBucketResourceNames[name] {
 				return nullResource{}
@@ -437,6 +597,66 @@ func (bucketResource) post(a *action) interface{} {
 	return nil
 }
 
+// policyResource is the policy of a bucket.
+type policyResource struct {
+	bucketResource
+}
+
+// GET on the policy of a bucket returns it.
+// http://docs.aws.amazon.com/AmazonS3/latest/API/RESTBucketGETpolicy.html
+func (r policyResource) get(a *action) interface{} {
+	if r.bucket == nil {
+		fatalf(404, "NoSuchBucket", "The specified bucket does not exist")
+	}
+	if r.bucket.policy == nil {
+		fatalf(404, "NoSuchBucketPolicy", "The bucket policy does not exist")
+	}
+	a.w.Header().Set("Content-Type", "application/json")
+	io.WriteString(a.w, r.bucket.policyText)
+	return nil
+}
+
+// PUT on the policy of a bucket sets it.
+// http://docs.aws.amazon.com/AmazonS3/latest/API/RESTBucketPUTpolicy.html
+func (r policyResource) put(a *action) interface{} {
+	if r.bucket == nil {
+		fatalf(404, "NoSuchBucket", "The specified bucket does not exist")
+	}
+	data, err := ioutil.ReadAll(a.req.Body)
+	if err != nil {
+		fatalf(400, "IncompleteBody", "Cannot read the policy: %v", err)
+	}
+	doc, err := policy.Parse(string(data))
+	if err != nil {
+		fatalf(400, "MalformedPolicy", "%v", err)
+	}
+	for _, st := range doc.Statement {
+		if st.Principal == nil && st.NotPrincipal == nil {
+			fatalf(400, "MalformedPolicy", "Missing required field Principal")
+		}
+	}
+	r.bucket.policy = doc
+	r.bucket.policyText = string(data)
+	a.w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// DELETE on the policy of a bucket removes it.
+// http://docs.aws.amazon.com/AmazonS3/latest/API/RESTBucketDELETEpolicy.html
+func (r policyResource) delete(a *action) interface{} {
+	if r.bucket == nil {
+		fatalf(404, "NoSuchBucket", "The specified bucket does not exist")
+	}
+	r.bucket.policy = nil
+	r.bucket.policyText = ""
+	a.w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (policyResource) post(a *action) interface{} {
+	return notAllowed()
+}
+
 // validBucketName returns whether name is a valid bucket name.
 // Here are the rules, from:
 // http://docs.amazonwebservices.com/AmazonS3/2006-03-01/dev/BucketRestrictions.html
@@ -512,24 +732,36 @@ func (objr objectResource) get(a *action) interface{} {
 			h.Set(name, vals[0])
 		}
 	}
-	if r := a.req.Header.Get("Range"); r != "" {
-		fatalf(400, "NotImplemented", "range unimplemented")
+	etag := hex.EncodeToString(obj.checksum)
+	if m := a.req.Header.Get("If-Match"); m != "" && strings.Trim(m, `"`) != etag {
+		fatalf(412, "PreconditionFailed", "At least one of the pre-conditions you specified did not hold")
+	}
+	data := obj.data
+	status := http.StatusOK
+	if r := a.req.Header.Get("Range"); r != "" && a.req.Method != "HEAD" {
+		start, end, ok := parseRange(r, int64(len(data)))
+		if !ok {
+			fatalf(416, "InvalidRange", "The requested range is not satisfiable")
+		}
+		h.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+		data = data[start : end+1]
+		status = http.StatusPartialContent
 	}
 	// TODO Last-Modified-Since
 	// TODO If-Modified-Since
 	// TODO If-Unmodified-Since
-	// TODO If-Match
 	// TODO If-None-Match
 	// TODO Connection: close ??
 	// TODO x-amz-request-id
-	h.Set("Content-Length", fmt.Sprint(len(obj.data)))
-	h.Set("ETag", hex.EncodeToString(obj.checksum))
+	h.Set("Content-Length", fmt.Sprint(len(data)))
+	h.Set("ETag", etag)
 	h.Set("Last-Modified", obj.mtime.Format(time.RFC1123))
 	if a.req.Method == "HEAD" {
 		return nil
 	}
+	a.w.WriteHeader(status)
 	// TODO avoid holding the lock when writing data.
-	_, err := a.w.Write(obj.data)
+	_, err := a.w.Write(data)
 	if err != nil {
 		// we can't do much except just log the fact.
 		log.Printf("error writing data: %v", err)
@@ -537,6 +769,47 @@ func (objr objectResource) get(a *action) interface{} {
 	return nil
 }
 
+// parseRange parses the single byte range r of the Range header of a
+// request for an object of the given size, and returns the offsets of its
+// first and last bytes. It reports whether the range is satisfiable.
+func parseRange(r string, size int64) (start, end int64, ok bool) {
+	if !strings.HasPrefix(r, "bytes=") || strings.Contains(r, ",") {
+		fatalf(400, "NotImplemented", "only single byte ranges are implemented")
+	}
+	spec := r[len("bytes="):]
+	i := strings.Index(spec, "-")
+	if i < 0 {
+		fatalf(400, "InvalidArgument", "Invalid range %q", r)
+	}
+	first, last := spec[:i], spec[i+1:]
+	var err error
+	switch {
+	case first == "":
+		// The last bytes of the object.
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n <= 0 {
+			return 0, 0, false
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, size - 1, size > 0
+	case last == "":
+		end = size - 1
+	default:
+		if end, err = strconv.ParseInt(last, 10, 64); err != nil {
+			return 0, 0, false
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+	if start, err = strconv.ParseInt(first, 10, 64); err != nil || start > end {
+		return 0, 0, false
+	}
+	return start, end, true
+}
+
 var metaHeaders = map[string]bool{
 	"Content-MD5":         true,
 	"x-amz-acl":           true,
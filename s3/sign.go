@@ -3,11 +3,18 @@ package s3
 import (
 	"crypto/hmac"
 	"crypto/sha1"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
+	"fmt"
 	"github.com/crowdmob/goamz/aws"
+	"io"
 	"log"
+	"net/http"
+	"net/url"
 	"sort"
 	"strings"
+	"time"
 )
 
 var b64 = base64.StdEncoding
@@ -112,3 +119,108 @@ func sign(auth aws.Auth, method, canonicalPath string, params, headers map[strin
 		log.Printf("Signature: %q", signature)
 	}
 }
+
+// ----------------------------------------------------------------------------
+// Signature Version 4 (http://goo.gl/Q8Kdvb)
+
+// unsignedPayload replaces the hash of payloads that cannot be read twice.
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// maxPresignValidity is the longest time a presigned URL may be valid.
+const maxPresignValidity = 7 * 24 * time.Hour
+
+// signV4 signs req, once prepared, with Signature Version 4.
+func (s3 *S3) signV4(req *request, auth aws.Auth) error {
+	if _, ok := req.headers["X-Amz-Content-Sha256"]; !ok {
+		hash, err := payloadHash(req.payload)
+		if err != nil {
+			return err
+		}
+		req.headers["X-Amz-Content-Sha256"] = []string{hash}
+	}
+	// Every attempt is signed afresh, with the current time.
+	delete(req.headers, "Authorization")
+	delete(req.headers, "X-Amz-Date")
+	u, err := s3.url(req)
+	if err != nil {
+		return err
+	}
+	hreq := &http.Request{Method: req.method, URL: u, Host: u.Host, Header: req.headers}
+	signer := s3.signerV4(auth)
+	signer.Sign(hreq)
+	if debug {
+		log.Printf("Authorization: %q", req.headers.Get("Authorization"))
+	}
+	return nil
+}
+
+// presignV4 returns the URL of req, once prepared, signed until expires
+// with Signature Version 4.
+func (s3 *S3) presignV4(req *request, expires time.Time) (*url.URL, error) {
+	auth, err := s3.Auth.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	u, err := s3.url(req)
+	if err != nil {
+		return nil, err
+	}
+	hreq := &http.Request{Method: req.method, URL: u, Host: u.Host, Header: req.headers}
+	signer := s3.signerV4(auth)
+	validity := expires.Sub(s3.Clock.Now())
+	if validity < 0 {
+		validity = 0
+	}
+	// Round up, so that the URL is valid until expires.
+	validity = (validity + time.Second - 1) / time.Second * time.Second
+	if validity > maxPresignValidity {
+		return nil, fmt.Errorf("s3: presigned URL expiry %s is more than 7 days ahead", expires.Format(time.RFC3339))
+	}
+	signer.Presign(hreq, validity)
+	return u, nil
+}
+
+func (s3 *S3) signerV4(auth aws.Auth) *aws.V4Signer {
+	region := s3.Region
+	if region.Name == "" {
+		region.Name = "us-east-1"
+	}
+	signer := aws.NewV4Signer(auth, "s3", region)
+	signer.Clock = s3.Clock
+	return signer
+}
+
+// payloadHash returns the hex encoded SHA-256 hash of payload, which is
+// read and then rewound. Payloads that cannot be rewound are streamed
+// without being signed.
+func payloadHash(payload io.Reader) (string, error) {
+	h := sha256.New()
+	if payload == nil {
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+	seeker, ok := payload.(io.ReadSeeker)
+	if !ok {
+		return unsignedPayload, nil
+	}
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(h, seeker); err != nil {
+		return "", err
+	}
+	if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// escapePath escapes every byte of path but the unreserved characters
+// and slashes, as V4 signatures of S3 requests expect.
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		segments[i] = aws.Encode(s)
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,427 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/crowdmob/goamz/aws"
+	"io"
+	"sort"
+	"sync"
+)
+
+// That's the S3 minimum. Here just for testing.
+var minPartSize int64 = 5 << 20
+
+const (
+	// maxPartSize and maxParts are the S3 limits on the size of
+	// each part and on the number of parts of a multipart upload.
+	maxPartSize = 5 << 30
+	maxParts    = 10000
+
+	// partSizeDoubling is the number of parts after which the
+	// Uploader doubles the part size. Starting with 5MB parts, that
+	// fits objects of up to 5TB, the largest S3 stores, in maxParts.
+	partSizeDoubling = 1000
+
+	defaultConcurrency = 5
+)
+
+// An Uploader uploads objects read from streams of unknown size, such as
+// pipes, through multipart uploads sending several parts at once.
+//
+// Parts are read into at most Concurrency buffers, so that memory use is
+// bounded by Concurrency times the part size. Parts start at PartSize
+// bytes and their size doubles every 1000 parts, so that the object fits
+// in the 10,000 parts a multipart upload may have:
+//
+//	u := &s3.Uploader{Bucket: b, Concurrency: 8}
+//	err := u.Upload("logs/today", os.Stdin, "text/plain", s3.Private)
+type Uploader struct {
+	Bucket *Bucket
+
+	// PartSize is the size of the first parts. It is raised to the
+	// 5MB S3 minimum if lower.
+	PartSize int64
+
+	// Concurrency is the number of parts sent at once. If zero, 5
+	// parts are.
+	Concurrency int
+
+	// RetryPolicy controls how the requests sending a part are
+	// retried. If nil, the policy of the bucket's client is used.
+	RetryPolicy *aws.RetryPolicy
+
+	// Progress, if set, is called each time a part has been sent.
+	// Calls are serialized.
+	Progress func(UploadProgress)
+
+	// Checkpoints records the progress of UploadAt. If nil, a
+	// FileCheckpointStore in the default directory is used.
+	Checkpoints CheckpointStore
+}
+
+// UploadProgress reports how much of an object the Uploader has sent.
+type UploadProgress struct {
+	Parts int   // Parts sent.
+	Bytes int64 // Bytes sent in those parts.
+}
+
+// Upload sends all of r to key through a new multipart upload initiated
+// with contType and perm, and completes the upload once r is exhausted.
+// If r fails or a part cannot be sent, the other parts are cancelled,
+// the multipart upload is aborted and the error is returned.
+func (u *Uploader) Upload(key string, r io.Reader, contType string, perm ACL) error {
+	m, err := u.Bucket.InitMulti(key, contType, perm)
+	if err != nil {
+		return err
+	}
+	parts, err := u.upload(m, r)
+	if err == nil {
+		err = m.Complete(parts)
+	}
+	if err != nil {
+		// Abort even when the upload was cancelled, so that the
+		// parts sent do not linger.
+		m.WithContext(context.Background()).Abort()
+		return err
+	}
+	return nil
+}
+
+// partSize returns the size of part n.
+func (u *Uploader) partSize(n int) int64 {
+	size := u.PartSize
+	if size < minPartSize {
+		size = minPartSize
+	}
+	size <<= uint((n - 1) / partSizeDoubling)
+	if size > maxPartSize {
+		size = maxPartSize
+	}
+	return size
+}
+
+// concurrency returns the number of parts sent at once.
+func (u *Uploader) concurrency() int {
+	if u.Concurrency <= 0 {
+		return defaultConcurrency
+	}
+	return u.Concurrency
+}
+
+// sender returns a copy of m sending parts with policy, if not nil, and
+// bound to a context cancelled by the returned function.
+func (m *Multi) sender(policy *aws.RetryPolicy) (*Multi, context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(m.Bucket.S3.Context())
+	s3 := m.Bucket.S3.WithContext(ctx)
+	if policy != nil {
+		s3.RetryPolicy = policy
+	}
+	c := *m
+	c.Bucket = &Bucket{s3, m.Bucket.Name}
+	return &c, ctx, cancel
+}
+
+// upload sends all of r as the parts of m, and returns them ordered by
+// part number.
+func (u *Uploader) upload(m *Multi, r io.Reader) ([]Part, error) {
+	m, ctx, cancel := m.sender(u.RetryPolicy)
+	defer cancel()
+	concurrency := u.concurrency()
+	// Buffers are handed from the reading loop to the senders and
+	// back, and allocated on first use.
+	free := make(chan []byte, concurrency)
+	for i := 0; i < concurrency; i++ {
+		free <- nil
+	}
+	type job struct {
+		n    int
+		data []byte
+	}
+	jobs := make(chan job)
+
+	var (
+		mu       sync.Mutex
+		parts    partSlice
+		progress UploadProgress
+		failed   error
+	)
+	fail := func(err error) {
+		mu.Lock()
+		if failed == nil {
+			failed = err
+			cancel()
+		}
+		mu.Unlock()
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				if ctx.Err() != nil {
+					// The upload failed while j was handed over.
+					free <- j.data
+					continue
+				}
+				part, err := m.putBytes(j.n, j.data)
+				free <- j.data
+				if err != nil {
+					fail(err)
+					continue
+				}
+				mu.Lock()
+				parts = append(parts, part)
+				progress.Parts++
+				progress.Bytes += part.Size
+				if u.Progress != nil {
+					u.Progress(progress)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for n := 1; ctx.Err() == nil; n++ {
+		var buf []byte
+		select {
+		case buf = <-free:
+		case <-ctx.Done():
+			continue
+		}
+		size := u.partSize(n)
+		if int64(cap(buf)) < size {
+			buf = make([]byte, size)
+		}
+		read, err := io.ReadFull(r, buf[:size])
+		if err == io.EOF && n > 1 {
+			// An empty stream is sent as a single empty part.
+			break
+		}
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			fail(err)
+			break
+		}
+		if n > maxParts {
+			fail(errors.New("s3: object too large for a multipart upload"))
+			break
+		}
+		select {
+		case jobs <- job{n, buf[:read]}:
+		case <-ctx.Done():
+		}
+		if err != nil {
+			break
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if failed != nil {
+		return nil, failed
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	sort.Sort(parts)
+	return parts, nil
+}
+
+// putBytes sends data as part n of m.
+func (m *Multi) putBytes(n int, data []byte) (Part, error) {
+	r := bytes.NewReader(data)
+	_, _, md5b64, err := seekerInfo(r)
+	if err != nil {
+		return Part{}, err
+	}
+	return m.putPart(n, r, int64(len(data)), md5b64)
+}
+
+// UploadAt sends the size bytes of r to key through a multipart upload
+// initiated with contType and perm, sending several parts at once.
+//
+// The upload is recorded in u.Checkpoints as parts are sent, so that when
+// UploadAt fails or the process is interrupted, calling UploadAt again
+// for the same key resumes the upload: the parts sent already are neither
+// read nor hashed again. r must then hold the same content, as only its
+// size is checked. The multipart upload is left in place when UploadAt
+// fails, for it to be resumed; see AbortOrphanedMulti for cleaning up
+// the uploads that are never resumed.
+func (u *Uploader) UploadAt(key string, r io.ReaderAt, size int64, contType string, perm ACL) error {
+	store := u.Checkpoints
+	if store == nil {
+		store = FileCheckpointStore{}
+	}
+	b := u.Bucket
+	cp, err := store.Load(b.Name, key)
+	if err != nil {
+		return err
+	}
+	m, err := u.resume(cp, key, size)
+	if err != nil {
+		return err
+	}
+	if m == nil {
+		partSize, err := fixedPartSize(u.partSize(1), size)
+		if err != nil {
+			return err
+		}
+		if m, err = b.InitMulti(key, contType, perm); err != nil {
+			return err
+		}
+		cp = &Checkpoint{Bucket: b.Name, Key: key, UploadId: m.UploadId, Size: size, PartSize: partSize}
+		if err := store.Save(cp); err != nil {
+			return err
+		}
+	}
+	parts, err := u.uploadAt(m, r, cp, store)
+	if err != nil {
+		return err
+	}
+	if err := m.Complete(parts); err != nil {
+		return err
+	}
+	return store.Delete(b.Name, key)
+}
+
+// resume returns the multipart upload recorded by cp, after dropping from
+// cp the parts S3 does not have. It returns nil if the upload cannot be
+// resumed, aborting it if it is for other content.
+func (u *Uploader) resume(cp *Checkpoint, key string, size int64) (*Multi, error) {
+	if cp == nil || cp.UploadId == "" {
+		return nil, nil
+	}
+	m := &Multi{Bucket: u.Bucket, Key: key, UploadId: cp.UploadId}
+	if cp.Size != size || cp.PartSize <= 0 {
+		m.Abort()
+		return nil, nil
+	}
+	listed, err := m.ListParts()
+	if hasCode(err, "NoSuchUpload") {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	sent := make(map[int]Part)
+	for _, p := range listed {
+		sent[p.N] = p
+	}
+	var parts []CheckpointPart
+	for _, p := range cp.Parts {
+		if s, ok := sent[p.N]; ok && s.ETag == p.ETag && s.Size == p.Size {
+			parts = append(parts, p)
+		}
+	}
+	cp.Parts = parts
+	return m, nil
+}
+
+// fixedPartSize returns the size of the parts of an object of the given
+// size, raising partSize so that the object fits in the parts a multipart
+// upload may have.
+func fixedPartSize(partSize, size int64) (int64, error) {
+	if min := (size + maxParts - 1) / maxParts; partSize < min {
+		partSize = min
+	}
+	if partSize > maxPartSize {
+		return 0, errors.New("s3: object too large for a multipart upload")
+	}
+	return partSize, nil
+}
+
+// uploadAt sends the parts of r that cp does not record as sent, saving
+// cp in store as they are, and returns all the parts ordered by part
+// number.
+func (u *Uploader) uploadAt(m *Multi, r io.ReaderAt, cp *Checkpoint, store CheckpointStore) ([]Part, error) {
+	m, ctx, cancel := m.sender(u.RetryPolicy)
+	defer cancel()
+
+	var progress UploadProgress
+	sent := make(map[int]bool)
+	for _, p := range cp.Parts {
+		sent[p.N] = true
+		progress.Parts++
+		progress.Bytes += p.Size
+	}
+	var (
+		mu     sync.Mutex
+		failed error
+	)
+	fail := func(err error) {
+		mu.Lock()
+		if failed == nil {
+			failed = err
+			cancel()
+		}
+		mu.Unlock()
+	}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < u.concurrency(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range jobs {
+				if ctx.Err() != nil {
+					continue
+				}
+				offset := int64(n-1) * cp.PartSize
+				size := cp.PartSize
+				if offset+size > cp.Size {
+					size = cp.Size - offset
+				}
+				part, err := m.PutPart(n, io.NewSectionReader(r, offset, size))
+				if err != nil {
+					fail(err)
+					continue
+				}
+				mu.Lock()
+				cp.Parts = append(cp.Parts, CheckpointPart{N: n, ETag: part.ETag, Offset: offset, Size: size})
+				err = store.Save(cp)
+				progress.Parts++
+				progress.Bytes += size
+				if u.Progress != nil {
+					u.Progress(progress)
+				}
+				mu.Unlock()
+				if err != nil {
+					fail(err)
+				}
+			}
+		}()
+	}
+	count := int((cp.Size + cp.PartSize - 1) / cp.PartSize)
+	if count == 0 {
+		// An empty object is sent as a single empty part.
+		count = 1
+	}
+Send:
+	for n := 1; n <= count; n++ {
+		if sent[n] {
+			continue
+		}
+		select {
+		case jobs <- n:
+		case <-ctx.Done():
+			break Send
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if failed != nil {
+		return nil, failed
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	parts := make(partSlice, len(cp.Parts))
+	for i, p := range cp.Parts {
+		parts[i] = Part{N: p.N, ETag: p.ETag, Size: p.Size}
+	}
+	sort.Sort(parts)
+	return parts, nil
+}
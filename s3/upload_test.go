@@ -0,0 +1,174 @@
+package s3_test
+
+import (
+	"encoding/xml"
+	"github.com/crowdmob/goamz/aws"
+	"github.com/crowdmob/goamz/s3"
+	"gopkg.in/check.v1"
+	"io"
+	"strings"
+)
+
+// stream hides the size of a reader from the Uploader.
+type stream struct {
+	io.Reader
+}
+
+func (s *S) TestUploaderUpload(c *check.C) {
+	s3.SetMinPartSize(5)
+	defer s3.SetMinPartSize(5 << 20)
+	testServer.Response(200, nil, InitMultiResultDump)
+	testServer.Responses(3, 200, map[string]string{"ETag": `"etag"`}, "")
+	testServer.Response(200, nil, "")
+
+	var progress []s3.UploadProgress
+	u := &s3.Uploader{
+		Bucket:      s.s3.Bucket("sample"),
+		Concurrency: 3,
+		Progress: func(p s3.UploadProgress) {
+			progress = append(progress, p)
+		},
+	}
+	err := u.Upload("multi", stream{strings.NewReader("0123456789abc")}, "text/plain", s3.Private)
+	c.Assert(err, check.IsNil)
+
+	req := testServer.WaitRequest()
+	c.Assert(req.Method, check.Equals, "POST")
+	c.Assert(req.Form["uploads"], check.DeepEquals, []string{""})
+	parts := make(map[string]string)
+	for _, req := range testServer.WaitRequests(3) {
+		c.Assert(req.Method, check.Equals, "PUT")
+		c.Assert(req.Form.Get("uploadId"), check.Matches, "JNbR_[A-Za-z0-9.]+QQ--")
+		parts[req.Form.Get("partNumber")] = readAll(req.Body)
+	}
+	c.Assert(parts, check.DeepEquals, map[string]string{"1": "01234", "2": "56789", "3": "abc"})
+
+	req = testServer.WaitRequest()
+	c.Assert(req.Method, check.Equals, "POST")
+	var payload struct {
+		Part []struct {
+			PartNumber int
+		}
+	}
+	c.Assert(xml.NewDecoder(req.Body).Decode(&payload), check.IsNil)
+	c.Assert(payload.Part, check.HasLen, 3)
+	c.Assert(payload.Part[2].PartNumber, check.Equals, 3)
+
+	c.Assert(progress, check.HasLen, 3)
+	c.Assert(progress[2], check.Equals, s3.UploadProgress{Parts: 3, Bytes: 13})
+}
+
+func (s *S) TestUploaderEmpty(c *check.C) {
+	testServer.Response(200, nil, InitMultiResultDump)
+	testServer.Response(200, map[string]string{"ETag": `"etag"`}, "")
+	testServer.Response(200, nil, "")
+
+	u := &s3.Uploader{Bucket: s.s3.Bucket("sample")}
+	err := u.Upload("multi", stream{strings.NewReader("")}, "text/plain", s3.Private)
+	c.Assert(err, check.IsNil)
+
+	testServer.WaitRequest()
+	req := testServer.WaitRequest()
+	c.Assert(req.Method, check.Equals, "PUT")
+	c.Assert(req.Form["partNumber"], check.DeepEquals, []string{"1"})
+	c.Assert(readAll(req.Body), check.Equals, "")
+	req = testServer.WaitRequest()
+	c.Assert(req.Method, check.Equals, "POST")
+}
+
+func (s *S) TestUploaderAbort(c *check.C) {
+	s3.SetMinPartSize(5)
+	defer s3.SetMinPartSize(5 << 20)
+	testServer.Response(200, nil, InitMultiResultDump)
+	// The first part succeeds once retried.
+	testServer.Response(500, nil, InternalErrorDump)
+	testServer.Response(200, map[string]string{"ETag": `"etag"`}, "")
+	testServer.Response(403, nil, AccessDeniedErrorDump)
+	testServer.Response(204, nil, "")
+
+	u := &s3.Uploader{
+		Bucket:      s.s3.Bucket("sample"),
+		Concurrency: 1,
+		RetryPolicy: &aws.RetryPolicy{MaxAttempts: 2},
+	}
+	err := u.Upload("multi", stream{strings.NewReader("0123456789abc")}, "text/plain", s3.Private)
+	c.Assert(err, check.ErrorMatches, "Access Denied")
+
+	reqs := testServer.WaitRequests(5)
+	c.Assert(reqs[1].Form["partNumber"], check.DeepEquals, []string{"1"})
+	c.Assert(reqs[2].Form["partNumber"], check.DeepEquals, []string{"1"})
+	c.Assert(reqs[3].Form["partNumber"], check.DeepEquals, []string{"2"})
+	c.Assert(reqs[4].Method, check.Equals, "DELETE")
+	c.Assert(reqs[4].Form.Get("uploadId"), check.Matches, "JNbR_[A-Za-z0-9.]+QQ--")
+}
+
+func (s *S) TestUploaderPartSize(c *check.C) {
+	u := &s3.Uploader{}
+	c.Assert(s3.UploaderPartSize(u, 1), check.Equals, int64(5<<20))
+	c.Assert(s3.UploaderPartSize(u, 1000), check.Equals, int64(5<<20))
+	c.Assert(s3.UploaderPartSize(u, 1001), check.Equals, int64(10<<20))
+	c.Assert(s3.UploaderPartSize(u, 10000), check.Equals, int64(5<<29))
+
+	// Parts are never larger than 5GB.
+	u.PartSize = 1 << 30
+	c.Assert(s3.UploaderPartSize(u, 1), check.Equals, int64(1<<30))
+	c.Assert(s3.UploaderPartSize(u, 3001), check.Equals, int64(5<<30))
+}
+
+func (s *S) TestUploaderUploadAtResume(c *check.C) {
+	s3.SetMinPartSize(5)
+	defer s3.SetMinPartSize(5 << 20)
+	testServer.Response(200, nil, InitMultiResultDump)
+	testServer.Response(200, map[string]string{"ETag": `"etag1"`}, "")
+	testServer.Response(403, nil, AccessDeniedErrorDump)
+
+	store := s3.FileCheckpointStore{Dir: c.MkDir()}
+	u := &s3.Uploader{Bucket: s.s3.Bucket("sample"), Concurrency: 1, Checkpoints: store}
+	r := strings.NewReader("0123456789abc")
+	err := u.UploadAt("multi", r, 13, "text/plain", s3.Private)
+	c.Assert(err, check.ErrorMatches, "Access Denied")
+	reqs := testServer.WaitRequests(3)
+	c.Assert(reqs[2].Form["partNumber"], check.DeepEquals, []string{"2"})
+
+	// The upload is not aborted, and its progress is recorded.
+	cp, err := store.Load("sample", "multi")
+	c.Assert(err, check.IsNil)
+	c.Assert(cp.UploadId, check.Matches, "JNbR_[A-Za-z0-9.]+QQ--")
+	c.Assert(cp.PartSize, check.Equals, int64(5))
+	c.Assert(cp.Parts, check.DeepEquals, []s3.CheckpointPart{{N: 1, ETag: `"etag1"`, Offset: 0, Size: 5}})
+
+	// Resuming checks which parts S3 has, and sends the others.
+	testServer.Response(200, nil, ListPartsResumeDump)
+	testServer.Response(200, map[string]string{"ETag": `"etag2"`}, "")
+	testServer.Response(200, map[string]string{"ETag": `"etag3"`}, "")
+	testServer.Response(200, nil, "")
+	var progress []s3.UploadProgress
+	u.Progress = func(p s3.UploadProgress) {
+		progress = append(progress, p)
+	}
+	err = u.UploadAt("multi", r, 13, "text/plain", s3.Private)
+	c.Assert(err, check.IsNil)
+
+	reqs = testServer.WaitRequests(4)
+	c.Assert(reqs[0].Method, check.Equals, "GET")
+	c.Assert(reqs[0].Form.Get("uploadId"), check.Equals, cp.UploadId)
+	c.Assert(reqs[1].Form["partNumber"], check.DeepEquals, []string{"2"})
+	c.Assert(readAll(reqs[1].Body), check.Equals, "56789")
+	c.Assert(reqs[2].Form["partNumber"], check.DeepEquals, []string{"3"})
+	c.Assert(readAll(reqs[2].Body), check.Equals, "abc")
+	c.Assert(reqs[3].Method, check.Equals, "POST")
+	var payload struct {
+		Part []struct {
+			PartNumber int
+			ETag       string
+		}
+	}
+	c.Assert(xml.NewDecoder(reqs[3].Body).Decode(&payload), check.IsNil)
+	c.Assert(payload.Part, check.HasLen, 3)
+	c.Assert(payload.Part[0].ETag, check.Equals, `"etag1"`)
+	c.Assert(progress, check.DeepEquals, []s3.UploadProgress{{Parts: 2, Bytes: 10}, {Parts: 3, Bytes: 13}})
+
+	cp, err = store.Load("sample", "multi")
+	c.Assert(err, check.IsNil)
+	c.Assert(cp, check.IsNil)
+}
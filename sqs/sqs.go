@@ -10,9 +10,11 @@
 package sqs
 
 import (
+	"context"
 	"encoding/xml"
 	"errors"
 	"fmt"
+	"github.com/crowdmob/goamz/arn"
 	"github.com/crowdmob/goamz/aws"
 	"io"
 	"io/ioutil"
@@ -21,6 +23,7 @@ import (
 	"net/http/httputil"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -30,6 +33,33 @@ const debug = false
 type SQS struct {
 	aws.Auth
 	aws.Region
+
+	// HTTPClient is used to send requests to SQS. If nil,
+	// http.DefaultClient is used.
+	HTTPClient *http.Client
+
+	// RetryPolicy controls how failed requests are retried. If nil,
+	// aws.DefaultRetryPolicy is used.
+	RetryPolicy *aws.RetryPolicy
+
+	// Handlers holds the hooks run while sending requests. If nil,
+	// aws.DefaultHandlers is used.
+	Handlers *aws.Handlers
+
+	// Clock gives the time requests are signed with, corrected when
+	// SQS reports clock skew. If nil, aws.DefaultClock is used.
+	Clock *aws.Clock
+
+	// Limiter, if set, limits the rate of the requests sent to SQS,
+	// and slows them down when SQS throttles them. It may be shared
+	// between clients.
+	Limiter *aws.RateLimiter
+
+	// SignV2 makes the client sign requests with Signature Version 2,
+	// for legacy endpoints that do not accept Signature Version 4.
+	SignV2 bool
+
+	aws.ContextBinding
 	private byte // Reserve the right of using private data.
 }
 
@@ -57,7 +87,25 @@ func NewFrom(accessKey, secretKey, region string) (*SQS, error) {
 
 // NewFrom Create A new SQS Client from an exisisting aws.Auth
 func New(auth aws.Auth, region aws.Region) *SQS {
-	return &SQS{auth, region, 0}
+	return &SQS{Auth: auth, Region: region, Clock: &aws.Clock{}}
+}
+
+// NewFromConfig creates a new SQS with the credentials, endpoints, HTTP
+// client, retry policy and rate limiter of cfg.
+func NewFromConfig(cfg *aws.Config) *SQS {
+	s := New(cfg.Auth, cfg.Region)
+	s.HTTPClient = cfg.HTTPClient
+	s.RetryPolicy = cfg.RetryPolicy(&aws.DefaultRetryPolicy)
+	s.Limiter = cfg.Limiter
+	return s
+}
+
+// WithContext returns a shallow copy of s bound to ctx; see
+// aws.ContextBinding.
+func (s *SQS) WithContext(ctx context.Context) *SQS {
+	c := *s
+	c.ContextBinding = aws.BindContext(ctx)
+	return &c
 }
 
 // Queue Reference to a Queue
@@ -66,6 +114,11 @@ type Queue struct {
 	Url string
 }
 
+// WithContext returns a copy of q whose operations are bound to ctx.
+func (q *Queue) WithContext(ctx context.Context) *Queue {
+	return &Queue{q.SQS.WithContext(ctx), q.Url}
+}
+
 type CreateQueueResponse struct {
 	QueueUrl         string `xml:"CreateQueueResult>QueueUrl"`
 	ResponseMetadata ResponseMetadata
@@ -141,6 +194,24 @@ func (err *Error) Error() string {
 	return fmt.Sprintf("%s (%s)", err.Message, err.Code)
 }
 
+// ErrorCode returns the SQS error code.
+func (err *Error) ErrorCode() string { return err.Code }
+
+// ErrorMessage returns the message of the error.
+func (err *Error) ErrorMessage() string { return err.Message }
+
+// HTTPStatusCode returns the status code of the HTTP response.
+func (err *Error) HTTPStatusCode() int { return err.StatusCode }
+
+// RequestID returns the ID of the failed request.
+func (err *Error) RequestID() string { return err.RequestId }
+
+// Retryable reports whether sending the request again may succeed.
+func (err *Error) Retryable() bool {
+	retry, _ := retryable(err)
+	return retry
+}
+
 func (err *Error) String() string {
 	return err.Message
 }
@@ -164,6 +235,9 @@ func (s *SQS) CreateQueueWithTimeout(queueName string, timeout int) (*Queue, err
 	return s.CreateQueueWithAttributes(queueName, params)
 }
 
+// CreateQueueWithAttributes creates a queue with the given attributes,
+// as in "VisibilityTimeout" or "Policy". The policy of a queue may be
+// built with the policy package, as the String of a policy.Document.
 func (s *SQS) CreateQueueWithAttributes(queueName string, attrs map[string]string) (q *Queue, err error) {
 	resp, err := s.newQueue(queueName, attrs)
 	if err != nil {
@@ -184,11 +258,105 @@ func (s *SQS) GetQueue(queueName string) (*Queue, error) {
 	return q, nil
 }
 
+// QueueFromArn returns the queue at queueUrl. Despite its name, it takes
+// the URL of the queue; see QueueFromARN for ARNs.
 func (s *SQS) QueueFromArn(queueUrl string) (q *Queue) {
 	q = &Queue{s, queueUrl}
 	return
 }
 
+// QueueFromARN returns the queue named by queueArn, as in
+// "arn:aws:sqs:us-east-1:123456789012:queue1". The URL of the queue is
+// built from the SQS endpoint of s if the queue is in its region, and
+// from the endpoint of the region of the queue otherwise.
+func (s *SQS) QueueFromARN(queueArn string) (*Queue, error) {
+	a, err := arn.Parse(queueArn)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := ""
+	if a.Region == s.Region.Name {
+		endpoint = s.Region.SQSEndpoint
+	}
+	queueUrl, err := queueURL(a, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &Queue{s, queueUrl}, nil
+}
+
+// ARN returns the ARN of q. Queues whose URL does not name a region, as
+// with test servers, are taken to be in the region of q.SQS.
+func (q *Queue) ARN() (arn.ARN, error) {
+	return queueARN(q.Url, q.Region.Name)
+}
+
+// QueueARN returns the ARN of the queue at queueUrl, as in
+// "https://sqs.us-east-1.amazonaws.com/123456789012/queue1".
+func QueueARN(queueUrl string) (arn.ARN, error) {
+	return queueARN(queueUrl, "")
+}
+
+// QueueURL returns the URL of the queue named by queueArn, at the SQS
+// endpoint of its region.
+func QueueURL(queueArn arn.ARN) (string, error) {
+	return queueURL(queueArn, "")
+}
+
+func queueARN(queueUrl, region string) (arn.ARN, error) {
+	u, err := url.Parse(queueUrl)
+	if err != nil {
+		return arn.ARN{}, err
+	}
+	// The path is "/<account>/<queue name>".
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return arn.ARN{}, fmt.Errorf("%q is not the URL of an SQS queue", queueUrl)
+	}
+	if r := queueRegion(u.Hostname()); r != "" {
+		region = r
+	}
+	if region == "" {
+		return arn.ARN{}, fmt.Errorf("no region in SQS queue URL %q", queueUrl)
+	}
+	a := arn.New("sqs", region, parts[0], parts[1])
+	if err := a.Validate(); err != nil {
+		return arn.ARN{}, err
+	}
+	return a, nil
+}
+
+func queueURL(a arn.ARN, endpoint string) (string, error) {
+	if a.Service != "sqs" || a.Region == "" || a.AccountID == "" || strings.Contains(a.Resource, "/") {
+		return "", fmt.Errorf("%s is not the ARN of an SQS queue", a)
+	}
+	if endpoint == "" {
+		e, err := aws.DefaultResolver.ResolveEndpoint("sqs", a.Region)
+		if err != nil {
+			return "", err
+		}
+		endpoint = e.URL
+	}
+	return strings.TrimSuffix(endpoint, "/") + "/" + a.AccountID + "/" + a.Resource, nil
+}
+
+// queueRegion returns the region named by the host of a queue URL, as in
+// "sqs.us-west-2.amazonaws.com", or in the legacy
+// "us-west-2.queue.amazonaws.com" and "queue.amazonaws.com", or "" if
+// there is none.
+func queueRegion(host string) string {
+	labels := strings.Split(host, ".")
+	switch {
+	case len(labels) > 2 && labels[0] == "sqs" && aws.PartitionForRegion(labels[1]) != nil:
+		return labels[1]
+	case len(labels) > 3 && labels[1] == "queue" && aws.PartitionForRegion(labels[0]) != nil:
+		return labels[0]
+	case host == "queue.amazonaws.com":
+		return "us-east-1"
+	}
+	return ""
+}
+
 func (s *SQS) getQueueUrl(queueName string) (resp *GetQueueUrlResponse, err error) {
 	resp = &GetQueueUrlResponse{}
 	params := makeParams("GetQueueUrl")
@@ -402,8 +570,17 @@ func (q *Queue) DeleteMessageBatch(msgList []Message) (resp *DeleteMessageBatchR
 }
 
 func (s *SQS) query(queueUrl string, params map[string]string, resp interface{}) (err error) {
+	policy := aws.RetryPolicyOrDefault(s.RetryPolicy, &aws.DefaultRetryPolicy)
+	info := &aws.RequestInfo{Service: "sqs", Operation: params["Action"], Context: s.Context(), Clock: s.Clock, Limiter: s.Limiter}
+	return s.Handlers.Retry(policy, retryable, info, func() error {
+		// Each attempt is signed afresh.
+		return s.send(info, queueUrl, copyParams(params), resp)
+	})
+}
+
+func (s *SQS) send(info *aws.RequestInfo, queueUrl string, params map[string]string, resp interface{}) (err error) {
 	params["Version"] = "2011-10-01"
-	params["Timestamp"] = time.Now().In(time.UTC).Format(time.RFC3339)
+	params["Timestamp"] = s.Clock.Now().Format(time.RFC3339)
 	var url_ *url.URL
 
 	var path string
@@ -423,18 +600,34 @@ func (s *SQS) query(queueUrl string, params map[string]string, resp interface{})
 	//	return err
 	//}
 
-	if s.Auth.Token() != "" {
-		params["SecurityToken"] = s.Auth.Token()
+	auth, err := s.Auth.Snapshot()
+	if err != nil {
+		return err
 	}
-	sign(s.Auth, "GET", path, params, url_.Host)
-
 	url_.RawQuery = multimap(params).Encode()
 
 	if debug {
 		log.Printf("GET ", url_.String())
 	}
 
-	r, err := http.Get(url_.String())
+	hreq, err := http.NewRequest("GET", url_.String(), nil)
+	if err != nil {
+		return err
+	}
+	r, err := s.Handlers.Send(s.HTTPClient, info, hreq, func(hreq *http.Request) error {
+		if !s.SignV2 {
+			signer := aws.NewV4Signer(auth, "sqs", s.Region)
+			signer.Clock = s.Clock
+			signer.Sign(hreq)
+			return nil
+		}
+		if auth.Token() != "" {
+			params["SecurityToken"] = auth.Token()
+		}
+		sign(auth, "GET", path, params, url_.Host)
+		hreq.URL.RawQuery = multimap(params).Encode()
+		return nil
+	})
 	if err != nil {
 		return err
 	}
@@ -478,6 +671,22 @@ func makeParams(action string) map[string]string {
 	return params
 }
 
+// retryable classifies the errors of failed SQS requests for retries.
+func retryable(err error) (retry, throttled bool) {
+	if e, ok := err.(*Error); ok {
+		return aws.ClassifyResponse(e.StatusCode, e.Code)
+	}
+	return aws.IsTransientNetError(err), false
+}
+
+func copyParams(p map[string]string) map[string]string {
+	c := make(map[string]string, len(p))
+	for k, v := range p {
+		c[k] = v
+	}
+	return c
+}
+
 func multimap(p map[string]string) url.Values {
 	q := make(url.Values, len(p))
 	for k, v := range p {
@@ -251,3 +251,43 @@ func (s *S) TestGetQueueAttributes(c *check.C) {
 
 	c.Assert(err, check.IsNil)
 }
+
+func (s *S) TestQueueARN(c *check.C) {
+	for _, queueUrl := range []string{
+		"https://sqs.us-west-2.amazonaws.com/123456789012/qfoo",
+		"https://us-west-2.queue.amazonaws.com/123456789012/qfoo",
+	} {
+		a, err := QueueARN(queueUrl)
+		c.Assert(err, check.IsNil)
+		c.Assert(a.String(), check.Equals, "arn:aws:sqs:us-west-2:123456789012:qfoo")
+	}
+	a, err := QueueARN("https://queue.amazonaws.com/123456789012/qfoo")
+	c.Assert(err, check.IsNil)
+	c.Assert(a.String(), check.Equals, "arn:aws:sqs:us-east-1:123456789012:qfoo")
+
+	_, err = QueueARN("https://sqs.us-west-2.amazonaws.com/qfoo")
+	c.Assert(err, check.ErrorMatches, `".*" is not the URL of an SQS queue`)
+	_, err = QueueARN(testServer.URL + "/123456789012/qfoo")
+	c.Assert(err, check.ErrorMatches, "no region in SQS queue URL .*")
+
+	sqs := New(s.sqs.Auth, aws.Region{Name: "eu-west-1", SQSEndpoint: testServer.URL})
+	a, err = sqs.QueueFromArn(testServer.URL + "/123456789012/qfoo").ARN()
+	c.Assert(err, check.IsNil)
+	c.Assert(a.String(), check.Equals, "arn:aws:sqs:eu-west-1:123456789012:qfoo")
+}
+
+func (s *S) TestQueueFromARN(c *check.C) {
+	sqs := New(s.sqs.Auth, aws.Region{Name: "eu-west-1", SQSEndpoint: testServer.URL})
+	q, err := sqs.QueueFromARN("arn:aws:sqs:eu-west-1:123456789012:qfoo")
+	c.Assert(err, check.IsNil)
+	c.Assert(q.Url, check.Equals, testServer.URL+"/123456789012/qfoo")
+
+	q, err = sqs.QueueFromARN("arn:aws-cn:sqs:cn-north-1:123456789012:qfoo")
+	c.Assert(err, check.IsNil)
+	c.Assert(q.Url, check.Equals, "https://sqs.cn-north-1.amazonaws.com.cn/123456789012/qfoo")
+
+	_, err = sqs.QueueFromARN("arn:aws:sns:eu-west-1:123456789012:topic")
+	c.Assert(err, check.ErrorMatches, ".* is not the ARN of an SQS queue")
+	_, err = sqs.QueueFromARN("https://sqs.eu-west-1.amazonaws.com/123456789012/qfoo")
+	c.Assert(err, check.ErrorMatches, "invalid ARN .*")
+}
@@ -0,0 +1,53 @@
+package sts
+
+import (
+	"fmt"
+	"github.com/crowdmob/goamz/aws"
+)
+
+// AssumeRoleProvider is an aws.CredentialsProvider handing out the
+// credentials of a role, usually in another account, assumed with the
+// credentials of STS.
+//
+// Each call to Retrieve assumes the role anew. Wrap the provider with
+// aws.NewCredentials, or use NewAssumeRoleCredentials, to share the
+// credentials between clients and have them refreshed before they
+// expire. As an MFA code cannot be used twice, a provider whose options
+// require MFA cannot refresh its credentials; set TokenFunc to obtain a
+// fresh code for each call instead.
+type AssumeRoleProvider struct {
+	STS     *STS
+	Options AssumeRoleOptions
+
+	// TokenFunc, if set, is called for the MFA code of each AssumeRole
+	// request, overriding Options.TokenCode.
+	TokenFunc func() (string, error)
+}
+
+func (p *AssumeRoleProvider) Retrieve() (aws.Auth, error) {
+	options := p.Options
+	if p.TokenFunc != nil {
+		code, err := p.TokenFunc()
+		if err != nil {
+			return aws.Auth{}, err
+		}
+		options.TokenCode = code
+	}
+	resp, err := p.STS.AssumeRole(&options)
+	if err != nil {
+		return aws.Auth{}, err
+	}
+	return resp.Credentials.Auth()
+}
+
+func (p *AssumeRoleProvider) String() string {
+	return fmt.Sprintf("assumed role %s", p.Options.RoleArn)
+}
+
+// NewAssumeRoleCredentials assumes the role described by options with
+// the credentials of sts, and returns the resulting credentials, which
+// are refreshed in the background before they expire. Use their Auth
+// method to obtain the aws.Auth for the clients acting as the role.
+func NewAssumeRoleCredentials(sts *STS, options AssumeRoleOptions) (*aws.Credentials, error) {
+	return aws.NewCredentials(&AssumeRoleProvider{STS: sts, Options: options})
+}
@@ -0,0 +1,452 @@
+// The sts package provides types and functions for interaction with the AWS
+// Security Token Service (STS), which hands out temporary credentials.
+package sts
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"github.com/crowdmob/goamz/aws"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The STS type encapsulates operations with the STS endpoint.
+type STS struct {
+	aws.Auth
+	aws.Region
+
+	// HTTPClient is used to send requests to STS. If nil,
+	// http.DefaultClient is used.
+	HTTPClient *http.Client
+
+	// RetryPolicy controls how failed requests are retried. If nil,
+	// aws.DefaultRetryPolicy is used.
+	RetryPolicy *aws.RetryPolicy
+
+	// Handlers holds the hooks run while sending requests. If nil,
+	// aws.DefaultHandlers is used.
+	Handlers *aws.Handlers
+
+	// Clock gives the time requests are signed with, corrected when
+	// STS reports clock skew. If nil, aws.DefaultClock is used.
+	Clock *aws.Clock
+
+	// Limiter, if set, limits the rate of the requests sent to STS,
+	// and slows them down when STS throttles them. It may be shared
+	// between clients.
+	Limiter *aws.RateLimiter
+
+	// SignV2 makes the client sign requests with Signature Version 2,
+	// for legacy endpoints that do not accept Signature Version 4.
+	SignV2 bool
+
+	aws.ContextBinding
+}
+
+// New creates a new STS instance.
+func New(auth aws.Auth, region aws.Region) *STS {
+	return &STS{Auth: auth, Region: region, Clock: &aws.Clock{}}
+}
+
+// NewFromConfig creates a new STS with the credentials, endpoints, HTTP
+// client, retry policy and rate limiter of cfg.
+func NewFromConfig(cfg *aws.Config) *STS {
+	sts := New(cfg.Auth, cfg.Region)
+	sts.HTTPClient = cfg.HTTPClient
+	sts.RetryPolicy = cfg.RetryPolicy(&aws.DefaultRetryPolicy)
+	sts.Limiter = cfg.Limiter
+	return sts
+}
+
+// WithContext returns a shallow copy of sts bound to ctx; see
+// aws.ContextBinding.
+func (sts *STS) WithContext(ctx context.Context) *STS {
+	c := *sts
+	c.ContextBinding = aws.BindContext(ctx)
+	return &c
+}
+
+// query posts params to the STS endpoint and decodes the response into
+// resp. The request is signed unless signed is false, as is the case for
+// the operations that are authenticated by the token they carry.
+func (sts *STS) query(params map[string]string, resp interface{}, signed bool) error {
+	policy := aws.RetryPolicyOrDefault(sts.RetryPolicy, &aws.DefaultRetryPolicy)
+	info := &aws.RequestInfo{Service: "sts", Operation: params["Action"], Context: sts.Context(), Clock: sts.Clock, Limiter: sts.Limiter}
+	return sts.Handlers.Retry(policy, retryable, info, func() error {
+		// Each attempt is signed afresh.
+		return sts.send(info, copyParams(params), resp, signed)
+	})
+}
+
+func (sts *STS) send(info *aws.RequestInfo, params map[string]string, resp interface{}, signed bool) error {
+	endpoint, err := url.Parse(sts.STSEndpoint)
+	if err != nil {
+		return err
+	}
+	if endpoint.Path == "" {
+		endpoint.Path = "/"
+	}
+	params["Version"] = "2011-06-15"
+	params["Timestamp"] = sts.Clock.Now().Format(time.RFC3339)
+	var auth aws.Auth
+	if signed {
+		if auth, err = sts.Auth.Snapshot(); err != nil {
+			return err
+		}
+	}
+	encoded := multimap(params).Encode()
+	req, err := http.NewRequest("POST", endpoint.String(), strings.NewReader(encoded))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Content-Length", strconv.Itoa(len(encoded)))
+	r, err := sts.Handlers.Send(sts.HTTPClient, info, req, func(req *http.Request) error {
+		switch {
+		case !signed:
+		case !sts.SignV2:
+			signer := aws.NewV4Signer(auth, "sts", sts.signingRegion())
+			signer.Clock = sts.Clock
+			signer.Sign(req)
+		default:
+			// Version 2 signatures go in the body.
+			sign(auth, "POST", endpoint.Path, params, endpoint.Host)
+			encoded := multimap(params).Encode()
+			req.Body = ioutil.NopCloser(strings.NewReader(encoded))
+			req.ContentLength = int64(len(encoded))
+			req.Header.Set("Content-Length", strconv.Itoa(len(encoded)))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+	if r.StatusCode > 200 {
+		return buildError(r)
+	}
+	return xml.NewDecoder(r.Body).Decode(resp)
+}
+
+// signingRegion returns the region requests are signed for. The global
+// STS endpoint of the aws partition is signed for us-east-1.
+func (sts *STS) signingRegion() aws.Region {
+	if sts.Region.Name == "" {
+		return aws.USEast
+	}
+	return aws.Region{Name: aws.SigningRegion("sts", sts.Region.Name)}
+}
+
+func buildError(r *http.Response) error {
+	var (
+		err    Error
+		errors xmlErrors
+	)
+	xml.NewDecoder(r.Body).Decode(&errors)
+	if len(errors.Errors) > 0 {
+		err = errors.Errors[0]
+	}
+	err.RequestId = errors.RequestId
+	err.StatusCode = r.StatusCode
+	if err.Message == "" {
+		err.Message = r.Status
+	}
+	return &err
+}
+
+// retryable classifies the errors of failed STS requests for retries.
+func retryable(err error) (retry, throttled bool) {
+	if e, ok := err.(*Error); ok {
+		return aws.ClassifyResponse(e.StatusCode, e.Code)
+	}
+	return aws.IsTransientNetError(err), false
+}
+
+func copyParams(p map[string]string) map[string]string {
+	c := make(map[string]string, len(p))
+	for k, v := range p {
+		c[k] = v
+	}
+	return c
+}
+
+func multimap(p map[string]string) url.Values {
+	q := make(url.Values, len(p))
+	for k, v := range p {
+		q[k] = []string{v}
+	}
+	return q
+}
+
+func addDuration(params map[string]string, seconds int) {
+	if seconds != 0 {
+		params["DurationSeconds"] = strconv.Itoa(seconds)
+	}
+}
+
+// Credentials holds the temporary security credentials handed out by STS.
+//
+// See http://goo.gl/1Fj1c7 for more details.
+type Credentials struct {
+	AccessKeyId     string
+	SecretAccessKey string
+	SessionToken    string
+	Expiration      time.Time
+}
+
+// Auth returns an aws.Auth signing requests with the credentials. An
+// error is returned if they are incomplete.
+func (c *Credentials) Auth() (aws.Auth, error) {
+	if c.AccessKeyId == "" || c.SecretAccessKey == "" {
+		return aws.Auth{}, errors.New("sts: incomplete credentials")
+	}
+	return aws.NewAuth(c.AccessKeyId, c.SecretAccessKey, c.SessionToken, c.Expiration), nil
+}
+
+// AssumedRoleUser identifies the session created by AssumeRole and
+// AssumeRoleWithWebIdentity.
+type AssumedRoleUser struct {
+	Arn           string
+	AssumedRoleId string
+}
+
+// FederatedUser identifies the session created by GetFederationToken.
+type FederatedUser struct {
+	Arn             string
+	FederatedUserId string
+}
+
+// AssumeRoleOptions holds the parameters of an AssumeRole request.
+// RoleArn and RoleSessionName are required.
+//
+// See http://goo.gl/DZIM6z for more details.
+type AssumeRoleOptions struct {
+	RoleArn         string
+	RoleSessionName string
+
+	// DurationSeconds is how long the credentials remain valid. If
+	// zero, STS picks its default of an hour.
+	DurationSeconds int
+
+	// ExternalId is the identifier agreed on with the account that owns
+	// the role, when its trust policy requires one.
+	ExternalId string
+
+	// Policy further restricts the permissions of the session.
+	Policy string
+
+	// SerialNumber and TokenCode identify the MFA device of the caller
+	// and its current code, when the trust policy requires MFA.
+	SerialNumber string
+	TokenCode    string
+}
+
+// Response to an AssumeRole request.
+//
+// See http://goo.gl/DZIM6z for more details.
+type AssumeRoleResp struct {
+	RequestId        string          `xml:"ResponseMetadata>RequestId"`
+	Credentials      Credentials     `xml:"AssumeRoleResult>Credentials"`
+	AssumedRoleUser  AssumedRoleUser `xml:"AssumeRoleResult>AssumedRoleUser"`
+	PackedPolicySize int             `xml:"AssumeRoleResult>PackedPolicySize"`
+}
+
+// AssumeRole returns temporary credentials for the role described by
+// options, which may belong to another account.
+//
+// See http://goo.gl/DZIM6z for more details.
+func (sts *STS) AssumeRole(options *AssumeRoleOptions) (*AssumeRoleResp, error) {
+	params := map[string]string{
+		"Action":          "AssumeRole",
+		"RoleArn":         options.RoleArn,
+		"RoleSessionName": options.RoleSessionName,
+	}
+	addDuration(params, options.DurationSeconds)
+	if options.ExternalId != "" {
+		params["ExternalId"] = options.ExternalId
+	}
+	if options.Policy != "" {
+		params["Policy"] = options.Policy
+	}
+	if options.SerialNumber != "" {
+		params["SerialNumber"] = options.SerialNumber
+		params["TokenCode"] = options.TokenCode
+	}
+	resp := new(AssumeRoleResp)
+	if err := sts.query(params, resp, true); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Response to a GetSessionToken request.
+//
+// See http://goo.gl/GCFD8h for more details.
+type GetSessionTokenResp struct {
+	RequestId   string      `xml:"ResponseMetadata>RequestId"`
+	Credentials Credentials `xml:"GetSessionTokenResult>Credentials"`
+}
+
+// GetSessionToken returns temporary credentials for the calling user,
+// valid for durationSeconds (or STS's default if zero). serialNumber and
+// tokenCode identify the user's MFA device and its current code; they
+// may be empty when MFA is not in use.
+//
+// See http://goo.gl/GCFD8h for more details.
+func (sts *STS) GetSessionToken(durationSeconds int, serialNumber, tokenCode string) (*GetSessionTokenResp, error) {
+	params := map[string]string{
+		"Action": "GetSessionToken",
+	}
+	addDuration(params, durationSeconds)
+	if serialNumber != "" {
+		params["SerialNumber"] = serialNumber
+		params["TokenCode"] = tokenCode
+	}
+	resp := new(GetSessionTokenResp)
+	if err := sts.query(params, resp, true); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Response to a GetFederationToken request.
+//
+// See http://goo.gl/Qs5tAW for more details.
+type GetFederationTokenResp struct {
+	RequestId        string        `xml:"ResponseMetadata>RequestId"`
+	Credentials      Credentials   `xml:"GetFederationTokenResult>Credentials"`
+	FederatedUser    FederatedUser `xml:"GetFederationTokenResult>FederatedUser"`
+	PackedPolicySize int           `xml:"GetFederationTokenResult>PackedPolicySize"`
+}
+
+// GetFederationToken returns temporary credentials for the federated
+// user name, restricted by policy and valid for durationSeconds (or
+// STS's default if zero).
+//
+// See http://goo.gl/Qs5tAW for more details.
+func (sts *STS) GetFederationToken(name, policy string, durationSeconds int) (*GetFederationTokenResp, error) {
+	params := map[string]string{
+		"Action": "GetFederationToken",
+		"Name":   name,
+	}
+	addDuration(params, durationSeconds)
+	if policy != "" {
+		params["Policy"] = policy
+	}
+	resp := new(GetFederationTokenResp)
+	if err := sts.query(params, resp, true); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// AssumeRoleWithWebIdentityOptions holds the parameters of an
+// AssumeRoleWithWebIdentity request. RoleArn, RoleSessionName and
+// WebIdentityToken are required.
+//
+// See http://goo.gl/9ZFu8c for more details.
+type AssumeRoleWithWebIdentityOptions struct {
+	RoleArn          string
+	RoleSessionName  string
+	WebIdentityToken string
+
+	// ProviderId names the OAuth 2.0 identity provider that issued
+	// WebIdentityToken. It is left empty for OpenID Connect tokens.
+	ProviderId string
+
+	DurationSeconds int
+	Policy          string
+}
+
+// Response to an AssumeRoleWithWebIdentity request.
+//
+// See http://goo.gl/9ZFu8c for more details.
+type AssumeRoleWithWebIdentityResp struct {
+	RequestId                   string          `xml:"ResponseMetadata>RequestId"`
+	Credentials                 Credentials     `xml:"AssumeRoleWithWebIdentityResult>Credentials"`
+	AssumedRoleUser             AssumedRoleUser `xml:"AssumeRoleWithWebIdentityResult>AssumedRoleUser"`
+	SubjectFromWebIdentityToken string          `xml:"AssumeRoleWithWebIdentityResult>SubjectFromWebIdentityToken"`
+	Audience                    string          `xml:"AssumeRoleWithWebIdentityResult>Audience"`
+	Provider                    string          `xml:"AssumeRoleWithWebIdentityResult>Provider"`
+	PackedPolicySize            int             `xml:"AssumeRoleWithWebIdentityResult>PackedPolicySize"`
+}
+
+// AssumeRoleWithWebIdentity returns temporary credentials for a user
+// authenticated by a web identity provider. The request is authenticated
+// by the token it carries, so sts needs no credentials of its own.
+//
+// See http://goo.gl/9ZFu8c for more details.
+func (sts *STS) AssumeRoleWithWebIdentity(options *AssumeRoleWithWebIdentityOptions) (*AssumeRoleWithWebIdentityResp, error) {
+	params := map[string]string{
+		"Action":           "AssumeRoleWithWebIdentity",
+		"RoleArn":          options.RoleArn,
+		"RoleSessionName":  options.RoleSessionName,
+		"WebIdentityToken": options.WebIdentityToken,
+	}
+	addDuration(params, options.DurationSeconds)
+	if options.ProviderId != "" {
+		params["ProviderId"] = options.ProviderId
+	}
+	if options.Policy != "" {
+		params["Policy"] = options.Policy
+	}
+	resp := new(AssumeRoleWithWebIdentityResp)
+	if err := sts.query(params, resp, false); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+type xmlErrors struct {
+	Errors    []Error `xml:"Error"`
+	RequestId string
+}
+
+// Error encapsulates an STS error.
+type Error struct {
+	// HTTP status code of the error.
+	StatusCode int
+
+	// AWS code of the error.
+	Code string
+
+	// Message explaining the error.
+	Message string
+
+	// RequestId of the failed request.
+	RequestId string
+}
+
+func (e *Error) Error() string {
+	var prefix string
+	if e.Code != "" {
+		prefix = e.Code + ": "
+	}
+	if prefix == "" && e.StatusCode > 0 {
+		prefix = strconv.Itoa(e.StatusCode) + ": "
+	}
+	return prefix + e.Message
+}
+
+// ErrorCode returns the STS error code.
+func (e *Error) ErrorCode() string { return e.Code }
+
+// ErrorMessage returns the message of the error.
+func (e *Error) ErrorMessage() string { return e.Message }
+
+// HTTPStatusCode returns the status code of the HTTP response.
+func (e *Error) HTTPStatusCode() int { return e.StatusCode }
+
+// RequestID returns the ID of the failed request.
+func (e *Error) RequestID() string { return e.RequestId }
+
+// Retryable reports whether sending the request again may succeed.
+func (e *Error) Retryable() bool {
+	retry, _ := retryable(e)
+	return retry
+}
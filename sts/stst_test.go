@@ -0,0 +1,137 @@
+package sts_test
+
+import (
+	"github.com/crowdmob/goamz/aws"
+	"github.com/crowdmob/goamz/sts"
+	"github.com/crowdmob/goamz/sts/ststest"
+	"gopkg.in/check.v1"
+	"time"
+)
+
+// LocalServerSuite defines tests that will run
+// against the local ststest server.
+type LocalServerSuite struct {
+	srv *ststest.Server
+	sts *sts.STS
+}
+
+var _ = check.Suite(&LocalServerSuite{})
+
+func (s *LocalServerSuite) SetUpSuite(c *check.C) {
+	srv, err := ststest.NewServer()
+	c.Assert(err, check.IsNil)
+	s.srv = srv
+	auth := aws.Auth{AccessKey: "abc", SecretKey: "123"}
+	s.sts = sts.New(auth, aws.Region{STSEndpoint: srv.URL()})
+}
+
+func (s *LocalServerSuite) TearDownSuite(c *check.C) {
+	s.srv.Quit()
+}
+
+func (s *LocalServerSuite) TestAssumeRoleCrossAccount(c *check.C) {
+	resp, err := s.sts.AssumeRole(&sts.AssumeRoleOptions{
+		RoleArn:         "arn:aws:iam::210987654321:role/ops/deploy",
+		RoleSessionName: "ci-build",
+		DurationSeconds: 900,
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.AssumedRoleUser.Arn, check.Equals, "arn:aws:sts::210987654321:assumed-role/deploy/ci-build")
+	c.Assert(resp.Credentials.AccessKeyId, check.Not(check.Equals), "")
+	left := resp.Credentials.Expiration.Sub(time.Now())
+	c.Assert(left > 890*time.Second && left <= 900*time.Second, check.Equals, true)
+}
+
+func (s *LocalServerSuite) TestAssumeRoleBadArn(c *check.C) {
+	_, err := s.sts.AssumeRole(&sts.AssumeRoleOptions{
+		RoleArn:         "arn:aws:iam::123:user/bob",
+		RoleSessionName: "ci-build",
+	})
+	c.Assert(err, check.ErrorMatches, "ValidationError: .*RoleArn.*")
+}
+
+func (s *LocalServerSuite) TestAssumeRoleBadDuration(c *check.C) {
+	_, err := s.sts.AssumeRole(&sts.AssumeRoleOptions{
+		RoleArn:         "arn:aws:iam::123456789012:role/demo",
+		RoleSessionName: "ci-build",
+		DurationSeconds: 60,
+	})
+	c.Assert(err, check.ErrorMatches, "ValidationError: The requested DurationSeconds .*")
+}
+
+func (s *LocalServerSuite) TestGetSessionTokenBadMFA(c *check.C) {
+	_, err := s.sts.GetSessionToken(0, "arn:aws:iam::123456789012:mfa/bob", "12")
+	c.Assert(err, check.ErrorMatches, "AccessDenied: .*MFA.*")
+	e := err.(*sts.Error)
+	c.Assert(e.StatusCode, check.Equals, 403)
+	c.Assert(e.RequestId, check.Not(check.Equals), "")
+}
+
+func (s *LocalServerSuite) TestGetFederationToken(c *check.C) {
+	resp, err := s.sts.GetFederationToken("Bob", "", 0)
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.FederatedUser.Arn, check.Equals, "arn:aws:sts::123456789012:federated-user/Bob")
+}
+
+func (s *LocalServerSuite) TestAssumeRoleWithWebIdentity(c *check.C) {
+	anon := sts.New(aws.Auth{}, aws.Region{STSEndpoint: s.srv.URL()})
+	resp, err := anon.AssumeRoleWithWebIdentity(&sts.AssumeRoleWithWebIdentityOptions{
+		RoleArn:          "arn:aws:iam::123456789012:role/web",
+		RoleSessionName:  "app1",
+		WebIdentityToken: "token",
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(resp.AssumedRoleUser.Arn, check.Equals, "arn:aws:sts::123456789012:assumed-role/web/app1")
+
+	_, err = anon.AssumeRole(&sts.AssumeRoleOptions{
+		RoleArn:         "arn:aws:iam::123456789012:role/web",
+		RoleSessionName: "app1",
+	})
+	c.Assert(err, check.ErrorMatches, "MissingAuthenticationToken: .*")
+}
+
+func (s *LocalServerSuite) TestAssumeRoleCredentials(c *check.C) {
+	creds, err := sts.NewAssumeRoleCredentials(s.sts, sts.AssumeRoleOptions{
+		RoleArn:         "arn:aws:iam::210987654321:role/deploy",
+		RoleSessionName: "ci-build",
+	})
+	c.Assert(err, check.IsNil)
+	defer creds.Stop()
+	issued := s.srv.Issued()
+	last := issued[len(issued)-1]
+
+	auth := creds.Auth()
+	c.Assert(auth.AccessKey, check.Equals, last.AccessKeyId)
+	c.Assert(auth.Token(), check.Equals, last.SessionToken)
+	c.Assert(auth.Expiration().Equal(last.Expiration), check.Equals, true)
+
+	c.Assert(creds.Refresh(), check.IsNil)
+	issued = s.srv.Issued()
+	last = issued[len(issued)-1]
+	snap, err := auth.Snapshot()
+	c.Assert(err, check.IsNil)
+	c.Assert(snap.AccessKey, check.Equals, last.AccessKeyId)
+	c.Assert(snap.SecretKey, check.Equals, last.SecretAccessKey)
+}
+
+func (s *LocalServerSuite) TestAssumeRoleProviderTokenFunc(c *check.C) {
+	codes := []string{"111111", "222222"}
+	p := &sts.AssumeRoleProvider{
+		STS: s.sts,
+		Options: sts.AssumeRoleOptions{
+			RoleArn:         "arn:aws:iam::123456789012:role/demo",
+			RoleSessionName: "mfa",
+			SerialNumber:    "arn:aws:iam::123456789012:mfa/bob",
+		},
+		TokenFunc: func() (string, error) {
+			code := codes[0]
+			codes = codes[1:]
+			return code, nil
+		},
+	}
+	for i := 0; i < 2; i++ {
+		_, err := p.Retrieve()
+		c.Assert(err, check.IsNil)
+	}
+	c.Assert(codes, check.HasLen, 0)
+}
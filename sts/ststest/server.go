@@ -0,0 +1,333 @@
+// Package ststest implements a fake STS provider that hands out made-up
+// temporary credentials, and keeps track of those it has issued, so that
+// STS clients can be tested offline.
+package ststest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/crowdmob/goamz/arn"
+	"github.com/crowdmob/goamz/sts"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server implements an STS simulator for use in tests.
+type Server struct {
+	reqId    int
+	url      string
+	listener net.Listener
+	issued   []sts.Credentials
+	mutex    sync.Mutex
+}
+
+func NewServer() (*Server, error) {
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return nil, fmt.Errorf("cannot listen on localhost: %v", err)
+	}
+	srv := &Server{
+		listener: l,
+		url:      "http://" + l.Addr().String(),
+	}
+	go http.Serve(l, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		srv.serveHTTP(w, req)
+	}))
+	return srv, nil
+}
+
+// Quit closes down the server.
+func (srv *Server) Quit() error {
+	return srv.listener.Close()
+}
+
+// URL returns a URL for the server.
+func (srv *Server) URL() string {
+	return srv.url
+}
+
+// Issued returns the credentials handed out by the server so far.
+func (srv *Server) Issued() []sts.Credentials {
+	srv.mutex.Lock()
+	defer srv.mutex.Unlock()
+	return append([]sts.Credentials(nil), srv.issued...)
+}
+
+type xmlErrors struct {
+	XMLName   string `xml:"ErrorResponse"`
+	Error     sts.Error
+	RequestId string
+}
+
+func (srv *Server) error(w http.ResponseWriter, err *sts.Error) {
+	w.WriteHeader(err.StatusCode)
+	xmlErr := xmlErrors{Error: *err, RequestId: err.RequestId}
+	if e := xml.NewEncoder(w).Encode(xmlErr); e != nil {
+		panic(e)
+	}
+}
+
+func (srv *Server) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	req.ParseForm()
+	srv.mutex.Lock()
+	defer srv.mutex.Unlock()
+	action := req.FormValue("Action")
+	if action == "" {
+		srv.error(w, &sts.Error{
+			StatusCode: 400,
+			Code:       "MissingAction",
+			Message:    "Missing action",
+		})
+		return
+	}
+	if a, ok := actions[action]; ok {
+		reqId := fmt.Sprintf("req%0X", srv.reqId)
+		srv.reqId++
+		if resp, err := a(srv, w, req, reqId); err == nil {
+			if err := xml.NewEncoder(w).Encode(resp); err != nil {
+				panic(err)
+			}
+		} else {
+			switch err.(type) {
+			case *sts.Error:
+				err.(*sts.Error).RequestId = reqId
+				srv.error(w, err.(*sts.Error))
+			default:
+				panic(err)
+			}
+		}
+	} else {
+		srv.error(w, &sts.Error{
+			StatusCode: 400,
+			Code:       "InvalidAction",
+			Message:    "Invalid action: " + action,
+		})
+	}
+}
+
+var (
+	accountPattern     = regexp.MustCompile(`^\d{12}$`)
+	roleNamePattern    = regexp.MustCompile(`^[\w+=,.@-]+$`)
+	sessionNamePattern = regexp.MustCompile(`^[\w+=,.@-]{2,64}$`)
+	federatedPattern   = regexp.MustCompile(`^[\w+=,.@-]{2,32}$`)
+	tokenCodePattern   = regexp.MustCompile(`^\d{6}$`)
+)
+
+func (srv *Server) assumeRole(w http.ResponseWriter, req *http.Request, reqId string) (interface{}, error) {
+	if err := srv.validate(req, []string{"RoleArn", "RoleSessionName"}); err != nil {
+		return nil, err
+	}
+	if err := srv.validateSigned(req); err != nil {
+		return nil, err
+	}
+	if err := srv.validateMFA(req); err != nil {
+		return nil, err
+	}
+	user, err := srv.assumedRoleUser(req, reqId)
+	if err != nil {
+		return nil, err
+	}
+	creds, err := srv.issue(req, reqId, 3600, 43200)
+	if err != nil {
+		return nil, err
+	}
+	return sts.AssumeRoleResp{
+		RequestId:       reqId,
+		Credentials:     creds,
+		AssumedRoleUser: user,
+	}, nil
+}
+
+func (srv *Server) getSessionToken(w http.ResponseWriter, req *http.Request, reqId string) (interface{}, error) {
+	if err := srv.validateSigned(req); err != nil {
+		return nil, err
+	}
+	if err := srv.validateMFA(req); err != nil {
+		return nil, err
+	}
+	creds, err := srv.issue(req, reqId, 43200, 129600)
+	if err != nil {
+		return nil, err
+	}
+	return sts.GetSessionTokenResp{
+		RequestId:   reqId,
+		Credentials: creds,
+	}, nil
+}
+
+func (srv *Server) getFederationToken(w http.ResponseWriter, req *http.Request, reqId string) (interface{}, error) {
+	if err := srv.validate(req, []string{"Name"}); err != nil {
+		return nil, err
+	}
+	if err := srv.validateSigned(req); err != nil {
+		return nil, err
+	}
+	name := req.FormValue("Name")
+	if !federatedPattern.MatchString(name) {
+		return nil, validationError("Name", name)
+	}
+	creds, err := srv.issue(req, reqId, 43200, 129600)
+	if err != nil {
+		return nil, err
+	}
+	return sts.GetFederationTokenResp{
+		RequestId:   reqId,
+		Credentials: creds,
+		FederatedUser: sts.FederatedUser{
+			Arn:             arn.New("sts", "", "123456789012", "federated-user/"+name).String(),
+			FederatedUserId: "123456789012:" + name,
+		},
+	}, nil
+}
+
+func (srv *Server) assumeRoleWithWebIdentity(w http.ResponseWriter, req *http.Request, reqId string) (interface{}, error) {
+	if err := srv.validate(req, []string{"RoleArn", "RoleSessionName", "WebIdentityToken"}); err != nil {
+		return nil, err
+	}
+	user, err := srv.assumedRoleUser(req, reqId)
+	if err != nil {
+		return nil, err
+	}
+	creds, err := srv.issue(req, reqId, 3600, 43200)
+	if err != nil {
+		return nil, err
+	}
+	provider := req.FormValue("ProviderId")
+	if provider == "" {
+		provider = "accounts.example.com"
+	}
+	return sts.AssumeRoleWithWebIdentityResp{
+		RequestId:                   reqId,
+		Credentials:                 creds,
+		AssumedRoleUser:             user,
+		SubjectFromWebIdentityToken: "subject-" + reqId,
+		Audience:                    "client-" + reqId,
+		Provider:                    provider,
+	}, nil
+}
+
+// assumedRoleUser checks the role and session names of req and returns
+// the user of the session they describe.
+func (srv *Server) assumedRoleUser(req *http.Request, reqId string) (sts.AssumedRoleUser, error) {
+	roleArn := req.FormValue("RoleArn")
+	role, name, ok := parseRoleArn(roleArn)
+	if !ok {
+		return sts.AssumedRoleUser{}, validationError("RoleArn", roleArn)
+	}
+	session := req.FormValue("RoleSessionName")
+	if !sessionNamePattern.MatchString(session) {
+		return sts.AssumedRoleUser{}, validationError("RoleSessionName", session)
+	}
+	roleId := "AROA" + strings.ToUpper(reqId) + "EXAMPLE"
+	return sts.AssumedRoleUser{
+		Arn:           arn.ARN{Partition: role.Partition, Service: "sts", AccountID: role.AccountID, Resource: "assumed-role/" + name + "/" + session}.String(),
+		AssumedRoleId: roleId + ":" + session,
+	}, nil
+}
+
+// parseRoleArn parses the ARN of an IAM role, as in
+// "arn:aws:iam::123456789012:role/path/name", and returns it along with
+// the name of the role.
+func parseRoleArn(roleArn string) (role arn.ARN, name string, ok bool) {
+	role, err := arn.Parse(roleArn)
+	if err != nil || role.Service != "iam" || role.ResourceType() != "role" || !accountPattern.MatchString(role.AccountID) {
+		return role, "", false
+	}
+	path := strings.Split(role.ResourceID(), "/")
+	for _, segment := range path {
+		if !roleNamePattern.MatchString(segment) {
+			return role, "", false
+		}
+	}
+	return role, path[len(path)-1], true
+}
+
+// issue hands out new credentials, valid for the duration requested in
+// req, or def seconds by default.
+func (srv *Server) issue(req *http.Request, reqId string, def, max int) (sts.Credentials, error) {
+	duration := def
+	if s := req.FormValue("DurationSeconds"); s != "" {
+		d, err := strconv.Atoi(s)
+		if err != nil || d < 900 || d > max {
+			return sts.Credentials{}, &sts.Error{
+				StatusCode: 400,
+				Code:       "ValidationError",
+				Message:    fmt.Sprintf("The requested DurationSeconds must be between 900 and %d seconds.", max),
+			}
+		}
+		duration = d
+	}
+	id := strings.ToUpper(reqId)
+	creds := sts.Credentials{
+		AccessKeyId:     "ASIA" + id + "EXAMPLE",
+		SecretAccessKey: "secret" + id,
+		SessionToken:    "token" + id,
+		Expiration:      time.Now().UTC().Add(time.Duration(duration) * time.Second).Truncate(time.Second),
+	}
+	srv.issued = append(srv.issued, creds)
+	return creds, nil
+}
+
+// Validates the presence of required request parameters.
+func (srv *Server) validate(req *http.Request, required []string) error {
+	for _, r := range required {
+		if req.FormValue(r) == "" {
+			return &sts.Error{
+				StatusCode: 400,
+				Code:       "MissingParameter",
+				Message:    fmt.Sprintf("%s is required.", r),
+			}
+		}
+	}
+	return nil
+}
+
+// validateSigned checks that req carries the credentials of a caller.
+func (srv *Server) validateSigned(req *http.Request) error {
+	v2 := req.FormValue("AWSAccessKeyId") != "" && req.FormValue("Signature") != ""
+	v4 := strings.HasPrefix(req.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=") &&
+		!strings.HasPrefix(req.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=/")
+	if !v2 && !v4 {
+		return &sts.Error{
+			StatusCode: 403,
+			Code:       "MissingAuthenticationToken",
+			Message:    "Request is missing Authentication Token",
+		}
+	}
+	return nil
+}
+
+// validateMFA checks the MFA device and code of req, if any.
+func (srv *Server) validateMFA(req *http.Request) error {
+	if req.FormValue("SerialNumber") == "" {
+		return nil
+	}
+	if code := req.FormValue("TokenCode"); !tokenCodePattern.MatchString(code) {
+		return &sts.Error{
+			StatusCode: 403,
+			Code:       "AccessDenied",
+			Message:    "MultiFactorAuthentication failed with invalid MFA one time pass code.",
+		}
+	}
+	return nil
+}
+
+func validationError(name, value string) error {
+	return &sts.Error{
+		StatusCode: 400,
+		Code:       "ValidationError",
+		Message:    fmt.Sprintf("1 validation error detected: Value '%s' at '%s' failed to satisfy constraint", value, name),
+	}
+}
+
+var actions = map[string]func(*Server, http.ResponseWriter, *http.Request, string) (interface{}, error){
+	"AssumeRole":                (*Server).assumeRole,
+	"GetSessionToken":           (*Server).getSessionToken,
+	"GetFederationToken":        (*Server).getFederationToken,
+	"AssumeRoleWithWebIdentity": (*Server).assumeRoleWithWebIdentity,
+}